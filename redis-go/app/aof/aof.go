@@ -0,0 +1,295 @@
+package aof
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/rdb"
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/rs/zerolog"
+)
+
+/* Append only file:
+
+every write command is appended to the file in the same resp format it's
+replicated in, e.g.
+
+	*3\r\n$3\r\nSET\r\n$3\r\nfoo\r\n$3\r\nbar\r\n
+
+a rewrite compacts the file by replacing it with an rdb snapshot of the store
+(the preamble) followed by any writes made whilst the snapshot was being written
+*/
+
+const (
+	FsyncAlways   = "always"   // fsync after every write
+	FsyncEverySec = "everysec" // fsync in the background once a second
+	FsyncNo       = "no"       // leave flushing to the os
+)
+
+var (
+	ErrRewriteInProgress = errors.New("aof rewrite already in progress")
+	ErrInvalidAof        = errors.New("invalid aof file")
+)
+
+type Aof struct {
+	path       string
+	fsync      string
+	file       *os.File
+	dirty      bool          // written to since the last fsync
+	rewriteBuf *bytes.Buffer // writes made whilst a rewrite is in progress
+	stop       chan struct{}
+	logger     zerolog.Logger
+	mu         sync.Mutex
+}
+
+func IsValidFsync(policy string) bool {
+	return policy == FsyncAlways || policy == FsyncEverySec || policy == FsyncNo
+}
+
+// opens the aof for appending, creating it if it doesn't exist
+func Open(path string, fsync string, logger zerolog.Logger) (*Aof, error) {
+	if !IsValidFsync(fsync) {
+		return nil, fmt.Errorf("invalid appendfsync policy %q", fsync)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("error creating aof directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Aof{
+		path:   path,
+		fsync:  fsync,
+		file:   file,
+		stop:   make(chan struct{}),
+		logger: logger,
+	}
+
+	if fsync == FsyncEverySec {
+		go a.fsyncLoop()
+	}
+
+	logger.Info().Str("path", path).Str("appendfsync", fsync).Msg("aof opened")
+
+	return a, nil
+}
+
+func (a *Aof) Append(command string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, err := a.file.WriteString(command); err != nil {
+		return fmt.Errorf("error writing to aof: %w", err)
+	}
+
+	if a.rewriteBuf != nil {
+		a.rewriteBuf.WriteString(command)
+	}
+
+	switch a.fsync {
+	case FsyncAlways:
+		return a.file.Sync()
+	case FsyncEverySec:
+		a.dirty = true
+	}
+
+	return nil
+}
+
+func (a *Aof) fsyncLoop() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.stop:
+			return
+		case <-ticker.C:
+			a.mu.Lock()
+			if a.dirty {
+				if err := a.file.Sync(); err != nil {
+					a.logger.Error().Err(err).Msg("error fsyncing aof")
+				} else {
+					a.dirty = false
+				}
+			}
+			a.mu.Unlock()
+		}
+	}
+}
+
+// starts buffering writes so they can be appended to the rewritten file, the snapshot
+// for the rewrite should be taken after this returns
+func (a *Aof) StartRewrite() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.rewriteBuf != nil {
+		return ErrRewriteInProgress
+	}
+
+	a.rewriteBuf = &bytes.Buffer{}
+	return nil
+}
+
+func (a *Aof) AbortRewrite() {
+	a.mu.Lock()
+	a.rewriteBuf = nil
+	a.mu.Unlock()
+}
+
+func (a *Aof) IsRewriting() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.rewriteBuf != nil
+}
+
+// replaces the aof with the rdb preamble followed by the writes made since the rewrite started
+func (a *Aof) FinishRewrite(preamble []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(a.path), fmt.Sprintf("temp-rewriteaof-%d-*.aof", os.Getpid()))
+	if err != nil {
+		a.AbortRewrite()
+		return fmt.Errorf("error creating temp aof: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	fail := func(err error) error {
+		tmp.Close()
+		a.AbortRewrite()
+		return err
+	}
+
+	// the bulk of the file is written without blocking appends
+	if _, err := tmp.Write(preamble); err != nil {
+		return fail(fmt.Errorf("error writing aof preamble: %w", err))
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.rewriteBuf == nil {
+		tmp.Close()
+		return errors.New("aof rewrite was aborted")
+	}
+
+	if _, err := tmp.Write(a.rewriteBuf.Bytes()); err != nil {
+		tmp.Close()
+		a.rewriteBuf = nil
+		return fmt.Errorf("error writing aof rewrite buffer: %w", err)
+	}
+
+	a.rewriteBuf = nil
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error syncing rewritten aof: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error closing rewritten aof: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), a.path); err != nil {
+		return fmt.Errorf("error replacing aof: %w", err)
+	}
+
+	file, err := os.OpenFile(a.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("error reopening rewritten aof: %w", err)
+	}
+
+	a.file.Close()
+	a.file = file
+	a.dirty = false
+
+	return nil
+}
+
+func (a *Aof) Close() error {
+	close(a.stop)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := a.file.Sync(); err != nil {
+		return err
+	}
+
+	return a.file.Close()
+}
+
+// replays the aof, calling onRdb with the preamble (if any) and then onCommand for
+// every command in order. A truncated command at the end of the file (e.g. from a
+// crash mid write) is discarded and the file truncated, same as redis' aof-load-truncated
+func Load(path string, onRdb func(*rdb.RdbContents), onCommand func(string, resp.RespArray), logger zerolog.Logger) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	offset := 0
+	if bytes.HasPrefix(data, []byte("REDIS")) {
+		contents, n, err := rdb.ParseRdbPrefix(data)
+		if err != nil {
+			return fmt.Errorf("error reading aof rdb preamble: %w", err)
+		}
+
+		onRdb(contents)
+		offset = n
+	}
+
+	rest := data[offset:]
+	lexer := resp.NewLexer(bytes.NewReader(rest))
+	parser := resp.NewParser(lexer)
+
+	loaded := 0 // bytes of complete commands
+	count := 0
+
+	for loaded < len(rest) {
+		value, err := parser.Parse()
+		if err != nil {
+			// only an incomplete command at the very end is recoverable
+			if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+				return fmt.Errorf("%w: bad command at offset %d: %v", ErrInvalidAof, offset+loaded, err)
+			}
+
+			logger.Warn().Int("offset", offset+loaded).Int("discarded", len(rest)-loaded).Msg("aof ends with a truncated command, truncating")
+
+			if err := os.Truncate(path, int64(offset+loaded)); err != nil {
+				return fmt.Errorf("error truncating aof: %w", err)
+			}
+
+			break
+		}
+
+		arr, ok := value.(*resp.RespArray)
+		if !ok || len(arr.Elements) == 0 {
+			return fmt.Errorf("%w: expected a command at offset %d", ErrInvalidAof, offset+loaded)
+		}
+
+		name, ok := arr.Elements[0].(*resp.RespBulkString)
+		if !ok {
+			return fmt.Errorf("%w: expected a command name at offset %d", ErrInvalidAof, offset+loaded)
+		}
+
+		onCommand(name.Content, *arr)
+
+		loaded = lexer.ByteCounter
+		count++
+	}
+
+	logger.Info().Str("path", path).Int("commands", count).Bool("preamble", offset > 0).Msg("aof loaded")
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package aof
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/app/rdb"
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/rs/zerolog"
+)
+
+func TestLoadTruncatedTail(t *testing.T) {
+	// arrange
+	complete := "*3\r\n$3\r\nSET\r\n$3\r\nfoo\r\n$3\r\nbar\r\n"
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	os.WriteFile(path, []byte(complete+"*3\r\n$3\r\nSET\r\n$3\r\nba"), 0o644)
+
+	var commands []string
+
+	// act
+	err := Load(path, func(*rdb.RdbContents) {}, func(command string, _ resp.RespArray) {
+		commands = append(commands, command)
+	}, zerolog.Nop())
+
+	// assert
+	if err != nil {
+		t.Fatalf("expected truncated tail to load but got %v", err)
+	}
+
+	if len(commands) != 1 || commands[0] != "SET" {
+		t.Errorf("expected a single SET but got %v", commands)
+	}
+
+	data, _ := os.ReadFile(path)
+	if string(data) != complete {
+		t.Errorf("expected file truncated to %q but got %q", complete, data)
+	}
+}
+
+func TestLoadCorrupt(t *testing.T) {
+	// arrange
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	os.WriteFile(path, []byte("*1\r\n$4\r\nPING\r\ngarbage\r\n*1\r\n$4\r\nPING\r\n"), 0o644)
+
+	// act
+	err := Load(path, func(*rdb.RdbContents) {}, func(string, resp.RespArray) {}, zerolog.Nop())
+
+	// assert
+	if err == nil {
+		t.Error("expected corrupt aof to fail to load")
+	}
+}
+
+func TestLoadPreamble(t *testing.T) {
+	// arrange
+	preamble, err := rdb.SerializeRdb(rdb.RdbContents{
+		Metadata:  rdb.NewMetadata(),
+		Databases: []rdb.RedisDatabase{{Keys: map[string]interface{}{"a": "1"}, Expiries: map[string]uint64{}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	os.WriteFile(path, append(preamble, "*3\r\n$3\r\nSET\r\n$1\r\nb\r\n$1\r\n2\r\n"...), 0o644)
+
+	var keys int
+	var commands int
+
+	// act
+	err = Load(path, func(contents *rdb.RdbContents) {
+		keys = len(contents.Databases[0].Keys)
+	}, func(string, resp.RespArray) {
+		commands++
+	}, zerolog.Nop())
+
+	// assert
+	if err != nil {
+		t.Fatalf("expected aof to load but got %v", err)
+	}
+
+	if keys != 1 || commands != 1 {
+		t.Errorf("expected 1 key and 1 command but got %d keys and %d commands", keys, commands)
+	}
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/codecrafters-io/redis-starter-go/app/aof"
+	"github.com/codecrafters-io/redis-starter-go/app/rdb"
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/google/uuid"
+)
+
+func HandleBgRewriteAof(ctx HandleContext) (string, error) {
+	if ctx.HostCtx.Aof == nil {
+		return resp.NewRespError("ERR Append only file is disabled").AsRespString(), nil
+	}
+
+	var err error
+	ctx.exclusively(func() { err = ctx.HostCtx.rewriteAof() })
+	if errors.Is(err, aof.ErrRewriteInProgress) {
+		return resp.NewRespError("ERR Background append only file rewriting already in progress").AsRespString(), nil
+	}
+
+	if err != nil {
+		return resp.NewRespError("ERR " + err.Error()).AsRespString(), nil
+	}
+
+	return resp.NewRespSimpleString("Background append only file rewriting started").AsRespString(), nil
+}
+
+// compacts the aof in the background, replacing it with a snapshot of the store. Must be
+// called holding the command lock exclusively, so no command can write between starting
+// the rewrite buffer and taking the snapshot. Only expiries can, and a DEL of a key missing
+// from the snapshot is harmless
+func (h *HostContext) rewriteAof() error {
+	// the rewritten aof starts from the snapshot, so its first command must SELECT
+	h.propagateMu.Lock()
+	err := h.Aof.StartRewrite()
+	if err == nil {
+		h.resetPropagatedDb()
+	}
+	h.propagateMu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	// taken without propagateMu as expiring keys propagate with the store's lock held
+	contents := h.RdbSnapshot()
+
+	go func() {
+		data, err := rdb.SerializeRdb(contents)
+		if err != nil {
+			h.Aof.AbortRewrite()
+			h.Logger.Error().Err(err).Msg("aof rewrite failed serializing snapshot")
+			return
+		}
+
+		if err := h.Aof.FinishRewrite(data); err != nil {
+			h.Logger.Error().Err(err).Msg("aof rewrite failed")
+			return
+		}
+
+		h.Logger.Info().Msg("aof rewrite complete")
+	}()
+
+	return nil
+}
+
+// replays the aof into the store, must be called before serving any connections
+func (h *HostContext) LoadAof(path string) error {
+	h.loading.Store(true)
+	defer h.loading.Store(false)
+
+	connId := uuid.New() // single connection so replayed transactions queue correctly
+	session := &Session{}
+	logger := h.Logger.With().Str("component", "aof_loader").Logger()
+
+	return aof.Load(path, func(contents *rdb.RdbContents) {
+		if err := h.LoadRdb(*contents); err != nil {
+			logger.Fatal().Err(err).Msg("error loading aof preamble")
+		}
+	}, func(command string, arr resp.RespArray) {
+		_, err := HandleCommand(HandleContext{
+			HostCtx: h,
+			RespArr: arr,
+			Logger:  logger.With().Str("command", command).Logger(),
+			ConnId:  connId,
+			Session: session,
+		}, command)
+
+		if err != nil {
+			logger.Error().Err(err).Str("command", command).Msg("error replaying aof command")
+		}
+	}, logger)
+}
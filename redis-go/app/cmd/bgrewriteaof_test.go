@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/aof"
+)
+
+func TestRewriteAofWithRacingWrites(t *testing.T) {
+	// arrange
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+
+	h := newTestHost(t)
+	var err error
+	h.Aof, err = aof.Open(path, aof.FsyncAlways, h.Logger)
+	if err != nil {
+		t.Fatalf("error opening aof: %v", err)
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			client := newTestClient(h)
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					client.do("INCR", "counter")
+				}
+			}
+		}()
+	}
+
+	// act - the rewrite starts whilst the counter is being incremented
+	time.Sleep(10 * time.Millisecond)
+	res := newTestClient(h).do("BGREWRITEAOF")
+	waitFor(t, func() bool { return !h.Aof.IsRewriting() })
+	time.Sleep(10 * time.Millisecond)
+
+	close(stop)
+	wg.Wait()
+	h.Aof.Close()
+
+	reloaded := newTestHost(t)
+	if err := reloaded.LoadAof(path); err != nil {
+		t.Fatalf("error loading aof: %v", err)
+	}
+
+	// assert - every increment is either in the preamble or the rewrite buffer, never both
+	if res != "+Background append only file rewriting started\r\n" {
+		t.Errorf("expected the rewrite to start but got %q", res)
+	}
+
+	want := newTestClient(h).do("GET", "counter")
+	got := newTestClient(reloaded).do("GET", "counter")
+	if got != want {
+		t.Errorf("expected the reloaded counter to match %q but got %q", want, got)
+	}
+}
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+)
+
+// format: BLPOP|BRPOP <KEY> [KEY ...] <TIMEOUT>
+//
+// pops from the first non empty list, blocking until another client pushes to one of
+// the keys or the timeout in seconds expires (0 blocks forever). Replicated as LPOP/RPOP
+func HandleBlockingPop(ctx HandleContext, command string) (string, error) {
+	if len(ctx.RespArr.Elements) < 3 {
+		return resp.WrongArgsError(command).AsRespString(), nil
+	}
+
+	last := len(ctx.RespArr.Elements) - 1
+
+	timeout, errRes := parseBlockingTimeout(ctx.RespArr.Elements[last].(*resp.RespBulkString).Content)
+	if errRes != nil {
+		return errRes.AsRespString(), nil
+	}
+
+	keys := make([]string, 0, last-1)
+	for _, element := range ctx.RespArr.Elements[1:last] {
+		keys = append(keys, element.(*resp.RespBulkString).Content)
+	}
+
+	left := strings.ToLower(command) == "blpop"
+
+	w, pop, err := ctx.Store().BlockListPop(keys, left)
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	if pop != nil {
+		ctx.wrote(ctx.HostCtx.propagateListPops(ctx.Db(), []store.ListPop{*pop}))
+	} else {
+		pop, err = awaitListWaiter(ctx, w, timeout) // propagated by whoever served it
+		if err != nil {
+			return resp.NewRespError(err.Error()).AsRespString(), nil
+		}
+
+		if pop == nil {
+			return resp.NullArray().AsRespString(), nil
+		}
+	}
+
+	return resp.NewRespArrFromStrings([]string{pop.Key, pop.Value}).AsRespString(), nil
+}
+
+// timeouts are in seconds and can be fractional
+func parseBlockingTimeout(value string) (time.Duration, *resp.RespError) {
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+		return 0, resp.NewRespError("ERR timeout is not a float or out of range")
+	}
+
+	if seconds < 0 {
+		return 0, resp.NewRespError("ERR timeout is negative")
+	}
+
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// blocks until the waiter is served, the timeout expires or the client disconnects,
+// returning nil for the latter two. Commands that can't block (e.g. inside a
+// transaction or replayed from the aof) return straight away as if they timed out
+func awaitListWaiter(ctx HandleContext, w *store.ListWaiter, timeout time.Duration) (*store.ListPop, error) {
+	var disconnected <-chan struct{}
+	if ctx.CanBlock {
+		closed, stop := ctx.WatchDisconnect()
+		defer stop()
+		disconnected = closed
+	} else {
+		timeout = time.Nanosecond
+	}
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	var pop store.ListPop
+	served, ok := false, false
+	ctx.unlockedWhile(func() {
+		select {
+		case pop, ok = <-w.Result():
+			served = true
+		case <-expired:
+		case <-disconnected:
+			ctx.Logger.Debug().Msg("client disconnected whilst blocked")
+		}
+	})
+
+	if served {
+		return servedPop(pop, ok)
+	}
+
+	if ctx.Store().Unblock(w) {
+		return nil, nil
+	}
+
+	// served whilst timing out
+	pop, ok = <-w.Result()
+	return servedPop(pop, ok)
+}
+
+func servedPop(pop store.ListPop, ok bool) (*store.ListPop, error) {
+	if !ok {
+		return nil, store.ErrWrongType // the destination of the move changed type
+	}
+
+	return &pop, nil
+}
+
+// replicates pops made on behalf of clients in the order they happened, returning the
+// master offset after the last, see Propagate
+func (h *HostContext) propagateListPops(db int, pops []store.ListPop) int {
+	offset := 0
+	for _, pop := range pops {
+		var command []string
+
+		if pop.Move {
+			command = []string{"LMOVE", pop.Key, pop.Dest, listEnd(pop.Left), listEnd(pop.DestLeft)}
+		} else if pop.Left {
+			command = []string{"LPOP", pop.Key}
+		} else {
+			command = []string{"RPOP", pop.Key}
+		}
+
+		offset = h.Propagate(db, resp.NewRespArrFromStrings(command))
+	}
+
+	return offset
+}
+
+func listEnd(left bool) string {
+	if left {
+		return "LEFT"
+	}
+	return "RIGHT"
+}
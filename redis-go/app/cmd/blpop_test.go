@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBLPopServesBlockedClientsInOrder(t *testing.T) {
+	// arrange
+	h := newTestHost(t)
+	first, second, writer := newTestClient(h), newTestClient(h), newTestClient(h)
+	firstRes := first.doBlocking(t, "BLPOP", "l", "0")
+	secondRes := second.doBlocking(t, "BLPOP", "l", "0")
+
+	// act
+	writer.do("RPUSH", "l", "a", "b")
+
+	// assert
+	for i, c := range []struct {
+		res  <-chan string
+		want string
+	}{
+		{res: firstRes, want: "*2\r\n$1\r\nl\r\n$1\r\na\r\n"},
+		{res: secondRes, want: "*2\r\n$1\r\nl\r\n$1\r\nb\r\n"},
+	} {
+		select {
+		case got := <-c.res:
+			if got != c.want {
+				t.Errorf("expected client %d to get %q but got %q", i+1, c.want, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected client %d to be served", i+1)
+		}
+	}
+}
+
+func TestBLPopDisconnectedClientIsntServed(t *testing.T) {
+	// arrange
+	h := newTestHost(t)
+	gone, writer := newTestClient(h), newTestClient(h)
+	res := gone.doBlocking(t, "BLPOP", "l", "0")
+
+	// act
+	close(gone.disconnect)
+	select {
+	case <-res:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the client to stop blocking once disconnected")
+	}
+
+	writer.do("RPUSH", "l", "a")
+
+	// assert - the element isn't handed to the client that's gone
+	if got := writer.do("LRANGE", "l", "0", "-1"); got != "*1\r\n$1\r\na\r\n" {
+		t.Errorf("expected a to stay in the list but got %q", got)
+	}
+}
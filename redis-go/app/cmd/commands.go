@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// number of elements each command takes including its name, negative where it's a minimum
+// (the same convention as redis' COMMAND INFO). Checked before a command is run or queued
+var commandArity = map[string]int{
+	"bgrewriteaof":     1,
+	"bgsave":           -1,
+	"blmove":           6,
+	"blpop":            -3,
+	"brpop":            -3,
+	"config":           -3,
+	"del":              -2,
+	"discard":          1,
+	"echo":             2,
+	"eval":             -3,
+	"evalsha":          -3,
+	"exec":             1,
+	"expire":           -3,
+	"expireat":         -3,
+	"fcall":            -3,
+	"fcall_ro":         -3,
+	"flushall":         -1,
+	"flushdb":          -1,
+	"function":         -2,
+	"get":              2,
+	"getset":           3,
+	"hdel":             -3,
+	"hexists":          3,
+	"hget":             3,
+	"hgetall":          2,
+	"hincrby":          4,
+	"hkeys":            2,
+	"hlen":             2,
+	"hmget":            -3,
+	"hscan":            -3,
+	"hset":             -4,
+	"hvals":            2,
+	"incr":             2,
+	"info":             -1,
+	"keys":             2,
+	"lindex":           3,
+	"llen":             2,
+	"lmove":            5,
+	"lpop":             -2,
+	"lpush":            -3,
+	"lrange":           4,
+	"lset":             4,
+	"ltrim":            4,
+	"move":             3,
+	"multi":            1,
+	"persist":          2,
+	"pexpire":          -3,
+	"pexpireat":        -3,
+	"ping":             -1,
+	"psetex":           4,
+	"psubscribe":       -2,
+	"psync":            -1,
+	"pttl":             2,
+	"publish":          3,
+	"pubsub":           -2,
+	"punsubscribe":     -1,
+	"replconf":         -3,
+	"replicaof":        3,
+	"rpop":             -2,
+	"rpush":            -3,
+	"sadd":             -3,
+	"save":             1,
+	"scan":             -2,
+	"scard":            2,
+	"script":           -2,
+	"sdiff":            -2,
+	"sdiffstore":       -3,
+	"select":           2,
+	"set":              -3,
+	"setex":            4,
+	"setnx":            3,
+	"sinter":           -2,
+	"sinterstore":      -3,
+	"sismember":        3,
+	"slaveof":          3,
+	"smembers":         2,
+	"spublish":         3,
+	"srem":             -3,
+	"sscan":            -3,
+	"ssubscribe":       -2,
+	"subscribe":        -2,
+	"sunsubscribe":     -1,
+	"sunion":           -2,
+	"sunionstore":      -3,
+	"swapdb":           3,
+	"ttl":              2,
+	"type":             2,
+	"unsubscribe":      -1,
+	"unwatch":          1,
+	"wait":             3,
+	"watch":            -2,
+	"xack":             -4,
+	"xadd":             -5,
+	"xautoclaim":       -6,
+	"xclaim":           -6,
+	"xdel":             -3,
+	"xgroup":           -2,
+	"xinfo":            -2,
+	"xlen":             2,
+	"xpending":         -3,
+	"xrange":           -4,
+	"xread":            -4,
+	"xreadgroup":       -7,
+	"xtrim":            -4,
+	"zadd":             -4,
+	"zcard":            2,
+	"zcount":           4,
+	"zincrby":          4,
+	"zpopmax":          -2,
+	"zpopmin":          -2,
+	"zrange":           -4,
+	"zrangebyscore":    -4,
+	"zrank":            3,
+	"zrem":             -3,
+	"zremrangebylex":   4,
+	"zremrangebyrank":  4,
+	"zremrangebyscore": 4,
+	"zrevrank":         3,
+	"zscan":            -3,
+	"zscore":           3,
+}
+
+// commands that can change the dataset, refused by read only scripts
+var writeCommands = map[string]bool{
+	"blmove":           true,
+	"blpop":            true,
+	"brpop":            true,
+	"del":              true,
+	"expire":           true,
+	"expireat":         true,
+	"flushall":         true,
+	"flushdb":          true,
+	"getset":           true,
+	"hdel":             true,
+	"hincrby":          true,
+	"hset":             true,
+	"incr":             true,
+	"lmove":            true,
+	"lpop":             true,
+	"lpush":            true,
+	"lset":             true,
+	"ltrim":            true,
+	"move":             true,
+	"persist":          true,
+	"pexpire":          true,
+	"pexpireat":        true,
+	"psetex":           true,
+	"rpop":             true,
+	"rpush":            true,
+	"sadd":             true,
+	"sdiffstore":       true,
+	"set":              true,
+	"setex":            true,
+	"setnx":            true,
+	"sinterstore":      true,
+	"srem":             true,
+	"sunionstore":      true,
+	"swapdb":           true,
+	"xack":             true,
+	"xadd":             true,
+	"xautoclaim":       true,
+	"xclaim":           true,
+	"xdel":             true,
+	"xgroup":           true,
+	"xreadgroup":       true,
+	"xtrim":            true,
+	"zadd":             true,
+	"zincrby":          true,
+	"zpopmax":          true,
+	"zpopmin":          true,
+	"zrem":             true,
+	"zremrangebylex":   true,
+	"zremrangebyrank":  true,
+	"zremrangebyscore": true,
+}
+
+// rejects unknown commands and the wrong number of arguments, the errors that make a
+// transaction fail to queue
+func checkCommand(command string, arr resp.RespArray) *resp.RespError {
+	arity, exists := commandArity[command]
+	if !exists {
+		var args strings.Builder
+		for _, el := range arr.Elements[1:] {
+			if s, ok := el.(*resp.RespBulkString); ok {
+				fmt.Fprintf(&args, "'%s' ", s.Content)
+			}
+		}
+		return resp.NewRespError(fmt.Sprintf("ERR unknown command '%s', with args beginning with: %s", command, args.String()))
+	}
+
+	count := len(arr.Elements)
+	if (arity >= 0 && count != arity) || (arity < 0 && count < -arity) {
+		return resp.WrongArgsError(command)
+	}
+
+	return nil
+}
@@ -1,16 +1,47 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/codecrafters-io/redis-starter-go/app/resp"
 )
 
+// configs CONFIG SET can change, applying the value or returning why it's invalid
+var settableConfigs = map[string]func(h *HostContext, value string) error{
+	"notify-keyspace-events": func(h *HostContext, value string) error {
+		if err := h.SetKeyspaceEvents(value); err != nil {
+			return errors.New("Invalid event class character. Use 'Ag$lshzxeKEtmdn'.")
+		}
+		return nil
+	},
+}
+
+// format: CONFIG GET <PARAMETER>
+// format: CONFIG SET <PARAMETER> <VALUE>
 func HandleConfig(ctx HandleContext) (string, error) {
 	op := ctx.RespArr.Elements[1].(*resp.RespBulkString).Content
 
-	if strings.ToLower(op) == "get" {
+	if strings.ToLower(op) == "set" {
+		if len(ctx.RespArr.Elements) != 4 {
+			return resp.WrongArgsError("config|set").AsRespString(), nil
+		}
+
+		params := bulkStrings(ctx.RespArr.Elements[2:])
+		key := strings.ToLower(params[0])
+
+		set, settable := settableConfigs[key]
+		if !settable {
+			return resp.NewRespError(fmt.Sprintf("ERR Unknown option or number of arguments for CONFIG SET - '%s'", params[0])).AsRespString(), nil
+		}
+
+		if err := set(ctx.HostCtx, params[1]); err != nil {
+			return resp.NewRespError(fmt.Sprintf("ERR CONFIG SET failed (possibly related to argument '%s') - %s", key, err)).AsRespString(), nil
+		}
+
+		return resp.OkResponse().AsRespString(), nil
+	} else if strings.ToLower(op) == "get" {
 		key := ctx.RespArr.Elements[2].(*resp.RespBulkString).Content
 		val, exists := ctx.HostCtx.ConfigStore.Get(key)
 
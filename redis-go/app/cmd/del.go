@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// format: DEL <KEY> [KEY ...]
+func HandleDel(ctx HandleContext) (string, error) {
+	if len(ctx.RespArr.Elements) < 2 {
+		return resp.WrongArgsError("del").AsRespString(), nil
+	}
+
+	deleted := ctx.Store().Delete(bulkStrings(ctx.RespArr.Elements[1:]))
+
+	if deleted > 0 {
+		ctx.Propagate(&ctx.RespArr)
+	}
+
+	return resp.NewRespInteger(deleted).AsRespString(), nil
+}
+
+// propagates keys expired by the database's store as DELs, so followers and the aof
+// don't have to expire keys themselves
+func (h *HostContext) PropagateExpired(db int, key string) {
+	h.Propagate(db, resp.NewRespArrFromStrings([]string{"DEL", key}))
+}
@@ -9,6 +9,7 @@ func HandleDiscard(ctx HandleContext) (string, error) {
 	}
 
 	_ = ctx.HostCtx.ConsumeTransactionQueue(ctx.ConnId)
+	ctx.HostCtx.UnwatchAll(ctx.Session)
 
 	return resp.OkResponse().AsRespString(), nil
 }
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/script"
+)
+
+// commands that would break out of the script running as a single unit
+var notFromScripts = map[string]bool{
+	"discard":      true,
+	"eval":         true,
+	"evalsha":      true,
+	"exec":         true,
+	"fcall":        true,
+	"fcall_ro":     true,
+	"function":     true,
+	"multi":        true,
+	"psubscribe":   true,
+	"psync":        true,
+	"punsubscribe": true,
+	"replconf":     true,
+	"replicaof":    true,
+	"script":       true,
+	"slaveof":      true,
+	"ssubscribe":   true,
+	"subscribe":    true,
+	"sunsubscribe": true,
+	"unsubscribe":  true,
+	"unwatch":      true,
+	"wait":         true,
+	"watch":        true,
+}
+
+// format: EVAL <SCRIPT> <NUMKEYS> [KEY ...] [ARG ...]
+// format: EVALSHA <SHA1> <NUMKEYS> [KEY ...] [ARG ...]
+//
+// runs the script with no other command in between. Rather than the script itself its
+// writes are replicated, wrapped in MULTI/EXEC
+func HandleEval(ctx HandleContext, command string) (string, error) {
+	source := ctx.RespArr.Elements[1].(*resp.RespBulkString).Content
+
+	keys, args, errRes := parseScriptArgs(ctx.RespArr.Elements)
+	if errRes != nil {
+		return errRes.AsRespString(), nil
+	}
+
+	sha := source
+	if command == "eval" {
+		var err error
+		sha, err = ctx.HostCtx.Scripts.Load(source)
+		if err != nil {
+			return resp.NewRespError(err.Error()).AsRespString(), nil
+		}
+	}
+
+	reply, exists := ctx.runScript(false, func(call script.Caller) (resp.RespType, bool) {
+		return ctx.HostCtx.Scripts.Run(sha, keys, args, call)
+	})
+	if !exists {
+		return resp.NewRespError("NOSCRIPT No matching script. Please use EVAL.").AsRespString(), nil
+	}
+
+	return reply.AsRespString(), nil
+}
+
+// splits the keys from the args of EVAL, FCALL etc. by the number of keys
+//
+//	format: <COMMAND> <SCRIPT> <NUMKEYS> [KEY ...] [ARG ...]
+func parseScriptArgs(elements []resp.RespType) ([]string, []string, *resp.RespError) {
+	numkeys, err := strconv.Atoi(elements[2].(*resp.RespBulkString).Content)
+	if err != nil {
+		return nil, nil, resp.NewRespError("ERR value is not an integer or out of range")
+	}
+
+	if numkeys < 0 {
+		return nil, nil, resp.NewRespError("ERR Number of keys can't be negative")
+	}
+
+	if numkeys > len(elements)-3 {
+		return nil, nil, resp.NewRespError("ERR Number of keys can't be greater than number of args")
+	}
+
+	rest := bulkStrings(elements[3:])
+	return rest[:numkeys], rest[numkeys:], nil
+}
+
+// runs a script or function as a single unit, its writes are propagated together and a
+// SELECT in it doesn't change the connection's database
+func (ctx HandleContext) runScript(readOnly bool, run func(script.Caller) (resp.RespType, bool)) (resp.RespType, bool) {
+	ctx.HostCtx.beginTransactionPropagation()
+	defer func() { ctx.wrote(ctx.HostCtx.endTransactionPropagation()) }()
+
+	scriptCtx := ctx
+	scriptCtx.Session = &Session{Db: ctx.Db()}
+
+	return run(scriptCtx.scriptCaller(readOnly))
+}
+
+// runs redis.call for a script as the connection that ran it, refusing writes when the
+// script is read only
+func (ctx HandleContext) scriptCaller(readOnly bool) script.Caller {
+	logger := ctx.Logger.With().Str("apply_from", "script").Logger()
+
+	return func(args []string) (resp.RespType, bool) {
+		command := strings.ToLower(args[0])
+		if notFromScripts[command] {
+			return resp.NewRespError("ERR This Redis command is not allowed from script"), false
+		}
+
+		if readOnly && writeCommands[command] {
+			return resp.NewRespError("ERR Write commands are not allowed from read-only scripts."), false
+		}
+
+		before := ctx.HostCtx.bufferedCount()
+
+		res, err := HandleCommand(HandleContext{
+			Conn:    ctx.Conn,
+			ConnId:  ctx.ConnId,
+			HostCtx: ctx.HostCtx,
+			RespArr: *resp.NewRespArrFromStrings(args),
+			Logger:  logger,
+			Session: ctx.Session,
+			nested:  true,
+		}, command)
+
+		wrote := ctx.HostCtx.bufferedCount() > before
+
+		if err != nil {
+			return resp.NewRespError("ERR " + err.Error()), wrote
+		}
+
+		reply, err := resp.NewParser(resp.NewLexer(strings.NewReader(res))).Parse()
+		if err != nil {
+			return resp.NewRespError("ERR " + err.Error()), wrote
+		}
+
+		return reply, wrote
+	}
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEvalWritesArePropagatedAsATransaction(t *testing.T) {
+	// arrange
+	h := newTestHost(t)
+	c := newTestClient(h)
+	c.do("SET", "warm", "up") // so the db is already selected on the replication stream
+	offset := h.PubSubManager.Offset()
+
+	// act
+	res := c.do("EVAL", "redis.call('set', KEYS[1], 'a') redis.call('get', KEYS[1]) return redis.call('incr', KEYS[2])", "2", "k", "n")
+
+	// assert
+	if res != ":1\r\n" {
+		t.Errorf("expected :1 but got %q", res)
+	}
+
+	want := "*1\r\n$5\r\nMULTI\r\n" +
+		"*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\na\r\n" +
+		"*2\r\n$4\r\nincr\r\n$1\r\nn\r\n" +
+		"*1\r\n$4\r\nEXEC\r\n"
+	if replicated := replicatedSince(h, offset); replicated != want {
+		t.Errorf("expected the writes replicated as %q but got %q", want, replicated)
+	}
+}
+
+func TestEvalWithoutWritesIsntPropagated(t *testing.T) {
+	// arrange
+	h := newTestHost(t)
+	c := newTestClient(h)
+	c.do("SET", "k", "v")
+	offset := h.PubSubManager.Offset()
+
+	// act
+	c.do("EVAL", "return redis.call('get', KEYS[1])", "1", "k")
+
+	// assert
+	if replicated := replicatedSince(h, offset); replicated != "" {
+		t.Errorf("expected nothing replicated but got %q", replicated)
+	}
+}
+
+func TestEvalSelectLeavesTheConnectionsDb(t *testing.T) {
+	// arrange
+	h := newTestHost(t)
+	c, db1 := newTestClient(h), newTestClient(h)
+	db1.do("SELECT", "1")
+	offset := h.PubSubManager.Offset()
+
+	// act
+	c.do("EVAL", "redis.call('select', '1') redis.call('set', 'k', 'script')", "0")
+	c.do("SET", "after", "v")
+
+	// assert
+	if got := c.do("GET", "k"); got != "$-1\r\n" {
+		t.Errorf("expected k not set in db 0 but got %q", got)
+	}
+
+	if got := db1.do("GET", "k"); got != "$6\r\nscript\r\n" {
+		t.Errorf("expected the script to set k in db 1 but got %q", got)
+	}
+
+	if got := db1.do("GET", "after"); got != "$-1\r\n" {
+		t.Errorf("expected the connection to still be on db 0 after the script but got %q in db 1", got)
+	}
+
+	// the script's write goes to db 1 and the connection's after it back to db 0
+	replicated := replicatedSince(h, offset)
+	if i, j := strings.Index(replicated, "$6\r\nscript"), strings.LastIndex(replicated, "SELECT\r\n$1\r\n0"); i < 0 || j < i {
+		t.Errorf("expected db 0 selected again after the script's write but got %q", replicated)
+	}
+}
+
+func TestScriptsRejectCommands(t *testing.T) {
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"EVAL", "return redis.pcall('multi')", "0"}, want: "-ERR This Redis command is not allowed from script\r\n"},
+		{args: []string{"EVAL", "return redis.pcall('eval', 'return 1', '0')", "0"}, want: "-ERR This Redis command is not allowed from script\r\n"},
+		{args: []string{"EVAL", "return redis.pcall('subscribe', 'ch')", "0"}, want: "-ERR This Redis command is not allowed from script\r\n"},
+		{args: []string{"EVAL", "return redis.pcall('watch', 'k')", "0"}, want: "-ERR This Redis command is not allowed from script\r\n"},
+		{args: []string{"FCALL_RO", "get_ro", "1", "k"}, want: "$-1\r\n"},
+		{args: []string{"FCALL_RO", "set_ro", "1", "k"}, want: "-ERR Write commands are not allowed from read-only scripts.\r\n"},
+		{args: []string{"FCALL_RO", "set_rw", "1", "k"}, want: "-ERR Can not execute a script with write flag using *_ro command.\r\n"},
+		{args: []string{"FCALL", "set_rw", "1", "k"}, want: "+OK\r\n"},
+	}
+
+	library := `#!lua name=lib
+redis.register_function{function_name = 'get_ro', flags = {'no-writes'}, callback = function(keys) return redis.pcall('get', keys[1]) end}
+redis.register_function{function_name = 'set_ro', flags = {'no-writes'}, callback = function(keys) return redis.pcall('set', keys[1], 'v') end}
+redis.register_function('set_rw', function(keys) return redis.call('set', keys[1], 'v') end)`
+
+	for _, tc := range cases {
+		// arrange
+		h := newTestHost(t)
+		c := newTestClient(h)
+		if res := c.do("FUNCTION", "LOAD", library); res != "$3\r\nlib\r\n" {
+			t.Fatalf("expected the library to load but got %q", res)
+		}
+		offset := h.PubSubManager.Offset()
+
+		// act
+		got := c.do(tc.args...)
+
+		// assert
+		if got != tc.want {
+			t.Errorf("expected %v to reply %q but got %q", tc.args, tc.want, got)
+		}
+
+		if tc.want == "+OK\r\n" {
+			continue
+		}
+
+		if replicated := replicatedSince(h, offset); replicated != "" {
+			t.Errorf("expected nothing replicated for %v but got %q", tc.args, replicated)
+		}
+
+		if value := c.do("GET", "k"); value != "$-1\r\n" {
+			t.Errorf("expected k not to be written by %v but got %q", tc.args, value)
+		}
+	}
+}
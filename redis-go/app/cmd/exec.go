@@ -1,29 +1,51 @@
 package cmd
 
 import (
-	"fmt"
-
 	"github.com/codecrafters-io/redis-starter-go/app/resp"
 )
 
+// format: EXEC
+//
+// runs the queued commands with no other command in between, returning each result in
+// turn. Errors from a command go in its place in the result rather than stopping the rest
 func HandleExec(ctx HandleContext) (string, error) {
 	if !ctx.HostCtx.IsInTransaction(ctx.ConnId) {
 		return resp.NewRespError("ERR EXEC without MULTI").AsRespString(), nil
 	}
 
+	aborted := ctx.HostCtx.TransactionAborted(ctx.ConnId)
 	queue := ctx.HostCtx.ConsumeTransactionQueue(ctx.ConnId)
 
+	changed := ctx.HostCtx.watchesChanged(ctx.Session)
+	ctx.HostCtx.UnwatchAll(ctx.Session)
+
+	if aborted {
+		return resp.NewRespError("EXECABORT Transaction discarded because of previous errors.").AsRespString(), nil
+	}
+
+	if changed {
+		return resp.NullArray().AsRespString(), nil
+	}
+
+	// the writes are replicated together as MULTI ... EXEC
+	ctx.HostCtx.beginTransactionPropagation()
+	defer func() { ctx.wrote(ctx.HostCtx.endTransactionPropagation()) }()
+
 	result := make([]string, 0, len(queue))
 	for _, c := range queue {
 		res, err := HandleCommand(HandleContext{
 			Conn:    ctx.Conn,
+			ConnId:  ctx.ConnId,
 			HostCtx: ctx.HostCtx,
 			RespArr: c.arr,
 			Logger:  ctx.Logger.With().Str("apply_from", "tx").Logger(),
+			Session: ctx.Session,
+			nested:  true,
 		}, c.command)
 
 		if err != nil {
-			return "", fmt.Errorf("got error whilst executing transaction: %w", err)
+			ctx.Logger.Err(err).Str("command", c.command).Msg("error executing queued command")
+			res = resp.NewRespError("ERR " + err.Error()).AsRespString()
 		}
 
 		result = append(result, res)
@@ -31,6 +53,6 @@ func HandleExec(ctx HandleContext) (string, error) {
 
 	final := resp.NewRespArrStringFromRespStrings(result)
 
-	ctx.Logger.Info().Msg(final)
+	ctx.Logger.Debug().Int("replies", len(result)).Msg("transaction executed")
 	return final, nil
 }
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestExecAbortsAfterACommandFailsToQueue(t *testing.T) {
+	cases := []struct {
+		name    string
+		invalid []string
+		err     string
+	}{
+		{name: "unknown command", invalid: []string{"NOSUCHCOMMAND", "a"}, err: "-ERR unknown command 'nosuchcommand', with args beginning with: 'a' \r\n"},
+		{name: "wrong arity", invalid: []string{"GET"}, err: "-ERR wrong number of arguments for 'get' command\r\n"},
+		{name: "subscribe", invalid: []string{"SUBSCRIBE", "ch"}, err: "-ERR Command not allowed inside a transaction\r\n"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// arrange
+			h := newTestHost(t)
+			c := newTestClient(h)
+			c.do("MULTI")
+			c.do("SET", "k", "1")
+
+			// act
+			queueErr := c.do(tc.invalid...)
+			queued := c.do("SET", "j", "1")
+			res := c.do("EXEC")
+
+			// assert
+			if queueErr != tc.err {
+				t.Errorf("expected %q but got %q", tc.err, queueErr)
+			}
+
+			if queued != "+QUEUED\r\n" {
+				t.Errorf("expected later commands to still be queued but got %q", queued)
+			}
+
+			if res != "-EXECABORT Transaction discarded because of previous errors.\r\n" {
+				t.Errorf("expected EXECABORT but got %q", res)
+			}
+
+			if got := c.do("KEYS", "*"); got != "*0\r\n" {
+				t.Errorf("expected none of the queued commands to have run but got %q", got)
+			}
+
+			if got := c.do("EXEC"); got != "-ERR EXEC without MULTI\r\n" {
+				t.Errorf("expected the transaction to be over but got %q", got)
+			}
+		})
+	}
+}
+
+func TestDiscardClearsAnAbortedTransaction(t *testing.T) {
+	// arrange
+	h := newTestHost(t)
+	c := newTestClient(h)
+	c.do("MULTI")
+	c.do("NOSUCHCOMMAND")
+
+	// act
+	c.do("DISCARD")
+	c.do("MULTI")
+	c.do("SET", "k", "1")
+	res := c.do("EXEC")
+
+	// assert
+	if res != "*1\r\n+OK\r\n" {
+		t.Errorf("expected the next transaction to run but got %q", res)
+	}
+}
+
+func TestExecRunsTheRestAfterACommandErrors(t *testing.T) {
+	// arrange
+	h := newTestHost(t)
+	c := newTestClient(h)
+	c.do("MULTI")
+	c.do("SET", "k", "a")
+	c.do("INCR", "k")
+	c.do("SET", "j", "b")
+
+	// act
+	res := c.do("EXEC")
+
+	// assert
+	want := "*3\r\n+OK\r\n-ERR value is not an integer or out of range\r\n+OK\r\n"
+	if res != want {
+		t.Errorf("expected %q but got %q", want, res)
+	}
+}
+
+func TestExecIsIsolatedFromOtherClients(t *testing.T) {
+	// arrange
+	h := newTestHost(t)
+	writer, reader := newTestClient(h), newTestClient(h)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			writer.do("MULTI")
+			writer.do("RPUSH", "l", "a")
+			writer.do("RPUSH", "l", "b")
+			writer.do("EXEC")
+		}
+	}()
+
+	// act - reading the list whilst the transactions run
+	var lengths []int
+	for i := 0; i < 200; i++ {
+		res := reader.do("LLEN", "l")
+		n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(res, ":"), "\r\n"))
+		if err != nil {
+			t.Fatalf("expected an integer reply but got %q", res)
+		}
+		lengths = append(lengths, n)
+	}
+	wg.Wait()
+
+	// assert - both pushes of a transaction are always seen together
+	for _, n := range lengths {
+		if n%2 != 0 {
+			t.Errorf("expected an even length but got %d", n)
+		}
+	}
+}
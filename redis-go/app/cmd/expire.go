@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+)
+
+// format: EXPIRE|PEXPIRE|EXPIREAT|PEXPIREAT <KEY> <TIME> [NX|XX|GT|LT]
+//
+// replies 1 if the expiry was set, 0 if the key doesn't exist or the options prevented it.
+// A time in the past deletes the key
+func HandleExpire(ctx HandleContext, command string) (string, error) {
+	command = strings.ToLower(command)
+
+	if len(ctx.RespArr.Elements) < 3 {
+		return resp.WrongArgsError(command).AsRespString(), nil
+	}
+
+	args := bulkStrings(ctx.RespArr.Elements[1:])
+	key := args[0]
+
+	options := store.ExpireOptions{}
+	for _, arg := range args[2:] {
+		switch strings.ToLower(arg) {
+		case "nx":
+			options.NX = true
+		case "xx":
+			options.XX = true
+		case "gt":
+			options.GT = true
+		case "lt":
+			options.LT = true
+		default:
+			return resp.NewRespError(fmt.Sprintf("ERR Unsupported option %s", arg)).AsRespString(), nil
+		}
+	}
+
+	if options.NX && (options.XX || options.GT || options.LT) {
+		return resp.NewRespError("ERR NX and XX, GT or LT options at the same time are not compatible").AsRespString(), nil
+	}
+
+	if options.GT && options.LT {
+		return resp.NewRespError("ERR GT and LT options at the same time are not compatible").AsRespString(), nil
+	}
+
+	at, errRes := parseExpireTime(command, args[1])
+	if errRes != nil {
+		return errRes.AsRespString(), nil
+	}
+
+	applied, deleted := ctx.Store().Expire(key, at, options)
+	if !applied {
+		return resp.NewRespInteger(0).AsRespString(), nil
+	}
+
+	// propagated as absolute times so replaying later doesn't extend the expiry
+	if deleted {
+		ctx.Propagate(resp.NewRespArrFromStrings([]string{"DEL", key}))
+	} else {
+		ctx.Propagate(resp.NewRespArrFromStrings([]string{"PEXPIREAT", key, strconv.FormatUint(at, 10)}))
+	}
+
+	return resp.NewRespInteger(1).AsRespString(), nil
+}
+
+// unix time in ms for the command's time argument, times before the epoch are clamped
+// to 0 as they've passed either way
+func parseExpireTime(command string, value string) (uint64, *resp.RespError) {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, resp.NewRespError("ERR value is not an integer or out of range")
+	}
+
+	invalid := resp.NewRespError(fmt.Sprintf("ERR invalid expire time in '%s' command", command))
+
+	if command == "expire" || command == "expireat" {
+		if n > math.MaxInt64/1000 || n < math.MinInt64/1000 {
+			return 0, invalid
+		}
+		n *= 1000
+	}
+
+	if command == "expire" || command == "pexpire" {
+		now := time.Now().UnixMilli()
+		if n > math.MaxInt64-now {
+			return 0, invalid
+		}
+		n += now
+	}
+
+	if n < 0 {
+		return 0, nil
+	}
+
+	return uint64(n), nil
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// what the leader has replicated since the offset
+func replicatedSince(h *HostContext, offset int) string {
+	data, _ := h.PubSubManager.Backlog.ReadFrom(offset + 1)
+	return string(data)
+}
+
+func TestExpireFamily(t *testing.T) {
+	soon := strconv.FormatInt(time.Now().Add(100*time.Second).Unix(), 10)
+	soonMs := strconv.FormatInt(time.Now().Add(100*time.Second).UnixMilli(), 10)
+
+	cases := []struct {
+		name  string
+		setup [][]string
+		args  []string
+		want  string
+		ttl   string // TTL reply afterwards
+	}{
+		{name: "EXPIRE", args: []string{"EXPIRE", "k", "100"}, want: ":1\r\n", ttl: ":100\r\n"},
+		{name: "PEXPIRE", args: []string{"PEXPIRE", "k", "100000"}, want: ":1\r\n", ttl: ":100\r\n"},
+		{name: "EXPIREAT", args: []string{"EXPIREAT", "k", soon}, want: ":1\r\n", ttl: ":100\r\n"},
+		{name: "PEXPIREAT", args: []string{"PEXPIREAT", "k", soonMs}, want: ":1\r\n", ttl: ":100\r\n"},
+		{name: "missing key", args: []string{"EXPIRE", "missing", "100"}, want: ":0\r\n", ttl: ":-1\r\n"},
+		{name: "in the past deletes", args: []string{"EXPIRE", "k", "-1"}, want: ":1\r\n", ttl: ":-2\r\n"},
+		{name: "NX without an expiry", args: []string{"EXPIRE", "k", "100", "NX"}, want: ":1\r\n", ttl: ":100\r\n"},
+		{name: "NX with an expiry", setup: [][]string{{"EXPIRE", "k", "50"}}, args: []string{"EXPIRE", "k", "100", "nx"}, want: ":0\r\n", ttl: ":50\r\n"},
+		{name: "XX without an expiry", args: []string{"EXPIRE", "k", "100", "XX"}, want: ":0\r\n", ttl: ":-1\r\n"},
+		{name: "GT later", setup: [][]string{{"EXPIRE", "k", "50"}}, args: []string{"EXPIRE", "k", "100", "GT"}, want: ":1\r\n", ttl: ":100\r\n"},
+		{name: "LT later", setup: [][]string{{"EXPIRE", "k", "50"}}, args: []string{"EXPIRE", "k", "100", "LT"}, want: ":0\r\n", ttl: ":50\r\n"},
+		{name: "NX and XX", args: []string{"EXPIRE", "k", "100", "NX", "XX"}, want: "-ERR NX and XX, GT or LT options at the same time are not compatible\r\n", ttl: ":-1\r\n"},
+		{name: "GT and LT", args: []string{"EXPIRE", "k", "100", "GT", "LT"}, want: "-ERR GT and LT options at the same time are not compatible\r\n", ttl: ":-1\r\n"},
+		{name: "unknown option", args: []string{"EXPIRE", "k", "100", "FOO"}, want: "-ERR Unsupported option FOO\r\n", ttl: ":-1\r\n"},
+		{name: "not an integer", args: []string{"EXPIRE", "k", "1.5"}, want: "-ERR value is not an integer or out of range\r\n", ttl: ":-1\r\n"},
+		{name: "overflows", args: []string{"EXPIRE", "k", "9223372036854775807"}, want: "-ERR invalid expire time in 'expire' command\r\n", ttl: ":-1\r\n"},
+		{name: "PERSIST", setup: [][]string{{"EXPIRE", "k", "50"}}, args: []string{"PERSIST", "k"}, want: ":1\r\n", ttl: ":-1\r\n"},
+		{name: "PERSIST without an expiry", args: []string{"PERSIST", "k"}, want: ":0\r\n", ttl: ":-1\r\n"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// arrange
+			c := newTestClient(newTestHost(t))
+			c.do("SET", "k", "v")
+			for _, args := range tc.setup {
+				c.do(args...)
+			}
+
+			// act
+			res := c.do(tc.args...)
+
+			// assert
+			if res != tc.want {
+				t.Errorf("expected %q but got %q", tc.want, res)
+			}
+
+			// whole second times can land a second short
+			got := c.do("TTL", "k")
+			if want, _ := strconv.Atoi(strings.Trim(tc.ttl, ":\r\n")); got != tc.ttl && (want <= 0 || got != ":"+strconv.Itoa(want-1)+"\r\n") {
+				t.Errorf("expected TTL %q but got %q", tc.ttl, got)
+			}
+		})
+	}
+}
+
+func TestTTLOfMissingKey(t *testing.T) {
+	// arrange
+	c := newTestClient(newTestHost(t))
+
+	// act
+	ttl, pttl := c.do("TTL", "missing"), c.do("PTTL", "missing")
+
+	// assert
+	if ttl != ":-2\r\n" || pttl != ":-2\r\n" {
+		t.Errorf("expected -2 but got %q and %q", ttl, pttl)
+	}
+}
+
+func TestExpireIsReplicatedAsAnAbsoluteTime(t *testing.T) {
+	// arrange
+	h := newTestHost(t)
+	c := newTestClient(h)
+	c.do("SET", "k", "v")
+	offset := h.PubSubManager.Offset()
+
+	// act
+	c.do("EXPIRE", "k", "100")
+
+	// assert
+	replicated := replicatedSince(h, offset)
+	if !strings.Contains(replicated, "PEXPIREAT\r\n$1\r\nk\r\n") {
+		t.Errorf("expected the expiry replicated as PEXPIREAT but got %q", replicated)
+	}
+}
+
+func TestExpiredKeysAreReplicatedAsDel(t *testing.T) {
+	// arrange
+	leader := newTestHost(t)
+	addr := serveTestHost(t, leader)
+	follower := newTestHost(t)
+	followTestHost(t, follower, addr)
+
+	c := newTestClient(leader)
+	c.do("SET", "k", "v", "PX", "30")
+	waitForCatchUp(t, follower, leader)
+	offset := leader.PubSubManager.Offset()
+
+	time.Sleep(50 * time.Millisecond)
+
+	// act - the follower hides the key but waits for the leader to delete it
+	hidden := newTestClient(follower).do("GET", "k")
+	replicatedBefore := replicatedSince(leader, offset)
+	c.do("GET", "k")
+
+	// assert
+	if hidden != "$-1\r\n" {
+		t.Errorf("expected the follower to hide the expired key but got %q", hidden)
+	}
+
+	if replicatedBefore != "" {
+		t.Errorf("expected nothing replicated before the leader expired the key but got %q", replicatedBefore)
+	}
+
+	if replicated := replicatedSince(leader, offset); !strings.HasSuffix(replicated, "*2\r\n$3\r\nDEL\r\n$1\r\nk\r\n") {
+		t.Errorf("expected the expiry replicated as a DEL but got %q", replicated)
+	}
+
+	waitForCatchUp(t, follower, leader)
+	if got := newTestClient(follower).do("GET", "k"); got != "$-1\r\n" {
+		t.Errorf("expected the follower to have deleted the key but got %q", got)
+	}
+}
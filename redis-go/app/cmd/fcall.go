@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/script"
+)
+
+// format: FCALL <FUNCTION> <NUMKEYS> [KEY ...] [ARG ...]
+// format: FCALL_RO <FUNCTION> <NUMKEYS> [KEY ...] [ARG ...]
+//
+// calls a function loaded by FUNCTION LOAD, which runs like EVAL. FCALL_RO can only call
+// functions flagged no-writes
+func HandleFCall(ctx HandleContext, command string) (string, error) {
+	name := ctx.RespArr.Elements[1].(*resp.RespBulkString).Content
+
+	keys, args, errRes := parseScriptArgs(ctx.RespArr.Elements)
+	if errRes != nil {
+		return errRes.AsRespString(), nil
+	}
+
+	info, exists := ctx.HostCtx.Scripts.Function(name)
+	if !exists {
+		return resp.NewRespError(script.ErrFunctionNotFound.Error()).AsRespString(), nil
+	}
+
+	if command == "fcall_ro" && !info.NoWrites() {
+		return resp.NewRespError("ERR Can not execute a script with write flag using *_ro command.").AsRespString(), nil
+	}
+
+	reply, exists := ctx.runScript(info.NoWrites(), func(call script.Caller) (resp.RespType, bool) {
+		return ctx.HostCtx.Scripts.Call(name, keys, args, call)
+	})
+	if !exists {
+		return resp.NewRespError(script.ErrFunctionNotFound.Error()).AsRespString(), nil
+	}
+
+	return reply.AsRespString(), nil
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// format: FLUSHDB [ASYNC|SYNC] or FLUSHALL [ASYNC|SYNC]
+//
+// deletes every key in the selected database, or in every database for FLUSHALL. Flushes
+// are always synchronous
+func HandleFlush(ctx HandleContext, command string) (string, error) {
+	command = strings.ToLower(command)
+
+	if len(ctx.RespArr.Elements) > 2 {
+		return resp.WrongArgsError(command).AsRespString(), nil
+	}
+
+	if len(ctx.RespArr.Elements) == 2 {
+		switch strings.ToLower(ctx.RespArr.Elements[1].(*resp.RespBulkString).Content) {
+		case "async", "sync":
+		default:
+			return resp.NewRespError("ERR syntax error").AsRespString(), nil
+		}
+	}
+
+	if command == "flushall" {
+		for _, db := range ctx.HostCtx.Databases {
+			db.Flush()
+		}
+		ctx.wrote(ctx.HostCtx.Propagate(-1, &ctx.RespArr))
+	} else {
+		ctx.Store().Flush()
+		ctx.Propagate(&ctx.RespArr)
+	}
+
+	return resp.OkResponse().AsRespString(), nil
+}
@@ -0,0 +1,313 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/rdb"
+	"github.com/codecrafters-io/redis-starter-go/app/replication"
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/google/uuid"
+)
+
+var errReplicationStopped = errors.New("replication stopped")
+
+const (
+	minReconnectBackoff = 100 * time.Millisecond
+	maxReconnectBackoff = 5 * time.Second
+)
+
+// the running replication loop when this host is a follower
+type follower struct {
+	stop    chan struct{}
+	done    chan struct{}
+	conn    net.Conn // current link to the leader, closed to interrupt the listener
+	session *Session // kept across reconnects as a partial resync continues the stream
+	mu      sync.Mutex
+}
+
+func (f *follower) setConn(conn net.Conn) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	select {
+	case <-f.stop:
+		return false
+	default:
+		f.conn = conn
+		return true
+	}
+}
+
+// tells the loop to stop and interrupts the listener, without waiting for either
+func (f *follower) signal() {
+	f.mu.Lock()
+	close(f.stop)
+	if f.conn != nil {
+		f.conn.Close()
+	}
+	f.mu.Unlock()
+}
+
+func (f *follower) close() {
+	f.signal()
+	<-f.done
+}
+
+// follows the leader, replacing any existing replication. The follower keeps connected
+// to the leader, reconnecting with backoff whenever the link drops and resuming from the
+// backlog where the leader still has it.
+//
+// Callers stop any existing replication first, one that's still running is only told to
+// stop as waiting for it here could deadlock on the command lock
+func (h *HostContext) StartReplication(leaderAddr string) {
+	if f := h.detachFollower(); f != nil {
+		f.signal()
+	}
+
+	// the leader propagates its expiries as DELs
+	h.setReplicaMode(true)
+
+	logger := h.Logger.With().Str("component", "replication").Str("leader", leaderAddr).Logger()
+
+	f := &follower{
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+		session: &Session{},
+	}
+
+	h.mu.Lock()
+	h.LeaderAddr = leaderAddr
+	h.follower = f
+	h.mu.Unlock()
+
+	go func() {
+		defer close(f.done)
+
+		replid := "?"
+		offset := -1
+		backoff := minReconnectBackoff
+
+		for {
+			repl_client, err := h.connectToLeader(f, leaderAddr, replid, offset)
+			if err != nil {
+				logger.Error().Err(err).Dur("backoff", backoff).Msg("error syncing with leader, retrying")
+
+				select {
+				case <-f.stop:
+					return
+				case <-time.After(backoff):
+				}
+
+				backoff = min(backoff*2, maxReconnectBackoff)
+				continue
+			}
+
+			if !f.setConn(repl_client.Conn) {
+				repl_client.Conn.Close()
+				return
+			}
+
+			backoff = minReconnectBackoff
+			h.whileFollowing(f, func() { h.LeaderLinkUp.Store(true) })
+
+			// the leader's stream after a full resync starts with a SELECT
+			if repl_client.FullResync() {
+				f.session.Db = 0
+			}
+
+			h.runReplicationListener(repl_client.Conn, repl_client.Reader, f.session, f.stop)
+
+			h.whileFollowing(f, func() { h.LeaderLinkUp.Store(false) })
+			repl_client.Conn.Close()
+
+			select {
+			case <-f.stop:
+				logger.Info().Msg("stopped replicating from leader")
+				return
+			default:
+			}
+
+			// resume from wherever the listener got up to
+			h.mu.Lock()
+			replid = h.LeaderReplId
+			offset = h.ProcessedBytes
+			h.mu.Unlock()
+
+			logger.Warn().Int("offset", offset).Msg("lost connection to leader, reconnecting")
+		}
+	}()
+}
+
+// stops following the leader, once returned no more replicated commands are applied.
+// Must not be called holding the command lock, as the listener may be waiting on it
+func (h *HostContext) StopReplication() {
+	if f := h.detachFollower(); f != nil {
+		f.close()
+	}
+}
+
+// stops following the leader from a command, which holds the command lock. It's released
+// whilst waiting for the listener, which may be queued on it behind another client's EXEC.
+// Nested commands hold it exclusively, so the listener can't be part way through a command
+// and only needs telling to stop, it skips anything it reads once it gets the lock
+func (ctx HandleContext) stopReplication() {
+	if ctx.nested {
+		if f := ctx.HostCtx.detachFollower(); f != nil {
+			f.signal()
+		}
+		return
+	}
+
+	ctx.unlockedWhile(ctx.HostCtx.StopReplication)
+}
+
+func (h *HostContext) detachFollower() *follower {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	f := h.follower
+	h.follower = nil
+	return f
+}
+
+// runs fn with mu held if f is still the host's follower, so a stopped follower that hasn't
+// finished yet can't overwrite the replication state of the one that replaced it
+func (h *HostContext) whileFollowing(f *follower, fn func()) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.follower != f {
+		return false
+	}
+
+	fn()
+	return true
+}
+
+func (h *HostContext) connectToLeader(f *follower, leaderAddr string, replid string, offset int) (replication.ReplicationClient, error) {
+	// a full resync replaces the store, once the follower has been stopped nothing more
+	// from this leader is loaded
+	load := func(contents rdb.RdbContents) error {
+		h.commandMu.Lock()
+		defer h.commandMu.Unlock()
+
+		if !h.whileFollowing(f, func() {}) {
+			return errReplicationStopped
+		}
+
+		if err := h.LoadRdb(contents); err != nil {
+			return err
+		}
+		h.dropFollowers()
+
+		// the snapshot bypasses the aof, so rewrite it to match the store
+		if h.Aof != nil {
+			if err := h.rewriteAof(); err != nil {
+				h.Logger.Error().Err(err).Msg("error rewriting aof after full resync")
+			}
+		}
+
+		return nil
+	}
+
+	repl_client, err := replication.NewReplicationClient(leaderAddr, h.Port, load, h.Logger.With().Str("component", "replclient").Logger())
+	if err != nil {
+		return repl_client, fmt.Errorf("error creating connection to leader: %w", err)
+	}
+
+	repl_client.SetReplicationState(replid, offset)
+
+	err = repl_client.SendHandshake()
+	if err != nil {
+		repl_client.Conn.Close()
+		return repl_client, fmt.Errorf("error sending handshake: %w", err)
+	}
+
+	err = repl_client.PSync()
+	if err != nil {
+		repl_client.Conn.Close()
+		return repl_client, fmt.Errorf("error psyncing: %w", err)
+	}
+
+	following := h.whileFollowing(f, func() {
+		h.LeaderReplId = repl_client.ReplId()
+		h.ProcessedBytes = repl_client.Offset()
+	})
+
+	if !following {
+		repl_client.Conn.Close()
+		return repl_client, errReplicationStopped
+	}
+
+	return repl_client, nil
+}
+
+// applies the replication stream from the leader until the connection drops or replication
+// is stopped
+func (h *HostContext) runReplicationListener(conn net.Conn, reader io.Reader, session *Session, stop <-chan struct{}) {
+	logger := h.Logger.With().Str("component", "repl_listener").Logger()
+	logger.Info().Msg("Starting replication listener...")
+
+	lexer := resp.NewLexer(reader)
+	parser := resp.NewParser(lexer)
+	connId := uuid.New()
+
+	for {
+		p := lexer.ByteCounter
+		c, arr, err := ParseServerCommand(*parser)
+		if err != nil {
+			if err == io.EOF {
+				logger.Debug().Msg("EOF")
+				return
+			}
+
+			// can't find the start of the next command once the stream is broken, so
+			// drop the link and resync
+			logger.Err(err).Msg("error parsing the server command")
+			return
+		}
+
+		logger.Info().Int("elements", len(arr.Elements)).Str("command", c).Msg("got replication command")
+
+		commandCtx := HandleContext{
+			Conn:    conn,
+			HostCtx: h,
+			RespArr: arr,
+			Logger:  logger.With().Str("command", c).Logger(),
+			ConnId:  connId,
+			Session: session,
+			stop:    stop,
+		}
+
+		res, err := HandleCommand(commandCtx, c)
+		if errors.Is(err, errReplicationStopped) {
+			return
+		}
+
+		if err != nil {
+			logger.Err(err).Msg("error handling command")
+		}
+
+		// deliberately don't send response to conn for most replication commands
+		//		TODO need a better way to handle this
+		if err == nil && strings.ToLower(c) == "replconf" && strings.ToLower(arr.Elements[1].(*resp.RespBulkString).Content) == "getack" {
+			conn.Write([]byte(res))
+		}
+
+		h.AppendProcessedBytes(lexer.ByteCounter - p)
+		logger.Debug().Int("processed_bytes", lexer.ByteCounter-p).Msg("Processed bytes")
+	}
+}
+
+// replicas leave expiring keys to the leader, see store.KvStore.SetReplicaMode
+func (h *HostContext) setReplicaMode(replica bool) {
+	for _, db := range h.Databases {
+		db.SetReplicaMode(replica)
+	}
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// a leader that can be taken off the network and brought back on the same address
+type droppableLeader struct {
+	t     *testing.T
+	h     *HostContext
+	addr  string
+	mu    sync.Mutex
+	l     net.Listener
+	conns []net.Conn
+}
+
+// an address for the leader, with nothing listening on it until it's brought up
+func newDroppableLeader(t *testing.T, h *HostContext) *droppableLeader {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error listening: %v", err)
+	}
+	l.Close()
+
+	d := &droppableLeader{t: t, h: h, addr: l.Addr().String()}
+	t.Cleanup(d.down)
+	return d
+}
+
+func (d *droppableLeader) up() {
+	d.t.Helper()
+
+	l, err := net.Listen("tcp", d.addr)
+	if err != nil {
+		d.t.Skipf("couldn't listen on %s again: %v", d.addr, err)
+	}
+
+	d.mu.Lock()
+	d.l = l
+	d.mu.Unlock()
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			d.mu.Lock()
+			d.conns = append(d.conns, conn)
+			d.mu.Unlock()
+
+			go serveTestConn(d.h, conn)
+		}
+	}()
+}
+
+// stops listening and drops every connection, as if the network failed
+func (d *droppableLeader) down() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.l != nil {
+		d.l.Close()
+		d.l = nil
+	}
+
+	for _, conn := range d.conns {
+		conn.Close()
+	}
+	d.conns = nil
+}
+
+func linkStatus(h *HostContext) string {
+	info := newTestClient(h).do("INFO", "replication")
+	for _, line := range strings.Split(info, "\r\n") {
+		if status, found := strings.CutPrefix(line, "master_link_status:"); found {
+			return status
+		}
+	}
+	return ""
+}
+
+func TestFollowerReconnectsWhenTheLinkDrops(t *testing.T) {
+	// arrange
+	leader := newTestHost(t)
+	d := newDroppableLeader(t, leader)
+	d.up()
+	follower := newTestHost(t)
+	followTestHost(t, follower, d.addr)
+	waitForCatchUp(t, follower, leader)
+
+	if status := linkStatus(follower); status != "up" {
+		t.Fatalf("expected the link to be up but got %q", status)
+	}
+
+	// act
+	d.down()
+	waitFor(t, func() bool { return !follower.LeaderLinkUp.Load() })
+	status := linkStatus(follower)
+
+	newTestClient(leader).do("SET", "k", "whilst down")
+	d.up()
+
+	// assert - reconnects by itself and catches up
+	if status != "down" {
+		t.Errorf("expected the link to be down once dropped but got %q", status)
+	}
+
+	waitForCatchUp(t, follower, leader)
+
+	if status := linkStatus(follower); status != "up" {
+		t.Errorf("expected the link to be up again but got %q", status)
+	}
+
+	if got := newTestClient(follower).do("GET", "k"); got != "$11\r\nwhilst down\r\n" {
+		t.Errorf("expected the write made whilst down to be replicated but got %q", got)
+	}
+}
+
+func TestFollowerRetriesUntilTheLeaderIsUp(t *testing.T) {
+	// arrange
+	leader := newTestHost(t)
+	newTestClient(leader).do("SET", "k", "v")
+	d := newDroppableLeader(t, leader)
+
+	follower := newTestHost(t)
+	followTestHost(t, follower, d.addr)
+
+	// act
+	time.Sleep(50 * time.Millisecond) // a failed attempt or two
+	status := linkStatus(follower)
+	d.up()
+
+	// assert
+	if status != "down" {
+		t.Errorf("expected the link to be down without a leader but got %q", status)
+	}
+
+	waitForCatchUp(t, follower, leader)
+
+	if got := newTestClient(follower).do("GET", "k"); got != "$1\r\nv\r\n" {
+		t.Errorf("expected the leader's data once connected but got %q", got)
+	}
+}
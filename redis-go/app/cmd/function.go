@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/glob"
+	"github.com/codecrafters-io/redis-starter-go/app/rdb"
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/script"
+)
+
+// format: FUNCTION LOAD [REPLACE] <LIBRARY CODE>
+// format: FUNCTION DELETE <LIBRARY>
+// format: FUNCTION FLUSH [ASYNC | SYNC]
+// format: FUNCTION LIST [LIBRARYNAME <PATTERN>] [WITHCODE]
+// format: FUNCTION DUMP
+// format: FUNCTION RESTORE <PAYLOAD> [FLUSH | APPEND | REPLACE]
+// format: FUNCTION KILL
+//
+// libraries are kept in the rdb, and changes to them replicated as they're made
+func HandleFunction(ctx HandleContext) (string, error) {
+	elements := ctx.RespArr.Elements
+	subcommand := strings.ToLower(elements[1].(*resp.RespBulkString).Content)
+	args := bulkStrings(elements[2:])
+
+	switch subcommand {
+	case "load":
+		replace := len(args) == 2 && strings.EqualFold(args[0], "replace")
+		if len(args) != 1 && !replace {
+			return resp.WrongArgsError("function|load").AsRespString(), nil
+		}
+
+		name, err := ctx.HostCtx.Scripts.LoadLibrary(args[len(args)-1], replace)
+		if err != nil {
+			return resp.NewRespError(err.Error()).AsRespString(), nil
+		}
+
+		ctx.wrote(ctx.HostCtx.Propagate(-1, &ctx.RespArr))
+		return resp.NewRespBulkString(name).AsRespString(), nil
+	case "delete":
+		if len(args) != 1 {
+			return resp.WrongArgsError("function|delete").AsRespString(), nil
+		}
+
+		if err := ctx.HostCtx.Scripts.DeleteLibrary(args[0]); err != nil {
+			return resp.NewRespError(err.Error()).AsRespString(), nil
+		}
+
+		ctx.wrote(ctx.HostCtx.Propagate(-1, &ctx.RespArr))
+		return resp.OkResponse().AsRespString(), nil
+	case "flush":
+		if len(args) > 1 {
+			return resp.WrongArgsError("function|flush").AsRespString(), nil
+		}
+
+		// the libraries are dropped straight away either way
+		if len(args) == 1 && !strings.EqualFold(args[0], "async") && !strings.EqualFold(args[0], "sync") {
+			return resp.NewRespError("ERR FUNCTION FLUSH only supports SYNC|ASYNC option").AsRespString(), nil
+		}
+
+		ctx.HostCtx.Scripts.FlushLibraries()
+		ctx.wrote(ctx.HostCtx.Propagate(-1, &ctx.RespArr))
+		return resp.OkResponse().AsRespString(), nil
+	case "list":
+		return functionList(ctx, args)
+	case "dump":
+		if len(args) != 0 {
+			return resp.WrongArgsError("function|dump").AsRespString(), nil
+		}
+
+		return resp.NewRespBulkString(string(rdb.DumpFunctions(ctx.HostCtx.libraryCodes()))).AsRespString(), nil
+	case "restore":
+		return functionRestore(ctx, args)
+	case "kill":
+		return HandleScriptKill(ctx)
+	default:
+		return resp.NewRespError("ERR unknown subcommand '" + subcommand + "'. Try FUNCTION HELP.").AsRespString(), nil
+	}
+}
+
+func functionList(ctx HandleContext, args []string) (string, error) {
+	pattern := ""
+	withCode := false
+
+	for i := 0; i < len(args); i++ {
+		switch strings.ToLower(args[i]) {
+		case "withcode":
+			withCode = true
+		case "libraryname":
+			if i+1 >= len(args) {
+				return resp.NewRespError("ERR library name argument was not given").AsRespString(), nil
+			}
+			pattern = args[i+1]
+			i++
+		default:
+			return resp.NewRespError("ERR Unknown argument " + args[i]).AsRespString(), nil
+		}
+	}
+
+	libraries := make([]resp.RespType, 0)
+	for _, lib := range ctx.HostCtx.Scripts.Libraries() {
+		if pattern != "" && !glob.Match(pattern, lib.Name) {
+			continue
+		}
+
+		functions := make([]resp.RespType, 0, len(lib.Functions))
+		for _, fn := range lib.Functions {
+			description := resp.NullBulkString()
+			if fn.Description != "" {
+				description = resp.NewRespBulkString(fn.Description)
+			}
+
+			functions = append(functions, resp.NewRespArray([]resp.RespType{
+				resp.NewRespBulkString("name"), resp.NewRespBulkString(fn.Name),
+				resp.NewRespBulkString("description"), description,
+				resp.NewRespBulkString("flags"), resp.NewRespArrFromStrings(fn.Flags),
+			}))
+		}
+
+		fields := []resp.RespType{
+			resp.NewRespBulkString("library_name"), resp.NewRespBulkString(lib.Name),
+			resp.NewRespBulkString("engine"), resp.NewRespBulkString("LUA"),
+			resp.NewRespBulkString("functions"), resp.NewRespArray(functions),
+		}
+		if withCode {
+			fields = append(fields, resp.NewRespBulkString("library_code"), resp.NewRespBulkString(lib.Code))
+		}
+
+		libraries = append(libraries, resp.NewRespArray(fields))
+	}
+
+	return resp.NewRespArray(libraries).AsRespString(), nil
+}
+
+func functionRestore(ctx HandleContext, args []string) (string, error) {
+	if len(args) != 1 && len(args) != 2 {
+		return resp.WrongArgsError("function|restore").AsRespString(), nil
+	}
+
+	policy := script.RestoreAppend
+	if len(args) == 2 {
+		switch strings.ToLower(args[1]) {
+		case "append":
+		case "replace":
+			policy = script.RestoreReplace
+		case "flush":
+			policy = script.RestoreFlush
+		default:
+			return resp.NewRespError("ERR Wrong restore policy given, value should be either FLUSH, APPEND or REPLACE.").AsRespString(), nil
+		}
+	}
+
+	codes, err := rdb.RestoreFunctions([]byte(args[0]))
+	if err != nil {
+		return resp.NewRespError("ERR " + err.Error()).AsRespString(), nil
+	}
+
+	if err := ctx.HostCtx.Scripts.RestoreLibraries(codes, policy); err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	ctx.wrote(ctx.HostCtx.Propagate(-1, &ctx.RespArr))
+	return resp.OkResponse().AsRespString(), nil
+}
+
+// the code of every library, as kept in the rdb
+func (h *HostContext) libraryCodes() []string {
+	libraries := h.Scripts.Libraries()
+
+	codes := make([]string, 0, len(libraries))
+	for _, lib := range libraries {
+		codes = append(codes, lib.Code)
+	}
+	return codes
+}
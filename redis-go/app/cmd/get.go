@@ -1,14 +1,13 @@
 package cmd
 
 import (
-	"fmt"
-
 	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
 )
 
 func HandleGet(ctx HandleContext) (string, error) {
 	key := ctx.RespArr.Elements[1].(*resp.RespBulkString)
-	val, exists := ctx.HostCtx.Store.Get(key.Content)
+	val, exists := ctx.Store().Get(key.Content)
 
 	if !exists {
 		return resp.NullBulkString().AsRespString(), nil
@@ -26,7 +25,7 @@ func HandleGet(ctx HandleContext) (string, error) {
 			t = resp.NewRespInteger(v)
 		}
 	default:
-		return "", fmt.Errorf("unexpected value type")
+		return resp.NewRespError(store.ErrWrongType.Error()).AsRespString(), nil
 	}
 
 	return t.AsRespString(), nil
@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+)
+
+// format: GETSET <KEY> <VALUE>
+//
+// same as SET <KEY> <VALUE> GET, replies with the old value or nil
+func HandleGetSet(ctx HandleContext) (string, error) {
+	if len(ctx.RespArr.Elements) != 3 {
+		return resp.WrongArgsError("getset").AsRespString(), nil
+	}
+
+	key := ctx.RespArr.Elements[1].(*resp.RespBulkString)
+	val := ctx.RespArr.Elements[2].(*resp.RespBulkString)
+
+	result, err := setValue(ctx, key.Content, val.Content, store.ValueOptions{Get: true})
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	return oldValueResponse(result).AsRespString(), nil
+}
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// format: HDEL <KEY> <FIELD> [FIELD ...]
+func HandleHDel(ctx HandleContext) (string, error) {
+	if len(ctx.RespArr.Elements) < 3 {
+		return resp.WrongArgsError("hdel").AsRespString(), nil
+	}
+
+	key := ctx.RespArr.Elements[1].(*resp.RespBulkString)
+
+	fields := make([]string, 0, len(ctx.RespArr.Elements)-2)
+	for _, element := range ctx.RespArr.Elements[2:] {
+		fields = append(fields, element.(*resp.RespBulkString).Content)
+	}
+
+	removed, err := ctx.Store().HashDel(key.Content, fields)
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	if removed > 0 {
+		ctx.Propagate(&ctx.RespArr)
+	}
+
+	return resp.NewRespInteger(removed).AsRespString(), nil
+}
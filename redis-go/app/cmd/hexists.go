@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// format: HEXISTS <KEY> <FIELD>
+func HandleHExists(ctx HandleContext) (string, error) {
+	if len(ctx.RespArr.Elements) != 3 {
+		return resp.WrongArgsError("hexists").AsRespString(), nil
+	}
+
+	key := ctx.RespArr.Elements[1].(*resp.RespBulkString)
+	field := ctx.RespArr.Elements[2].(*resp.RespBulkString)
+
+	exists, err := ctx.Store().HashExists(key.Content, field.Content)
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	if exists {
+		return resp.NewRespInteger(1).AsRespString(), nil
+	}
+
+	return resp.NewRespInteger(0).AsRespString(), nil
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// format: HGET <KEY> <FIELD>
+func HandleHGet(ctx HandleContext) (string, error) {
+	if len(ctx.RespArr.Elements) != 3 {
+		return resp.WrongArgsError("hget").AsRespString(), nil
+	}
+
+	key := ctx.RespArr.Elements[1].(*resp.RespBulkString)
+	field := ctx.RespArr.Elements[2].(*resp.RespBulkString)
+
+	values, exists, err := ctx.Store().HashGet(key.Content, []string{field.Content})
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	if !exists[0] {
+		return resp.NullBulkString().AsRespString(), nil
+	}
+
+	return resp.NewRespBulkString(values[0]).AsRespString(), nil
+}
+
+// format: HMGET <KEY> <FIELD> [FIELD ...]
+func HandleHMGet(ctx HandleContext) (string, error) {
+	if len(ctx.RespArr.Elements) < 3 {
+		return resp.WrongArgsError("hmget").AsRespString(), nil
+	}
+
+	key := ctx.RespArr.Elements[1].(*resp.RespBulkString)
+
+	fields := make([]string, 0, len(ctx.RespArr.Elements)-2)
+	for _, element := range ctx.RespArr.Elements[2:] {
+		fields = append(fields, element.(*resp.RespBulkString).Content)
+	}
+
+	values, exists, err := ctx.Store().HashGet(key.Content, fields)
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	elements := make([]resp.RespType, 0, len(values))
+	for i, value := range values {
+		if exists[i] {
+			elements = append(elements, resp.NewRespBulkString(value))
+		} else {
+			elements = append(elements, resp.NullBulkString())
+		}
+	}
+
+	return resp.NewRespArray(elements).AsRespString(), nil
+}
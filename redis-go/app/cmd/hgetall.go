@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// format: HGETALL|HKEYS|HVALS <KEY>
+//
+// fields are returned in sorted order so replies are stable
+func HandleHGetAll(ctx HandleContext, command string) (string, error) {
+	if len(ctx.RespArr.Elements) != 2 {
+		return resp.WrongArgsError(command).AsRespString(), nil
+	}
+
+	key := ctx.RespArr.Elements[1].(*resp.RespBulkString)
+
+	hash, err := ctx.Store().HashGetAll(key.Content)
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	fields := make([]string, 0, len(hash))
+	for field := range hash {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	command = strings.ToLower(command)
+
+	items := make([]string, 0, len(hash)*2)
+	for _, field := range fields {
+		if command != "hvals" {
+			items = append(items, field)
+		}
+		if command != "hkeys" {
+			items = append(items, hash[field])
+		}
+	}
+
+	return resp.NewRespArrFromStrings(items).AsRespString(), nil
+}
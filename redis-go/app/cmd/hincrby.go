@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"strconv"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// format: HINCRBY <KEY> <FIELD> <INCREMENT>
+func HandleHIncrBy(ctx HandleContext) (string, error) {
+	if len(ctx.RespArr.Elements) != 4 {
+		return resp.WrongArgsError("hincrby").AsRespString(), nil
+	}
+
+	key := ctx.RespArr.Elements[1].(*resp.RespBulkString)
+	field := ctx.RespArr.Elements[2].(*resp.RespBulkString)
+	incr, err := strconv.ParseInt(ctx.RespArr.Elements[3].(*resp.RespBulkString).Content, 10, 64)
+	if err != nil {
+		return resp.NewRespError("ERR value is not an integer or out of range").AsRespString(), nil
+	}
+
+	value, err := ctx.Store().HashIncrBy(key.Content, field.Content, incr)
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	ctx.Propagate(&ctx.RespArr)
+
+	return resp.NewRespInteger(int(value)).AsRespString(), nil
+}
@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// format: HLEN <KEY>
+func HandleHLen(ctx HandleContext) (string, error) {
+	if len(ctx.RespArr.Elements) != 2 {
+		return resp.WrongArgsError("hlen").AsRespString(), nil
+	}
+
+	key := ctx.RespArr.Elements[1].(*resp.RespBulkString)
+
+	length, err := ctx.Store().HashLen(key.Content)
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	return resp.NewRespInteger(length).AsRespString(), nil
+}
@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/app/replication"
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/script"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// a leader with the same wiring as the server, without active expiry or an aof
+func newTestHost(t *testing.T) *HostContext {
+	t.Helper()
+
+	logger := zerolog.Nop()
+	h := &HostContext{
+		Databases:     make([]*store.KvStore, 16),
+		ConfigStore:   store.NewKvStore(logger),
+		TxQueue:       make(map[uuid.UUID][]QueuedCommand),
+		LeaderReplId:  replication.GenerateReplId(),
+		PubSubManager: replication.NewPubSubManager(replication.DefaultBacklogSize, logger),
+		Replicas:      replication.NewReplicaRegistry(),
+		PubSub:        pubsub.NewBroker(),
+		Scripts:       script.NewEngine(5*time.Second, logger),
+		Logger:        logger,
+	}
+	h.ConfigStore.Set("dir", t.TempDir(), store.ValueOptions{})
+	h.ConfigStore.Set("dbfilename", "dump.rdb", store.ValueOptions{})
+	h.PubSubManager.Start()
+
+	for i := range h.Databases {
+		db := store.NewKvStore(logger)
+		db.OnExpire(func(key string) { h.PropagateExpired(i, key) })
+		h.Databases[i] = db
+	}
+
+	return h
+}
+
+// a client connection to the host, running its commands in turn like the server does
+type testClient struct {
+	h          *HostContext
+	connId     uuid.UUID
+	session    *Session
+	disconnect chan struct{} // closed to make a blocked command see the client go away
+	blocked    chan struct{} // signalled when a command is about to block
+}
+
+func newTestClient(h *HostContext) *testClient {
+	return &testClient{
+		h:          h,
+		connId:     uuid.New(),
+		session:    &Session{},
+		disconnect: make(chan struct{}),
+		blocked:    make(chan struct{}, 1),
+	}
+}
+
+func (c *testClient) do(args ...string) string {
+	res, err := HandleCommand(HandleContext{
+		ConnId:   c.connId,
+		HostCtx:  c.h,
+		RespArr:  *resp.NewRespArrFromStrings(args),
+		Logger:   c.h.Logger,
+		Session:  c.session,
+		CanBlock: true,
+		WatchDisconnect: func() (<-chan struct{}, func()) {
+			select {
+			case c.blocked <- struct{}{}:
+			default:
+			}
+			return c.disconnect, func() {}
+		},
+	}, args[0])
+
+	if err != nil {
+		return "error: " + err.Error()
+	}
+
+	return res
+}
+
+// runs the command in the background, returning once it's about to block. The reply is
+// sent on the channel
+func (c *testClient) doBlocking(t *testing.T, args ...string) <-chan string {
+	t.Helper()
+
+	res := make(chan string, 1)
+	go func() { res <- c.do(args...) }()
+
+	select {
+	case <-c.blocked:
+	case r := <-res:
+		t.Fatalf("expected %v to block but got %q", args, r)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %v to block", args)
+	}
+
+	return res
+}
+
+// serves the host on a local port for followers to connect to, returning its address
+func serveTestHost(t *testing.T, h *HostContext) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error listening: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go serveTestConn(h, conn)
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func serveTestConn(h *HostContext, conn net.Conn) {
+	defer conn.Close()
+
+	connId := uuid.New()
+	defer h.Replicas.Unregister(connId.String())
+
+	session := &Session{}
+	lexer := resp.NewLexer(conn)
+	parser := resp.NewParser(lexer)
+
+	for {
+		c, arr, err := ParseServerCommand(*parser)
+		if err != nil {
+			return
+		}
+
+		res, err := HandleCommand(HandleContext{
+			Conn:    conn,
+			ConnId:  connId,
+			HostCtx: h,
+			RespArr: arr,
+			Logger:  h.Logger,
+			Session: session,
+		}, c)
+		if err != nil {
+			return
+		}
+
+		if res != "" {
+			conn.Write([]byte(res))
+		}
+	}
+}
+
+// starts following the leader, stopping when the test ends
+func followTestHost(t *testing.T, follower *HostContext, leaderAddr string) {
+	t.Helper()
+
+	follower.StartReplication(leaderAddr)
+	t.Cleanup(follower.StopReplication)
+}
+
+// waits for the follower to have applied everything the leader has replicated so far
+func waitForCatchUp(t *testing.T, follower *HostContext, leader *HostContext) {
+	t.Helper()
+
+	waitFor(t, func() bool {
+		return follower.LeaderLinkUp.Load() && follower.processedBytes() == leader.PubSubManager.Offset()
+	})
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
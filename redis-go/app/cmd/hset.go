@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// format: HSET <KEY> <FIELD> <VALUE> [FIELD VALUE ...]
+func HandleHSet(ctx HandleContext) (string, error) {
+	if len(ctx.RespArr.Elements) < 4 || len(ctx.RespArr.Elements)%2 != 0 {
+		return resp.WrongArgsError("hset").AsRespString(), nil
+	}
+
+	key := ctx.RespArr.Elements[1].(*resp.RespBulkString)
+
+	pairs := make([]string, 0, len(ctx.RespArr.Elements)-2)
+	for _, element := range ctx.RespArr.Elements[2:] {
+		pairs = append(pairs, element.(*resp.RespBulkString).Content)
+	}
+
+	added, err := ctx.Store().HashSet(key.Content, pairs)
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	ctx.Propagate(&ctx.RespArr)
+
+	return resp.NewRespInteger(added).AsRespString(), nil
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import "testing"
+
+func TestHashCommands(t *testing.T) {
+	cases := []struct {
+		name  string
+		setup [][]string
+		args  []string
+		want  string
+		field string // HGET h f reply afterwards
+		hlen  string // HLEN h reply afterwards
+	}{
+		{name: "HSET new", args: []string{"HSET", "h", "g", "1"}, want: ":1\r\n", field: "$1\r\n5\r\n", hlen: ":2\r\n"},
+		{name: "HSET existing", args: []string{"HSET", "h", "f", "6", "g", "1"}, want: ":1\r\n", field: "$1\r\n6\r\n", hlen: ":2\r\n"},
+		{name: "HSET wrong type", setup: [][]string{{"DEL", "h"}, {"SET", "h", "v"}}, args: []string{"HSET", "h", "f", "1"}, want: wrongType, field: wrongType, hlen: wrongType},
+
+		{name: "HINCRBY", args: []string{"HINCRBY", "h", "f", "-7"}, want: ":-2\r\n", field: "$2\r\n-2\r\n", hlen: ":1\r\n"},
+		{name: "HINCRBY new field", args: []string{"HINCRBY", "h", "g", "3"}, want: ":3\r\n", field: "$1\r\n5\r\n", hlen: ":2\r\n"},
+		{name: "HINCRBY new key", setup: [][]string{{"DEL", "h"}}, args: []string{"HINCRBY", "h", "f", "3"}, want: ":3\r\n", field: "$1\r\n3\r\n", hlen: ":1\r\n"},
+		{name: "HINCRBY overflow", setup: [][]string{{"HSET", "h", "f", "9223372036854775807"}}, args: []string{"HINCRBY", "h", "f", "1"}, want: "-ERR increment or decrement would overflow\r\n", field: "$19\r\n9223372036854775807\r\n", hlen: ":1\r\n"},
+		{name: "HINCRBY underflow", setup: [][]string{{"HSET", "h", "f", "-9223372036854775808"}}, args: []string{"HINCRBY", "h", "f", "-1"}, want: "-ERR increment or decrement would overflow\r\n", field: "$20\r\n-9223372036854775808\r\n", hlen: ":1\r\n"},
+		{name: "HINCRBY field not an integer", setup: [][]string{{"HSET", "h", "f", "abc"}}, args: []string{"HINCRBY", "h", "f", "1"}, want: "-ERR hash value is not an integer\r\n", field: "$3\r\nabc\r\n", hlen: ":1\r\n"},
+		{name: "HINCRBY increment not an integer", args: []string{"HINCRBY", "h", "f", "1.5"}, want: "-ERR value is not an integer or out of range\r\n", field: "$1\r\n5\r\n", hlen: ":1\r\n"},
+		{name: "HINCRBY wrong type", setup: [][]string{{"DEL", "h"}, {"SET", "h", "v"}}, args: []string{"HINCRBY", "h", "f", "1"}, want: wrongType, field: wrongType, hlen: wrongType},
+
+		{name: "HDEL", setup: [][]string{{"HSET", "h", "g", "1"}}, args: []string{"HDEL", "h", "f", "missing"}, want: ":1\r\n", field: "$-1\r\n", hlen: ":1\r\n"},
+		{name: "HDEL the last field", args: []string{"HDEL", "h", "f"}, want: ":1\r\n", field: "$-1\r\n", hlen: ":0\r\n"},
+		{name: "HDEL missing key", setup: [][]string{{"DEL", "h"}}, args: []string{"HDEL", "h", "f"}, want: ":0\r\n", field: "$-1\r\n", hlen: ":0\r\n"},
+		{name: "HDEL wrong type", setup: [][]string{{"DEL", "h"}, {"SET", "h", "v"}}, args: []string{"HDEL", "h", "f"}, want: wrongType, field: wrongType, hlen: wrongType},
+		{name: "HGET wrong type", setup: [][]string{{"DEL", "h"}, {"RPUSH", "h", "a"}}, args: []string{"HGET", "h", "f"}, want: wrongType, field: wrongType, hlen: wrongType},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// arrange
+			h := newTestHost(t)
+			c := newTestClient(h)
+			c.do("HSET", "h", "f", "5")
+			for _, args := range tc.setup {
+				c.do(args...)
+			}
+
+			// act
+			got := c.do(tc.args...)
+
+			// assert
+			if got != tc.want {
+				t.Errorf("expected %v to reply %q but got %q", tc.args, tc.want, got)
+			}
+
+			if field := c.do("HGET", "h", "f"); field != tc.field {
+				t.Errorf("expected HGET h f %q after %v but got %q", tc.field, tc.args, field)
+			}
+
+			if hlen := c.do("HLEN", "h"); hlen != tc.hlen {
+				t.Errorf("expected HLEN h %q after %v but got %q", tc.hlen, tc.args, hlen)
+			}
+		})
+	}
+}
+
+func TestHDelTheLastFieldRemovesTheKey(t *testing.T) {
+	// arrange
+	h := newTestHost(t)
+	c := newTestClient(h)
+	c.do("HSET", "h", "f", "1", "g", "2")
+
+	// act
+	c.do("HDEL", "h", "f", "g")
+
+	// assert
+	if typ := c.do("TYPE", "h"); typ != "+none\r\n" {
+		t.Errorf("expected the hash to be deleted but TYPE replied %q", typ)
+	}
+}
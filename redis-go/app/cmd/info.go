@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"net"
 	"strings"
 
 	"github.com/codecrafters-io/redis-starter-go/app/resp"
@@ -13,17 +14,47 @@ const (
 )
 
 func HandleInfo(ctx HandleContext) (string, error) {
+	leaderAddr := ctx.HostCtx.leaderAddr()
 	role := LeaderRole
-	if ctx.HostCtx.LeaderAddr != "" {
+	if leaderAddr != "" {
 		role = FollowerRole
 	}
 
 	info := make([]string, 0, 10)
 	info = append(info, fmt.Sprintf("role:%s", role))
 
-	if ctx.HostCtx.LeaderReplId != "" {
-		info = append(info, fmt.Sprintf("master_replid:%s", ctx.HostCtx.LeaderReplId))
-		info = append(info, fmt.Sprintf("master_repl_offset:%d", 0))
+	ctx.HostCtx.mu.Lock()
+	replid, prevReplId, prevReplOffset := ctx.HostCtx.LeaderReplId, ctx.HostCtx.prevReplId, ctx.HostCtx.prevReplOffset
+	ctx.HostCtx.mu.Unlock()
+
+	if replid != "" {
+		info = append(info, fmt.Sprintf("master_replid:%s", replid))
+	}
+
+	if prevReplId != "" {
+		info = append(info, fmt.Sprintf("master_replid2:%s", prevReplId))
+		info = append(info, fmt.Sprintf("second_repl_offset:%d", prevReplOffset+1))
+	}
+
+	if role == LeaderRole {
+		backlog := ctx.HostCtx.PubSubManager.Backlog
+		info = append(info, fmt.Sprintf("connected_slaves:%d", ctx.HostCtx.Replicas.Count()))
+		info = append(info, fmt.Sprintf("master_repl_offset:%d", backlog.Offset()))
+		info = append(info, "repl_backlog_active:1")
+		info = append(info, fmt.Sprintf("repl_backlog_size:%d", backlog.Size()))
+		info = append(info, fmt.Sprintf("repl_backlog_first_byte_offset:%d", backlog.FirstByteOffset()))
+		info = append(info, fmt.Sprintf("repl_backlog_histlen:%d", backlog.HistLen()))
+	} else {
+		host, port, _ := net.SplitHostPort(leaderAddr)
+		info = append(info, fmt.Sprintf("master_host:%s", host))
+		info = append(info, fmt.Sprintf("master_port:%s", port))
+
+		status := "down"
+		if ctx.HostCtx.LeaderLinkUp.Load() {
+			status = "up"
+		}
+		info = append(info, fmt.Sprintf("master_link_status:%s", status))
+		info = append(info, fmt.Sprintf("master_repl_offset:%d", ctx.HostCtx.processedBytes()))
 	}
 
 	return resp.NewRespBulkString(strings.Join(info, "\r\n") + "\r\n").AsRespString(), nil
@@ -2,9 +2,15 @@ package cmd
 
 import "github.com/codecrafters-io/redis-starter-go/app/resp"
 
+// format: KEYS <PATTERN>
 func HandleKeys(ctx HandleContext) (string, error) {
+	if len(ctx.RespArr.Elements) != 2 {
+		return resp.WrongArgsError("keys").AsRespString(), nil
+	}
+
+	pattern := ctx.RespArr.Elements[1].(*resp.RespBulkString)
 
-	keys := ctx.HostCtx.Store.List("*")
+	keys := ctx.Store().List(pattern.Content)
 
 	respBulkStrings := make([]resp.RespType, 0, len(keys))
 
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"strconv"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// format: LINDEX <KEY> <INDEX>
+func HandleLIndex(ctx HandleContext) (string, error) {
+	if len(ctx.RespArr.Elements) != 3 {
+		return resp.WrongArgsError("lindex").AsRespString(), nil
+	}
+
+	key := ctx.RespArr.Elements[1].(*resp.RespBulkString)
+	index, err := strconv.Atoi(ctx.RespArr.Elements[2].(*resp.RespBulkString).Content)
+	if err != nil {
+		return resp.NewRespError("ERR value is not an integer or out of range").AsRespString(), nil
+	}
+
+	value, exists, err := ctx.Store().ListIndex(key.Content, index)
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	if !exists {
+		return resp.NullBulkString().AsRespString(), nil
+	}
+
+	return resp.NewRespBulkString(value).AsRespString(), nil
+}
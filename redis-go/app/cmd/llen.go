@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// format: LLEN <KEY>
+func HandleLLen(ctx HandleContext) (string, error) {
+	if len(ctx.RespArr.Elements) != 2 {
+		return resp.WrongArgsError("llen").AsRespString(), nil
+	}
+
+	key := ctx.RespArr.Elements[1].(*resp.RespBulkString)
+
+	length, err := ctx.Store().ListLen(key.Content)
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	return resp.NewRespInteger(length).AsRespString(), nil
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+)
+
+// format: LMOVE <SOURCE> <DESTINATION> <LEFT|RIGHT> <LEFT|RIGHT>
+func HandleLMove(ctx HandleContext) (string, error) {
+	if len(ctx.RespArr.Elements) != 5 {
+		return resp.WrongArgsError("lmove").AsRespString(), nil
+	}
+
+	return handleMove(ctx, 0, false)
+}
+
+// format: BLMOVE <SOURCE> <DESTINATION> <LEFT|RIGHT> <LEFT|RIGHT> <TIMEOUT>
+//
+// blocking LMOVE, replicated as LMOVE
+func HandleBLMove(ctx HandleContext) (string, error) {
+	if len(ctx.RespArr.Elements) != 6 {
+		return resp.WrongArgsError("blmove").AsRespString(), nil
+	}
+
+	timeout, errRes := parseBlockingTimeout(ctx.RespArr.Elements[5].(*resp.RespBulkString).Content)
+	if errRes != nil {
+		return errRes.AsRespString(), nil
+	}
+
+	return handleMove(ctx, timeout, true)
+}
+
+func handleMove(ctx HandleContext, timeout time.Duration, wait bool) (string, error) {
+	source := ctx.RespArr.Elements[1].(*resp.RespBulkString)
+	dest := ctx.RespArr.Elements[2].(*resp.RespBulkString)
+
+	left, ok1 := parseListEnd(ctx.RespArr.Elements[3].(*resp.RespBulkString).Content)
+	destLeft, ok2 := parseListEnd(ctx.RespArr.Elements[4].(*resp.RespBulkString).Content)
+	if !ok1 || !ok2 {
+		return resp.NewRespError("ERR syntax error").AsRespString(), nil
+	}
+
+	w, pop, served, err := ctx.Store().BlockListMove(source.Content, dest.Content, left, destLeft, wait)
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	if pop != nil {
+		ctx.wrote(ctx.HostCtx.propagateListPops(ctx.Db(), append([]store.ListPop{*pop}, served...)))
+		return resp.NewRespBulkString(pop.Value).AsRespString(), nil
+	}
+
+	if w == nil {
+		return resp.NullBulkString().AsRespString(), nil
+	}
+
+	pop, err = awaitListWaiter(ctx, w, timeout) // propagated by whoever served it
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	if pop == nil {
+		return resp.NullBulkString().AsRespString(), nil
+	}
+
+	return resp.NewRespBulkString(pop.Value).AsRespString(), nil
+}
+
+func parseListEnd(value string) (bool, bool) {
+	switch strings.ToLower(value) {
+	case "left":
+		return true, true
+	case "right":
+		return false, true
+	default:
+		return false, false
+	}
+}
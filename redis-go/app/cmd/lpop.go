@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// format: LPOP|RPOP <KEY> [COUNT]
+//
+// without a count the popped element is returned as a bulk string, otherwise as an array
+func HandlePop(ctx HandleContext, command string) (string, error) {
+	if len(ctx.RespArr.Elements) < 2 || len(ctx.RespArr.Elements) > 3 {
+		return resp.WrongArgsError(command).AsRespString(), nil
+	}
+
+	key := ctx.RespArr.Elements[1].(*resp.RespBulkString)
+
+	count := 1
+	withCount := len(ctx.RespArr.Elements) == 3
+	if withCount {
+		c, err := strconv.Atoi(ctx.RespArr.Elements[2].(*resp.RespBulkString).Content)
+		if err != nil || c < 0 {
+			return resp.NewRespError("ERR value is out of range, must be positive").AsRespString(), nil
+		}
+		count = c
+	}
+
+	popped, err := ctx.Store().ListPop(key.Content, count, strings.ToLower(command) == "lpop")
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	if popped == nil {
+		if withCount {
+			return resp.NullArray().AsRespString(), nil
+		}
+		return resp.NullBulkString().AsRespString(), nil
+	}
+
+	if len(popped) > 0 {
+		ctx.Propagate(&ctx.RespArr)
+	}
+
+	if withCount {
+		return resp.NewRespArrFromStrings(popped).AsRespString(), nil
+	}
+
+	return resp.NewRespBulkString(popped[0]).AsRespString(), nil
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// format: LPUSH|RPUSH <KEY> <ELEMENT> [ELEMENT ...]
+func HandlePush(ctx HandleContext, command string) (string, error) {
+	if len(ctx.RespArr.Elements) < 3 {
+		return resp.WrongArgsError(command).AsRespString(), nil
+	}
+
+	key := ctx.RespArr.Elements[1].(*resp.RespBulkString)
+
+	values := make([]string, 0, len(ctx.RespArr.Elements)-2)
+	for _, element := range ctx.RespArr.Elements[2:] {
+		values = append(values, element.(*resp.RespBulkString).Content)
+	}
+
+	length, served, err := ctx.Store().ListPush(key.Content, values, strings.ToLower(command) == "lpush")
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	ctx.Propagate(&ctx.RespArr)
+	ctx.wrote(ctx.HostCtx.propagateListPops(ctx.Db(), served))
+
+	return resp.NewRespInteger(length).AsRespString(), nil
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import "testing"
+
+func TestListCommands(t *testing.T) {
+	cases := []struct {
+		name  string
+		setup [][]string
+		args  []string
+		want  string
+		list  string // LRANGE l 0 -1 reply afterwards
+	}{
+		{name: "LPUSH new", args: []string{"LPUSH", "new", "x"}, want: ":1\r\n", list: "*3\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n"},
+		{name: "LPUSH prepends in order", args: []string{"LPUSH", "l", "x", "y"}, want: ":5\r\n", list: "*5\r\n$1\r\ny\r\n$1\r\nx\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n"},
+		{name: "LPUSH wrong type", setup: [][]string{{"SET", "str", "v"}}, args: []string{"LPUSH", "str", "x"}, want: wrongType, list: "*3\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n"},
+
+		{name: "LINDEX", args: []string{"LINDEX", "l", "1"}, want: "$1\r\nb\r\n", list: "*3\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n"},
+		{name: "LINDEX negative", args: []string{"LINDEX", "l", "-1"}, want: "$1\r\nc\r\n", list: "*3\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n"},
+		{name: "LINDEX out of range", args: []string{"LINDEX", "l", "3"}, want: "$-1\r\n", list: "*3\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n"},
+		{name: "LINDEX negative out of range", args: []string{"LINDEX", "l", "-4"}, want: "$-1\r\n", list: "*3\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n"},
+		{name: "LINDEX missing", args: []string{"LINDEX", "missing", "0"}, want: "$-1\r\n", list: "*3\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n"},
+		{name: "LINDEX not an integer", args: []string{"LINDEX", "l", "x"}, want: "-ERR value is not an integer or out of range\r\n", list: "*3\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n"},
+
+		{name: "LSET", args: []string{"LSET", "l", "0", "x"}, want: "+OK\r\n", list: "*3\r\n$1\r\nx\r\n$1\r\nb\r\n$1\r\nc\r\n"},
+		{name: "LSET negative", args: []string{"LSET", "l", "-2", "x"}, want: "+OK\r\n", list: "*3\r\n$1\r\na\r\n$1\r\nx\r\n$1\r\nc\r\n"},
+		{name: "LSET out of range", args: []string{"LSET", "l", "3", "x"}, want: "-ERR index out of range\r\n", list: "*3\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n"},
+		{name: "LSET negative out of range", args: []string{"LSET", "l", "-4", "x"}, want: "-ERR index out of range\r\n", list: "*3\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n"},
+		{name: "LSET missing", args: []string{"LSET", "missing", "0", "x"}, want: "-ERR no such key\r\n", list: "*3\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n"},
+		{name: "LSET wrong type", setup: [][]string{{"SET", "str", "v"}}, args: []string{"LSET", "str", "0", "x"}, want: wrongType, list: "*3\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n"},
+
+		{name: "LTRIM", args: []string{"LTRIM", "l", "1", "2"}, want: "+OK\r\n", list: "*2\r\n$1\r\nb\r\n$1\r\nc\r\n"},
+		{name: "LTRIM negative", args: []string{"LTRIM", "l", "0", "-2"}, want: "+OK\r\n", list: "*2\r\n$1\r\na\r\n$1\r\nb\r\n"},
+		{name: "LTRIM past the end", args: []string{"LTRIM", "l", "-100", "100"}, want: "+OK\r\n", list: "*3\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n"},
+		{name: "LTRIM everything", args: []string{"LTRIM", "l", "2", "1"}, want: "+OK\r\n", list: "*0\r\n"},
+		{name: "LTRIM missing", args: []string{"LTRIM", "missing", "0", "1"}, want: "+OK\r\n", list: "*3\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// arrange
+			h := newTestHost(t)
+			c := newTestClient(h)
+			c.do("RPUSH", "l", "a", "b", "c")
+			for _, args := range tc.setup {
+				c.do(args...)
+			}
+
+			// act
+			got := c.do(tc.args...)
+
+			// assert
+			if got != tc.want {
+				t.Errorf("expected %v to reply %q but got %q", tc.args, tc.want, got)
+			}
+
+			if list := c.do("LRANGE", "l", "0", "-1"); list != tc.list {
+				t.Errorf("expected the list to be %q after %v but got %q", tc.list, tc.args, list)
+			}
+		})
+	}
+}
+
+func TestLTrimEverythingRemovesTheKey(t *testing.T) {
+	// arrange
+	h := newTestHost(t)
+	c := newTestClient(h)
+	c.do("RPUSH", "l", "a", "b")
+
+	// act
+	c.do("LTRIM", "l", "5", "10")
+
+	// assert
+	if typ := c.do("TYPE", "l"); typ != "+none\r\n" {
+		t.Errorf("expected the list to be deleted but TYPE replied %q", typ)
+	}
+}
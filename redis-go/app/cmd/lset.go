@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"strconv"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// format: LSET <KEY> <INDEX> <ELEMENT>
+func HandleLSet(ctx HandleContext) (string, error) {
+	if len(ctx.RespArr.Elements) != 4 {
+		return resp.WrongArgsError("lset").AsRespString(), nil
+	}
+
+	key := ctx.RespArr.Elements[1].(*resp.RespBulkString)
+	index, err := strconv.Atoi(ctx.RespArr.Elements[2].(*resp.RespBulkString).Content)
+	if err != nil {
+		return resp.NewRespError("ERR value is not an integer or out of range").AsRespString(), nil
+	}
+	value := ctx.RespArr.Elements[3].(*resp.RespBulkString)
+
+	err = ctx.Store().ListSet(key.Content, index, value.Content)
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	ctx.Propagate(&ctx.RespArr)
+
+	return resp.OkResponse().AsRespString(), nil
+}
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"strconv"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// format: LTRIM <KEY> <START> <STOP>
+func HandleLTrim(ctx HandleContext) (string, error) {
+	if len(ctx.RespArr.Elements) != 4 {
+		return resp.WrongArgsError("ltrim").AsRespString(), nil
+	}
+
+	key := ctx.RespArr.Elements[1].(*resp.RespBulkString)
+	start, err1 := strconv.Atoi(ctx.RespArr.Elements[2].(*resp.RespBulkString).Content)
+	stop, err2 := strconv.Atoi(ctx.RespArr.Elements[3].(*resp.RespBulkString).Content)
+	if err1 != nil || err2 != nil {
+		return resp.NewRespError("ERR value is not an integer or out of range").AsRespString(), nil
+	}
+
+	err := ctx.Store().ListTrim(key.Content, start, stop)
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	ctx.Propagate(&ctx.RespArr)
+
+	return resp.OkResponse().AsRespString(), nil
+}
@@ -5,11 +5,16 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 
+	"github.com/codecrafters-io/redis-starter-go/app/aof"
+	"github.com/codecrafters-io/redis-starter-go/app/pubsub"
 	"github.com/codecrafters-io/redis-starter-go/app/replication"
 	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/script"
 	"github.com/codecrafters-io/redis-starter-go/app/store"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
@@ -21,19 +26,63 @@ type HandleContext struct {
 	HostCtx *HostContext
 	RespArr resp.RespArray
 	Logger  zerolog.Logger
+	Session *Session // nil is treated as a new connection
+
+	// false where the command can't block (inside a transaction or script, replicated or
+	// replayed from the aof), in which case it returns straight away as if it timed out
+	CanBlock bool
+
+	// watches for the client disconnecting whilst a command blocks, returning a channel
+	// closed on disconnect and a func to stop watching. Set wherever CanBlock is
+	WatchDisconnect func() (<-chan struct{}, func())
+
+	nested bool            // run by EXEC or a script, which already hold the command lock exclusively
+	stop   <-chan struct{} // replicated commands are skipped once closed, see stopReplication
+}
+
+// state kept for the lifetime of a connection, shared by each of its commands
+type Session struct {
+	Db            int     // index of the selected database
+	watches       []watch // keys to check before the next EXEC
+	subscriber    *pubsub.Subscriber
+	endSubscriber func() // stops writing the subscriber's queue to the connection
+	replOffset    int    // master offset just after the connection's last replicated write, for WAIT
 }
 
 type HostContext struct {
-	Store          *store.KvStore
+	Databases      []*store.KvStore // selected per connection, see HandleContext.Store
+	Aof            *aof.Aof         // nil unless appendonly is enabled
 	ConfigStore    *store.KvStore
-	LeaderAddr     string
+	LeaderAddr     string // guarded by mu once serving, as are LeaderReplId and ProcessedBytes
 	Port           int
 	LeaderReplId   string
+	prevReplId     string // the leader's replid before this host was promoted, see HandlePSync
+	prevReplOffset int    // offset the previous replid's stream is valid up to
 	PubSubManager  replication.PubSubManager
+	Replicas       *replication.ReplicaRegistry
+	PubSub         *pubsub.Broker // clients' channels, unrelated to PubSubManager
+	keyspaceEvents atomic.Pointer[pubsub.KeyspaceEvents]
 	Logger         zerolog.Logger
 	ProcessedBytes int
 	mu             sync.Mutex
 	TxQueue        map[uuid.UUID][]QueuedCommand
+	txAborted      map[uuid.UUID]bool // transactions with a command that failed to queue
+	commandMu      sync.RWMutex       // shared by each command, exclusive whilst EXEC runs a transaction
+	LeaderLinkUp   atomic.Bool
+	follower       *follower
+	saving         atomic.Bool
+	loading        atomic.Bool
+	propagateMu    sync.Mutex
+	propagatedDb   int  // database selected by the last propagated command
+	propagatedAny  bool // false until the next propagated command must SELECT its database
+	buffering      int  // depth of EXECs and scripts collecting their writes into buffered
+	buffered       []propagatedCommand
+	Scripts        *script.Engine
+}
+
+type propagatedCommand struct {
+	db    int
+	event string
 }
 
 type QueuedCommand struct {
@@ -47,7 +96,192 @@ func (h *HostContext) AppendProcessedBytes(count int) {
 	h.mu.Unlock()
 }
 
+func (h *HostContext) SetProcessedBytes(count int) {
+	h.mu.Lock()
+	h.ProcessedBytes = count
+	h.mu.Unlock()
+}
+
+func (h *HostContext) processedBytes() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.ProcessedBytes
+}
+
+func (h *HostContext) leaderAddr() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.LeaderAddr
+}
+
+// index of the database selected by the connection
+func (ctx HandleContext) Db() int {
+	if ctx.Session == nil {
+		return 0
+	}
+	return ctx.Session.Db
+}
+
+// the database selected by the connection
+func (ctx HandleContext) Store() *store.KvStore {
+	return ctx.HostCtx.Databases[ctx.Db()]
+}
+
+// runs fn, which blocks, without holding the command lock so it doesn't hold up EXEC.
+// Nested commands can't block so keep it
+func (ctx HandleContext) unlockedWhile(fn func()) {
+	if ctx.nested {
+		fn()
+		return
+	}
+
+	ctx.HostCtx.commandMu.RUnlock()
+	defer ctx.HostCtx.commandMu.RLock()
+	fn()
+}
+
+// runs fn holding the command lock exclusively, so no write can land part way through it.
+// Nested commands already hold it exclusively
+func (ctx HandleContext) exclusively(fn func()) {
+	if ctx.nested {
+		fn()
+		return
+	}
+
+	ctx.HostCtx.commandMu.RUnlock()
+	defer ctx.HostCtx.commandMu.RLock()
+
+	ctx.HostCtx.commandMu.Lock()
+	defer ctx.HostCtx.commandMu.Unlock()
+	fn()
+}
+
+// propagates a write command made against the connection's database
+func (ctx HandleContext) Propagate(command resp.RespType) {
+	ctx.wrote(ctx.HostCtx.Propagate(ctx.Db(), command))
+}
+
+// records the master offset a write the connection made was replicated up to, so WAIT
+// knows what its followers need to acknowledge. 0 is a write that wasn't replicated yet
+func (ctx HandleContext) wrote(offset int) {
+	if ctx.Session != nil && offset > 0 {
+		ctx.Session.replOffset = offset
+	}
+}
+
+// propagates a write command to the aof and the followers, preceded by a SELECT when it's
+// for a different database to the last. A db of -1 is for commands that apply to every db.
+// Returns the master offset once it's replicated, 0 whilst it's buffered by a transaction
+func (h *HostContext) Propagate(db int, command resp.RespType) int {
+	if h.loading.Load() {
+		return 0 // replaying the aof
+	}
+
+	h.propagateMu.Lock()
+	defer h.propagateMu.Unlock()
+
+	if h.buffering > 0 {
+		h.buffered = append(h.buffered, propagatedCommand{db: db, event: command.AsRespString()})
+		return 0
+	}
+
+	// sent as one event so the SELECT can't be separated from its command
+	return h.emit(h.selectDb(db) + command.AsRespString())
+}
+
+// buffers propagated commands until endTransactionPropagation, so a transaction or script
+// reaches the aof and the followers as a single unit. Nests, e.g. for EVAL inside EXEC
+func (h *HostContext) beginTransactionPropagation() {
+	h.propagateMu.Lock()
+	h.buffering++
+	h.propagateMu.Unlock()
+}
+
+// propagates everything buffered since the outermost beginTransactionPropagation wrapped
+// in MULTI/EXEC, or nothing when the transaction didn't write. Returns the master offset
+// once it's replicated, 0 when nothing was
+func (h *HostContext) endTransactionPropagation() int {
+	h.propagateMu.Lock()
+	defer h.propagateMu.Unlock()
+
+	h.buffering--
+	if h.buffering > 0 {
+		return 0
+	}
+
+	buffered := h.buffered
+	h.buffered = nil
+
+	if len(buffered) == 0 {
+		return 0
+	}
+
+	var event strings.Builder
+	event.WriteString(resp.NewRespArrFromStrings([]string{"MULTI"}).AsRespString())
+	for _, c := range buffered {
+		event.WriteString(h.selectDb(c.db))
+		event.WriteString(c.event)
+	}
+	event.WriteString(resp.NewRespArrFromStrings([]string{"EXEC"}).AsRespString())
+
+	return h.emit(event.String())
+}
+
+// number of commands buffered by the current transaction, so a script can tell whether
+// a command it ran wrote
+func (h *HostContext) bufferedCount() int {
+	h.propagateMu.Lock()
+	defer h.propagateMu.Unlock()
+
+	return len(h.buffered)
+}
+
+// the SELECT needed before a command for db, if any. Must be called with propagateMu held
+func (h *HostContext) selectDb(db int) string {
+	if db < 0 || (h.propagatedAny && db == h.propagatedDb) {
+		return ""
+	}
+
+	h.propagatedDb = db
+	h.propagatedAny = true
+	return resp.NewRespArrFromStrings([]string{"SELECT", strconv.Itoa(db)}).AsRespString()
+}
+
+// returns the master offset once the event is replicated. Must be called with propagateMu held
+func (h *HostContext) emit(event string) int {
+	if h.Aof != nil {
+		if err := h.Aof.Append(event); err != nil {
+			h.Logger.Error().Err(err).Msg("error appending to aof")
+		}
+	}
+
+	return h.PubSubManager.Publish(replication.PubSubEvent(event))
+}
+
+// makes the next propagated command SELECT its database, for when a new stream starts
+// (a follower's full resync or an aof rewrite). Must be called with propagateMu held
+func (h *HostContext) resetPropagatedDb() {
+	h.propagatedAny = false
+}
+
+// disconnects this host's followers once its dataset has been replaced. The stream restarts
+// from 0 so none can continue from the backlog and keep the data they had, they get the new
+// dataset with a full resync when they reconnect
+func (h *HostContext) dropFollowers() {
+	h.propagateMu.Lock()
+	defer h.propagateMu.Unlock()
+
+	h.PubSubManager.DropSubscribers()
+	h.PubSubManager.ContinueFrom(0)
+	h.resetPropagatedDb()
+}
+
 func (h *HostContext) IsInTransaction(connid uuid.UUID) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
 	_, exists := h.TxQueue[connid]
 	return exists
 }
@@ -58,12 +292,35 @@ func (h *HostContext) BeginTransaction(connid uuid.UUID) {
 	h.mu.Unlock()
 }
 
+// marks the transaction so EXEC discards it rather than running it
+func (h *HostContext) AbortTransaction(connid uuid.UUID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, exists := h.TxQueue[connid]; !exists {
+		return
+	}
+
+	if h.txAborted == nil {
+		h.txAborted = make(map[uuid.UUID]bool)
+	}
+	h.txAborted[connid] = true
+}
+
+func (h *HostContext) TransactionAborted(connid uuid.UUID) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.txAborted[connid]
+}
+
 func (h *HostContext) ConsumeTransactionQueue(connid uuid.UUID) []QueuedCommand {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
 	queue := h.TxQueue[connid]
 	delete(h.TxQueue, connid)
+	delete(h.txAborted, connid)
 	return queue
 }
 
@@ -121,50 +378,245 @@ func HandleCommand(ctx HandleContext, content string) (string, error) {
 	content = strings.ToLower(content)
 	ctx.Logger.Info().Msgf("handling %s", content)
 
-	if ctx.HostCtx.IsInTransaction(ctx.ConnId) && content != "exec" && content != "discard" {
+	inTransaction := ctx.HostCtx.IsInTransaction(ctx.ConnId)
+
+	if respErr := checkCommand(content, ctx.RespArr); respErr != nil {
+		if inTransaction {
+			ctx.HostCtx.AbortTransaction(ctx.ConnId)
+		}
+		return respErr.AsRespString(), nil
+	}
+
+	if ctx.Session.Subscribed() && !subscriberCommands[content] {
+		return resp.NewRespError(fmt.Sprintf("ERR Can't execute '%s': only (P|S)SUBSCRIBE / (P|S)UNSUBSCRIBE / PING / QUIT / RESET are allowed in this context", content)).AsRespString(), nil
+	}
+
+	if inTransaction && content != "exec" && content != "discard" {
+		switch content {
+		case "multi":
+			return resp.NewRespError("ERR MULTI calls can not be nested").AsRespString(), nil
+		case "watch":
+			return resp.NewRespError("ERR WATCH inside MULTI is not allowed").AsRespString(), nil
+		case "subscribe", "psubscribe", "ssubscribe", "unsubscribe", "punsubscribe", "sunsubscribe":
+			ctx.HostCtx.AbortTransaction(ctx.ConnId)
+			return resp.NewRespError("ERR Command not allowed inside a transaction").AsRespString(), nil
+		}
+
 		ctx.HostCtx.QueueCommand(ctx.ConnId, content, ctx.RespArr)
 		return resp.NewRespSimpleString("QUEUED").AsRespString(), nil
 	}
 
+	if !ctx.nested && (content == "script" || content == "function") && strings.EqualFold(ctx.RespArr.Elements[1].(*resp.RespBulkString).Content, "kill") {
+		return HandleScriptKill(ctx) // runs alongside the script it stops, so takes no lock
+	}
+
+	if !ctx.nested && ctx.HostCtx.Scripts.Busy() {
+		return resp.NewRespError(script.ErrBusy.Error()).AsRespString(), nil
+	}
+
+	// EXEC and scripts keep every other command out whilst they run
+	if !ctx.nested {
+		if content == "exec" || content == "eval" || content == "evalsha" || content == "fcall" || content == "fcall_ro" {
+			ctx.HostCtx.commandMu.Lock()
+			defer ctx.HostCtx.commandMu.Unlock()
+		} else {
+			ctx.HostCtx.commandMu.RLock()
+			defer ctx.HostCtx.commandMu.RUnlock()
+		}
+	}
+
+	if ctx.stop != nil {
+		select {
+		case <-ctx.stop:
+			return "", errReplicationStopped // whilst it waited for the lock
+		default:
+		}
+	}
+
 	switch content {
+	case "bgrewriteaof":
+		return HandleBgRewriteAof(ctx)
+	case "bgsave":
+		return HandleBgSave(ctx)
+	case "blmove":
+		return HandleBLMove(ctx)
+	case "blpop", "brpop":
+		return HandleBlockingPop(ctx, content)
 	case "config":
 		return HandleConfig(ctx)
+	case "del":
+		return HandleDel(ctx)
 	case "discard":
 		return HandleDiscard(ctx)
 	case "echo":
 		return HandleEcho(ctx)
+	case "eval", "evalsha":
+		return HandleEval(ctx, content)
 	case "exec":
 		return HandleExec(ctx)
+	case "expire", "pexpire", "expireat", "pexpireat":
+		return HandleExpire(ctx, content)
+	case "fcall", "fcall_ro":
+		return HandleFCall(ctx, content)
+	case "flushdb", "flushall":
+		return HandleFlush(ctx, content)
+	case "function":
+		return HandleFunction(ctx)
 	case "get":
 		return HandleGet(ctx)
+	case "getset":
+		return HandleGetSet(ctx)
+	case "hdel":
+		return HandleHDel(ctx)
+	case "hexists":
+		return HandleHExists(ctx)
+	case "hget":
+		return HandleHGet(ctx)
+	case "hgetall", "hkeys", "hvals":
+		return HandleHGetAll(ctx, content)
+	case "hincrby":
+		return HandleHIncrBy(ctx)
+	case "hlen":
+		return HandleHLen(ctx)
+	case "hmget":
+		return HandleHMGet(ctx)
+	case "hscan", "sscan", "zscan":
+		return HandleCollectionScan(ctx, content)
+	case "hset":
+		return HandleHSet(ctx)
 	case "incr":
 		return HandleIncr(ctx)
 	case "info":
 		return HandleInfo(ctx)
 	case "keys":
 		return HandleKeys(ctx)
+	case "lindex":
+		return HandleLIndex(ctx)
+	case "llen":
+		return HandleLLen(ctx)
+	case "lmove":
+		return HandleLMove(ctx)
+	case "lpop", "rpop":
+		return HandlePop(ctx, content)
+	case "lpush", "rpush":
+		return HandlePush(ctx, content)
+	case "lrange":
+		return HandleLRange(ctx)
+	case "lset":
+		return HandleLSet(ctx)
+	case "ltrim":
+		return HandleLTrim(ctx)
+	case "move":
+		return HandleMove(ctx)
 	case "multi":
 		return HandleMulti(ctx)
+	case "persist":
+		return HandlePersist(ctx)
 	case "ping":
 		return HandlePing(ctx)
+	case "psubscribe", "punsubscribe", "ssubscribe", "subscribe", "sunsubscribe", "unsubscribe":
+		return HandleSubscribe(ctx, content)
 	case "psync":
 		HandlePSync(ctx)
-		return "", nil // writes several responses direct to the conn (refactor?)
+		return "", nil // writes several responses direct to the conn, then replicates in the background
+	case "publish", "spublish":
+		return HandlePublish(ctx, content)
+	case "pubsub":
+		return HandlePubSub(ctx)
 	case "replconf":
 		return HandleReplconf(ctx)
+	case "replicaof", "slaveof":
+		return HandleReplicaOf(ctx)
+	case "sadd":
+		return HandleSAdd(ctx)
+	case "save":
+		return HandleSave(ctx)
+	case "scan":
+		return HandleScan(ctx)
+	case "scard":
+		return HandleSCard(ctx)
+	case "script":
+		return HandleScript(ctx)
+	case "sinter", "sunion", "sdiff":
+		return HandleSetOp(ctx, content)
+	case "sinterstore", "sunionstore", "sdiffstore":
+		return HandleSetOpStore(ctx, content)
+	case "sismember":
+		return HandleSIsMember(ctx)
+	case "select":
+		return HandleSelect(ctx)
 	case "set":
 		return HandleSet(ctx)
+	case "setex", "psetex":
+		return HandleSetEx(ctx, content)
+	case "setnx":
+		return HandleSetNx(ctx)
+	case "smembers":
+		return HandleSMembers(ctx)
+	case "srem":
+		return HandleSRem(ctx)
+	case "swapdb":
+		return HandleSwapDb(ctx)
+	case "ttl", "pttl":
+		return HandleTTL(ctx, content)
 	case "type":
 		return HandleType(ctx)
+	case "unwatch":
+		return HandleUnwatch(ctx)
 	case "wait":
 		return HandleWait(ctx)
+	case "watch":
+		return HandleWatch(ctx)
+	case "xack":
+		return HandleXAck(ctx)
 	case "xadd":
 		return HandleXAdd(ctx)
+	case "xautoclaim":
+		return HandleXAutoClaim(ctx)
+	case "xclaim":
+		return HandleXClaim(ctx)
+	case "xdel":
+		return HandleXDel(ctx)
+	case "xgroup":
+		return HandleXGroup(ctx)
+	case "xinfo":
+		return HandleXInfo(ctx)
+	case "xlen":
+		return HandleXLen(ctx)
+	case "xpending":
+		return HandleXPending(ctx)
 	case "xrange":
 		return HandleXRange(ctx)
 	case "xread":
 		return HandleXRead(ctx)
+	case "xreadgroup":
+		return HandleXReadGroup(ctx)
+	case "xtrim":
+		return HandleXTrim(ctx)
+	case "zadd":
+		return HandleZAdd(ctx)
+	case "zcard":
+		return HandleZCard(ctx)
+	case "zcount":
+		return HandleZCount(ctx)
+	case "zincrby":
+		return HandleZIncrBy(ctx)
+	case "zpopmin", "zpopmax":
+		return HandleZPop(ctx, content)
+	case "zrange":
+		return HandleZRange(ctx)
+	case "zrangebyscore":
+		return HandleZRangeByScore(ctx)
+	case "zrank", "zrevrank":
+		return HandleZRank(ctx, content)
+	case "zrem":
+		return HandleZRem(ctx)
+	case "zremrangebyrank", "zremrangebyscore", "zremrangebylex":
+		return HandleZRemRange(ctx, content)
+	case "zscore":
+		return HandleZScore(ctx)
 	default:
+		// every command in commandArity is handled above
 		ctx.Logger.Error().Msgf("unexpected command %s", content)
 		panic(1)
 	}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// format: MOVE <KEY> <DB>
+//
+// replies 1 if the key was moved, 0 if it doesn't exist or the destination already has it
+func HandleMove(ctx HandleContext) (string, error) {
+	if len(ctx.RespArr.Elements) != 3 {
+		return resp.WrongArgsError("move").AsRespString(), nil
+	}
+
+	key := ctx.RespArr.Elements[1].(*resp.RespBulkString)
+
+	dst, errRes := ctx.HostCtx.parseDbIndex(ctx.RespArr.Elements[2].(*resp.RespBulkString).Content)
+	if errRes != nil {
+		return errRes.AsRespString(), nil
+	}
+
+	if dst == ctx.Db() {
+		return resp.NewRespError("ERR source and destination objects are the same").AsRespString(), nil
+	}
+
+	moved, served := ctx.Store().MoveTo(key.Content, ctx.HostCtx.Databases[dst])
+	if !moved {
+		return resp.NewRespInteger(0).AsRespString(), nil
+	}
+
+	ctx.Propagate(&ctx.RespArr)
+	ctx.wrote(ctx.HostCtx.propagateListPops(dst, served))
+
+	return resp.NewRespInteger(1).AsRespString(), nil
+}
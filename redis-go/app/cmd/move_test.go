@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMove(t *testing.T) {
+	cases := []struct {
+		name  string
+		setup [][]string // run in db 1
+		args  []string
+		want  string
+		src   string // GET k in db 0 afterwards
+		dst   string // GET k in db 1 afterwards
+	}{
+		{name: "moved", args: []string{"MOVE", "k", "1"}, want: ":1\r\n", src: "$-1\r\n", dst: "$1\r\nv\r\n"},
+		{name: "missing", args: []string{"MOVE", "missing", "1"}, want: ":0\r\n", src: "$1\r\nv\r\n", dst: "$-1\r\n"},
+		{name: "destination has the key", setup: [][]string{{"SET", "k", "other"}}, args: []string{"MOVE", "k", "1"}, want: ":0\r\n", src: "$1\r\nv\r\n", dst: "$5\r\nother\r\n"},
+		{name: "same db", args: []string{"MOVE", "k", "0"}, want: "-ERR source and destination objects are the same\r\n", src: "$1\r\nv\r\n", dst: "$-1\r\n"},
+		{name: "db out of range", args: []string{"MOVE", "k", "16"}, want: "-ERR DB index is out of range\r\n", src: "$1\r\nv\r\n", dst: "$-1\r\n"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// arrange
+			h := newTestHost(t)
+			c, other := newTestClient(h), newTestClient(h)
+			c.do("SET", "k", "v")
+			other.do("SELECT", "1")
+			for _, args := range tc.setup {
+				other.do(args...)
+			}
+
+			// act
+			got := c.do(tc.args...)
+
+			// assert
+			if got != tc.want {
+				t.Errorf("expected %v to reply %q but got %q", tc.args, tc.want, got)
+			}
+
+			if src := c.do("GET", "k"); src != tc.src {
+				t.Errorf("expected k in db 0 to be %q but got %q", tc.src, src)
+			}
+
+			if dst := other.do("GET", "k"); dst != tc.dst {
+				t.Errorf("expected k in db 1 to be %q but got %q", tc.dst, dst)
+			}
+		})
+	}
+}
+
+func TestMoveKeepsTheTtl(t *testing.T) {
+	// arrange
+	h := newTestHost(t)
+	c, other := newTestClient(h), newTestClient(h)
+	c.do("SET", "k", "v", "EX", "100")
+	other.do("SELECT", "1")
+
+	// act
+	c.do("MOVE", "k", "1")
+
+	// assert
+	if ttl := ttlKind(other, "k"); ttl != 1 {
+		t.Errorf("expected the moved key to still expire but got ttl kind %d", ttl)
+	}
+}
+
+func TestMoveAndSwapDbServeBlockedClients(t *testing.T) {
+	cases := []struct {
+		name string
+		args []string
+	}{
+		{name: "MOVE", args: []string{"MOVE", "l", "1"}},
+		{name: "SWAPDB", args: []string{"SWAPDB", "0", "1"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// arrange
+			h := newTestHost(t)
+			c, blocked := newTestClient(h), newTestClient(h)
+			blocked.do("SELECT", "1")
+			res := blocked.doBlocking(t, "BLPOP", "l", "0")
+			c.do("RPUSH", "l", "a", "b")
+
+			// act
+			c.do(tc.args...)
+
+			// assert
+			select {
+			case got := <-res:
+				if want := "*2\r\n$1\r\nl\r\n$1\r\na\r\n"; got != want {
+					t.Errorf("expected the blocked client to get %q but got %q", want, got)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("expected the blocked client to be served after %v", tc.args)
+			}
+
+			db1 := newTestClient(h)
+			db1.do("SELECT", "1")
+			if list := db1.do("LRANGE", "l", "0", "-1"); list != "*1\r\n$1\r\nb\r\n" {
+				t.Errorf("expected b left in db 1 but got %q", list)
+			}
+		})
+	}
+}
+
+func TestSwapDb(t *testing.T) {
+	// arrange
+	h := newTestHost(t)
+	c, other := newTestClient(h), newTestClient(h)
+	c.do("SET", "a", "0")
+	other.do("SELECT", "1")
+	other.do("SET", "b", "1")
+
+	// act
+	res := c.do("SWAPDB", "0", "1")
+
+	// assert
+	if res != "+OK\r\n" {
+		t.Errorf("expected +OK but got %q", res)
+	}
+
+	for _, check := range []struct {
+		c    *testClient
+		key  string
+		want string
+	}{
+		{c: c, key: "a", want: "$-1\r\n"},
+		{c: c, key: "b", want: "$1\r\n1\r\n"},
+		{c: other, key: "a", want: "$1\r\n0\r\n"},
+		{c: other, key: "b", want: "$-1\r\n"},
+	} {
+		if got := check.c.do("GET", check.key); got != check.want {
+			t.Errorf("expected GET %s to be %q after the swap but got %q", check.key, check.want, got)
+		}
+	}
+
+	if res := c.do("SWAPDB", "0", "x"); res != "-ERR invalid second DB index\r\n" {
+		t.Errorf("expected an invalid index error but got %q", res)
+	}
+}
+
+func TestFlush(t *testing.T) {
+	cases := []struct {
+		args []string
+		want string
+		db0  string // GET k in db 0 afterwards
+		db1  string // GET k in db 1 afterwards
+	}{
+		{args: []string{"FLUSHDB"}, want: "+OK\r\n", db0: "$-1\r\n", db1: "$1\r\n1\r\n"},
+		{args: []string{"FLUSHDB", "ASYNC"}, want: "+OK\r\n", db0: "$-1\r\n", db1: "$1\r\n1\r\n"},
+		{args: []string{"FLUSHALL"}, want: "+OK\r\n", db0: "$-1\r\n", db1: "$-1\r\n"},
+		{args: []string{"FLUSHALL", "sync"}, want: "+OK\r\n", db0: "$-1\r\n", db1: "$-1\r\n"},
+		{args: []string{"FLUSHALL", "now"}, want: "-ERR syntax error\r\n", db0: "$1\r\n0\r\n", db1: "$1\r\n1\r\n"},
+	}
+
+	for _, tc := range cases {
+		// arrange
+		h := newTestHost(t)
+		c, other := newTestClient(h), newTestClient(h)
+		c.do("SET", "k", "0")
+		other.do("SELECT", "1")
+		other.do("SET", "k", "1")
+
+		// act
+		got := c.do(tc.args...)
+
+		// assert
+		if got != tc.want {
+			t.Errorf("expected %v to reply %q but got %q", tc.args, tc.want, got)
+		}
+
+		if db0 := c.do("GET", "k"); db0 != tc.db0 {
+			t.Errorf("expected k in db 0 to be %q after %v but got %q", tc.db0, tc.args, db0)
+		}
+
+		if db1 := other.do("GET", "k"); db1 != tc.db1 {
+			t.Errorf("expected k in db 1 to be %q after %v but got %q", tc.db1, tc.args, db1)
+		}
+	}
+}
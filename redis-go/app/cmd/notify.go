@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+)
+
+// sets notify-keyspace-events, storing the canonical flags in the config store
+func (h *HostContext) SetKeyspaceEvents(flags string) error {
+	events, err := pubsub.ParseKeyspaceEvents(flags)
+	if err != nil {
+		return err
+	}
+
+	h.keyspaceEvents.Store(&events)
+	h.ConfigStore.Set("notify-keyspace-events", events.String(), store.ValueOptions{})
+
+	return nil
+}
+
+// publishes a keyspace event from the database when notify-keyspace-events selects it.
+// Called by the store with its lock held, publishing never blocks
+func (h *HostContext) NotifyKeyspaceEvent(db int, class store.EventClass, event string, key string) {
+	events := h.keyspaceEvents.Load()
+	if events == nil {
+		return
+	}
+
+	h.PubSub.PublishKeyspaceEvent(*events, db, byte(class), event, key)
+}
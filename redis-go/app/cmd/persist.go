@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// format: PERSIST <KEY>
+//
+// replies 1 if the key's expiry was removed, 0 if it doesn't exist or has no expiry
+func HandlePersist(ctx HandleContext) (string, error) {
+	if len(ctx.RespArr.Elements) != 2 {
+		return resp.WrongArgsError("persist").AsRespString(), nil
+	}
+
+	key := ctx.RespArr.Elements[1].(*resp.RespBulkString)
+
+	if !ctx.Store().Persist(key.Content) {
+		return resp.NewRespInteger(0).AsRespString(), nil
+	}
+
+	ctx.Propagate(&ctx.RespArr)
+
+	return resp.NewRespInteger(1).AsRespString(), nil
+}
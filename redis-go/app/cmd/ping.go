@@ -1,5 +1,27 @@
 package cmd
 
+import (
+	"fmt"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// format: PING [MESSAGE]
+//
+// a subscribed connection gets the reply as a pong message, as it can only be sent messages
 func HandlePing(ctx HandleContext) (string, error) {
+	message := ""
+	if len(ctx.RespArr.Elements) > 1 {
+		message = bulkStrings(ctx.RespArr.Elements[1:2])[0]
+	}
+
+	if ctx.Session.Subscribed() {
+		return fmt.Sprintf("*2\r\n$4\r\npong\r\n$%d\r\n%s\r\n", len(message), message), nil
+	}
+
+	if message != "" {
+		return resp.NewRespBulkString(message).AsRespString(), nil
+	}
+
 	return "+PONG\r\n", nil
 }
@@ -1,7 +1,7 @@
 package cmd
 
 import (
-	"os"
+	"strconv"
 
 	"github.com/codecrafters-io/redis-starter-go/app/rdb"
 	"github.com/codecrafters-io/redis-starter-go/app/replication"
@@ -9,51 +9,124 @@ import (
 	"github.com/google/uuid"
 )
 
+// replication channel is buffered as the backlog replay is pushed onto it before the
+// handler starts reading from it. A follower this many events behind is disconnected, and
+// continues from the backlog when it reconnects
+const replicationChannelSize = 4096
+
+// format: PSYNC <LEADER_REPL_ID> <OFFSET>
+//
+// continues from the backlog when the follower was following this leader and its offset
+// is still in the backlog, otherwise falls back to a full resync
 func HandlePSync(ctx HandleContext) {
-	// TODO hardcoded response for this stage only
-	ctx.Conn.Write([]byte(resp.PSyncResponse(ctx.HostCtx.LeaderReplId, 0).AsRespString()))
+	replid := "?"
+	offset := -1
+	if len(ctx.RespArr.Elements) == 3 {
+		replid = ctx.RespArr.Elements[1].(*resp.RespBulkString).Content
+		if o, err := strconv.Atoi(ctx.RespArr.Elements[2].(*resp.RespBulkString).Content); err == nil {
+			offset = o
+		}
+	}
 
-	rdbfile := rdb.ReadRdb()
-	rdbstr, err := rdb.SerializeB64RdbToString(rdbfile)
+	ctx.HostCtx.mu.Lock()
+	leaderReplId := ctx.HostCtx.LeaderReplId
+	// a follower of the leader this host was promoted from can continue up to where it was
+	promotedFrom := replid == ctx.HostCtx.prevReplId && offset <= ctx.HostCtx.prevReplOffset+1
+	ctx.HostCtx.mu.Unlock()
 
-	if err != nil {
-		ctx.Logger.Error().Err(err).Msg("Error serializing rdb file")
-		os.Exit(1)
+	requested := 0
+	if (replid == leaderReplId || promotedFrom) && offset > 0 {
+		requested = offset
 	}
 
-	ctx.Conn.Write([]byte(rdbstr))
-
 	subscriberId := uuid.New().String()
 
-	if err != nil {
-		ctx.Logger.Error().Err(err).Msg("Error generating subscription id")
-		os.Exit(1)
-	}
-
 	// begin replicating to the replica
-	replicationChannel := make(chan replication.PubSubEvent)
+	replicationChannel := make(chan replication.PubSubEvent, replicationChannelSize)
+	ack := make(chan replication.SubscriptionAck, 1)
 
-	ctx.HostCtx.PubSubManager.SubscriptionsChannel <- replication.SubscriberEvent{
-		Action:            replication.SubscribeAction,
-		SubscriberId:      subscriberId,
-		SubscriberChannel: replicationChannel,
-	}
-
-	defer func() {
+	unsubscribe := func() {
 		ctx.HostCtx.PubSubManager.SubscriptionsChannel <- replication.SubscriberEvent{
 			Action:       replication.UnsubscribeAction,
 			SubscriberId: subscriberId,
 		}
+	}
 
-		close(replicationChannel)
-	}()
+	// no command can write between subscribing and taking the snapshot, so a full resync's
+	// snapshot holds exactly what came before the stream it's followed by. Only expiries
+	// can, and a DEL of a key missing from the snapshot is harmless
+	var subscription replication.SubscriptionAck
+	var snapshot rdb.RdbContents
+	ctx.exclusively(func() {
+		// nothing is propagated whilst subscribing, so a full resync's stream starts with a SELECT
+		ctx.HostCtx.propagateMu.Lock()
+		ctx.HostCtx.PubSubManager.SubscriptionsChannel <- replication.SubscriberEvent{
+			Action:            replication.SubscribeAction,
+			SubscriberId:      subscriberId,
+			SubscriberChannel: replicationChannel,
+			Offset:            requested,
+			Ack:               ack,
+		}
+
+		subscription = <-ack
+		if !subscription.Continued {
+			ctx.HostCtx.resetPropagatedDb()
+		}
+		ctx.HostCtx.propagateMu.Unlock()
+
+		// taken without propagateMu as expiring keys propagate with the store's lock held
+		if !subscription.Continued {
+			snapshot = ctx.HostCtx.RdbSnapshot()
+		}
+	})
 
-	for event := range replicationChannel {
-		_, err := ctx.Conn.Write([]byte(event))
+	if subscription.Continued {
+		ctx.Logger.Info().Int("offset", offset).Msg("continuing replication from backlog")
+		ctx.Conn.Write([]byte(resp.PSyncContinueResponse(leaderReplId).AsRespString()))
+	} else {
+		ctx.Conn.Write([]byte(resp.PSyncResponse(leaderReplId, subscription.Offset).AsRespString()))
 
+		// full resync - send the follower the snapshot of the store
+		rdbfile, err := rdb.SerializeRdb(snapshot)
 		if err != nil {
-			ctx.Logger.Error().Err(err).Msg("Failed to write to follower, connection may be closed")
-			break // Exit the loop if the connection is closed
+			ctx.Logger.Error().Err(err).Msg("Error serializing rdb file")
+			unsubscribe()
+			return
+		}
+
+		ctx.Logger.Info().Int("bytes", len(rdbfile)).Msg("sending rdb snapshot to follower")
+		_, err = ctx.Conn.Write([]byte(rdb.SerializeRdbPayload(rdbfile)))
+		if err != nil {
+			ctx.Logger.Error().Err(err).Msg("Failed to write rdb to follower")
+			unsubscribe()
+			return
 		}
 	}
+
+	// the follower has everything up to the offset it was subscribed from
+	replicaId := ctx.ConnId.String()
+	ctx.HostCtx.Replicas.Register(replicaId, subscription.Offset)
+
+	// replicate in the background so the connection handler keeps reading the
+	// follower's REPLCONF ACKs
+	go func() {
+		defer func() {
+			ctx.HostCtx.Replicas.Unregister(replicaId)
+			unsubscribe()
+		}()
+
+		for event := range replicationChannel {
+			_, err := ctx.Conn.Write([]byte(event))
+
+			if err != nil {
+				ctx.Logger.Error().Err(err).Msg("Failed to write to follower, connection may be closed")
+				return // the connection is closed
+			}
+		}
+
+		// the channel is only closed whilst replicating when the follower fell too far behind
+		// or this host's dataset was replaced
+		ctx.Logger.Warn().Msg("follower dropped, disconnecting")
+		ctx.Conn.Close()
+	}()
 }
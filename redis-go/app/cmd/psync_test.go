@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFullResyncSendsTheLeadersData(t *testing.T) {
+	// arrange - data the leader has before the follower attaches
+	leader := newTestHost(t)
+	addr := serveTestHost(t, leader)
+	c := newTestClient(leader)
+	c.do("SET", "str", "v")
+	c.do("SET", "expiring", "v", "PX", "60000")
+	c.do("RPUSH", "list", "a", "b")
+	c.do("HSET", "hash", "f", "v")
+	c.do("ZADD", "zset", "1.5", "m")
+	c.do("XADD", "stream", "1-1", "f", "v")
+	c.do("SELECT", "1")
+	c.do("SET", "other", "v")
+
+	follower := newTestHost(t)
+
+	// act
+	followTestHost(t, follower, addr)
+	waitForCatchUp(t, follower, leader)
+
+	// assert
+	f := newTestClient(follower)
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"GET", "str"}, want: "$1\r\nv\r\n"},
+		{args: []string{"LRANGE", "list", "0", "-1"}, want: "*2\r\n$1\r\na\r\n$1\r\nb\r\n"},
+		{args: []string{"HGET", "hash", "f"}, want: "$1\r\nv\r\n"},
+		{args: []string{"ZSCORE", "zset", "m"}, want: "$3\r\n1.5\r\n"},
+		{args: []string{"XRANGE", "stream", "-", "+"}, want: "*1\r\n*2\r\n$3\r\n1-1\r\n*2\r\n$1\r\nf\r\n$1\r\nv\r\n"},
+		{args: []string{"SELECT", "1"}, want: "+OK\r\n"},
+		{args: []string{"GET", "other"}, want: "$1\r\nv\r\n"},
+	}
+
+	for _, tc := range cases {
+		if got := f.do(tc.args...); got != tc.want {
+			t.Errorf("expected %v on the follower to reply %q but got %q", tc.args, tc.want, got)
+		}
+	}
+
+	// the expiry comes across with the key
+	f.do("SELECT", "0")
+	res := f.do("PTTL", "expiring")
+	ttl, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(res, ":"), "\r\n"))
+	if err != nil || ttl <= 0 || ttl > 60000 {
+		t.Errorf("expected the follower's key to expire within a minute but got %q", res)
+	}
+}
+
+func TestFullResyncWithRacingWrites(t *testing.T) {
+	// arrange
+	leader := newTestHost(t)
+	addr := serveTestHost(t, leader)
+	follower := newTestHost(t)
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			client := newTestClient(leader)
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					client.do("RPUSH", "list", "x")
+				}
+			}
+		}()
+	}
+
+	// act - the follower syncs whilst the leader is being written to
+	time.Sleep(10 * time.Millisecond)
+	followTestHost(t, follower, addr)
+	waitFor(t, follower.LeaderLinkUp.Load)
+	time.Sleep(10 * time.Millisecond)
+
+	close(stop)
+	wg.Wait()
+	waitForCatchUp(t, follower, leader)
+
+	// assert - every write is either in the snapshot or replicated, never both
+	want := newTestClient(leader).do("LLEN", "list")
+	got := newTestClient(follower).do("LLEN", "list")
+	if got != want {
+		t.Errorf("expected the follower's list to match the leader's %q but got %q", want, got)
+	}
+}
@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// format: PUBLISH <CHANNEL> <MESSAGE>
+// format: SPUBLISH <SHARDCHANNEL> <MESSAGE>
+//
+// replies with the number of subscribers the message was sent to
+func HandlePublish(ctx HandleContext, command string) (string, error) {
+	elements := bulkStrings(ctx.RespArr.Elements[1:])
+
+	publish := ctx.HostCtx.PubSub.Publish
+	if command == "spublish" {
+		publish = ctx.HostCtx.PubSub.SPublish
+	}
+
+	received := publish(elements[0], elements[1])
+	return resp.NewRespInteger(received).AsRespString(), nil
+}
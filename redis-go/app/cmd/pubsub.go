@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// format: PUBSUB CHANNELS [PATTERN]
+// format: PUBSUB NUMSUB [CHANNEL ...]
+// format: PUBSUB NUMPAT
+// format: PUBSUB SHARDCHANNELS [PATTERN]
+// format: PUBSUB SHARDNUMSUB [SHARDCHANNEL ...]
+func HandlePubSub(ctx HandleContext) (string, error) {
+	elements := ctx.RespArr.Elements
+	subcommand := strings.ToLower(elements[1].(*resp.RespBulkString).Content)
+	broker := ctx.HostCtx.PubSub
+
+	switch subcommand {
+	case "channels", "shardchannels":
+		if len(elements) > 3 {
+			return resp.WrongArgsError("pubsub|" + subcommand).AsRespString(), nil
+		}
+
+		pattern := ""
+		if len(elements) == 3 {
+			pattern = elements[2].(*resp.RespBulkString).Content
+		}
+
+		if subcommand == "shardchannels" {
+			return resp.NewRespArrFromStrings(broker.ShardChannels(pattern)).AsRespString(), nil
+		}
+		return resp.NewRespArrFromStrings(broker.Channels(pattern)).AsRespString(), nil
+	case "numsub", "shardnumsub":
+		channels := bulkStrings(elements[2:])
+
+		numSub := broker.NumSub
+		if subcommand == "shardnumsub" {
+			numSub = broker.ShardNumSub
+		}
+
+		result := make([]resp.RespType, 0, 2*len(channels))
+		for i, count := range numSub(channels) {
+			result = append(result, resp.NewRespBulkString(channels[i]), resp.NewRespInteger(count))
+		}
+
+		return resp.NewRespArray(result).AsRespString(), nil
+	case "numpat":
+		if len(elements) != 2 {
+			return resp.WrongArgsError("pubsub|numpat").AsRespString(), nil
+		}
+
+		return resp.NewRespInteger(broker.NumPat()).AsRespString(), nil
+	default:
+		return resp.NewRespError("ERR unknown subcommand '" + subcommand + "'. Try PUBSUB HELP.").AsRespString(), nil
+	}
+}
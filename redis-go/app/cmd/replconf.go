@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"errors"
+	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/codecrafters-io/redis-starter-go/app/resp"
@@ -16,9 +18,18 @@ func HandleReplconf(ctx HandleContext) (string, error) {
 	case "capa":
 		return resp.OkResponse().AsRespString(), nil
 	case "getack":
-		res := resp.AckResponse(ctx.HostCtx.ProcessedBytes).AsRespString()
+		res := resp.AckResponse(ctx.HostCtx.processedBytes()).AsRespString()
 		ctx.Logger.Info().Msgf("[replconf] got an ACK request, responding with: %v", res)
 		return res, nil
+	case "ack":
+		// follower acknowledging its offset, deliberately no response
+		offset, err := strconv.Atoi(ctx.RespArr.Elements[2].(*resp.RespBulkString).Content)
+		if err != nil {
+			return "", fmt.Errorf("unexpected replconf ack offset: %w", err)
+		}
+
+		ctx.HostCtx.Replicas.Ack(ctx.ConnId.String(), offset)
+		return "", nil
 	default:
 		return "", errors.New("unexpected replconf command")
 	}
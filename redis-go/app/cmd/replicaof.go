@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/rdb"
+	"github.com/codecrafters-io/redis-starter-go/app/replication"
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// format: REPLICAOF <HOST> <PORT> | REPLICAOF NO ONE
+//
+// changes the role of the host at runtime, following a new leader discards the local data
+// and disconnects this host's own followers whilst NO ONE promotes the follower to a leader
+// with a new replication id
+func HandleReplicaOf(ctx HandleContext) (string, error) {
+	if len(ctx.RespArr.Elements) != 3 {
+		return resp.NewRespError("ERR wrong number of arguments for 'replicaof' command").AsRespString(), nil
+	}
+
+	host := ctx.RespArr.Elements[1].(*resp.RespBulkString).Content
+	port := ctx.RespArr.Elements[2].(*resp.RespBulkString).Content
+
+	if strings.ToLower(host) == "no" && strings.ToLower(port) == "one" {
+		if ctx.HostCtx.leaderAddr() == "" {
+			return resp.OkResponse().AsRespString(), nil
+		}
+
+		ctx.stopReplication()
+		ctx.HostCtx.setReplicaMode(false)
+
+		replid := replication.GenerateReplId()
+		ctx.HostCtx.mu.Lock()
+		offset := ctx.HostCtx.ProcessedBytes
+		ctx.HostCtx.prevReplId = ctx.HostCtx.LeaderReplId
+		ctx.HostCtx.prevReplOffset = offset
+		ctx.HostCtx.LeaderAddr = ""
+		ctx.HostCtx.LeaderReplId = replid
+		ctx.HostCtx.mu.Unlock()
+
+		// the stream carries on from where the old leader's left off, so its other followers
+		// can continue from here rather than needing a full resync
+		ctx.HostCtx.propagateMu.Lock()
+		ctx.HostCtx.PubSubManager.ContinueFrom(offset)
+		ctx.HostCtx.resetPropagatedDb()
+		ctx.HostCtx.propagateMu.Unlock()
+
+		ctx.Logger.Info().Str("replid", replid).Int("offset", offset).Msg("promoted to leader")
+
+		return resp.OkResponse().AsRespString(), nil
+	}
+
+	p, err := strconv.Atoi(port)
+	if err != nil || p <= 0 || p > 65535 {
+		return resp.NewRespError("ERR Invalid master port").AsRespString(), nil
+	}
+
+	leaderAddr := net.JoinHostPort(host, strconv.Itoa(p))
+	if leaderAddr == ctx.HostCtx.leaderAddr() {
+		return resp.NewRespSimpleString("OK Already connected to specified master").AsRespString(), nil
+	}
+
+	ctx.stopReplication()
+
+	// the new leader's dataset replaces ours, so our own followers can't carry on from it
+	if err := ctx.HostCtx.LoadRdb(rdb.RdbContents{}); err != nil {
+		return resp.NewRespError("ERR error discarding the dataset: " + err.Error()).AsRespString(), nil
+	}
+
+	ctx.HostCtx.mu.Lock()
+	ctx.HostCtx.ProcessedBytes = 0
+	ctx.HostCtx.LeaderReplId = replication.GenerateReplId()
+	ctx.HostCtx.prevReplId = ""
+	ctx.HostCtx.mu.Unlock()
+
+	ctx.HostCtx.dropFollowers()
+
+	ctx.HostCtx.StartReplication(leaderAddr)
+
+	ctx.Logger.Info().Str("leader", leaderAddr).Msg("following new leader")
+
+	return resp.OkResponse().AsRespString(), nil
+}
@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReplicaOfNoOnePromotesFollower(t *testing.T) {
+	// arrange
+	leader := newTestHost(t)
+	addr := serveTestHost(t, leader)
+	follower := newTestHost(t)
+	client := newTestClient(follower)
+
+	// INFO reads the replication state whilst the follower's connecting
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		info := newTestClient(follower)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				info.do("INFO", "replication")
+			}
+		}
+	}()
+
+	client.do("REPLICAOF", "127.0.0.1", strings.Split(addr, ":")[1])
+	t.Cleanup(follower.StopReplication)
+
+	newTestClient(leader).do("SET", "before", "1")
+	waitForCatchUp(t, follower, leader)
+
+	// act
+	res := client.do("REPLICAOF", "NO", "ONE")
+	newTestClient(leader).do("SET", "after", "1")
+	close(done)
+	wg.Wait()
+
+	// assert
+	if res != "+OK\r\n" {
+		t.Errorf("expected OK but got %q", res)
+	}
+
+	if info := client.do("INFO", "replication"); !strings.Contains(info, "role:master") {
+		t.Errorf("expected to be promoted but got %q", info)
+	}
+
+	if got := client.do("GET", "before"); got != "$1\r\n1\r\n" {
+		t.Errorf("expected to keep what was replicated before promotion but got %q", got)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if got := client.do("GET", "after"); got != "$-1\r\n" {
+		t.Errorf("expected nothing replicated after promotion but got %q", got)
+	}
+}
+
+func TestReplicaOfWhilstTransactionsWaitForTheCommandLock(t *testing.T) {
+	// arrange
+	leader := newTestHost(t)
+	addr := serveTestHost(t, leader)
+	follower := newTestHost(t)
+	followTestHost(t, follower, addr)
+	waitForCatchUp(t, follower, leader)
+
+	// the listener's commands queue on the command lock behind the EXECs
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, h := range []*HostContext{leader, follower} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			client := newTestClient(h)
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					client.do("MULTI")
+					client.do("INCR", "counter")
+					client.do("EXEC")
+				}
+			}
+		}()
+	}
+	defer func() {
+		close(done)
+		wg.Wait()
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+
+	// act
+	promoted := make(chan string)
+	go func() { promoted <- newTestClient(follower).do("REPLICAOF", "NO", "ONE") }()
+
+	// assert
+	select {
+	case res := <-promoted:
+		if res != "+OK\r\n" {
+			t.Errorf("expected OK but got %q", res)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected REPLICAOF not to deadlock with the listener")
+	}
+}
+
+func TestReplicaOfInsideTransaction(t *testing.T) {
+	// arrange
+	leader := newTestHost(t)
+	addr := serveTestHost(t, leader)
+	follower := newTestHost(t)
+	followTestHost(t, follower, addr)
+	waitForCatchUp(t, follower, leader)
+
+	client := newTestClient(follower)
+
+	// act - EXEC holds the command lock exclusively whilst replication is stopped
+	client.do("MULTI")
+	client.do("REPLICAOF", "NO", "ONE")
+	res := client.do("EXEC")
+	newTestClient(leader).do("SET", "after", "1")
+
+	// assert
+	if res != "*1\r\n+OK\r\n" {
+		t.Errorf("expected OK but got %q", res)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if got := client.do("GET", "after"); got != "$-1\r\n" {
+		t.Errorf("expected nothing replicated after promotion but got %q", got)
+	}
+}
+
+func TestPromotedFollowerContinuesTheLeadersOffset(t *testing.T) {
+	// arrange
+	leader := newTestHost(t)
+	addr := serveTestHost(t, leader)
+	follower := newTestHost(t)
+	followTestHost(t, follower, addr)
+
+	newTestClient(leader).do("SET", "key", "value")
+	waitForCatchUp(t, follower, leader)
+
+	processed := follower.processedBytes()
+	oldReplId := leader.LeaderReplId
+
+	client := newTestClient(follower)
+	client.do("REPLICAOF", "NO", "ONE")
+	promotedAddr := serveTestHost(t, follower)
+
+	// act - the old leader's other followers reconnect with where they got up to
+	caughtUp := psyncTestHost(t, promotedAddr, oldReplId, processed+1)
+	behind := psyncTestHost(t, promotedAddr, oldReplId, processed)
+
+	// assert
+	if !strings.HasPrefix(caughtUp, "+CONTINUE") {
+		t.Errorf("expected a follower level with the promoted one to continue but got %q", caughtUp)
+	}
+
+	if !strings.HasPrefix(behind, "+FULLRESYNC") {
+		t.Errorf("expected a follower behind the promoted one to need a full resync but got %q", behind)
+	}
+
+	info := client.do("INFO", "replication")
+	if want := "master_repl_offset:" + strconv.Itoa(processed) + "\r\n"; !strings.Contains(info, want) {
+		t.Errorf("expected %q in %q", want, info)
+	}
+
+	if want := "master_replid2:" + oldReplId + "\r\n"; !strings.Contains(info, want) {
+		t.Errorf("expected %q in %q", want, info)
+	}
+}
+
+func TestReplicaOfNewLeaderResyncsChainedFollowers(t *testing.T) {
+	// arrange - middle follows leader, and tail follows middle
+	leader := newTestHost(t)
+	leaderAddr := serveTestHost(t, leader)
+	middle := newTestHost(t)
+	middleAddr := serveTestHost(t, middle)
+	tail := newTestHost(t)
+
+	newTestClient(leader).do("SET", "old", "1")
+	followTestHost(t, middle, leaderAddr)
+	followTestHost(t, tail, middleAddr)
+	waitForCatchUp(t, middle, leader)
+	waitForCatchUp(t, tail, middle)
+
+	newLeader := newTestHost(t)
+	newLeaderAddr := serveTestHost(t, newLeader)
+	newTestClient(newLeader).do("SET", "new", "1")
+
+	// act
+	res := newTestClient(middle).do("REPLICAOF", "127.0.0.1", strings.Split(newLeaderAddr, ":")[1])
+	t.Cleanup(middle.StopReplication)
+	newTestClient(newLeader).do("SET", "after", "1")
+
+	// assert
+	if res != "+OK\r\n" {
+		t.Errorf("expected OK but got %q", res)
+	}
+
+	client := newTestClient(tail)
+	waitFor(t, func() bool { return client.do("GET", "after") == "$1\r\n1\r\n" })
+
+	if got := client.do("GET", "new"); got != "$1\r\n1\r\n" {
+		t.Errorf("expected the chained follower to have the new leader's data but got %q", got)
+	}
+
+	if got := client.do("GET", "old"); got != "$-1\r\n" {
+		t.Errorf("expected the chained follower to have dropped the discarded data but got %q", got)
+	}
+}
+
+// sends PSYNC to the host, returning the first line of its response
+func psyncTestHost(t *testing.T, addr string, replid string, offset int) string {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("error connecting: %v", err)
+	}
+	defer conn.Close()
+
+	fmt.Fprintf(conn, "*3\r\n$5\r\nPSYNC\r\n$%d\r\n%s\r\n$%d\r\n%d\r\n", len(replid), replid, len(strconv.Itoa(offset)), offset)
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatalf("error reading psync response: %v", err)
+	}
+
+	return line
+}
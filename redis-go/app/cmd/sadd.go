@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// format: SADD <KEY> <MEMBER> [MEMBER ...]
+func HandleSAdd(ctx HandleContext) (string, error) {
+	if len(ctx.RespArr.Elements) < 3 {
+		return resp.WrongArgsError("sadd").AsRespString(), nil
+	}
+
+	key := ctx.RespArr.Elements[1].(*resp.RespBulkString)
+
+	added, err := ctx.Store().SetAdd(key.Content, bulkStrings(ctx.RespArr.Elements[2:]))
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	if added > 0 {
+		ctx.Propagate(&ctx.RespArr)
+	}
+
+	return resp.NewRespInteger(added).AsRespString(), nil
+}
+
+// contents of the bulk string arguments
+func bulkStrings(elements []resp.RespType) []string {
+	values := make([]string, 0, len(elements))
+	for _, element := range elements {
+		values = append(values, element.(*resp.RespBulkString).Content)
+	}
+
+	return values
+}
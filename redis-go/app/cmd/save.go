@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/codecrafters-io/redis-starter-go/app/rdb"
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/script"
+)
+
+var (
+	ErrSaveInProgress = errors.New("ERR Background save already in progress")
+)
+
+func HandleSave(ctx HandleContext) (string, error) {
+	if !ctx.HostCtx.saving.CompareAndSwap(false, true) {
+		return resp.NewRespError(ErrSaveInProgress.Error()).AsRespString(), nil
+	}
+	defer ctx.HostCtx.saving.Store(false)
+
+	err := ctx.HostCtx.SaveRdb(ctx.HostCtx.RdbSnapshot())
+	if err != nil {
+		ctx.Logger.Error().Err(err).Msg("error saving rdb")
+		return resp.NewRespError("ERR " + err.Error()).AsRespString(), nil
+	}
+
+	return resp.OkResponse().AsRespString(), nil
+}
+
+// snapshot is taken synchronously so the file reflects the store at the time of the command,
+// serializing and writing it happens in the background
+func HandleBgSave(ctx HandleContext) (string, error) {
+	if !ctx.HostCtx.saving.CompareAndSwap(false, true) {
+		return resp.NewRespError(ErrSaveInProgress.Error()).AsRespString(), nil
+	}
+
+	contents := ctx.HostCtx.RdbSnapshot()
+	logger := ctx.Logger
+
+	go func() {
+		defer ctx.HostCtx.saving.Store(false)
+
+		if err := ctx.HostCtx.SaveRdb(contents); err != nil {
+			logger.Error().Err(err).Msg("background save failed")
+			return
+		}
+
+		logger.Info().Msg("background save complete")
+	}()
+
+	return resp.NewRespSimpleString("Background saving started").AsRespString(), nil
+}
+
+func (h *HostContext) RdbSnapshot() rdb.RdbContents {
+	contents := rdb.RdbContents{
+		Metadata:  rdb.NewMetadata(),
+		Functions: h.libraryCodes(),
+		Databases: make([]rdb.RedisDatabase, 0, len(h.Databases)),
+	}
+
+	for i, store := range h.Databases {
+		db := store.Snapshot()
+		db.Index = i
+		contents.Databases = append(contents.Databases, db)
+	}
+
+	return contents
+}
+
+// replaces every database and function library with the contents, routing each rdb
+// section by its index
+func (h *HostContext) LoadRdb(contents rdb.RdbContents) error {
+	for _, db := range contents.Databases {
+		if db.Index < 0 || db.Index >= len(h.Databases) {
+			return fmt.Errorf("rdb has database %d but only %d are configured", db.Index, len(h.Databases))
+		}
+	}
+
+	if err := h.Scripts.RestoreLibraries(contents.Functions, script.RestoreFlush); err != nil {
+		return fmt.Errorf("error loading function libraries: %w", err)
+	}
+
+	for _, store := range h.Databases {
+		store.Flush()
+	}
+
+	for _, db := range contents.Databases {
+		h.Databases[db.Index].Load(db)
+	}
+
+	return nil
+}
+
+func (h *HostContext) InitialiseFromRdbFile(dir string, filename string) {
+	h.Logger.Info().Str("path", dir).Str("filename", filename).Msg("loading rdb from file")
+
+	contents, err := rdb.ReadRdbFromFile(dir, filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			h.Logger.Info().Msg("No rdb file found")
+			return
+		}
+
+		h.Logger.Fatal().Err(err).Msg("Error reading rdb file")
+	}
+
+	if err := h.LoadRdb(*contents); err != nil {
+		h.Logger.Fatal().Err(err).Msg("Error loading rdb file")
+	}
+
+	for _, db := range contents.Databases {
+		h.Logger.Info().Int("db", db.Index).Int("keycount", len(db.Keys)).Int("expirycount", len(db.Expiries)).Msg("loaded db")
+	}
+}
+
+// writes the contents to the file configured by the dir & dbfilename config
+func (h *HostContext) SaveRdb(contents rdb.RdbContents) error {
+	dir, _ := h.ConfigStore.Get("dir")
+	dbfilename, _ := h.ConfigStore.Get("dbfilename")
+
+	sdir, ok := dir.(string)
+	if !ok {
+		return errors.New("expected dir config to be a string")
+	}
+
+	sdbfilename, ok := dbfilename.(string)
+	if !ok {
+		return errors.New("expected dbfilename config to be a string")
+	}
+
+	return rdb.WriteRdbToFile(sdir, sdbfilename, contents)
+}
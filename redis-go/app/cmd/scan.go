@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/glob"
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+)
+
+const defaultScanCount = 10
+
+type scanOptions struct {
+	match string // empty to match everything
+	count int    // how many elements to look at, not how many are returned
+	typ   string // empty for any type
+}
+
+// format: SCAN <CURSOR> [MATCH PATTERN] [COUNT COUNT] [TYPE TYPE]
+//
+// replies with the next cursor, 0 once the iteration is complete, and the batch of keys.
+// MATCH and TYPE filter the batch, so it may be empty before the end
+func HandleScan(ctx HandleContext) (string, error) {
+	if len(ctx.RespArr.Elements) < 2 {
+		return resp.WrongArgsError("scan").AsRespString(), nil
+	}
+
+	args := bulkStrings(ctx.RespArr.Elements[1:])
+
+	cursor, options, errRes := parseScanArgs(args, true)
+	if errRes != nil {
+		return errRes.AsRespString(), nil
+	}
+
+	next, keys := ctx.Store().Scan(cursor, options.count)
+
+	filtered := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if options.match != "" && !glob.Match(options.match, key) {
+			continue
+		}
+
+		if options.typ != "" {
+			val, exists := ctx.Store().Get(key)
+			if !exists || typeName(val) != options.typ {
+				continue
+			}
+		}
+
+		filtered = append(filtered, key)
+	}
+
+	return scanResponse(next, filtered).AsRespString(), nil
+}
+
+// format: HSCAN|SSCAN|ZSCAN <KEY> <CURSOR> [MATCH PATTERN] [COUNT COUNT]
+//
+// hashes reply with field value pairs and sorted sets with member score pairs
+func HandleCollectionScan(ctx HandleContext, command string) (string, error) {
+	command = strings.ToLower(command)
+
+	if len(ctx.RespArr.Elements) < 3 {
+		return resp.WrongArgsError(command).AsRespString(), nil
+	}
+
+	args := bulkStrings(ctx.RespArr.Elements[1:])
+	key := args[0]
+
+	cursor, options, errRes := parseScanArgs(args[1:], false)
+	if errRes != nil {
+		return errRes.AsRespString(), nil
+	}
+
+	var next uint64
+	var items []string
+	var err error
+
+	switch command {
+	case "hscan":
+		next, items, err = ctx.Store().HashScan(key, cursor, options.count)
+		items = filterPairs(items, options.match)
+	case "sscan":
+		next, items, err = ctx.Store().SetScan(key, cursor, options.count)
+
+		filtered := items[:0]
+		for _, member := range items {
+			if options.match == "" || glob.Match(options.match, member) {
+				filtered = append(filtered, member)
+			}
+		}
+		items = filtered
+	case "zscan":
+		var members []store.ZMember
+		next, members, err = ctx.Store().ZScan(key, cursor, options.count)
+
+		items = make([]string, 0, len(members)*2)
+		for _, m := range members {
+			items = append(items, m.Member, formatScore(m.Score))
+		}
+		items = filterPairs(items, options.match)
+	}
+
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	return scanResponse(next, items).AsRespString(), nil
+}
+
+// CURSOR [MATCH PATTERN] [COUNT COUNT] and TYPE TYPE if allowed
+func parseScanArgs(args []string, allowType bool) (uint64, scanOptions, *resp.RespError) {
+	options := scanOptions{count: defaultScanCount}
+
+	cursor, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return 0, options, resp.NewRespError("ERR invalid cursor")
+	}
+
+	syntaxErr := resp.NewRespError("ERR syntax error")
+
+	for i := 1; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			return 0, options, syntaxErr
+		}
+
+		switch strings.ToLower(args[i]) {
+		case "match":
+			options.match = args[i+1]
+			if options.match == "*" {
+				options.match = ""
+			}
+		case "count":
+			count, err := strconv.Atoi(args[i+1])
+			if err != nil {
+				return 0, options, resp.NewRespError("ERR value is not an integer or out of range")
+			}
+			if count < 1 {
+				return 0, options, syntaxErr
+			}
+			options.count = count
+		case "type":
+			if !allowType {
+				return 0, options, syntaxErr
+			}
+			options.typ = strings.ToLower(args[i+1])
+		default:
+			return 0, options, syntaxErr
+		}
+	}
+
+	return cursor, options, nil
+}
+
+// keeps the pairs whose first element matches the pattern
+func filterPairs(pairs []string, match string) []string {
+	if match == "" {
+		return pairs
+	}
+
+	filtered := make([]string, 0, len(pairs))
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if glob.Match(match, pairs[i]) {
+			filtered = append(filtered, pairs[i], pairs[i+1])
+		}
+	}
+
+	return filtered
+}
+
+// [cursor, [elements...]] with the cursor as a bulk string
+func scanResponse(next uint64, items []string) *resp.RespArray {
+	return resp.NewRespArray([]resp.RespType{
+		resp.NewRespBulkString(strconv.FormatUint(next, 10)),
+		resp.NewRespArrFromStrings(items),
+	})
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// format: SCRIPT LOAD <SCRIPT>
+// format: SCRIPT EXISTS <SHA1> [SHA1 ...]
+// format: SCRIPT FLUSH [ASYNC | SYNC]
+// format: SCRIPT KILL
+func HandleScript(ctx HandleContext) (string, error) {
+	elements := ctx.RespArr.Elements
+	subcommand := strings.ToLower(elements[1].(*resp.RespBulkString).Content)
+
+	switch subcommand {
+	case "load":
+		if len(elements) != 3 {
+			return resp.WrongArgsError("script|load").AsRespString(), nil
+		}
+
+		sha, err := ctx.HostCtx.Scripts.Load(elements[2].(*resp.RespBulkString).Content)
+		if err != nil {
+			return resp.NewRespError(err.Error()).AsRespString(), nil
+		}
+
+		return resp.NewRespBulkString(sha).AsRespString(), nil
+	case "exists":
+		if len(elements) < 3 {
+			return resp.WrongArgsError("script|exists").AsRespString(), nil
+		}
+
+		result := make([]resp.RespType, 0, len(elements)-2)
+		for _, sha := range bulkStrings(elements[2:]) {
+			exists := 0
+			if ctx.HostCtx.Scripts.Exists(sha) {
+				exists = 1
+			}
+			result = append(result, resp.NewRespInteger(exists))
+		}
+
+		return resp.NewRespArray(result).AsRespString(), nil
+	case "flush":
+		if len(elements) > 3 {
+			return resp.WrongArgsError("script|flush").AsRespString(), nil
+		}
+
+		// the cache is dropped straight away either way
+		if len(elements) == 3 {
+			mode := strings.ToLower(elements[2].(*resp.RespBulkString).Content)
+			if mode != "async" && mode != "sync" {
+				return resp.NewRespError("ERR SCRIPT FLUSH only support SYNC|ASYNC option").AsRespString(), nil
+			}
+		}
+
+		ctx.HostCtx.Scripts.Flush()
+		return resp.OkResponse().AsRespString(), nil
+	case "kill":
+		return HandleScriptKill(ctx)
+	default:
+		return resp.NewRespError("ERR unknown subcommand '" + subcommand + "'. Try SCRIPT HELP.").AsRespString(), nil
+	}
+}
+
+// stops the running script or function (SCRIPT KILL and FUNCTION KILL are the same),
+// so long as it hasn't written
+func HandleScriptKill(ctx HandleContext) (string, error) {
+	if len(ctx.RespArr.Elements) != 2 {
+		command := ctx.RespArr.Elements[0].(*resp.RespBulkString).Content
+		return resp.WrongArgsError(command + "|kill").AsRespString(), nil
+	}
+
+	if err := ctx.HostCtx.Scripts.Kill(); err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	return resp.OkResponse().AsRespString(), nil
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"strconv"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// format: SELECT <INDEX>
+//
+// selects the database for the rest of the connection's commands
+func HandleSelect(ctx HandleContext) (string, error) {
+	if len(ctx.RespArr.Elements) != 2 {
+		return resp.WrongArgsError("select").AsRespString(), nil
+	}
+
+	index, errRes := ctx.HostCtx.parseDbIndex(ctx.RespArr.Elements[1].(*resp.RespBulkString).Content)
+	if errRes != nil {
+		return errRes.AsRespString(), nil
+	}
+
+	if ctx.Session != nil {
+		ctx.Session.Db = index
+	}
+
+	return resp.OkResponse().AsRespString(), nil
+}
+
+func (h *HostContext) parseDbIndex(value string) (int, *resp.RespError) {
+	index, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, resp.NewRespError("ERR value is not an integer or out of range")
+	}
+
+	if index < 0 || index >= len(h.Databases) {
+		return 0, resp.NewRespError("ERR DB index is out of range")
+	}
+
+	return index, nil
+}
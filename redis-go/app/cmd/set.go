@@ -1,14 +1,25 @@
 package cmd
 
 import (
+	"fmt"
+	"math"
 	"strconv"
+	"strings"
+	"time"
 
-	"github.com/codecrafters-io/redis-starter-go/app/replication"
 	"github.com/codecrafters-io/redis-starter-go/app/resp"
 	"github.com/codecrafters-io/redis-starter-go/app/store"
 )
 
+// format: SET <KEY> <VALUE> [NX|XX] [GET] [EX SECONDS|PX MS|EXAT UNIX_SECONDS|PXAT UNIX_MS|KEEPTTL]
+//
+// replies OK, or nil when NX or XX prevented the set. With GET it replies with the old
+// value instead
 func HandleSet(ctx HandleContext) (string, error) {
+	if len(ctx.RespArr.Elements) < 3 {
+		return resp.WrongArgsError("set").AsRespString(), nil
+	}
+
 	key := ctx.RespArr.Elements[1].(*resp.RespBulkString)
 	val := ctx.RespArr.Elements[2].(*resp.RespBulkString)
 
@@ -18,31 +29,131 @@ func HandleSet(ctx HandleContext) (string, error) {
 		Str("type", ctx.RespArr.Elements[2].Type()).
 		Msg("setting key")
 
+	options, errRes := parseSetOptions(bulkStrings(ctx.RespArr.Elements[3:]))
+	if errRes != nil {
+		return errRes.AsRespString(), nil
+	}
+
+	result, err := setValue(ctx, key.Content, val.Content, options)
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	if options.Get {
+		return oldValueResponse(result).AsRespString(), nil
+	}
+
+	if !result.Written {
+		return resp.NullBulkString().AsRespString(), nil
+	}
+
+	return resp.OkResponse().AsRespString(), nil
+}
+
+// options may be in any order, but only one of the expiry options and one of NX|XX
+func parseSetOptions(args []string) (store.ValueOptions, *resp.RespError) {
 	options := store.ValueOptions{}
-	if len(ctx.RespArr.Elements) > 3 { // 1. set, 2. key, 3. val, [...options]
-		arg1 := ctx.RespArr.Elements[3].(*resp.RespBulkString)
+	syntaxErr := resp.NewRespError("ERR syntax error")
 
-		if arg1.Content == "px" {
-			arg1Val := ctx.RespArr.Elements[4].(*resp.RespBulkString) // TODO is this always a bulk string even tho its an int?
-			expiryMs, err := strconv.Atoi(arg1Val.Content)
+	expirySet := false
+
+	for i := 0; i < len(args); i++ {
+		switch option := strings.ToLower(args[i]); option {
+		case "nx":
+			if options.XX {
+				return options, syntaxErr
+			}
+			options.NX = true
+		case "xx":
+			if options.NX {
+				return options, syntaxErr
+			}
+			options.XX = true
+		case "get":
+			options.Get = true
+		case "keepttl":
+			if expirySet {
+				return options, syntaxErr
+			}
+			options.KeepTTL = true
+		case "ex", "px", "exat", "pxat":
+			if expirySet || options.KeepTTL || i+1 >= len(args) {
+				return options, syntaxErr
+			}
 
-			if err != nil {
-				ctx.Logger.Error().Msgf("Expected expiry to be an int but got %s", arg1Val.Content)
-				return "", err
+			expireAt, errRes := parseExpireAt(option, args[i+1], "set")
+			if errRes != nil {
+				return options, errRes
 			}
-			options.Expiry = uint64(expiryMs)
+
+			options.ExpireAt = expireAt
+			expirySet = true
+			i++
+		default:
+			return options, syntaxErr
+		}
+	}
+
+	return options, nil
+}
+
+// converts an EX, PX, EXAT or PXAT argument into a unix time in ms
+func parseExpireAt(unit string, value string, command string) (uint64, *resp.RespError) {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, resp.NewRespError("ERR value is not an integer or out of range")
+	}
+
+	invalid := resp.NewRespError(fmt.Sprintf("ERR invalid expire time in '%s' command", command))
+
+	if n <= 0 {
+		return 0, invalid
+	}
+
+	if unit == "ex" || unit == "exat" {
+		if n > math.MaxInt64/1000 {
+			return 0, invalid
 		}
+		n *= 1000
+	}
 
+	if unit == "ex" || unit == "px" {
+		now := time.Now().UnixMilli()
+		if n > math.MaxInt64-now {
+			return 0, invalid
+		}
+		n += now
 	}
 
-	err := ctx.HostCtx.Store.Set(key.Content, val.Content, options)
+	return uint64(n), nil
+}
 
-	ctx.HostCtx.PubSubManager.EventsChannel <- replication.PubSubEvent(ctx.RespArr.AsRespString())
+// sets the value and propagates it as a plain SET with an absolute expiry, so that
+// replicas and the aof don't depend on when the command is applied
+func setValue(ctx HandleContext, key string, value string, options store.ValueOptions) (store.SetResult, error) {
+	result, err := ctx.Store().Set(key, value, options)
+	if err != nil || !result.Written {
+		return result, err
+	}
 
-	if err != nil {
-		ctx.Logger.Error().Msgf("Error setting key %s with value %s: %v", key.Content, val.Content, err)
-		return "", err
+	command := []string{"SET", key, value}
+	if options.ExpireAt != 0 {
+		command = append(command, "PXAT", strconv.FormatUint(options.ExpireAt, 10))
+	} else if options.KeepTTL {
+		command = append(command, "KEEPTTL")
+	}
+
+	ctx.Propagate(resp.NewRespArrFromStrings(command))
+
+	return result, nil
+}
+
+// the value replaced by a set, the store has already checked it's a string
+func oldValueResponse(result store.SetResult) *resp.RespBulkString {
+	old, ok := result.Old.(string)
+	if !ok {
+		return resp.NullBulkString()
 	}
 
-	return resp.NewRespSimpleString("OK").AsRespString(), nil
+	return resp.NewRespBulkString(old)
 }
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// the key's ttl as -2 (missing), -1 (no expiry) or 1 (expires)
+func ttlKind(c *testClient, key string) int {
+	res := c.do("PTTL", key)
+	n, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(res, ":"), "\r\n"))
+	return min(n, 1)
+}
+
+func TestSetOptions(t *testing.T) {
+	future := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	futureMs := strconv.FormatInt(time.Now().Add(time.Hour).UnixMilli(), 10)
+
+	cases := []struct {
+		name  string
+		setup [][]string
+		args  []string
+		want  string
+		value string // GET reply afterwards
+		ttl   int    // see ttlKind
+	}{
+		{name: "plain", args: []string{"SET", "k", "v"}, want: "+OK\r\n", value: "$1\r\nv\r\n", ttl: -1},
+		{name: "EX", args: []string{"SET", "k", "v", "EX", "100"}, want: "+OK\r\n", value: "$1\r\nv\r\n", ttl: 1},
+		{name: "PX", args: []string{"SET", "k", "v", "px", "100000"}, want: "+OK\r\n", value: "$1\r\nv\r\n", ttl: 1},
+		{name: "EXAT", args: []string{"SET", "k", "v", "EXAT", future}, want: "+OK\r\n", value: "$1\r\nv\r\n", ttl: 1},
+		{name: "PXAT", args: []string{"SET", "k", "v", "PXAT", futureMs}, want: "+OK\r\n", value: "$1\r\nv\r\n", ttl: 1},
+		{name: "PXAT in the past", args: []string{"SET", "k", "v", "PXAT", "1"}, want: "+OK\r\n", value: "$-1\r\n", ttl: -2},
+		{name: "clears the ttl", setup: [][]string{{"SET", "k", "old", "EX", "100"}}, args: []string{"SET", "k", "v"}, want: "+OK\r\n", value: "$1\r\nv\r\n", ttl: -1},
+		{name: "KEEPTTL", setup: [][]string{{"SET", "k", "old", "EX", "100"}}, args: []string{"SET", "k", "v", "KEEPTTL"}, want: "+OK\r\n", value: "$1\r\nv\r\n", ttl: 1},
+		{name: "NX missing", args: []string{"SET", "k", "v", "NX"}, want: "+OK\r\n", value: "$1\r\nv\r\n", ttl: -1},
+		{name: "NX existing", setup: [][]string{{"SET", "k", "old"}}, args: []string{"SET", "k", "v", "NX"}, want: "$-1\r\n", value: "$3\r\nold\r\n", ttl: -1},
+		{name: "NX PX lock", args: []string{"SET", "k", "v", "NX", "PX", "30000"}, want: "+OK\r\n", value: "$1\r\nv\r\n", ttl: 1},
+		{name: "XX missing", args: []string{"SET", "k", "v", "XX"}, want: "$-1\r\n", value: "$-1\r\n", ttl: -2},
+		{name: "XX existing", setup: [][]string{{"SET", "k", "old"}}, args: []string{"SET", "k", "v", "xx"}, want: "+OK\r\n", value: "$1\r\nv\r\n", ttl: -1},
+		{name: "GET missing", args: []string{"SET", "k", "v", "GET"}, want: "$-1\r\n", value: "$1\r\nv\r\n", ttl: -1},
+		{name: "GET existing", setup: [][]string{{"SET", "k", "old"}}, args: []string{"SET", "k", "v", "GET"}, want: "$3\r\nold\r\n", value: "$1\r\nv\r\n", ttl: -1},
+		{name: "NX GET existing", setup: [][]string{{"SET", "k", "old"}}, args: []string{"SET", "k", "v", "NX", "GET"}, want: "$3\r\nold\r\n", value: "$3\r\nold\r\n", ttl: -1},
+		{name: "GET wrong type", setup: [][]string{{"RPUSH", "k", "a"}}, args: []string{"SET", "k", "v", "GET"}, want: "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n", value: "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n", ttl: -1},
+		{name: "options in any order", args: []string{"SET", "k", "v", "PX", "100000", "nx", "get"}, want: "$-1\r\n", value: "$1\r\nv\r\n", ttl: 1},
+		{name: "NX and XX", args: []string{"SET", "k", "v", "NX", "XX"}, want: "-ERR syntax error\r\n", value: "$-1\r\n", ttl: -2},
+		{name: "two expiries", args: []string{"SET", "k", "v", "EX", "1", "PX", "1000"}, want: "-ERR syntax error\r\n", value: "$-1\r\n", ttl: -2},
+		{name: "expiry and KEEPTTL", args: []string{"SET", "k", "v", "KEEPTTL", "EX", "1"}, want: "-ERR syntax error\r\n", value: "$-1\r\n", ttl: -2},
+		{name: "missing expiry", args: []string{"SET", "k", "v", "EX"}, want: "-ERR syntax error\r\n", value: "$-1\r\n", ttl: -2},
+		{name: "unknown option", args: []string{"SET", "k", "v", "FOO"}, want: "-ERR syntax error\r\n", value: "$-1\r\n", ttl: -2},
+		{name: "zero expiry", args: []string{"SET", "k", "v", "EX", "0"}, want: "-ERR invalid expire time in 'set' command\r\n", value: "$-1\r\n", ttl: -2},
+		{name: "negative expiry", args: []string{"SET", "k", "v", "PX", "-1"}, want: "-ERR invalid expire time in 'set' command\r\n", value: "$-1\r\n", ttl: -2},
+		{name: "expiry not an integer", args: []string{"SET", "k", "v", "EX", "1.5"}, want: "-ERR value is not an integer or out of range\r\n", value: "$-1\r\n", ttl: -2},
+		{name: "expiry overflows", args: []string{"SET", "k", "v", "EX", "9223372036854775807"}, want: "-ERR invalid expire time in 'set' command\r\n", value: "$-1\r\n", ttl: -2},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// arrange
+			c := newTestClient(newTestHost(t))
+			for _, args := range tc.setup {
+				c.do(args...)
+			}
+
+			// act
+			res := c.do(tc.args...)
+
+			// assert
+			if res != tc.want {
+				t.Errorf("expected %q but got %q", tc.want, res)
+			}
+
+			if got := c.do("GET", "k"); got != tc.value {
+				t.Errorf("expected GET to reply %q but got %q", tc.value, got)
+			}
+
+			if got := ttlKind(c, "k"); got != tc.ttl {
+				t.Errorf("expected ttl kind %d but got %d", tc.ttl, got)
+			}
+		})
+	}
+}
+
+func TestSetVariants(t *testing.T) {
+	cases := []struct {
+		name  string
+		setup [][]string
+		args  []string
+		want  string
+		value string
+		ttl   int
+	}{
+		{name: "SETNX missing", args: []string{"SETNX", "k", "v"}, want: ":1\r\n", value: "$1\r\nv\r\n", ttl: -1},
+		{name: "SETNX existing", setup: [][]string{{"SET", "k", "old"}}, args: []string{"SETNX", "k", "v"}, want: ":0\r\n", value: "$3\r\nold\r\n", ttl: -1},
+		{name: "SETEX", args: []string{"SETEX", "k", "100", "v"}, want: "+OK\r\n", value: "$1\r\nv\r\n", ttl: 1},
+		{name: "SETEX zero", args: []string{"SETEX", "k", "0", "v"}, want: "-ERR invalid expire time in 'setex' command\r\n", value: "$-1\r\n", ttl: -2},
+		{name: "PSETEX", args: []string{"PSETEX", "k", "100000", "v"}, want: "+OK\r\n", value: "$1\r\nv\r\n", ttl: 1},
+		{name: "PSETEX negative", args: []string{"PSETEX", "k", "-5", "v"}, want: "-ERR invalid expire time in 'psetex' command\r\n", value: "$-1\r\n", ttl: -2},
+		{name: "GETSET missing", args: []string{"GETSET", "k", "v"}, want: "$-1\r\n", value: "$1\r\nv\r\n", ttl: -1},
+		{name: "GETSET existing clears the ttl", setup: [][]string{{"SET", "k", "old", "EX", "100"}}, args: []string{"GETSET", "k", "v"}, want: "$3\r\nold\r\n", value: "$1\r\nv\r\n", ttl: -1},
+		{name: "GETSET wrong type", setup: [][]string{{"RPUSH", "k", "a"}}, args: []string{"GETSET", "k", "v"}, want: "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n", value: "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n", ttl: -1},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// arrange
+			c := newTestClient(newTestHost(t))
+			for _, args := range tc.setup {
+				c.do(args...)
+			}
+
+			// act
+			res := c.do(tc.args...)
+
+			// assert
+			if res != tc.want {
+				t.Errorf("expected %q but got %q", tc.want, res)
+			}
+
+			if got := c.do("GET", "k"); got != tc.value {
+				t.Errorf("expected GET to reply %q but got %q", tc.value, got)
+			}
+
+			if got := ttlKind(c, "k"); got != tc.ttl {
+				t.Errorf("expected ttl kind %d but got %d", tc.ttl, got)
+			}
+		})
+	}
+}
+
+func TestSetNxIsAtomic(t *testing.T) {
+	// arrange
+	h := newTestHost(t)
+	results := make(chan string, 20)
+
+	// act - clients racing for the same lock
+	for i := 0; i < cap(results); i++ {
+		go func() {
+			results <- newTestClient(h).do("SET", "lock", strconv.Itoa(i), "NX", "PX", "30000")
+		}()
+	}
+
+	// assert
+	acquired := 0
+	for i := 0; i < cap(results); i++ {
+		if <-results == "+OK\r\n" {
+			acquired++
+		}
+	}
+
+	if acquired != 1 {
+		t.Errorf("expected exactly one client to acquire the lock but got %d", acquired)
+	}
+}
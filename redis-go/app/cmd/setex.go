@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+)
+
+// format: SETEX <KEY> <SECONDS> <VALUE> or PSETEX <KEY> <MS> <VALUE>
+func HandleSetEx(ctx HandleContext, command string) (string, error) {
+	command = strings.ToLower(command)
+
+	if len(ctx.RespArr.Elements) != 4 {
+		return resp.WrongArgsError(command).AsRespString(), nil
+	}
+
+	key := ctx.RespArr.Elements[1].(*resp.RespBulkString)
+	ttl := ctx.RespArr.Elements[2].(*resp.RespBulkString)
+	val := ctx.RespArr.Elements[3].(*resp.RespBulkString)
+
+	unit := "ex"
+	if command == "psetex" {
+		unit = "px"
+	}
+
+	expireAt, errRes := parseExpireAt(unit, ttl.Content, command)
+	if errRes != nil {
+		return errRes.AsRespString(), nil
+	}
+
+	if _, err := setValue(ctx, key.Content, val.Content, store.ValueOptions{ExpireAt: expireAt}); err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	return resp.OkResponse().AsRespString(), nil
+}
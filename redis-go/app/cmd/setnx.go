@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+)
+
+// format: SETNX <KEY> <VALUE>
+//
+// replies 1 if the key was set, 0 if it already existed
+func HandleSetNx(ctx HandleContext) (string, error) {
+	if len(ctx.RespArr.Elements) != 3 {
+		return resp.WrongArgsError("setnx").AsRespString(), nil
+	}
+
+	key := ctx.RespArr.Elements[1].(*resp.RespBulkString)
+	val := ctx.RespArr.Elements[2].(*resp.RespBulkString)
+
+	result, err := setValue(ctx, key.Content, val.Content, store.ValueOptions{NX: true})
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	if !result.Written {
+		return resp.NewRespInteger(0).AsRespString(), nil
+	}
+
+	return resp.NewRespInteger(1).AsRespString(), nil
+}
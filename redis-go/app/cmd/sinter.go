@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+)
+
+var setOps = map[string]store.SetOp{
+	"sinter": store.SetInter,
+	"sunion": store.SetUnion,
+	"sdiff":  store.SetDiff,
+}
+
+// format: SINTER|SUNION|SDIFF <KEY> [KEY ...]
+//
+// missing keys are treated as empty sets
+func HandleSetOp(ctx HandleContext, command string) (string, error) {
+	if len(ctx.RespArr.Elements) < 2 {
+		return resp.WrongArgsError(command).AsRespString(), nil
+	}
+
+	op := setOps[strings.ToLower(command)]
+
+	set, err := ctx.Store().SetCombine(op, bulkStrings(ctx.RespArr.Elements[1:]))
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	return resp.NewRespArrFromStrings(sortedMembers(set)).AsRespString(), nil
+}
+
+// format: SINTERSTORE|SUNIONSTORE|SDIFFSTORE <DESTINATION> <KEY> [KEY ...]
+func HandleSetOpStore(ctx HandleContext, command string) (string, error) {
+	if len(ctx.RespArr.Elements) < 3 {
+		return resp.WrongArgsError(command).AsRespString(), nil
+	}
+
+	op := setOps[strings.TrimSuffix(strings.ToLower(command), "store")]
+	dest := ctx.RespArr.Elements[1].(*resp.RespBulkString)
+
+	size, err := ctx.Store().SetCombineStore(op, dest.Content, bulkStrings(ctx.RespArr.Elements[2:]))
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	ctx.Propagate(&ctx.RespArr)
+
+	return resp.NewRespInteger(size).AsRespString(), nil
+}
+
+// sorted so replies are stable
+func sortedMembers(set store.Set) []string {
+	members := make([]string, 0, len(set))
+	for member := range set {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+
+	return members
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import "testing"
+
+const wrongType = "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"
+
+func TestSetCommands(t *testing.T) {
+	cases := []struct {
+		name    string
+		setup   [][]string
+		args    []string
+		want    string
+		members string // SMEMBERS reply for the key afterwards
+		key     string
+	}{
+		{name: "SADD new", args: []string{"SADD", "s", "a", "b", "a"}, want: ":2\r\n", key: "s", members: "*2\r\n$1\r\na\r\n$1\r\nb\r\n"},
+		{name: "SADD existing", setup: [][]string{{"SADD", "s", "a"}}, args: []string{"SADD", "s", "a", "b"}, want: ":1\r\n", key: "s", members: "*2\r\n$1\r\na\r\n$1\r\nb\r\n"},
+		{name: "SADD wrong type", setup: [][]string{{"SET", "s", "v"}}, args: []string{"SADD", "s", "a"}, want: wrongType, key: "s", members: wrongType},
+		{name: "SREM", setup: [][]string{{"SADD", "s", "a", "b"}}, args: []string{"SREM", "s", "a", "c"}, want: ":1\r\n", key: "s", members: "*1\r\n$1\r\nb\r\n"},
+		{name: "SREM the last member", setup: [][]string{{"SADD", "s", "a"}}, args: []string{"SREM", "s", "a"}, want: ":1\r\n", key: "s", members: "*0\r\n"},
+		{name: "SREM wrong type", setup: [][]string{{"SET", "s", "v"}}, args: []string{"SREM", "s", "a"}, want: wrongType, key: "s", members: wrongType},
+
+		{name: "SINTER", args: []string{"SINTER", "x", "y"}, want: "*1\r\n$1\r\nb\r\n"},
+		{name: "SINTER missing key", args: []string{"SINTER", "x", "missing"}, want: "*0\r\n"},
+		{name: "SINTER wrong type", setup: [][]string{{"SET", "str", "v"}}, args: []string{"SINTER", "x", "str"}, want: wrongType},
+		{name: "SUNION", args: []string{"SUNION", "x", "y", "missing"}, want: "*3\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n"},
+		{name: "SDIFF", args: []string{"SDIFF", "x", "y"}, want: "*1\r\n$1\r\na\r\n"},
+		{name: "SDIFF missing first key", args: []string{"SDIFF", "missing", "x"}, want: "*0\r\n"},
+		{name: "SDIFF missing later key", args: []string{"SDIFF", "x", "missing"}, want: "*2\r\n$1\r\na\r\n$1\r\nb\r\n"},
+
+		{name: "SINTERSTORE", args: []string{"SINTERSTORE", "d", "x", "y"}, want: ":1\r\n", key: "d", members: "*1\r\n$1\r\nb\r\n"},
+		{name: "SINTERSTORE wrong type", setup: [][]string{{"SET", "str", "v"}}, args: []string{"SINTERSTORE", "d", "x", "str"}, want: wrongType, key: "d", members: "*0\r\n"},
+		{name: "SINTERSTORE overwrites any type", setup: [][]string{{"SET", "d", "v"}}, args: []string{"SINTERSTORE", "d", "x", "y"}, want: ":1\r\n", key: "d", members: "*1\r\n$1\r\nb\r\n"},
+		{name: "SUNIONSTORE into a source", args: []string{"SUNIONSTORE", "x", "x", "y"}, want: ":3\r\n", key: "x", members: "*3\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n"},
+		{name: "SDIFFSTORE into a source", args: []string{"SDIFFSTORE", "y", "x", "y"}, want: ":1\r\n", key: "y", members: "*1\r\n$1\r\na\r\n"},
+		{name: "empty result deletes the destination", setup: [][]string{{"SADD", "d", "z"}}, args: []string{"SINTERSTORE", "d", "x", "missing"}, want: ":0\r\n", key: "d", members: "*0\r\n"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// arrange
+			h := newTestHost(t)
+			c := newTestClient(h)
+			c.do("SADD", "x", "a", "b")
+			c.do("SADD", "y", "b", "c")
+			for _, args := range tc.setup {
+				c.do(args...)
+			}
+
+			// act
+			got := c.do(tc.args...)
+
+			// assert
+			if got != tc.want {
+				t.Errorf("expected %v to reply %q but got %q", tc.args, tc.want, got)
+			}
+
+			if tc.key == "" {
+				return
+			}
+
+			if members := c.do("SMEMBERS", tc.key); members != tc.members {
+				t.Errorf("expected SMEMBERS %s to be %q but got %q", tc.key, tc.members, members)
+			}
+		})
+	}
+}
+
+func TestSetOpStoreWithEmptyResultRemovesTheKey(t *testing.T) {
+	// arrange
+	h := newTestHost(t)
+	c := newTestClient(h)
+	c.do("SADD", "x", "a")
+	c.do("SADD", "d", "z")
+
+	// act
+	res := c.do("SDIFFSTORE", "d", "x", "x")
+
+	// assert
+	if res != ":0\r\n" {
+		t.Errorf("expected :0 but got %q", res)
+	}
+
+	if typ := c.do("TYPE", "d"); typ != "+none\r\n" {
+		t.Errorf("expected the destination to be deleted but TYPE replied %q", typ)
+	}
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// format: SISMEMBER <KEY> <MEMBER>
+func HandleSIsMember(ctx HandleContext) (string, error) {
+	if len(ctx.RespArr.Elements) != 3 {
+		return resp.WrongArgsError("sismember").AsRespString(), nil
+	}
+
+	key := ctx.RespArr.Elements[1].(*resp.RespBulkString)
+	member := ctx.RespArr.Elements[2].(*resp.RespBulkString)
+
+	exists, err := ctx.Store().SetIsMember(key.Content, member.Content)
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	if exists {
+		return resp.NewRespInteger(1).AsRespString(), nil
+	}
+
+	return resp.NewRespInteger(0).AsRespString(), nil
+}
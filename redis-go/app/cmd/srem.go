@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// format: SREM <KEY> <MEMBER> [MEMBER ...]
+func HandleSRem(ctx HandleContext) (string, error) {
+	if len(ctx.RespArr.Elements) < 3 {
+		return resp.WrongArgsError("srem").AsRespString(), nil
+	}
+
+	key := ctx.RespArr.Elements[1].(*resp.RespBulkString)
+
+	removed, err := ctx.Store().SetRemove(key.Content, bulkStrings(ctx.RespArr.Elements[2:]))
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	if removed > 0 {
+		ctx.Propagate(&ctx.RespArr)
+	}
+
+	return resp.NewRespInteger(removed).AsRespString(), nil
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"net"
+
+	"github.com/codecrafters-io/redis-starter-go/app/pubsub"
+)
+
+// commands a connection can run whilst it's subscribed to anything
+var subscriberCommands = map[string]bool{
+	"ping":         true,
+	"psubscribe":   true,
+	"punsubscribe": true,
+	"ssubscribe":   true,
+	"subscribe":    true,
+	"sunsubscribe": true,
+	"unsubscribe":  true,
+}
+
+// format: SUBSCRIBE <CHANNEL> [CHANNEL ...]
+// format: PSUBSCRIBE <PATTERN> [PATTERN ...]
+// format: UNSUBSCRIBE [CHANNEL ...]
+// format: PUNSUBSCRIBE [PATTERN ...]
+// format: SSUBSCRIBE <SHARDCHANNEL> [SHARDCHANNEL ...]
+// format: SUNSUBSCRIBE [SHARDCHANNEL ...]
+//
+// each (un)subscribe is confirmed with its own reply, queued behind any messages already
+// waiting for the connection so they arrive in order
+func HandleSubscribe(ctx HandleContext, command string) (string, error) {
+	if ctx.Session == nil {
+		return "", nil
+	}
+
+	s := ctx.Session.startSubscriber(ctx.Conn)
+	names := bulkStrings(ctx.RespArr.Elements[1:])
+	broker := ctx.HostCtx.PubSub
+
+	switch command {
+	case "subscribe":
+		broker.Subscribe(s, names)
+	case "psubscribe":
+		broker.PSubscribe(s, names)
+	case "unsubscribe":
+		broker.Unsubscribe(s, names)
+	case "punsubscribe":
+		broker.PUnsubscribe(s, names)
+	case "ssubscribe":
+		broker.SSubscribe(s, names)
+	case "sunsubscribe":
+		broker.SUnsubscribe(s, names)
+	}
+
+	return "", nil
+}
+
+// whether the connection is subscribed to anything, so only subscriberCommands can run
+func (s *Session) Subscribed() bool {
+	return s != nil && s.subscriber != nil && s.subscriber.Count() > 0
+}
+
+// writes the reply to the connection. Once the session has subscribed, replies are queued
+// behind the messages it's been sent instead of overtaking them
+func (s *Session) Write(conn net.Conn, res string) {
+	if s != nil && s.subscriber != nil {
+		s.subscriber.Send(res)
+		return
+	}
+
+	conn.Write([]byte(res))
+}
+
+// the session's subscriber, started on its first (un)subscribe along with a goroutine
+// writing its queue to the connection until the session ends. A subscriber that can't
+// keep up has lost messages, so its connection is closed
+func (s *Session) startSubscriber(conn net.Conn) *pubsub.Subscriber {
+	if s.subscriber != nil {
+		return s.subscriber
+	}
+
+	sub := pubsub.NewSubscriber()
+	ended := make(chan struct{})
+	s.subscriber = sub
+	s.endSubscriber = func() { close(ended) }
+
+	go func() {
+		for {
+			select {
+			case res := <-sub.Queue():
+				if _, err := conn.Write([]byte(res)); err != nil {
+					return
+				}
+			case <-ended:
+				return
+			}
+		}
+	}()
+
+	// watched separately as the writer is likely stuck writing to the slow connection
+	go func() {
+		select {
+		case <-sub.Dropped():
+			conn.Close()
+		case <-ended:
+		}
+	}()
+
+	return sub
+}
+
+// drops the session's subscriptions, called when the connection closes
+func (h *HostContext) UnsubscribeAll(session *Session) {
+	if session == nil || session.subscriber == nil {
+		return
+	}
+
+	h.PubSub.UnsubscribeAll(session.subscriber)
+	session.endSubscriber()
+	session.subscriber = nil
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"strconv"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// format: SWAPDB <INDEX1> <INDEX2>
+//
+// swaps the contents of the databases, connections keep their selected index so see the
+// other database's data
+func HandleSwapDb(ctx HandleContext) (string, error) {
+	if len(ctx.RespArr.Elements) != 3 {
+		return resp.WrongArgsError("swapdb").AsRespString(), nil
+	}
+
+	args := bulkStrings(ctx.RespArr.Elements[1:])
+
+	if _, err := strconv.Atoi(args[0]); err != nil {
+		return resp.NewRespError("ERR invalid first DB index").AsRespString(), nil
+	}
+
+	if _, err := strconv.Atoi(args[1]); err != nil {
+		return resp.NewRespError("ERR invalid second DB index").AsRespString(), nil
+	}
+
+	first, errRes := ctx.HostCtx.parseDbIndex(args[0])
+	if errRes != nil {
+		return errRes.AsRespString(), nil
+	}
+
+	second, errRes := ctx.HostCtx.parseDbIndex(args[1])
+	if errRes != nil {
+		return errRes.AsRespString(), nil
+	}
+
+	firstServed, secondServed := ctx.HostCtx.Databases[first].SwapWith(ctx.HostCtx.Databases[second])
+
+	ctx.wrote(ctx.HostCtx.Propagate(-1, &ctx.RespArr))
+	ctx.wrote(ctx.HostCtx.propagateListPops(first, firstServed))
+	ctx.wrote(ctx.HostCtx.propagateListPops(second, secondServed))
+
+	return resp.OkResponse().AsRespString(), nil
+}
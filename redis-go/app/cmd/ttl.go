@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// format: TTL|PTTL <KEY>
+//
+// replies with the seconds (or ms) until the key expires, -1 if it has no expiry and -2
+// if it doesn't exist
+func HandleTTL(ctx HandleContext, command string) (string, error) {
+	if len(ctx.RespArr.Elements) != 2 {
+		return resp.WrongArgsError(command).AsRespString(), nil
+	}
+
+	key := ctx.RespArr.Elements[1].(*resp.RespBulkString)
+
+	ttl := ctx.Store().PTTL(key.Content)
+	if ttl >= 0 && strings.ToLower(command) == "ttl" {
+		ttl = (ttl + 500) / 1000
+	}
+
+	return resp.NewRespInteger(int(ttl)).AsRespString(), nil
+}
@@ -9,22 +9,36 @@ import (
 
 func HandleType(ctx HandleContext) (string, error) {
 	key := ctx.RespArr.Elements[1].(*resp.RespBulkString)
-	val, exists := ctx.HostCtx.Store.Get(key.Content)
+	val, exists := ctx.Store().Get(key.Content)
 
 	if !exists {
 		return resp.NewRespSimpleString("none").AsRespString(), nil
 	}
 
+	name := typeName(val)
+	if name == "" {
+		return "", fmt.Errorf("unexpected value type")
+	}
+
+	return resp.NewRespSimpleString(name).AsRespString(), nil
+}
+
+// the redis type name of the value, empty if it's unknown
+func typeName(val interface{}) string {
 	switch val.(type) {
 	case string:
-		{
-			return resp.NewRespSimpleString("string").AsRespString(), nil
-		}
-	case store.Stream:
-		{
-			return resp.NewRespSimpleString("stream").AsRespString(), nil
-		}
+		return "string"
+	case store.Hash:
+		return "hash"
+	case *store.SortedSet:
+		return "zset"
+	case store.Set:
+		return "set"
+	case store.List:
+		return "list"
+	case *store.Stream:
+		return "stream"
 	default:
-		return "", fmt.Errorf("unexpected value type")
+		return ""
 	}
 }
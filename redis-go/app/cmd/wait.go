@@ -1,5 +1,52 @@
 package cmd
 
+import (
+	"strconv"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/replication"
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// format: WAIT <NUMREPLICAS> <TIMEOUT_MS>
+//
+// blocks until numreplicas followers have acknowledged the connection's writes so far or
+// the timeout expires (0 blocks forever), returning the number of followers that have
+// acknowledged them
 func HandleWait(ctx HandleContext) (string, error) {
-	return ":0\r\n", nil
+	if len(ctx.RespArr.Elements) != 3 {
+		return resp.NewRespError("ERR wrong number of arguments for 'wait' command").AsRespString(), nil
+	}
+
+	numreplicas, err := strconv.Atoi(ctx.RespArr.Elements[1].(*resp.RespBulkString).Content)
+	if err != nil || numreplicas < 0 {
+		return resp.NewRespError("ERR value is not an integer or out of range").AsRespString(), nil
+	}
+
+	timeout, err := strconv.Atoi(ctx.RespArr.Elements[2].(*resp.RespBulkString).Content)
+	if err != nil || timeout < 0 {
+		return resp.NewRespError("ERR timeout is not an integer or out of range").AsRespString(), nil
+	}
+
+	replicas := ctx.HostCtx.Replicas
+	offset := 0
+	if ctx.Session != nil {
+		offset = ctx.Session.replOffset
+	}
+
+	// where the command can't block (inside a transaction) it just counts the followers
+	acked := replicas.CountAcked(offset)
+	if acked < numreplicas && ctx.CanBlock {
+		// ask the followers where they're up to, the getack itself is part of the replication
+		// stream but isn't needed to satisfy the wait so the target offset is taken before it
+		ctx.HostCtx.PubSubManager.Publish(replication.PubSubEvent(resp.GetAckCommand().AsRespString()))
+
+		ctx.unlockedWhile(func() {
+			acked = replicas.WaitForAcks(offset, numreplicas, time.Duration(timeout)*time.Millisecond)
+		})
+	}
+
+	ctx.Logger.Info().Int("offset", offset).Int("numreplicas", numreplicas).Int("acked", acked).Msg("wait complete")
+
+	return resp.NewRespInteger(acked).AsRespString(), nil
 }
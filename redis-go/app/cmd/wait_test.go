@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWaitForFollowersToAcknowledgeWrites(t *testing.T) {
+	// arrange
+	leader := newTestHost(t)
+	addr := serveTestHost(t, leader)
+	followTestHost(t, newTestHost(t), addr)
+	waitFor(t, func() bool { return leader.Replicas.Count() == 1 })
+
+	client := newTestClient(leader)
+	client.do("SET", "key", "value")
+
+	// act
+	acked := client.do("WAIT", "1", "5000")
+
+	start := time.Now()
+	timedOut := client.do("WAIT", "2", "50")
+	elapsed := time.Since(start)
+
+	// assert
+	if acked != ":1\r\n" {
+		t.Errorf("expected the follower to acknowledge the write but got %q", acked)
+	}
+
+	if timedOut != ":1\r\n" {
+		t.Errorf("expected the 1 follower that acknowledged after the timeout but got %q", timedOut)
+	}
+
+	if elapsed < 50*time.Millisecond {
+		t.Errorf("expected to wait for the timeout but returned after %v", elapsed)
+	}
+}
+
+func TestWaitWithoutWritesDoesntBlock(t *testing.T) {
+	// arrange
+	leader := newTestHost(t)
+	addr := serveTestHost(t, leader)
+	followTestHost(t, newTestHost(t), addr)
+	waitFor(t, func() bool { return leader.Replicas.Count() == 1 })
+
+	// another client's write isn't this one's to wait for
+	newTestClient(leader).do("SET", "key", "value")
+
+	// act
+	res := newTestClient(leader).do("WAIT", "1", "0")
+
+	// assert
+	if res != ":1\r\n" {
+		t.Errorf("expected the connected follower but got %q", res)
+	}
+}
+
+func TestWaitInsideTransactionDoesntBlock(t *testing.T) {
+	// arrange
+	leader := newTestHost(t)
+	client := newTestClient(leader)
+
+	// act
+	client.do("MULTI")
+	client.do("SET", "key", "value")
+	client.do("WAIT", "1", "0")
+	res := client.do("EXEC")
+
+	// assert
+	if res != "*2\r\n+OK\r\n:0\r\n" {
+		t.Errorf("expected WAIT to count no followers without blocking but got %q", res)
+	}
+}
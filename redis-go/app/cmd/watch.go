@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+)
+
+// a key watched by a connection, along with its state at the time
+type watch struct {
+	db    int
+	key   string
+	token store.WatchToken
+}
+
+// format: WATCH <KEY> [KEY ...]
+//
+// the connection's next EXEC fails if any of the keys are modified (by any connection,
+// including this one) or expire before then
+func HandleWatch(ctx HandleContext) (string, error) {
+	if len(ctx.RespArr.Elements) < 2 {
+		return resp.WrongArgsError("watch").AsRespString(), nil
+	}
+
+	if ctx.Session == nil {
+		return resp.OkResponse().AsRespString(), nil
+	}
+
+	for _, key := range bulkStrings(ctx.RespArr.Elements[1:]) {
+		ctx.Session.watches = append(ctx.Session.watches, watch{
+			db:    ctx.Db(),
+			key:   key,
+			token: ctx.Store().Watch(key),
+		})
+	}
+
+	return resp.OkResponse().AsRespString(), nil
+}
+
+// format: UNWATCH
+func HandleUnwatch(ctx HandleContext) (string, error) {
+	ctx.HostCtx.UnwatchAll(ctx.Session)
+	return resp.OkResponse().AsRespString(), nil
+}
+
+// forgets the session's watches, called after EXEC, DISCARD and when the connection closes
+func (h *HostContext) UnwatchAll(session *Session) {
+	if session == nil {
+		return
+	}
+
+	for _, w := range session.watches {
+		h.Databases[w.db].Unwatch(w.key)
+	}
+
+	session.watches = nil
+}
+
+// whether any of the session's watched keys have changed since they were watched
+func (h *HostContext) watchesChanged(session *Session) bool {
+	if session == nil {
+		return false
+	}
+
+	for _, w := range session.watches {
+		if h.Databases[w.db].Changed(w.key, w.token) {
+			return true
+		}
+	}
+
+	return false
+}
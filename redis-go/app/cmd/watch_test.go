@@ -0,0 +1,91 @@
+package cmd
+
+import "testing"
+
+func TestExecAbortsWhenAWatchedKeyChanges(t *testing.T) {
+	// arrange
+	h := newTestHost(t)
+	c, other := newTestClient(h), newTestClient(h)
+	c.do("SET", "k", "1")
+	c.do("WATCH", "k")
+	other.do("SET", "k", "2")
+
+	// act
+	c.do("MULTI")
+	c.do("SET", "k", "3")
+	res := c.do("EXEC")
+
+	// assert
+	if res != "*-1\r\n" {
+		t.Errorf("expected a null reply but got %q", res)
+	}
+
+	if got := c.do("GET", "k"); got != "$1\r\n2\r\n" {
+		t.Errorf("expected k to keep the other client's value but got %q", got)
+	}
+
+	// EXEC unwatches, so the next transaction runs
+	c.do("MULTI")
+	c.do("SET", "k", "3")
+	if res := c.do("EXEC"); res != "*1\r\n+OK\r\n" {
+		t.Errorf("expected the next transaction to run but got %q", res)
+	}
+}
+
+func TestExecRunsWhenWatchedKeysAreUnchanged(t *testing.T) {
+	// arrange
+	h := newTestHost(t)
+	c, other := newTestClient(h), newTestClient(h)
+	c.do("SET", "k", "1")
+	c.do("WATCH", "k", "missing")
+	other.do("GET", "k")
+	other.do("SET", "unwatched", "1")
+
+	// act
+	c.do("MULTI")
+	c.do("INCR", "k")
+	res := c.do("EXEC")
+
+	// assert
+	if res != "*1\r\n:2\r\n" {
+		t.Errorf("expected the transaction to run but got %q", res)
+	}
+}
+
+func TestUnwatchForgetsChanges(t *testing.T) {
+	// arrange
+	h := newTestHost(t)
+	c, other := newTestClient(h), newTestClient(h)
+	c.do("WATCH", "k")
+	other.do("SET", "k", "1")
+
+	// act
+	c.do("UNWATCH")
+	c.do("MULTI")
+	c.do("GET", "k")
+	res := c.do("EXEC")
+
+	// assert
+	if res != "*1\r\n$1\r\n1\r\n" {
+		t.Errorf("expected the transaction to run but got %q", res)
+	}
+}
+
+func TestWatchedKeyChangedInAnotherDatabaseIsntAChange(t *testing.T) {
+	// arrange
+	h := newTestHost(t)
+	c, other := newTestClient(h), newTestClient(h)
+	c.do("WATCH", "k")
+	other.do("SELECT", "1")
+	other.do("SET", "k", "1")
+
+	// act
+	c.do("MULTI")
+	c.do("PING")
+	res := c.do("EXEC")
+
+	// assert
+	if res != "*1\r\n+PONG\r\n" {
+		t.Errorf("expected the transaction to run but got %q", res)
+	}
+}
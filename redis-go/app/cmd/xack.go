@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+)
+
+// format: XACK <KEY> <GROUP> <ID ...>
+//
+// replies with the number of entries that were pending and are now acknowledged
+func HandleXAck(ctx HandleContext) (string, error) {
+	args := bulkStrings(ctx.RespArr.Elements[1:])
+	key, group := args[0], args[1]
+
+	ids, err := parseStreamIds(args[2:])
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	acked, err := ctx.Store().XAck(key, group, ids)
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	if acked > 0 {
+		ctx.Propagate(&ctx.RespArr)
+	}
+
+	return resp.NewRespInteger(acked).AsRespString(), nil
+}
+
+func parseStreamIds(args []string) ([]store.StreamId, error) {
+	ids := make([]store.StreamId, 0, len(args))
+	for _, arg := range args {
+		id, err := store.ParseStreamId(arg, 0)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
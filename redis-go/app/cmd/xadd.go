@@ -1,28 +1,73 @@
 package cmd
 
 import (
+	"strconv"
+	"strings"
+
 	"github.com/codecrafters-io/redis-starter-go/app/resp"
 	"github.com/codecrafters-io/redis-starter-go/app/store"
 )
 
-// redis-cli XADD stream_key 0-1 foo bar
+// format: XADD <KEY> [NOMKSTREAM] [MAXLEN|MINID [=|~] THRESHOLD [LIMIT COUNT]] <*|ID> <FIELD> <VALUE> [FIELD VALUE ...]
+//
+// replies with the id of the added entry, or nil when NOMKSTREAM and the stream doesn't
+// exist. Replicated with the id it was given and any trim as an exact MAXLEN
+// e.g. redis-cli XADD stream_key 0-1 foo bar
 func HandleXAdd(ctx HandleContext) (string, error) {
-	streamkey := ctx.RespArr.Elements[1].(*resp.RespBulkString)
-	seqkey := ctx.RespArr.Elements[2].(*resp.RespBulkString)
-	key := ctx.RespArr.Elements[3].(*resp.RespBulkString)
-	value := ctx.RespArr.Elements[4].(*resp.RespBulkString)
+	args := bulkStrings(ctx.RespArr.Elements[1:])
+	streamkey := args[0]
+	args = args[1:]
+
+	options := store.StreamAddOptions{}
+
+options:
+	for len(args) > 0 {
+		switch strings.ToLower(args[0]) {
+		case "nomkstream":
+			options.NoMkStream = true
+			args = args[1:]
+		case "maxlen", "minid":
+			if options.Trim != nil {
+				return resp.NewRespError("ERR syntax error, MAXLEN and MINID options at the same time are not compatible").AsRespString(), nil
+			}
+
+			trim, rest, errRes := parseStreamTrim(args)
+			if errRes != nil {
+				return errRes.AsRespString(), nil
+			}
+			options.Trim, args = &trim, rest
+		default:
+			break options
+		}
+	}
+
+	if len(args) < 3 || len(args)%2 == 0 {
+		return resp.WrongArgsError("xadd").AsRespString(), nil
+	}
+
+	seqkey, fields := args[0], args[1:]
 
 	ctx.Logger.Info().
-		Str("key", key.Content).Str("value", value.Content).
-		Str("stream_key", streamkey.Content).
-		Str("seq_key", seqkey.Content).
+		Str("stream_key", streamkey).
+		Str("seq_key", seqkey).
+		Strs("fields", fields).
 		Msg("xadd handler")
 
-	skey, err := ctx.HostCtx.Store.SetStream(streamkey.Content, seqkey.Content, key.Content, value.Content, store.ValueOptions{})
-
+	result, err := ctx.Store().SetStream(streamkey, seqkey, fields, options)
 	if err != nil {
 		return resp.NewRespError(err.Error()).AsRespString(), nil
 	}
 
-	return resp.NewRespBulkString(skey).AsRespString(), nil
+	if result.Id == "" {
+		return resp.NullBulkString().AsRespString(), nil
+	}
+
+	command := []string{"XADD", streamkey}
+	if result.Trimmed > 0 {
+		command = append(command, "MAXLEN", strconv.Itoa(result.Length))
+	}
+	command = append(append(command, result.Id), fields...)
+	ctx.Propagate(resp.NewRespArrFromStrings(command))
+
+	return resp.NewRespBulkString(result.Id).AsRespString(), nil
 }
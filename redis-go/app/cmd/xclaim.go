@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+)
+
+// format: XCLAIM <KEY> <GROUP> <CONSUMER> <MIN_IDLE_MS> <ID ...> [IDLE MS] [TIME UNIX_MS] [RETRYCOUNT N] [FORCE] [JUSTID] [LASTID ID]
+//
+// gives the consumer those of the pending entries idle for at least min idle ms, replying
+// with the claimed entries (or only their ids with JUSTID)
+func HandleXClaim(ctx HandleContext) (string, error) {
+	args := bulkStrings(ctx.RespArr.Elements[1:])
+	key, group, consumer := args[0], args[1], args[2]
+
+	minIdle, err := strconv.ParseInt(args[3], 10, 64)
+	if err != nil {
+		return resp.NewRespError("ERR Invalid min-idle-time argument for XCLAIM").AsRespString(), nil
+	}
+
+	// ids run until the first option
+	i := 4
+	var ids []store.StreamId
+	for ; i < len(args); i++ {
+		id, err := store.ParseStreamId(args[i], 0)
+		if err != nil {
+			break
+		}
+		ids = append(ids, id)
+	}
+
+	now := uint64(time.Now().UnixMilli())
+	options := store.ClaimOptions{DeliveredAt: now}
+	syntaxErr := resp.NewRespError("ERR syntax error")
+	integerErr := resp.NewRespError("ERR value is not an integer or out of range")
+
+	for ; i < len(args); i++ {
+		switch option := strings.ToLower(args[i]); option {
+		case "force":
+			options.Force = true
+		case "justid":
+			options.JustId = true
+		case "idle", "time", "retrycount":
+			if i+1 >= len(args) {
+				return syntaxErr.AsRespString(), nil
+			}
+
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				return integerErr.AsRespString(), nil
+			}
+			if n < 0 {
+				n = 0
+			}
+
+			switch option {
+			case "idle":
+				options.DeliveredAt = now - min(uint64(n), now)
+			case "time":
+				options.DeliveredAt = uint64(n)
+			case "retrycount":
+				options.SetRetryCount = true
+				options.RetryCount = uint64(n)
+			}
+			i++
+		case "lastid":
+			if i+1 >= len(args) {
+				return syntaxErr.AsRespString(), nil
+			}
+
+			if options.LastId, err = store.ParseStreamId(args[i+1], 0); err != nil {
+				return resp.NewRespError(err.Error()).AsRespString(), nil
+			}
+			i++
+		default:
+			return resp.NewRespError("ERR Unrecognized XCLAIM option '" + args[i] + "'").AsRespString(), nil
+		}
+	}
+
+	if minIdle < 0 {
+		minIdle = 0
+	}
+
+	claimed, err := ctx.Store().XClaim(key, group, consumer, uint64(minIdle), ids, options)
+	if err != nil {
+		return noGroupError(err, key, group, "").AsRespString(), nil
+	}
+
+	propagateClaim(ctx, key, group, consumer, entryIds(claimed), options)
+
+	return claimedResponse(claimed, options.JustId).AsRespString(), nil
+}
+
+// format: XAUTOCLAIM <KEY> <GROUP> <CONSUMER> <MIN_IDLE_MS> <START> [COUNT N] [JUSTID]
+//
+// claims up to count (default 100) pending entries from start onwards that have been idle
+// for at least min idle ms. Replies with the id to continue from (0-0 once every pending
+// entry has been scanned), the claimed entries and the ids of pending entries that no
+// longer exist in the stream, which are removed from the pending entries
+func HandleXAutoClaim(ctx HandleContext) (string, error) {
+	args := bulkStrings(ctx.RespArr.Elements[1:])
+	key, group, consumer := args[0], args[1], args[2]
+
+	minIdle, err := strconv.ParseInt(args[3], 10, 64)
+	if err != nil {
+		return resp.NewRespError("ERR Invalid min-idle-time argument for XAUTOCLAIM").AsRespString(), nil
+	}
+	if minIdle < 0 {
+		minIdle = 0
+	}
+
+	start, ok, err := store.ParseStreamRangeId(args[4], false)
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+	if !ok {
+		return resp.NewRespError("ERR invalid start ID for the interval").AsRespString(), nil
+	}
+
+	count := 100
+	justId := false
+
+	for i := 5; i < len(args); i++ {
+		switch strings.ToLower(args[i]) {
+		case "count":
+			if i+1 >= len(args) {
+				return resp.NewRespError("ERR syntax error").AsRespString(), nil
+			}
+
+			c, err := strconv.Atoi(args[i+1])
+			if err != nil {
+				return resp.NewRespError("ERR value is not an integer or out of range").AsRespString(), nil
+			}
+			if c < 1 {
+				return resp.NewRespError("ERR COUNT must be > 0").AsRespString(), nil
+			}
+			count = c
+			i++
+		case "justid":
+			justId = true
+		default:
+			return resp.NewRespError("ERR syntax error").AsRespString(), nil
+		}
+	}
+
+	next, claimed, deleted, err := ctx.Store().XAutoClaim(key, group, consumer, uint64(minIdle), start, count, justId)
+	if err != nil {
+		return noGroupError(err, key, group, "").AsRespString(), nil
+	}
+
+	deletedIds := make([]string, 0, len(deleted))
+	for _, id := range deleted {
+		deletedIds = append(deletedIds, id.String())
+	}
+
+	// replicas drop the deleted entries from their pending entries when claiming them too
+	options := store.ClaimOptions{DeliveredAt: uint64(time.Now().UnixMilli()), JustId: justId}
+	propagateClaim(ctx, key, group, consumer, append(entryIds(claimed), deletedIds...), options)
+
+	return resp.NewRespArray([]resp.RespType{
+		resp.NewRespBulkString(next.String()),
+		claimedResponse(claimed, justId),
+		resp.NewRespArrFromStrings(deletedIds),
+	}).AsRespString(), nil
+}
+
+func claimedResponse(claimed []store.StreamEntry, justId bool) *resp.RespArray {
+	if !justId {
+		return streamEntriesResponse(claimed)
+	}
+	return resp.NewRespArrFromStrings(entryIds(claimed))
+}
+
+func entryIds(entries []store.StreamEntry) []string {
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		ids = append(ids, entry.Seqkey)
+	}
+	return ids
+}
+
+// replicas are sent an XCLAIM of just the claimed entries with the delivery time fixed,
+// so they don't depend on how idle the entries are there
+func propagateClaim(ctx HandleContext, key string, group string, consumer string, ids []string, options store.ClaimOptions) {
+	if len(ids) == 0 && options.LastId == (store.StreamId{}) {
+		return
+	}
+
+	command := append([]string{"XCLAIM", key, group, consumer, "0"}, ids...)
+
+	command = append(command, "TIME", strconv.FormatUint(options.DeliveredAt, 10))
+	if options.SetRetryCount {
+		command = append(command, "RETRYCOUNT", strconv.FormatUint(options.RetryCount, 10))
+	}
+	if options.Force {
+		command = append(command, "FORCE")
+	}
+	if options.JustId {
+		command = append(command, "JUSTID")
+	}
+	if options.LastId != (store.StreamId{}) {
+		command = append(command, "LASTID", options.LastId.String())
+	}
+
+	ctx.Propagate(resp.NewRespArrFromStrings(command))
+}
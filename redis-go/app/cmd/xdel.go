@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// format: XDEL <KEY> <ID ...>
+//
+// replies with the number of entries deleted
+func HandleXDel(ctx HandleContext) (string, error) {
+	args := bulkStrings(ctx.RespArr.Elements[1:])
+
+	ids, err := parseStreamIds(args[1:])
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	deleted, err := ctx.Store().XDel(args[0], ids)
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	if deleted > 0 {
+		ctx.Propagate(&ctx.RespArr)
+	}
+
+	return resp.NewRespInteger(deleted).AsRespString(), nil
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+)
+
+// format: XGROUP CREATE <KEY> <GROUP> <ID|$> [MKSTREAM]
+// format: XGROUP SETID <KEY> <GROUP> <ID|$>
+// format: XGROUP DESTROY <KEY> <GROUP>
+// format: XGROUP CREATECONSUMER <KEY> <GROUP> <CONSUMER>
+// format: XGROUP DELCONSUMER <KEY> <GROUP> <CONSUMER>
+//
+// DESTROY & CREATECONSUMER reply 1 or 0 for whether anything changed, DELCONSUMER with
+// the number of entries the consumer had pending
+func HandleXGroup(ctx HandleContext) (string, error) {
+	elements := ctx.RespArr.Elements
+	subcommand := strings.ToLower(elements[1].(*resp.RespBulkString).Content)
+	args := bulkStrings(elements[2:])
+
+	arity := map[string]int{"create": 3, "setid": 3, "destroy": 2, "createconsumer": 3, "delconsumer": 3}
+	required, exists := arity[subcommand]
+	if !exists {
+		return resp.NewRespError(fmt.Sprintf("ERR unknown subcommand '%s'. Try XGROUP HELP.", subcommand)).AsRespString(), nil
+	}
+
+	if len(args) < required || (subcommand != "create" && len(args) > required) {
+		return resp.WrongArgsError("xgroup|" + subcommand).AsRespString(), nil
+	}
+
+	key, group := args[0], args[1]
+
+	var res resp.RespType
+	var err error
+
+	switch subcommand {
+	case "create":
+		mkstream := false
+		for _, option := range args[3:] {
+			if strings.ToLower(option) != "mkstream" {
+				return resp.NewRespError("ERR syntax error").AsRespString(), nil
+			}
+			mkstream = true
+		}
+
+		if err = ctx.Store().XGroupCreate(key, group, args[2], mkstream); err == nil {
+			res = resp.OkResponse()
+		}
+	case "setid":
+		if err = ctx.Store().XGroupSetId(key, group, args[2]); err == nil {
+			res = resp.OkResponse()
+		}
+	case "destroy":
+		var destroyed bool
+		if destroyed, err = ctx.Store().XGroupDestroy(key, group); err == nil {
+			res = boolInteger(destroyed)
+		}
+	case "createconsumer":
+		var created bool
+		if created, err = ctx.Store().XGroupCreateConsumer(key, group, args[2]); err == nil {
+			res = boolInteger(created)
+		}
+	case "delconsumer":
+		var pending int
+		if pending, err = ctx.Store().XGroupDelConsumer(key, group, args[2]); err == nil {
+			res = resp.NewRespInteger(pending)
+		}
+	}
+
+	if errors.Is(err, store.ErrNoGroup) {
+		return resp.NewRespError(fmt.Sprintf("NOGROUP No such consumer group '%s' for key name '%s'", group, key)).AsRespString(), nil
+	}
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	ctx.Propagate(&ctx.RespArr)
+
+	return res.AsRespString(), nil
+}
+
+func boolInteger(b bool) *resp.RespInteger {
+	if b {
+		return resp.NewRespInteger(1)
+	}
+	return resp.NewRespInteger(0)
+}
+
+// the error for a missing key or consumer group, the message naming them as the command does
+func noGroupError(err error, key string, group string, suffix string) *resp.RespError {
+	if errors.Is(err, store.ErrNoGroup) {
+		return resp.NewRespError(fmt.Sprintf("NOGROUP No such key '%s' or consumer group '%s'%s", key, group, suffix))
+	}
+	return resp.NewRespError(err.Error())
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+)
+
+// format: XINFO STREAM <KEY>
+// format: XINFO GROUPS <KEY>
+// format: XINFO CONSUMERS <KEY> <GROUP>
+//
+// each replies with flat arrays of field names & values, GROUPS and CONSUMERS one per
+// group or consumer. STREAM leaves out the radix tree sizes, which don't apply to the
+// slice the entries are kept in, and GROUPS the entries read & lag as they aren't tracked
+func HandleXInfo(ctx HandleContext) (string, error) {
+	elements := ctx.RespArr.Elements
+	subcommand := strings.ToLower(elements[1].(*resp.RespBulkString).Content)
+	args := bulkStrings(elements[2:])
+
+	arity := map[string]int{"stream": 1, "groups": 1, "consumers": 2}
+	required, exists := arity[subcommand]
+	if !exists {
+		return resp.NewRespError(fmt.Sprintf("ERR unknown subcommand '%s'. Try XINFO HELP.", subcommand)).AsRespString(), nil
+	}
+
+	if len(args) != required {
+		return resp.WrongArgsError("xinfo|" + subcommand).AsRespString(), nil
+	}
+
+	switch subcommand {
+	case "stream":
+		info, err := ctx.Store().XInfoStream(args[0])
+		if err != nil {
+			return resp.NewRespError(err.Error()).AsRespString(), nil
+		}
+
+		return resp.NewRespArray([]resp.RespType{
+			resp.NewRespBulkString("length"), resp.NewRespInteger(info.Length),
+			resp.NewRespBulkString("last-generated-id"), resp.NewRespBulkString(info.LastId.String()),
+			resp.NewRespBulkString("max-deleted-entry-id"), resp.NewRespBulkString(info.MaxDeletedId.String()),
+			resp.NewRespBulkString("entries-added"), resp.NewRespInteger(int(info.EntriesAdded)),
+			resp.NewRespBulkString("recorded-first-entry-id"), resp.NewRespBulkString(info.FirstId.String()),
+			resp.NewRespBulkString("groups"), resp.NewRespInteger(info.Groups),
+			resp.NewRespBulkString("first-entry"), streamEntryResponse(info.First),
+			resp.NewRespBulkString("last-entry"), streamEntryResponse(info.Last),
+		}).AsRespString(), nil
+	case "groups":
+		groups, err := ctx.Store().XInfoGroups(args[0])
+		if err != nil {
+			return resp.NewRespError(err.Error()).AsRespString(), nil
+		}
+
+		result := make([]resp.RespType, 0, len(groups))
+		for _, g := range groups {
+			result = append(result, resp.NewRespArray([]resp.RespType{
+				resp.NewRespBulkString("name"), resp.NewRespBulkString(g.Name),
+				resp.NewRespBulkString("consumers"), resp.NewRespInteger(g.Consumers),
+				resp.NewRespBulkString("pending"), resp.NewRespInteger(g.Pending),
+				resp.NewRespBulkString("last-delivered-id"), resp.NewRespBulkString(g.LastDelivered.String()),
+			}))
+		}
+
+		return resp.NewRespArray(result).AsRespString(), nil
+	default:
+		key, group := args[0], args[1]
+
+		consumers, err := ctx.Store().XInfoConsumers(key, group)
+		if errors.Is(err, store.ErrNoGroup) {
+			return resp.NewRespError(fmt.Sprintf("NOGROUP No such consumer group '%s' for key name '%s'", group, key)).AsRespString(), nil
+		}
+		if err != nil {
+			return resp.NewRespError(err.Error()).AsRespString(), nil
+		}
+
+		ms := uint64(time.Now().UnixMilli())
+		result := make([]resp.RespType, 0, len(consumers))
+		for _, c := range consumers {
+			idle := 0
+			if ms > c.SeenAt {
+				idle = int(ms - c.SeenAt)
+			}
+
+			// -1 for a consumer that's never been given entries
+			inactive := -1
+			if c.ActiveAt > 0 {
+				inactive = max(int(ms)-int(c.ActiveAt), 0)
+			}
+
+			result = append(result, resp.NewRespArray([]resp.RespType{
+				resp.NewRespBulkString("name"), resp.NewRespBulkString(c.Name),
+				resp.NewRespBulkString("pending"), resp.NewRespInteger(c.Pending),
+				resp.NewRespBulkString("idle"), resp.NewRespInteger(idle),
+				resp.NewRespBulkString("inactive"), resp.NewRespInteger(inactive),
+			}))
+		}
+
+		return resp.NewRespArray(result).AsRespString(), nil
+	}
+}
+
+// the entry as its id and fields, nil for no entry
+func streamEntryResponse(entry *store.StreamEntry) resp.RespType {
+	if entry == nil {
+		return resp.NullBulkString()
+	}
+	return streamEntriesResponse([]store.StreamEntry{*entry}).Elements[0]
+}
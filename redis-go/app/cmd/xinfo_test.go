@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestXInfoStreamReportsAddedAndDeletedEntries(t *testing.T) {
+	// arrange
+	h := newTestHost(t)
+	c := newTestClient(h)
+	c.do("XADD", "s", "1-0", "f", "v")
+	c.do("XADD", "s", "2-0", "f", "v")
+	c.do("XADD", "s", "3-0", "f", "v")
+	c.do("XDEL", "s", "1-0", "3-0")
+
+	// act
+	res := c.do("XINFO", "STREAM", "s")
+
+	// assert
+	want := "$6\r\nlength\r\n:1\r\n" +
+		"$17\r\nlast-generated-id\r\n$3\r\n3-0\r\n" +
+		"$20\r\nmax-deleted-entry-id\r\n$3\r\n3-0\r\n" +
+		"$13\r\nentries-added\r\n:3\r\n" +
+		"$23\r\nrecorded-first-entry-id\r\n$3\r\n2-0\r\n"
+
+	if !strings.HasPrefix(res, "*16\r\n"+want) {
+		t.Errorf("expected the reply to start with %q but got %q", want, res)
+	}
+}
+
+func TestXInfoConsumersReportsInactive(t *testing.T) {
+	// arrange
+	h := newTestHost(t)
+	c := newTestClient(h)
+	c.do("XADD", "s", "1-0", "f", "v")
+	c.do("XGROUP", "CREATE", "s", "g", "0")
+	c.do("XREADGROUP", "GROUP", "g", "alice", "STREAMS", "s", ">")
+	c.do("XGROUP", "CREATECONSUMER", "s", "g", "bob")
+
+	// act
+	res := c.do("XINFO", "CONSUMERS", "s", "g")
+
+	// assert
+	alice, bob, _ := strings.Cut(strings.TrimPrefix(res, "*2\r\n"), "*8\r\n$4\r\nname\r\n$3\r\nbob")
+
+	if !strings.Contains(alice, "$8\r\ninactive\r\n:") || strings.Contains(alice, "inactive\r\n:-1") {
+		t.Errorf("expected alice to have been active but got %q", alice)
+	}
+
+	if !strings.HasSuffix(bob, "$8\r\ninactive\r\n:-1\r\n") {
+		t.Errorf("expected bob never to have been active but got %q", bob)
+	}
+}
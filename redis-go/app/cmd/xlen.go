@@ -0,0 +1,19 @@
+package cmd
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// format: XLEN <KEY>
+//
+// replies with the number of entries in the stream, 0 if it doesn't exist
+func HandleXLen(ctx HandleContext) (string, error) {
+	key := ctx.RespArr.Elements[1].(*resp.RespBulkString)
+
+	length, err := ctx.Store().XLen(key.Content)
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	return resp.NewRespInteger(length).AsRespString(), nil
+}
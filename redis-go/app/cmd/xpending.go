@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+)
+
+// format: XPENDING <KEY> <GROUP> [[IDLE MIN_IDLE_MS] <START> <END> <COUNT> [CONSUMER]]
+//
+// without a range it replies with a summary: the number of pending entries, the smallest
+// and largest pending ids and how many each consumer has. With one it replies with the id,
+// consumer, ms idle and delivery count of each pending entry in the range
+func HandleXPending(ctx HandleContext) (string, error) {
+	args := bulkStrings(ctx.RespArr.Elements[1:])
+	key, group := args[0], args[1]
+	args = args[2:]
+
+	if len(args) == 0 {
+		summary, err := ctx.Store().XPendingSummary(key, group)
+		if err != nil {
+			return noGroupError(err, key, group, "").AsRespString(), nil
+		}
+
+		return pendingSummaryResponse(summary).AsRespString(), nil
+	}
+
+	query := store.PendingQuery{}
+	syntaxErr := resp.NewRespError("ERR syntax error")
+
+	if strings.ToLower(args[0]) == "idle" {
+		if len(args) < 2 {
+			return syntaxErr.AsRespString(), nil
+		}
+
+		idle, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return resp.NewRespError("ERR value is not an integer or out of range").AsRespString(), nil
+		}
+		if idle > 0 {
+			query.MinIdle = uint64(idle)
+		}
+		args = args[2:]
+	}
+
+	if len(args) < 3 || len(args) > 4 {
+		return syntaxErr.AsRespString(), nil
+	}
+
+	start, startOk, err := store.ParseStreamRangeId(args[0], false)
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	end, endOk, err := store.ParseStreamRangeId(args[1], true)
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	count, err := strconv.Atoi(args[2])
+	if err != nil {
+		return resp.NewRespError("ERR value is not an integer or out of range").AsRespString(), nil
+	}
+
+	query.Start, query.End = start, end
+	if count > 0 && startOk && endOk {
+		query.Count = count
+	}
+
+	if len(args) == 4 {
+		query.Consumer = args[3]
+	}
+
+	pending, err := ctx.Store().XPending(key, group, query)
+	if err != nil {
+		return noGroupError(err, key, group, "").AsRespString(), nil
+	}
+
+	ms := uint64(time.Now().UnixMilli())
+	result := make([]resp.RespType, 0, len(pending))
+
+	for _, p := range pending {
+		idle := 0
+		if ms > p.DeliveredAt {
+			idle = int(ms - p.DeliveredAt)
+		}
+
+		result = append(result, resp.NewRespArray([]resp.RespType{
+			resp.NewRespBulkString(p.Id.String()),
+			resp.NewRespBulkString(p.Consumer),
+			resp.NewRespInteger(idle),
+			resp.NewRespInteger(int(p.Deliveries)),
+		}))
+	}
+
+	return resp.NewRespArray(result).AsRespString(), nil
+}
+
+func pendingSummaryResponse(summary store.PendingSummary) *resp.RespArray {
+	if summary.Count == 0 {
+		return resp.NewRespArray([]resp.RespType{
+			resp.NewRespInteger(0), resp.NullBulkString(), resp.NullBulkString(), resp.NullArray(),
+		})
+	}
+
+	consumers := make([]resp.RespType, 0, len(summary.Consumers))
+	for _, c := range summary.Consumers {
+		consumers = append(consumers, resp.NewRespArrFromStrings([]string{c.Name, strconv.Itoa(c.Count)}))
+	}
+
+	return resp.NewRespArray([]resp.RespType{
+		resp.NewRespInteger(summary.Count),
+		resp.NewRespBulkString(summary.Min.String()),
+		resp.NewRespBulkString(summary.Max.String()),
+		resp.NewRespArray(consumers),
+	})
+}
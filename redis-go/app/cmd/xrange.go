@@ -1,32 +1,74 @@
 package cmd
 
-import "github.com/codecrafters-io/redis-starter-go/app/resp"
+import (
+	"strconv"
+	"strings"
 
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+)
+
+// format: XRANGE <KEY> <START> <END> [COUNT N]
+//
+// replies with the entries from start to end inclusive, at most count of them when given.
+// - and + are the smallest and largest ids, an id without a sequence number covers every
+// sequence number and a leading ( makes the bound exclusive
 func HandleXRange(ctx HandleContext) (string, error) {
+	count := 0 // no limit
+	if options := bulkStrings(ctx.RespArr.Elements[4:]); len(options) > 0 {
+		if len(options) != 2 || strings.ToLower(options[0]) != "count" {
+			return resp.NewRespError("ERR syntax error").AsRespString(), nil
+		}
 
-	streamkey := ctx.RespArr.Elements[1].(*resp.RespBulkString)
-	start := ctx.RespArr.Elements[2].(*resp.RespBulkString)
-	end := ctx.RespArr.Elements[3].(*resp.RespBulkString)
+		n, err := strconv.Atoi(options[1])
+		if err != nil {
+			return resp.NewRespError("ERR value is not an integer or out of range").AsRespString(), nil
+		}
+		if n <= 0 {
+			return resp.NewRespArray([]resp.RespType{}).AsRespString(), nil
+		}
+		count = n
+	}
 
-	ctx.Logger.Info().
-		Str("stream_key", streamkey.Content).
-		Str("start", start.Content).
-		Str("end", end.Content).
-		Msg("xrange handler")
+	streamkey := ctx.RespArr.Elements[1].(*resp.RespBulkString).Content
 
-	r, err := ctx.HostCtx.Store.GetStream(streamkey.Content, start.Content, end.Content)
+	start, ok, err := store.ParseStreamRangeId(ctx.RespArr.Elements[2].(*resp.RespBulkString).Content, false)
 	if err != nil {
-		return "", err
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+	if !ok {
+		return resp.NewRespError("ERR invalid start ID for the interval").AsRespString(), nil
+	}
+
+	end, ok, err := store.ParseStreamRangeId(ctx.RespArr.Elements[3].(*resp.RespBulkString).Content, true)
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+	if !ok {
+		return resp.NewRespError("ERR invalid end ID for the interval").AsRespString(), nil
 	}
 
-	result := make([]resp.RespType, len(r))
+	entries, err := ctx.Store().XRange(streamkey, start, end, count)
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	return streamEntriesResponse(entries).AsRespString(), nil
+}
+
+// each entry as its id and a flat array of its fields. An entry deleted since a consumer
+// group delivered it has nil in place of its fields
+func streamEntriesResponse(entries []store.StreamEntry) *resp.RespArray {
+	result := make([]resp.RespType, len(entries))
+
+	for i, entry := range entries {
+		var fields resp.RespType = resp.NullArray()
+		if entry.Fields != nil {
+			fields = resp.NewRespArrFromStrings(entry.Fields)
+		}
 
-	for i, entry := range r {
-		result[i] = resp.NewRespArray([]resp.RespType{
-			resp.NewRespBulkString(entry.Seqkey),
-			resp.NewRespArrFromMap(entry.Values),
-		})
+		result[i] = resp.NewRespArray([]resp.RespType{resp.NewRespBulkString(entry.Seqkey), fields})
 	}
 
-	return resp.NewRespArray(result).AsRespString(), nil
+	return resp.NewRespArray(result)
 }
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestXRangeCount(t *testing.T) {
+	// arrange
+	h := newTestHost(t)
+	c := newTestClient(h)
+	c.do("XADD", "s", "1-0", "f", "v")
+	c.do("XADD", "s", "2-0", "f", "v")
+	c.do("XADD", "s", "3-0", "f", "v")
+
+	entry := func(id string) string {
+		return "*2\r\n$3\r\n" + id + "\r\n*2\r\n$1\r\nf\r\n$1\r\nv\r\n"
+	}
+
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"XRANGE", "s", "-", "+"}, want: "*3\r\n" + entry("1-0") + entry("2-0") + entry("3-0")},
+		{args: []string{"XRANGE", "s", "-", "+", "COUNT", "1"}, want: "*1\r\n" + entry("1-0")},
+		{args: []string{"XRANGE", "s", "2-0", "+", "count", "5"}, want: "*2\r\n" + entry("2-0") + entry("3-0")},
+		{args: []string{"XRANGE", "s", "-", "+", "COUNT", "0"}, want: "*0\r\n"},
+		{args: []string{"XRANGE", "s", "-", "+", "COUNT", "-1"}, want: "*0\r\n"},
+		{args: []string{"XRANGE", "s", "-", "+", "COUNT", "x"}, want: "-ERR value is not an integer or out of range\r\n"},
+		{args: []string{"XRANGE", "s", "-", "+", "COUNT"}, want: "-ERR syntax error\r\n"},
+		{args: []string{"XRANGE", "s", "-", "+", "LIMIT", "1"}, want: "-ERR syntax error\r\n"},
+	}
+
+	for _, tc := range cases {
+		// act
+		got := c.do(tc.args...)
+
+		// assert
+		if got != tc.want {
+			t.Errorf("expected %v to reply %q but got %q", tc.args, tc.want, got)
+		}
+	}
+}
+
+func TestXRangeBounds(t *testing.T) {
+	// arrange
+	h := newTestHost(t)
+	c := newTestClient(h)
+	c.do("XADD", "s", "1-0", "f", "v")
+	c.do("XADD", "s", "2-0", "f", "v")
+	c.do("XADD", "s", "2-1", "f", "v")
+	c.do("XADD", "s", "3-0", "f", "v")
+	c.do("SET", "str", "v")
+
+	entries := func(ids ...string) string {
+		res := "*" + strconv.Itoa(len(ids)) + "\r\n"
+		for _, id := range ids {
+			res += "*2\r\n$3\r\n" + id + "\r\n*2\r\n$1\r\nf\r\n$1\r\nv\r\n"
+		}
+		return res
+	}
+
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"XRANGE", "s", "2-0", "3-0"}, want: entries("2-0", "2-1", "3-0")},
+		{args: []string{"XRANGE", "s", "2", "2"}, want: entries("2-0", "2-1")},
+		{args: []string{"XRANGE", "s", "5-0", "+"}, want: entries()},
+		{args: []string{"XRANGE", "s", "-", "0-5"}, want: entries()},
+		{args: []string{"XRANGE", "s", "3-0", "1-0"}, want: entries()},
+		{args: []string{"XRANGE", "s", "(2-0", "+"}, want: entries("2-1", "3-0")},
+		{args: []string{"XRANGE", "s", "-", "(2-1"}, want: entries("1-0", "2-0")},
+		{args: []string{"XRANGE", "s", "(2", "(3-0"}, want: entries("2-1")},
+		{args: []string{"XRANGE", "missing", "-", "+"}, want: entries()},
+		{args: []string{"XRANGE", "s", "(18446744073709551615-18446744073709551615", "+"}, want: "-ERR invalid start ID for the interval\r\n"},
+		{args: []string{"XRANGE", "s", "-", "(0-0"}, want: "-ERR invalid end ID for the interval\r\n"},
+		{args: []string{"XRANGE", "s", "(-", "+"}, want: "-ERR Invalid stream ID specified as stream command argument\r\n"},
+		{args: []string{"XRANGE", "s", "x", "+"}, want: "-ERR Invalid stream ID specified as stream command argument\r\n"},
+		{args: []string{"XRANGE", "str", "-", "+"}, want: "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+	}
+
+	for _, tc := range cases {
+		// act
+		got := c.do(tc.args...)
+
+		// assert
+		if got != tc.want {
+			t.Errorf("expected %v to reply %q but got %q", tc.args, tc.want, got)
+		}
+	}
+}
@@ -1,44 +1,186 @@
 package cmd
 
 import (
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
 )
 
+// format: XREAD [COUNT N] [BLOCK MS] STREAMS <KEY ...> <ID ...>
+//
+// replies with up to count entries after each id for the streams that have any, missing
+// streams being skipped. An id of $ is the stream's last entry, so only entries added
+// whilst blocked are read. With BLOCK and nothing to read it waits up to the ms (0 for
+// ever) for an entry to be added to one of the streams. Replies nil when there's nothing
 func HandleXRead(ctx HandleContext) (string, error) {
-	count := (len(ctx.RespArr.Elements) - 2) / 2 // -2 as arr[0] = XREAD and arr[1] = streams
-	keys := make([]string, 0)
-	ranges := make([]string, 0)
+	args := bulkStrings(ctx.RespArr.Elements[1:])
 
-	for i := 2; i < 2+count; i++ {
-		keys = append(keys, ctx.RespArr.Elements[i].(*resp.RespBulkString).Content)
+	options, errRes := parseStreamReadOptions(args, "xread", "$")
+	if errRes != nil {
+		return errRes.AsRespString(), nil
 	}
 
-	for i := count + 2; i < len(ctx.RespArr.Elements); i++ {
-		ranges = append(ranges, ctx.RespArr.Elements[i].(*resp.RespBulkString).Content)
+	// $ is resolved once, so entries added whilst blocked are read
+	ids := make([]store.StreamId, len(options.keys))
+	for i, key := range options.keys {
+		var err error
+		if options.ids[i] == "$" {
+			ids[i], err = ctx.Store().StreamLastId(key)
+		} else {
+			ids[i], err = store.ParseStreamId(options.ids[i], 0)
+		}
+
+		if err != nil {
+			return resp.NewRespError(err.Error()).AsRespString(), nil
+		}
 	}
 
-	arrs := make([]resp.RespType, 0)
-	for i, key := range keys {
-		rng := ranges[i]
+	var result []resp.RespType
+	read := func() (bool, error) {
+		result = nil
+		for i, key := range options.keys {
+			entries, err := ctx.Store().XReadStream(key, ids[i], options.count)
+			if err != nil {
+				return false, err
+			}
 
-		r, err := ctx.HostCtx.Store.XReadStream(key, rng)
-		if err != nil {
-			return "", err
+			if len(entries) == 0 {
+				continue
+			}
+
+			result = append(result, resp.NewRespArray([]resp.RespType{
+				resp.NewRespBulkString(key),
+				streamEntriesResponse(entries),
+			}))
 		}
-		result := make([]resp.RespType, len(r))
 
-		for i, entry := range r {
-			result[i] = resp.NewRespArray([]resp.RespType{
-				resp.NewRespBulkString(entry.Seqkey),
-				resp.NewRespArrFromMap(entry.Values),
-			})
+		return len(result) > 0, nil
+	}
+
+	if err := readStreamsBlocking(ctx, options, read); err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	if len(result) == 0 {
+		return resp.NullArray().AsRespString(), nil
+	}
+
+	return resp.NewRespArray(result).AsRespString(), nil
+}
+
+type streamReadOptions struct {
+	count int           // 0 for no limit
+	block bool          // wait for entries when there are none
+	wait  time.Duration // how long to block for, 0 for ever
+	noack bool          // XREADGROUP only
+	keys  []string
+	ids   []string
+}
+
+// parses the options of XREAD, or XREADGROUP after the GROUP option, up to and including
+// the keys & ids following STREAMS. lastId names the special id in the unbalanced error
+func parseStreamReadOptions(args []string, command string, lastId string) (streamReadOptions, *resp.RespError) {
+	options := streamReadOptions{}
+	syntaxErr := resp.NewRespError("ERR syntax error")
+
+	for i := 0; i < len(args); i++ {
+		switch option := strings.ToLower(args[i]); option {
+		case "count":
+			if i+1 >= len(args) {
+				return options, syntaxErr
+			}
+
+			count, err := strconv.Atoi(args[i+1])
+			if err != nil {
+				return options, resp.NewRespError("ERR value is not an integer or out of range")
+			}
+			if count > 0 {
+				options.count = count
+			}
+			i++
+		case "block":
+			if i+1 >= len(args) {
+				return options, syntaxErr
+			}
+
+			ms, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				return options, resp.NewRespError("ERR timeout is not an integer or out of range")
+			}
+			if ms < 0 {
+				return options, resp.NewRespError("ERR timeout is negative")
+			}
+
+			options.block = true
+			options.wait = time.Duration(ms) * time.Millisecond
+			i++
+		case "noack":
+			if command != "xreadgroup" {
+				return options, syntaxErr
+			}
+			options.noack = true
+		case "streams":
+			rest := args[i+1:]
+			if len(rest) == 0 || len(rest)%2 != 0 {
+				return options, resp.NewRespError("ERR Unbalanced '" + command + "' list of streams: for each stream key an ID or '" + lastId + "' must be specified.")
+			}
+
+			options.keys, options.ids = rest[:len(rest)/2], rest[len(rest)/2:]
+			return options, nil
+		default:
+			return options, syntaxErr
 		}
+	}
+
+	return options, syntaxErr
+}
 
-		arrs = append(arrs, resp.NewRespArray([]resp.RespType{
-			resp.NewRespBulkString(key),
-			resp.NewRespArray(result),
-		}))
+// calls read until it finds something, blocking between attempts until an entry is added
+// to one of the streams. Gives up once the BLOCK timeout expires or the client disconnects,
+// straight away without BLOCK or where the command can't block (e.g. inside a transaction)
+func readStreamsBlocking(ctx HandleContext, options streamReadOptions, read func() (bool, error)) error {
+	found, err := read()
+	if found || err != nil || !options.block || !ctx.CanBlock {
+		return err
 	}
 
-	return resp.NewRespArray(arrs).AsRespString(), nil
+	disconnected, stop := ctx.WatchDisconnect()
+	defer stop()
+
+	var expired <-chan time.Time
+	if options.wait > 0 {
+		timer := time.NewTimer(options.wait)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	for {
+		// registered before reading so an entry added in between wakes it
+		w := ctx.Store().BlockStreams(options.keys)
+
+		found, err := read()
+		if found || err != nil {
+			ctx.Store().UnblockStreams(w)
+			return err
+		}
+
+		woken := false
+		ctx.unlockedWhile(func() {
+			select {
+			case <-w.Ready():
+				woken = true
+			case <-expired:
+			case <-disconnected:
+				ctx.Logger.Debug().Msg("client disconnected whilst blocked")
+			}
+		})
+
+		if !woken {
+			ctx.Store().UnblockStreams(w)
+			return nil
+		}
+	}
 }
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+// the XREAD reply for a single stream with entries <id> f v
+func xreadReply(key string, ids ...string) string {
+	res := "*1\r\n*2\r\n$" + strconv.Itoa(len(key)) + "\r\n" + key + "\r\n*" + strconv.Itoa(len(ids)) + "\r\n"
+	for _, id := range ids {
+		res += "*2\r\n$" + strconv.Itoa(len(id)) + "\r\n" + id + "\r\n*2\r\n$1\r\nf\r\n$1\r\nv\r\n"
+	}
+	return res
+}
+
+func TestXReadBlockingOnLastIdReadsOnlyNewEntries(t *testing.T) {
+	// arrange
+	h := newTestHost(t)
+	reader, writer := newTestClient(h), newTestClient(h)
+	writer.do("XADD", "s", "1-0", "f", "v")
+	writer.do("XADD", "s", "2-0", "f", "v")
+
+	// act
+	res := reader.doBlocking(t, "XREAD", "BLOCK", "0", "STREAMS", "s", "$")
+
+	writer.do("XADD", "other", "1-0", "f", "v")
+	select {
+	case r := <-res:
+		t.Fatalf("expected an entry added to another stream not to wake the reader but got %q", r)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	writer.do("XADD", "s", "3-0", "f", "v")
+
+	// assert
+	select {
+	case r := <-res:
+		if want := xreadReply("s", "3-0"); r != want {
+			t.Errorf("expected %q but got %q", want, r)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the reader to be woken by the entry")
+	}
+}
+
+func TestXReadLastIdWithoutBlockReadsNothing(t *testing.T) {
+	// arrange
+	h := newTestHost(t)
+	c := newTestClient(h)
+	c.do("XADD", "s", "1-0", "f", "v")
+
+	// act
+	res := c.do("XREAD", "STREAMS", "s", "$")
+
+	// assert
+	if res != "*-1\r\n" {
+		t.Errorf("expected a null reply but got %q", res)
+	}
+}
+
+func TestXReadCount(t *testing.T) {
+	// arrange
+	h := newTestHost(t)
+	c := newTestClient(h)
+	c.do("XADD", "s", "1-0", "f", "v")
+	c.do("XADD", "s", "2-0", "f", "v")
+	c.do("XADD", "s", "3-0", "f", "v")
+
+	// act
+	first := c.do("XREAD", "COUNT", "2", "STREAMS", "s", "0")
+	rest := c.do("XREAD", "COUNT", "2", "STREAMS", "s", "2-0")
+
+	// assert
+	if want := xreadReply("s", "1-0", "2-0"); first != want {
+		t.Errorf("expected %q but got %q", want, first)
+	}
+
+	if want := xreadReply("s", "3-0"); rest != want {
+		t.Errorf("expected %q but got %q", want, rest)
+	}
+}
+
+func TestXReadBlockingCountLimitsEntriesAddedTogether(t *testing.T) {
+	// arrange
+	h := newTestHost(t)
+	reader, writer := newTestClient(h), newTestClient(h)
+	writer.do("XADD", "s", "1-0", "f", "v")
+
+	// act - both entries are added before the reader runs again
+	res := reader.doBlocking(t, "XREAD", "COUNT", "1", "BLOCK", "0", "STREAMS", "s", "$")
+	writer.do("MULTI")
+	writer.do("XADD", "s", "2-0", "f", "v")
+	writer.do("XADD", "s", "3-0", "f", "v")
+	writer.do("EXEC")
+
+	// assert
+	select {
+	case r := <-res:
+		if want := xreadReply("s", "2-0"); r != want {
+			t.Errorf("expected %q but got %q", want, r)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the reader to be woken by the entries")
+	}
+}
+
+func TestXReadBlockTimesOut(t *testing.T) {
+	// arrange
+	h := newTestHost(t)
+	c := newTestClient(h)
+
+	// act
+	start := time.Now()
+	res := c.do("XREAD", "BLOCK", "50", "STREAMS", "s", "$")
+
+	// assert
+	if res != "*-1\r\n" {
+		t.Errorf("expected a null reply but got %q", res)
+	}
+
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("expected to block for the timeout but returned after %v", elapsed)
+	}
+}
+
+func TestXReadBlockingReturnsWhenClientDisconnects(t *testing.T) {
+	// arrange
+	h := newTestHost(t)
+	c := newTestClient(h)
+	res := c.doBlocking(t, "XREAD", "BLOCK", "0", "STREAMS", "s", "$")
+
+	// act
+	close(c.disconnect)
+
+	// assert
+	select {
+	case <-res:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the reader to stop blocking once disconnected")
+	}
+
+	c.do("XADD", "s", "1-0", "f", "v") // mustn't find the dropped waiter
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+)
+
+// format: XREADGROUP GROUP <GROUP> <CONSUMER> [COUNT N] [BLOCK MS] [NOACK] STREAMS <KEY ...> <ID ...>
+//
+// with an id of > the consumer is delivered entries the group hasn't delivered yet, which
+// stay pending until acknowledged (unless NOACK), blocking like XREAD when there are none.
+// Any other id re-reads the consumer's pending entries after it. Replies nil when no
+// stream had anything to deliver
+func HandleXReadGroup(ctx HandleContext) (string, error) {
+	args := bulkStrings(ctx.RespArr.Elements[1:])
+
+	if strings.ToLower(args[0]) != "group" {
+		return resp.NewRespError("ERR syntax error").AsRespString(), nil
+	}
+
+	group, consumer := args[1], args[2]
+
+	options, errRes := parseStreamReadOptions(args[3:], "xreadgroup", ">")
+	if errRes != nil {
+		return errRes.AsRespString(), nil
+	}
+
+	for _, id := range options.ids {
+		if id == "$" {
+			return resp.NewRespError("ERR The $ ID is meaningless in the context of XREADGROUP: you want to read the history of this consumer by specifying a proper ID, or use the > ID to get new messages. The $ ID would just return an empty result set.").AsRespString(), nil
+		}
+	}
+
+	reads := make([]store.GroupRead, len(options.keys))
+	for i, key := range options.keys {
+		reads[i] = store.GroupRead{Key: key, Start: options.ids[i]}
+	}
+
+	var result []resp.RespType
+	delivered := make([][]string, len(reads))
+	created := make([]bool, len(reads))
+
+	read := func() (bool, error) {
+		results, failed, err := ctx.Store().XReadGroups(group, consumer, reads, options.count, options.noack)
+		if err != nil {
+			return false, errors.New(noGroupError(err, failed, group, " in XREADGROUP with GROUP option").Message)
+		}
+
+		result = nil
+		for i, r := range results {
+			created[i] = created[i] || r.Created
+
+			// re-reading pending entries always includes the stream, even when there are none
+			if options.ids[i] == ">" {
+				if len(r.Entries) == 0 {
+					continue
+				}
+				delivered[i] = entryIds(r.Entries)
+			}
+
+			result = append(result, resp.NewRespArray([]resp.RespType{
+				resp.NewRespBulkString(reads[i].Key),
+				streamEntriesResponse(r.Entries),
+			}))
+		}
+
+		return len(result) > 0, nil
+	}
+
+	err := readStreamsBlocking(ctx, options, read)
+
+	// a blocked read can create the consumer before the group is destroyed
+	propagateGroupRead(ctx, group, consumer, reads, delivered, created, options.noack)
+
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	if len(result) == 0 {
+		return resp.NullArray().AsRespString(), nil
+	}
+
+	return resp.NewRespArray(result).AsRespString(), nil
+}
+
+// replicas are sent what the read changed rather than the read itself, which could
+// deliver other entries there. Each delivered entry is claimed for the consumer as though
+// delivered for the first time, or with NOACK the group's last delivered id is moved on.
+// Neither depends on the order it's applied in relative to other clients' writes
+func propagateGroupRead(ctx HandleContext, group string, consumer string, reads []store.GroupRead, delivered [][]string, created []bool, noack bool) {
+	deliveredAt := uint64(time.Now().UnixMilli())
+
+	for i, read := range reads {
+		if created[i] {
+			ctx.Propagate(resp.NewRespArrFromStrings([]string{"XGROUP", "CREATECONSUMER", read.Key, group, consumer}))
+		}
+
+		ids := delivered[i]
+		if len(ids) == 0 {
+			continue
+		}
+
+		if noack {
+			ctx.Propagate(resp.NewRespArrFromStrings([]string{"XGROUP", "SETID", read.Key, group, ids[len(ids)-1]}))
+			continue
+		}
+
+		for _, id := range ids {
+			lastId, _ := store.ParseStreamId(id, 0)
+			propagateClaim(ctx, read.Key, group, consumer, []string{id}, store.ClaimOptions{
+				DeliveredAt:   deliveredAt,
+				SetRetryCount: true,
+				RetryCount:    1,
+				Force:         true,
+				JustId:        true,
+				LastId:        lastId,
+			})
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestXReadGroupIsReplicatedAsWhatItDelivered(t *testing.T) {
+	cases := []struct {
+		name string
+		args []string
+	}{
+		{name: "pending", args: []string{"XREADGROUP", "GROUP", "g", "alice", "STREAMS", "s", ">"}},
+		{name: "NOACK", args: []string{"XREADGROUP", "GROUP", "g", "alice", "NOACK", "STREAMS", "s", ">"}},
+		{name: "history", args: []string{"XREADGROUP", "GROUP", "g", "bob", "STREAMS", "s", "0"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// arrange
+			leader := newTestHost(t)
+			addr := serveTestHost(t, leader)
+			follower := newTestHost(t)
+			followTestHost(t, follower, addr)
+
+			c := newTestClient(leader)
+			c.do("XADD", "s", "1-0", "f", "v")
+			c.do("XADD", "s", "2-0", "f", "v")
+			c.do("XGROUP", "CREATE", "s", "g", "0")
+			offset := leader.PubSubManager.Offset()
+
+			// act - an entry added after the read mustn't be delivered on the follower
+			c.do(tc.args...)
+			c.do("XADD", "s", "3-0", "f", "v")
+			waitForCatchUp(t, follower, leader)
+
+			// assert
+			if replicated := replicatedSince(leader, offset); strings.Contains(replicated, "XREADGROUP") {
+				t.Errorf("expected the read's effect to be replicated but got %q", replicated)
+			}
+
+			fc := newTestClient(follower)
+			for _, args := range [][]string{{"XINFO", "GROUPS", "s"}, {"XPENDING", "s", "g"}, {"XINFO", "CONSUMERS", "s", "g"}} {
+				want, got := c.do(args...), fc.do(args...)
+
+				// the consumers' seen & active times can be a millisecond apart
+				if args[1] == "CONSUMERS" {
+					want, got = consumerNames(want), consumerNames(got)
+				}
+
+				if got != want {
+					t.Errorf("expected the follower's %v to be %q but got %q", args, want, got)
+				}
+			}
+		})
+	}
+}
+
+func consumerNames(res string) string {
+	var names []string
+	for _, part := range strings.Split(res, "$4\r\nname\r\n")[1:] {
+		name, _, _ := strings.Cut(strings.SplitN(part, "\r\n", 2)[1], "\r\n")
+		names = append(names, name)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestXReadGroupChecksEveryGroupBeforeReading(t *testing.T) {
+	// arrange
+	h := newTestHost(t)
+	c := newTestClient(h)
+	c.do("XADD", "a", "1-0", "f", "v")
+	c.do("XADD", "b", "1-0", "f", "v")
+	c.do("XGROUP", "CREATE", "a", "g", "0")
+	offset := h.PubSubManager.Offset()
+
+	// act
+	res := c.do("XREADGROUP", "GROUP", "g", "alice", "STREAMS", "a", "b", ">", ">")
+
+	// assert
+	if want := "-NOGROUP No such key 'b' or consumer group 'g' in XREADGROUP with GROUP option\r\n"; res != want {
+		t.Errorf("expected %q but got %q", want, res)
+	}
+
+	if pending := c.do("XPENDING", "a", "g"); pending != "*4\r\n:0\r\n$-1\r\n$-1\r\n*-1\r\n" {
+		t.Errorf("expected nothing delivered from a but got %q", pending)
+	}
+
+	if consumers := c.do("XINFO", "CONSUMERS", "a", "g"); consumers != "*0\r\n" {
+		t.Errorf("expected no consumer created but got %q", consumers)
+	}
+
+	if replicated := replicatedSince(h, offset); replicated != "" {
+		t.Errorf("expected nothing replicated but got %q", replicated)
+	}
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+)
+
+// format: XTRIM <KEY> MAXLEN|MINID [=|~] <THRESHOLD> [LIMIT COUNT]
+//
+// replies with the number of entries removed. Replicated as an exact MAXLEN of the entries
+// left, so replicas trim the same entries however the trim was given
+func HandleXTrim(ctx HandleContext) (string, error) {
+	args := bulkStrings(ctx.RespArr.Elements[1:])
+	key := args[0]
+
+	trim, rest, errRes := parseStreamTrim(args[1:])
+	if errRes != nil {
+		return errRes.AsRespString(), nil
+	}
+	if len(rest) > 0 {
+		return resp.NewRespError("ERR syntax error").AsRespString(), nil
+	}
+
+	trimmed, length, err := ctx.Store().XTrim(key, trim)
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	if trimmed > 0 {
+		ctx.Propagate(resp.NewRespArrFromStrings([]string{"XTRIM", key, "MAXLEN", strconv.Itoa(length)}))
+	}
+
+	return resp.NewRespInteger(trimmed).AsRespString(), nil
+}
+
+// parses MAXLEN|MINID [=|~] <THRESHOLD> [LIMIT COUNT] from the start of the args,
+// returning those after it
+func parseStreamTrim(args []string) (store.StreamTrim, []string, *resp.RespError) {
+	trim := store.StreamTrim{MaxLen: strings.ToLower(args[0]) == "maxlen"}
+	syntaxErr := resp.NewRespError("ERR syntax error")
+	args = args[1:]
+
+	if len(args) > 0 && (args[0] == "=" || args[0] == "~") {
+		trim.Approx = args[0] == "~"
+		args = args[1:]
+	}
+
+	if len(args) == 0 {
+		return trim, nil, syntaxErr
+	}
+
+	if trim.MaxLen {
+		threshold, err := strconv.Atoi(args[0])
+		if err != nil {
+			return trim, nil, resp.NewRespError("ERR value is not an integer or out of range")
+		}
+		if threshold < 0 {
+			return trim, nil, resp.NewRespError("ERR The MAXLEN argument must be >= 0.")
+		}
+		trim.Threshold = threshold
+	} else {
+		minId, err := store.ParseStreamId(args[0], 0)
+		if err != nil {
+			return trim, nil, resp.NewRespError(err.Error())
+		}
+		trim.MinId = minId
+	}
+	args = args[1:]
+
+	if trim.Approx {
+		trim.Limit = store.DefaultTrimLimit
+	}
+
+	if len(args) > 0 && strings.ToLower(args[0]) == "limit" {
+		if len(args) < 2 {
+			return trim, nil, syntaxErr
+		}
+
+		limit, err := strconv.Atoi(args[1])
+		if err != nil {
+			return trim, nil, resp.NewRespError("ERR value is not an integer or out of range")
+		}
+		if limit < 0 {
+			return trim, nil, resp.NewRespError("ERR The LIMIT argument must be >= 0.")
+		}
+		if !trim.Approx {
+			return trim, nil, resp.NewRespError("ERR syntax error, LIMIT cannot be used without the special ~ option")
+		}
+
+		trim.Limit = limit
+		args = args[2:]
+	}
+
+	return trim, args, nil
+}
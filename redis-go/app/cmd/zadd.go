@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+)
+
+// format: ZADD <KEY> [NX|XX] [GT|LT] [CH] [INCR] <SCORE> <MEMBER> [SCORE MEMBER ...]
+//
+// replies with the number of members added, or also changed with CH. With INCR it
+// behaves like ZINCRBY, replying with the new score or nil if the options prevented it
+func HandleZAdd(ctx HandleContext) (string, error) {
+	if len(ctx.RespArr.Elements) < 4 {
+		return resp.WrongArgsError("zadd").AsRespString(), nil
+	}
+
+	key := ctx.RespArr.Elements[1].(*resp.RespBulkString)
+	args := bulkStrings(ctx.RespArr.Elements[2:])
+
+	options := store.ZAddOptions{}
+	ch := false
+
+	i := 0
+flags:
+	for ; i < len(args); i++ {
+		switch strings.ToLower(args[i]) {
+		case "nx":
+			options.NX = true
+		case "xx":
+			options.XX = true
+		case "gt":
+			options.GT = true
+		case "lt":
+			options.LT = true
+		case "ch":
+			ch = true
+		case "incr":
+			options.Incr = true
+		default:
+			break flags
+		}
+	}
+
+	pairs := args[i:]
+
+	if options.NX && options.XX {
+		return resp.NewRespError("ERR XX and NX options at the same time are not compatible").AsRespString(), nil
+	}
+
+	if (options.GT && options.LT) || ((options.GT || options.LT) && options.NX) {
+		return resp.NewRespError("ERR GT, LT, and/or NX options at the same time are not compatible").AsRespString(), nil
+	}
+
+	if len(pairs) == 0 || len(pairs)%2 != 0 {
+		return resp.NewRespError("ERR syntax error").AsRespString(), nil
+	}
+
+	if options.Incr && len(pairs) != 2 {
+		return resp.NewRespError("ERR INCR option supports a single increment-element pair").AsRespString(), nil
+	}
+
+	members := make([]store.ZMember, 0, len(pairs)/2)
+	for j := 0; j < len(pairs); j += 2 {
+		score, ok := parseScore(pairs[j])
+		if !ok {
+			return resp.NewRespError("ERR value is not a valid float").AsRespString(), nil
+		}
+
+		members = append(members, store.ZMember{Member: pairs[j+1], Score: score})
+	}
+
+	added, changed, score, ok, err := ctx.Store().ZAdd(key.Content, members, options)
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	if added+changed > 0 {
+		ctx.Propagate(&ctx.RespArr)
+	}
+
+	if options.Incr {
+		if !ok {
+			return resp.NullBulkString().AsRespString(), nil
+		}
+		return resp.NewRespBulkString(formatScore(score)).AsRespString(), nil
+	}
+
+	if ch {
+		return resp.NewRespInteger(added + changed).AsRespString(), nil
+	}
+
+	return resp.NewRespInteger(added).AsRespString(), nil
+}
+
+// format: ZINCRBY <KEY> <INCREMENT> <MEMBER>
+func HandleZIncrBy(ctx HandleContext) (string, error) {
+	if len(ctx.RespArr.Elements) != 4 {
+		return resp.WrongArgsError("zincrby").AsRespString(), nil
+	}
+
+	key := ctx.RespArr.Elements[1].(*resp.RespBulkString)
+	member := ctx.RespArr.Elements[3].(*resp.RespBulkString)
+
+	incr, ok := parseScore(ctx.RespArr.Elements[2].(*resp.RespBulkString).Content)
+	if !ok {
+		return resp.NewRespError("ERR value is not a valid float").AsRespString(), nil
+	}
+
+	_, _, score, _, err := ctx.Store().ZAdd(key.Content, []store.ZMember{{Member: member.Content, Score: incr}}, store.ZAddOptions{Incr: true})
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	ctx.Propagate(&ctx.RespArr)
+
+	return resp.NewRespBulkString(formatScore(score)).AsRespString(), nil
+}
+
+func parseScore(value string) (float64, bool) {
+	score, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(score) {
+		return 0, false
+	}
+
+	return score, true
+}
+
+// formats like redis, using exponents only for very large or small scores
+func formatScore(score float64) string {
+	switch {
+	case math.IsInf(score, 1):
+		return "inf"
+	case math.IsInf(score, -1):
+		return "-inf"
+	}
+
+	abs := math.Abs(score)
+	if abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		return strconv.FormatFloat(score, 'g', -1, 64)
+	}
+
+	return strconv.FormatFloat(score, 'f', -1, 64)
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+)
+
+// format: ZCOUNT <KEY> <MIN> <MAX>
+func HandleZCount(ctx HandleContext) (string, error) {
+	if len(ctx.RespArr.Elements) != 4 {
+		return resp.WrongArgsError("zcount").AsRespString(), nil
+	}
+
+	args := bulkStrings(ctx.RespArr.Elements[1:])
+
+	spec, errRes := parseZRangeSpec(store.ZRangeByScore, args[1], args[2])
+	if errRes != nil {
+		return errRes.AsRespString(), nil
+	}
+
+	count, err := ctx.Store().ZCount(args[0], spec.Score)
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	return resp.NewRespInteger(count).AsRespString(), nil
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// format: ZPOPMIN|ZPOPMAX <KEY> [COUNT]
+//
+// replies with a flat array of the popped members and their scores
+func HandleZPop(ctx HandleContext, command string) (string, error) {
+	if len(ctx.RespArr.Elements) < 2 || len(ctx.RespArr.Elements) > 3 {
+		return resp.WrongArgsError(command).AsRespString(), nil
+	}
+
+	key := ctx.RespArr.Elements[1].(*resp.RespBulkString)
+
+	count := 1
+	if len(ctx.RespArr.Elements) == 3 {
+		c, err := strconv.Atoi(ctx.RespArr.Elements[2].(*resp.RespBulkString).Content)
+		if err != nil || c < 0 {
+			return resp.NewRespError("ERR value is out of range, must be positive").AsRespString(), nil
+		}
+		count = c
+	}
+
+	popped, err := ctx.Store().ZPop(key.Content, count, strings.ToLower(command) == "zpopmax")
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	if len(popped) > 0 {
+		ctx.Propagate(&ctx.RespArr)
+	}
+
+	return zmembersResponse(popped, true).AsRespString(), nil
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import "testing"
+
+func TestZPop(t *testing.T) {
+	cases := []struct {
+		args  []string
+		want  string
+		zcard string // ZCARD reply afterwards
+	}{
+		{args: []string{"ZPOPMIN", "z"}, want: "*2\r\n$1\r\na\r\n$1\r\n1\r\n", zcard: ":2\r\n"},
+		{args: []string{"ZPOPMAX", "z"}, want: "*2\r\n$1\r\nc\r\n$1\r\n3\r\n", zcard: ":2\r\n"},
+		{args: []string{"ZPOPMIN", "z", "2"}, want: "*4\r\n$1\r\na\r\n$1\r\n1\r\n$1\r\nb\r\n$1\r\n2\r\n", zcard: ":1\r\n"},
+		{args: []string{"ZPOPMAX", "z", "5"}, want: "*6\r\n$1\r\nc\r\n$1\r\n3\r\n$1\r\nb\r\n$1\r\n2\r\n$1\r\na\r\n$1\r\n1\r\n", zcard: ":0\r\n"},
+		{args: []string{"ZPOPMIN", "z", "0"}, want: "*0\r\n", zcard: ":3\r\n"},
+		{args: []string{"ZPOPMAX", "z", "0"}, want: "*0\r\n", zcard: ":3\r\n"},
+		{args: []string{"ZPOPMIN", "z", "-1"}, want: "-ERR value is out of range, must be positive\r\n", zcard: ":3\r\n"},
+		{args: []string{"ZPOPMIN", "missing"}, want: "*0\r\n", zcard: ":3\r\n"},
+	}
+
+	for _, tc := range cases {
+		// arrange
+		h := newTestHost(t)
+		c := newTestClient(h)
+		c.do("ZADD", "z", "1", "a", "2", "b", "3", "c")
+
+		// act
+		got := c.do(tc.args...)
+
+		// assert
+		if got != tc.want {
+			t.Errorf("expected %v to reply %q but got %q", tc.args, tc.want, got)
+		}
+
+		if zcard := c.do("ZCARD", "z"); zcard != tc.zcard {
+			t.Errorf("expected ZCARD %q after %v but got %q", tc.zcard, tc.args, zcard)
+		}
+	}
+}
+
+func TestZPopNothingIsntReplicated(t *testing.T) {
+	// arrange
+	h := newTestHost(t)
+	c := newTestClient(h)
+	c.do("ZADD", "z", "1", "a")
+	offset := h.PubSubManager.Offset()
+
+	// act
+	c.do("ZPOPMIN", "z", "0")
+	c.do("ZPOPMAX", "missing")
+
+	// assert
+	if replicated := replicatedSince(h, offset); replicated != "" {
+		t.Errorf("expected nothing replicated but got %q", replicated)
+	}
+}
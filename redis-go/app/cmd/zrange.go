@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+)
+
+// format: ZRANGE <KEY> <START> <STOP> [BYSCORE|BYLEX] [REV] [LIMIT OFFSET COUNT] [WITHSCORES]
+//
+// with REV and BYSCORE or BYLEX, start and stop are the max and min
+func HandleZRange(ctx HandleContext) (string, error) {
+	if len(ctx.RespArr.Elements) < 4 {
+		return resp.WrongArgsError("zrange").AsRespString(), nil
+	}
+
+	args := bulkStrings(ctx.RespArr.Elements[1:])
+
+	by := store.ZRangeByIndex
+	rev, withScores, limited := false, false, false
+	offset, count := 0, -1
+
+	for i := 3; i < len(args); i++ {
+		switch strings.ToLower(args[i]) {
+		case "byscore":
+			by = store.ZRangeByScore
+		case "bylex":
+			by = store.ZRangeByLex
+		case "rev":
+			rev = true
+		case "withscores":
+			withScores = true
+		case "limit":
+			var errRes *resp.RespError
+			offset, count, errRes = parseLimit(args, i)
+			if errRes != nil {
+				return errRes.AsRespString(), nil
+			}
+			limited = true
+			i += 2
+		default:
+			return resp.NewRespError("ERR syntax error").AsRespString(), nil
+		}
+	}
+
+	if limited && by == store.ZRangeByIndex {
+		return resp.NewRespError("ERR syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX").AsRespString(), nil
+	}
+
+	if withScores && by == store.ZRangeByLex {
+		return resp.NewRespError("ERR syntax error, WITHSCORES not supported in combination with BYLEX").AsRespString(), nil
+	}
+
+	start, stop := args[1], args[2]
+	if rev && by != store.ZRangeByIndex {
+		start, stop = stop, start
+	}
+
+	spec, errRes := parseZRangeSpec(by, start, stop)
+	if errRes != nil {
+		return errRes.AsRespString(), nil
+	}
+
+	spec.Rev = rev
+	spec.Offset = offset
+	spec.Count = count
+
+	return zrange(ctx, args[0], spec, withScores)
+}
+
+// format: ZRANGEBYSCORE <KEY> <MIN> <MAX> [WITHSCORES] [LIMIT OFFSET COUNT]
+func HandleZRangeByScore(ctx HandleContext) (string, error) {
+	if len(ctx.RespArr.Elements) < 4 {
+		return resp.WrongArgsError("zrangebyscore").AsRespString(), nil
+	}
+
+	args := bulkStrings(ctx.RespArr.Elements[1:])
+
+	withScores := false
+	offset, count := 0, -1
+
+	for i := 3; i < len(args); i++ {
+		switch strings.ToLower(args[i]) {
+		case "withscores":
+			withScores = true
+		case "limit":
+			var errRes *resp.RespError
+			offset, count, errRes = parseLimit(args, i)
+			if errRes != nil {
+				return errRes.AsRespString(), nil
+			}
+			i += 2
+		default:
+			return resp.NewRespError("ERR syntax error").AsRespString(), nil
+		}
+	}
+
+	spec, errRes := parseZRangeSpec(store.ZRangeByScore, args[1], args[2])
+	if errRes != nil {
+		return errRes.AsRespString(), nil
+	}
+
+	spec.Offset = offset
+	spec.Count = count
+
+	return zrange(ctx, args[0], spec, withScores)
+}
+
+func zrange(ctx HandleContext, key string, spec store.ZRangeSpec, withScores bool) (string, error) {
+	// a negative offset selects nothing
+	if spec.Offset < 0 {
+		return resp.NewRespArrFromStrings([]string{}).AsRespString(), nil
+	}
+
+	members, err := ctx.Store().ZRange(key, spec)
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	return zmembersResponse(members, withScores).AsRespString(), nil
+}
+
+// LIMIT <OFFSET> <COUNT> where a negative count returns everything after the offset
+func parseLimit(args []string, i int) (int, int, *resp.RespError) {
+	if i+2 >= len(args) {
+		return 0, 0, resp.NewRespError("ERR syntax error")
+	}
+
+	offset, err1 := strconv.Atoi(args[i+1])
+	count, err2 := strconv.Atoi(args[i+2])
+	if err1 != nil || err2 != nil {
+		return 0, 0, resp.NewRespError("ERR value is not an integer or out of range")
+	}
+
+	if count < 0 {
+		count = -1
+	}
+
+	return offset, count, nil
+}
+
+// parses the min and max (or start and stop for ranks) of the range, Count defaults to all
+func parseZRangeSpec(by store.ZRangeBy, min string, max string) (store.ZRangeSpec, *resp.RespError) {
+	spec := store.ZRangeSpec{By: by, Count: -1}
+
+	switch by {
+	case store.ZRangeByIndex:
+		start, err1 := strconv.Atoi(min)
+		stop, err2 := strconv.Atoi(max)
+		if err1 != nil || err2 != nil {
+			return spec, resp.NewRespError("ERR value is not an integer or out of range")
+		}
+
+		spec.Start, spec.Stop = start, stop
+	case store.ZRangeByScore:
+		var ok1, ok2 bool
+		spec.Score.Min, spec.Score.MinEx, ok1 = parseScoreBound(min)
+		spec.Score.Max, spec.Score.MaxEx, ok2 = parseScoreBound(max)
+		if !ok1 || !ok2 {
+			return spec, resp.NewRespError("ERR min or max is not a float")
+		}
+	case store.ZRangeByLex:
+		var ok1, ok2 bool
+		spec.Lex.Min, ok1 = parseLexBound(min)
+		spec.Lex.Max, ok2 = parseLexBound(max)
+		if !ok1 || !ok2 {
+			return spec, resp.NewRespError("ERR min or max not valid string range item")
+		}
+	}
+
+	return spec, nil
+}
+
+// e.g. 1.5, (1.5 for exclusive, -inf and +inf
+func parseScoreBound(value string) (float64, bool, bool) {
+	exclusive := strings.HasPrefix(value, "(")
+	score, ok := parseScore(strings.TrimPrefix(value, "("))
+
+	return score, exclusive, ok
+}
+
+// e.g. [a inclusive, (a exclusive, - and + for the start and end of the set
+func parseLexBound(value string) (store.LexBound, bool) {
+	switch {
+	case value == "-":
+		return store.LexBound{Inf: -1}, true
+	case value == "+":
+		return store.LexBound{Inf: 1}, true
+	case strings.HasPrefix(value, "["):
+		return store.LexBound{Value: value[1:]}, true
+	case strings.HasPrefix(value, "("):
+		return store.LexBound{Value: value[1:], Exclusive: true}, true
+	default:
+		return store.LexBound{}, false
+	}
+}
+
+// flat array of members, each followed by its score when withScores is set
+func zmembersResponse(members []store.ZMember, withScores bool) *resp.RespArray {
+	items := make([]string, 0, len(members)*2)
+	for _, m := range members {
+		items = append(items, m.Member)
+		if withScores {
+			items = append(items, formatScore(m.Score))
+		}
+	}
+
+	return resp.NewRespArrFromStrings(items)
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// format: ZRANK|ZREVRANK <KEY> <MEMBER>
+func HandleZRank(ctx HandleContext, command string) (string, error) {
+	if len(ctx.RespArr.Elements) != 3 {
+		return resp.WrongArgsError(command).AsRespString(), nil
+	}
+
+	key := ctx.RespArr.Elements[1].(*resp.RespBulkString)
+	member := ctx.RespArr.Elements[2].(*resp.RespBulkString)
+
+	rank, exists, err := ctx.Store().ZRank(key.Content, member.Content, strings.ToLower(command) == "zrevrank")
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	if !exists {
+		return resp.NullBulkString().AsRespString(), nil
+	}
+
+	return resp.NewRespInteger(rank).AsRespString(), nil
+}
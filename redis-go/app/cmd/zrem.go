@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/store"
+)
+
+// format: ZREM <KEY> <MEMBER> [MEMBER ...]
+func HandleZRem(ctx HandleContext) (string, error) {
+	if len(ctx.RespArr.Elements) < 3 {
+		return resp.WrongArgsError("zrem").AsRespString(), nil
+	}
+
+	key := ctx.RespArr.Elements[1].(*resp.RespBulkString)
+
+	removed, err := ctx.Store().ZRem(key.Content, bulkStrings(ctx.RespArr.Elements[2:]))
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	if removed > 0 {
+		ctx.Propagate(&ctx.RespArr)
+	}
+
+	return resp.NewRespInteger(removed).AsRespString(), nil
+}
+
+var zremRangeBy = map[string]store.ZRangeBy{
+	"zremrangebyrank":  store.ZRangeByIndex,
+	"zremrangebyscore": store.ZRangeByScore,
+	"zremrangebylex":   store.ZRangeByLex,
+}
+
+// format: ZREMRANGEBYRANK|ZREMRANGEBYSCORE|ZREMRANGEBYLEX <KEY> <MIN> <MAX>
+func HandleZRemRange(ctx HandleContext, command string) (string, error) {
+	if len(ctx.RespArr.Elements) != 4 {
+		return resp.WrongArgsError(command).AsRespString(), nil
+	}
+
+	args := bulkStrings(ctx.RespArr.Elements[1:])
+
+	spec, errRes := parseZRangeSpec(zremRangeBy[strings.ToLower(command)], args[1], args[2])
+	if errRes != nil {
+		return errRes.AsRespString(), nil
+	}
+
+	removed, err := ctx.Store().ZRemRange(args[0], spec)
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	if removed > 0 {
+		ctx.Propagate(&ctx.RespArr)
+	}
+
+	return resp.NewRespInteger(removed).AsRespString(), nil
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// format: ZSCORE <KEY> <MEMBER>
+func HandleZScore(ctx HandleContext) (string, error) {
+	if len(ctx.RespArr.Elements) != 3 {
+		return resp.WrongArgsError("zscore").AsRespString(), nil
+	}
+
+	key := ctx.RespArr.Elements[1].(*resp.RespBulkString)
+	member := ctx.RespArr.Elements[2].(*resp.RespBulkString)
+
+	score, exists, err := ctx.Store().ZScore(key.Content, member.Content)
+	if err != nil {
+		return resp.NewRespError(err.Error()).AsRespString(), nil
+	}
+
+	if !exists {
+		return resp.NullBulkString().AsRespString(), nil
+	}
+
+	return resp.NewRespBulkString(formatScore(score)).AsRespString(), nil
+}
@@ -0,0 +1,102 @@
+package glob
+
+/* Redis style glob matching of byte strings, as used by KEYS, SCAN and PSUBSCRIBE:
+
+	*       any sequence of bytes, including none
+	?       any single byte
+	[abc]   one of the bytes, [a-z] for a range and [^a] to negate
+	\x      the byte x literally, both inside and outside of []
+
+an unterminated [ runs to the end of the pattern, like redis
+
+see: https://github.com/redis/redis/blob/7.2/src/util.c
+*/
+
+// whether the whole of s matches the pattern
+func Match(pattern string, s string) bool {
+	p, i := 0, 0
+
+	// where to resume from when a match after the last * fails, retrying with the *
+	// consuming one more byte. Only the last * ever needs retrying
+	star, starI := -1, 0
+
+	for i < len(s) {
+		if p < len(pattern) {
+			if pattern[p] == '*' {
+				star, starI = p, i
+				p++
+				continue
+			}
+
+			if width, ok := matchByte(pattern, p, s[i]); ok {
+				p += width
+				i++
+				continue
+			}
+		}
+
+		if star < 0 {
+			return false
+		}
+
+		starI++
+		p, i = star+1, starI
+	}
+
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+
+	return p == len(pattern)
+}
+
+// whether the single byte token at pattern[p] matches c, along with the token's width
+func matchByte(pattern string, p int, c byte) (int, bool) {
+	switch pattern[p] {
+	case '?':
+		return 1, true
+	case '\\':
+		if p+1 < len(pattern) {
+			return 2, pattern[p+1] == c
+		}
+		return 1, c == '\\'
+	case '[':
+		return matchClass(pattern, p, c)
+	default:
+		return 1, pattern[p] == c
+	}
+}
+
+func matchClass(pattern string, p int, c byte) (int, bool) {
+	i := p + 1
+
+	negate := i < len(pattern) && pattern[i] == '^'
+	if negate {
+		i++
+	}
+
+	match := false
+	for i < len(pattern) && pattern[i] != ']' {
+		switch {
+		case pattern[i] == '\\' && i+1 < len(pattern):
+			match = match || pattern[i+1] == c
+			i += 2
+		case i+2 < len(pattern) && pattern[i+1] == '-':
+			start, end := pattern[i], pattern[i+2]
+			if start > end {
+				start, end = end, start
+			}
+			match = match || (c >= start && c <= end)
+			i += 3
+		default:
+			match = match || pattern[i] == c
+			i++
+		}
+	}
+
+	if i < len(pattern) {
+		i++ // the closing ]
+	}
+
+	return i - p, match != negate
+}
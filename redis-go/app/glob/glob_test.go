@@ -0,0 +1,47 @@
+package glob
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+
+	tests := []struct {
+		pattern string
+		s       string
+		match   bool
+	}{
+		{"*", "", true},
+		{"*", "anything", true},
+		{"foo*", "foobar", true},
+		{"foo*", "barfoo", false},
+		{"*bar", "foobar", true},
+		{"f*o*r", "foobar", true},
+		{"f*o*z", "foobar", false},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-b]llo", "hbllo", true},
+		{"h[b-a]llo", "hallo", true}, // reversed ranges are swapped
+		{"h[a-b]llo", "hcllo", false},
+		{"h\\*llo", "h*llo", true},
+		{"h\\*llo", "hello", false},
+		{"[\\]]", "]", true},
+		{"[]", "a", false},
+		{"a[bc", "ab", true}, // unterminated class runs to the end
+		{"user:*:name", "user:42:name", true},
+		{"user:*:name", "user:42:email", false},
+		{"*a*a*a*a*a*a*a*a*b", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", false},
+	}
+
+	for _, test := range tests {
+		// act
+		result := Match(test.pattern, test.s)
+
+		// assert
+		if result != test.match {
+			t.Errorf("expected %q matching %q to be %v but got %v", test.pattern, test.s, test.match, result)
+		}
+	}
+}
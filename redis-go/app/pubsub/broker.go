@@ -0,0 +1,289 @@
+package pubsub
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/codecrafters-io/redis-starter-go/app/glob"
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+)
+
+// messages a subscriber can have waiting before it's dropped for not keeping up
+const subscriberQueueSize = 1024
+
+// routes published messages to the subscribers of matching channels & patterns. Publishing
+// never blocks, a subscriber whose queue is full is dropped instead. Shard channels (as in
+// SSUBSCRIBE) are a separate namespace, without a cluster there's only the one shard
+type Broker struct {
+	mu       sync.RWMutex
+	channels map[string]map[*Subscriber]struct{}
+	patterns map[string]map[*Subscriber]struct{}
+	shards   map[string]map[*Subscriber]struct{}
+}
+
+// a connection's subscriptions. Everything sent to it, including the replies confirming
+// (un)subscribes, goes through one queue so it arrives in order
+type Subscriber struct {
+	queue    chan string
+	dropped  chan struct{} // closed once the subscriber falls too far behind
+	dropOnce sync.Once
+	channels map[string]struct{}
+	patterns map[string]struct{}
+	shards   map[string]struct{}
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		channels: make(map[string]map[*Subscriber]struct{}),
+		patterns: make(map[string]map[*Subscriber]struct{}),
+		shards:   make(map[string]map[*Subscriber]struct{}),
+	}
+}
+
+func NewSubscriber() *Subscriber {
+	return &Subscriber{
+		queue:    make(chan string, subscriberQueueSize),
+		dropped:  make(chan struct{}),
+		channels: make(map[string]struct{}),
+		patterns: make(map[string]struct{}),
+		shards:   make(map[string]struct{}),
+	}
+}
+
+// RESP encoded messages and replies to write to the connection in order
+func (s *Subscriber) Queue() <-chan string {
+	return s.queue
+}
+
+// closed when the subscriber has been dropped, the connection should be closed as
+// messages have been lost
+func (s *Subscriber) Dropped() <-chan struct{} {
+	return s.dropped
+}
+
+// queues the message without blocking, dropping the subscriber if its queue is full
+func (s *Subscriber) Send(message string) {
+	select {
+	case s.queue <- message:
+	default:
+		s.dropOnce.Do(func() { close(s.dropped) })
+	}
+}
+
+// number of channels, patterns & shard channels subscribed to, the connection is in
+// subscriber mode whilst there are any. Must be called from the connection's goroutine
+func (s *Subscriber) Count() int {
+	return len(s.channels) + len(s.patterns) + len(s.shards)
+}
+
+// the count confirmations of channels & patterns report, shard channels are counted apart
+func (s *Subscriber) nonShardCount() int {
+	return len(s.channels) + len(s.patterns)
+}
+
+func (s *Subscriber) shardCount() int {
+	return len(s.shards)
+}
+
+func (b *Broker) Subscribe(s *Subscriber, channels []string) {
+	b.subscribe(s, channels, b.channels, s.channels, "subscribe", s.nonShardCount)
+}
+
+func (b *Broker) PSubscribe(s *Subscriber, patterns []string) {
+	b.subscribe(s, patterns, b.patterns, s.patterns, "psubscribe", s.nonShardCount)
+}
+
+func (b *Broker) SSubscribe(s *Subscriber, channels []string) {
+	b.subscribe(s, channels, b.shards, s.shards, "ssubscribe", s.shardCount)
+}
+
+// unsubscribes from the channels, or every channel when there are none
+func (b *Broker) Unsubscribe(s *Subscriber, channels []string) {
+	b.unsubscribe(s, channels, b.channels, s.channels, "unsubscribe", s.nonShardCount)
+}
+
+// unsubscribes from the patterns, or every pattern when there are none
+func (b *Broker) PUnsubscribe(s *Subscriber, patterns []string) {
+	b.unsubscribe(s, patterns, b.patterns, s.patterns, "punsubscribe", s.nonShardCount)
+}
+
+// unsubscribes from the shard channels, or every shard channel when there are none
+func (b *Broker) SUnsubscribe(s *Subscriber, channels []string) {
+	b.unsubscribe(s, channels, b.shards, s.shards, "sunsubscribe", s.shardCount)
+}
+
+// removes every subscription without confirming them, for when the connection closes
+func (b *Broker) UnsubscribeAll(s *Subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for channel := range s.channels {
+		removeSubscriber(b.channels, channel, s)
+	}
+	for pattern := range s.patterns {
+		removeSubscriber(b.patterns, pattern, s)
+	}
+	for channel := range s.shards {
+		removeSubscriber(b.shards, channel, s)
+	}
+
+	s.channels = make(map[string]struct{})
+	s.patterns = make(map[string]struct{})
+	s.shards = make(map[string]struct{})
+}
+
+// each subscription is confirmed with a reply of the kind, the name and the new count
+func (b *Broker) subscribe(s *Subscriber, names []string, index map[string]map[*Subscriber]struct{}, own map[string]struct{}, kind string, count func() int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, name := range names {
+		if index[name] == nil {
+			index[name] = make(map[*Subscriber]struct{})
+		}
+		index[name][s] = struct{}{}
+		own[name] = struct{}{}
+
+		s.Send(confirmation(kind, name, count()))
+	}
+}
+
+func (b *Broker) unsubscribe(s *Subscriber, names []string, index map[string]map[*Subscriber]struct{}, own map[string]struct{}, kind string, count func() int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(names) == 0 {
+		if len(own) == 0 {
+			s.Send(resp.NewRespArray([]resp.RespType{
+				resp.NewRespBulkString(kind), resp.NullBulkString(), resp.NewRespInteger(count()),
+			}).AsRespString())
+			return
+		}
+
+		for name := range own {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	for _, name := range names {
+		removeSubscriber(index, name, s)
+		delete(own, name)
+
+		s.Send(confirmation(kind, name, count()))
+	}
+}
+
+func removeSubscriber(index map[string]map[*Subscriber]struct{}, name string, s *Subscriber) {
+	delete(index[name], s)
+	if len(index[name]) == 0 {
+		delete(index, name)
+	}
+}
+
+func confirmation(kind string, name string, count int) string {
+	return resp.NewRespArray([]resp.RespType{
+		resp.NewRespBulkString(kind), resp.NewRespBulkString(name), resp.NewRespInteger(count),
+	}).AsRespString()
+}
+
+// sends the message to the channel's subscribers and those of matching patterns,
+// returning how many received it (a subscriber matching several times counts each)
+func (b *Broker) Publish(channel string, message string) int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	received := 0
+
+	if subscribers := b.channels[channel]; len(subscribers) > 0 {
+		msg := resp.NewRespArrFromStrings([]string{"message", channel, message}).AsRespString()
+		for s := range subscribers {
+			s.Send(msg)
+			received++
+		}
+	}
+
+	for pattern, subscribers := range b.patterns {
+		if !glob.Match(pattern, channel) {
+			continue
+		}
+
+		msg := resp.NewRespArrFromStrings([]string{"pmessage", pattern, channel, message}).AsRespString()
+		for s := range subscribers {
+			s.Send(msg)
+			received++
+		}
+	}
+
+	return received
+}
+
+// sends the message to the shard channel's subscribers, patterns never match them
+func (b *Broker) SPublish(channel string, message string) int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	subscribers := b.shards[channel]
+	if len(subscribers) == 0 {
+		return 0
+	}
+
+	msg := resp.NewRespArrFromStrings([]string{"smessage", channel, message}).AsRespString()
+	for s := range subscribers {
+		s.Send(msg)
+	}
+
+	return len(subscribers)
+}
+
+// channels with at least one subscriber matching the pattern (all of them when empty), sorted
+func (b *Broker) Channels(pattern string) []string {
+	return b.names(b.channels, pattern)
+}
+
+func (b *Broker) ShardChannels(pattern string) []string {
+	return b.names(b.shards, pattern)
+}
+
+// number of subscribers to each channel, not counting patterns
+func (b *Broker) NumSub(channels []string) []int {
+	return b.counts(b.channels, channels)
+}
+
+func (b *Broker) ShardNumSub(channels []string) []int {
+	return b.counts(b.shards, channels)
+}
+
+func (b *Broker) names(index map[string]map[*Subscriber]struct{}, pattern string) []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	names := make([]string, 0, len(index))
+	for name := range index {
+		if pattern == "" || glob.Match(pattern, name) {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+func (b *Broker) counts(index map[string]map[*Subscriber]struct{}, names []string) []int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	counts := make([]int, len(names))
+	for i, name := range names {
+		counts[i] = len(index[name])
+	}
+	return counts
+}
+
+// number of distinct patterns subscribed to
+func (b *Broker) NumPat() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return len(b.patterns)
+}
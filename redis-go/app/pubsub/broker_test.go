@@ -0,0 +1,165 @@
+package pubsub
+
+import (
+	"reflect"
+	"testing"
+)
+
+// everything waiting in the subscriber's queue
+func drain(s *Subscriber) []string {
+	var queued []string
+	for {
+		select {
+		case msg := <-s.Queue():
+			queued = append(queued, msg)
+		default:
+			return queued
+		}
+	}
+}
+
+func TestPublish(t *testing.T) {
+	b := NewBroker()
+	news, all := NewSubscriber(), NewSubscriber()
+
+	b.Subscribe(news, []string{"news", "sport"})
+	b.PSubscribe(all, []string{"n*"})
+
+	want := []string{
+		"*3\r\n$9\r\nsubscribe\r\n$4\r\nnews\r\n:1\r\n",
+		"*3\r\n$9\r\nsubscribe\r\n$5\r\nsport\r\n:2\r\n",
+	}
+	if got := drain(news); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q but got %q", want, got)
+	}
+	drain(all)
+
+	if received := b.Publish("news", "hi"); received != 2 {
+		t.Errorf("expected 2 receivers but got %d", received)
+	}
+
+	if got := drain(news); !reflect.DeepEqual(got, []string{"*3\r\n$7\r\nmessage\r\n$4\r\nnews\r\n$2\r\nhi\r\n"}) {
+		t.Errorf("expected a message but got %q", got)
+	}
+	if got := drain(all); !reflect.DeepEqual(got, []string{"*4\r\n$8\r\npmessage\r\n$2\r\nn*\r\n$4\r\nnews\r\n$2\r\nhi\r\n"}) {
+		t.Errorf("expected a pmessage but got %q", got)
+	}
+
+	if received := b.Publish("weather", "rain"); received != 0 {
+		t.Errorf("expected no receivers but got %d", received)
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	b := NewBroker()
+	s := NewSubscriber()
+
+	b.Subscribe(s, []string{"b", "a"})
+	b.PSubscribe(s, []string{"*"})
+	drain(s)
+
+	b.Unsubscribe(s, nil)
+
+	want := []string{
+		"*3\r\n$11\r\nunsubscribe\r\n$1\r\na\r\n:2\r\n",
+		"*3\r\n$11\r\nunsubscribe\r\n$1\r\nb\r\n:1\r\n",
+	}
+	if got := drain(s); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q but got %q", want, got)
+	}
+
+	if s.Count() != 1 || len(b.Channels("")) != 0 || b.NumPat() != 1 {
+		t.Errorf("expected only the pattern to be left")
+	}
+
+	b.Unsubscribe(s, nil)
+	if got := drain(s); !reflect.DeepEqual(got, []string{"*3\r\n$11\r\nunsubscribe\r\n$-1\r\n:1\r\n"}) {
+		t.Errorf("expected a null channel when there's nothing to unsubscribe from but got %q", got)
+	}
+
+	b.UnsubscribeAll(s)
+	if s.Count() != 0 || b.NumPat() != 0 || len(drain(s)) != 0 {
+		t.Errorf("expected everything to be removed without confirmations")
+	}
+}
+
+func TestIntrospection(t *testing.T) {
+	b := NewBroker()
+	first, second := NewSubscriber(), NewSubscriber()
+
+	b.Subscribe(first, []string{"news.tech", "sport"})
+	b.Subscribe(second, []string{"news.tech"})
+	b.PSubscribe(second, []string{"news.*", "sport"})
+
+	if got := b.Channels(""); !reflect.DeepEqual(got, []string{"news.tech", "sport"}) {
+		t.Errorf("expected every channel but got %v", got)
+	}
+
+	if got := b.Channels("news.*"); !reflect.DeepEqual(got, []string{"news.tech"}) {
+		t.Errorf("expected the matching channels but got %v", got)
+	}
+
+	if got := b.NumSub([]string{"news.tech", "sport", "missing"}); !reflect.DeepEqual(got, []int{2, 1, 0}) {
+		t.Errorf("expected subscriber counts but got %v", got)
+	}
+
+	if b.NumPat() != 2 {
+		t.Errorf("expected 2 patterns but got %d", b.NumPat())
+	}
+}
+
+func TestSlowSubscriberDropped(t *testing.T) {
+	b := NewBroker()
+	s := NewSubscriber()
+
+	b.Subscribe(s, []string{"c"})
+
+	for i := 0; i < subscriberQueueSize; i++ {
+		b.Publish("c", "m")
+	}
+
+	select {
+	case <-s.Dropped():
+	default:
+		t.Fatalf("expected the subscriber to be dropped once its queue filled")
+	}
+}
+
+func TestShardChannels(t *testing.T) {
+	b := NewBroker()
+	s := NewSubscriber()
+
+	b.Subscribe(s, []string{"orders"})
+	b.SSubscribe(s, []string{"orders"})
+	b.PSubscribe(s, []string{"*"})
+
+	want := []string{
+		"*3\r\n$9\r\nsubscribe\r\n$6\r\norders\r\n:1\r\n",
+		"*3\r\n$10\r\nssubscribe\r\n$6\r\norders\r\n:1\r\n",
+		"*3\r\n$10\r\npsubscribe\r\n$1\r\n*\r\n:2\r\n",
+	}
+	if got := drain(s); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected shard channels to be counted apart but got %q", got)
+	}
+
+	if received := b.SPublish("orders", "new"); received != 1 {
+		t.Errorf("expected only the shard subscriber to receive but got %d", received)
+	}
+
+	if got := drain(s); !reflect.DeepEqual(got, []string{"*3\r\n$8\r\nsmessage\r\n$6\r\norders\r\n$3\r\nnew\r\n"}) {
+		t.Errorf("expected an smessage but got %q", got)
+	}
+
+	if !reflect.DeepEqual(b.ShardChannels(""), []string{"orders"}) || !reflect.DeepEqual(b.ShardNumSub([]string{"orders"}), []int{1}) {
+		t.Errorf("expected the shard channel to be listed")
+	}
+
+	b.SUnsubscribe(s, nil)
+	if got := drain(s); !reflect.DeepEqual(got, []string{"*3\r\n$12\r\nsunsubscribe\r\n$6\r\norders\r\n:0\r\n"}) {
+		t.Errorf("expected to unsubscribe from the shard channel but got %q", got)
+	}
+
+	if s.Count() != 2 {
+		t.Errorf("expected the channel and pattern to be left but got %d", s.Count())
+	}
+}
@@ -0,0 +1,105 @@
+package pubsub
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// which keyspace events are published, parsed from the notify-keyspace-events flags:
+//
+//	K  keyspace events, published to __keyspace@<db>__:<key> with the event
+//	E  keyevent events, published to __keyevent@<db>__:<event> with the key
+//	g  generic (del, expire, ...)   $  string     l  list    s  set     h  hash
+//	z  sorted set                   t  stream     x  expired e  evicted d  module
+//	m  key miss                     n  new key    A  alias for g$lshztxed
+//
+// nothing is published unless K or E is given along with at least one class
+type KeyspaceEvents struct {
+	keyspace bool
+	keyevent bool
+	classes  string // the class flags selected, in canonical order
+}
+
+// the classes A selects, in the order CONFIG GET reports them
+const allClasses = "g$lshzxetd"
+
+var ErrInvalidKeyspaceEvents = errors.New("invalid keyspace event flags")
+
+func ParseKeyspaceEvents(flags string) (KeyspaceEvents, error) {
+	var events KeyspaceEvents
+	selected := make(map[byte]bool)
+
+	for i := 0; i < len(flags); i++ {
+		switch c := flags[i]; {
+		case c == 'K':
+			events.keyspace = true
+		case c == 'E':
+			events.keyevent = true
+		case c == 'A':
+			for j := 0; j < len(allClasses); j++ {
+				selected[allClasses[j]] = true
+			}
+		case strings.IndexByte(allClasses+"nm", c) >= 0:
+			selected[c] = true
+		default:
+			return KeyspaceEvents{}, ErrInvalidKeyspaceEvents
+		}
+	}
+
+	for _, c := range []byte(allClasses + "nm") {
+		if selected[c] {
+			events.classes += string(c)
+		}
+	}
+
+	return events, nil
+}
+
+// the canonical flags as redis reports them, e.g. AKE for KEA
+func (e KeyspaceEvents) String() string {
+	var flags strings.Builder
+
+	if strings.HasPrefix(e.classes, allClasses) {
+		flags.WriteString("A")
+	} else {
+		flags.WriteString(strings.TrimRight(e.classes, "nm"))
+	}
+
+	if strings.Contains(e.classes, "n") {
+		flags.WriteString("n")
+	}
+	if e.keyspace {
+		flags.WriteString("K")
+	}
+	if e.keyevent {
+		flags.WriteString("E")
+	}
+	if strings.Contains(e.classes, "m") {
+		flags.WriteString("m")
+	}
+
+	return flags.String()
+}
+
+// whether events of the class (its flag character) are published
+func (e KeyspaceEvents) Selects(class byte) bool {
+	return (e.keyspace || e.keyevent) && strings.IndexByte(e.classes, class) >= 0
+}
+
+// publishes the event for the key in the database to the keyspace and keyevent channels
+// the flags select
+func (b *Broker) PublishKeyspaceEvent(events KeyspaceEvents, db int, class byte, event string, key string) {
+	if !events.Selects(class) {
+		return
+	}
+
+	prefix := "@" + strconv.Itoa(db) + "__:"
+
+	if events.keyspace {
+		b.Publish("__keyspace"+prefix+key, event)
+	}
+	if events.keyevent {
+		b.Publish("__keyevent"+prefix+event, key)
+	}
+}
@@ -0,0 +1,62 @@
+package pubsub
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseKeyspaceEvents(t *testing.T) {
+	tests := []struct {
+		flags string
+		want  string
+	}{
+		{"", ""},
+		{"KEA", "AKE"},
+		{"Ex", "xE"},
+		{"K$lg", "g$lK"},
+		{"AnmK", "AnKm"},
+		{"g$lshzxetdE", "AE"},
+	}
+
+	for _, test := range tests {
+		events, err := ParseKeyspaceEvents(test.flags)
+		if err != nil {
+			t.Fatalf("%q: expected no error but got %v", test.flags, err)
+		}
+
+		if events.String() != test.want {
+			t.Errorf("%q: expected %q but got %q", test.flags, test.want, events.String())
+		}
+	}
+
+	if _, err := ParseKeyspaceEvents("KEq"); err != ErrInvalidKeyspaceEvents {
+		t.Errorf("expected %v but got %v", ErrInvalidKeyspaceEvents, err)
+	}
+}
+
+func TestPublishKeyspaceEvent(t *testing.T) {
+	b := NewBroker()
+	s := NewSubscriber()
+
+	b.PSubscribe(s, []string{"__key*__:*"})
+	drain(s)
+
+	events, _ := ParseKeyspaceEvents("KEx")
+
+	b.PublishKeyspaceEvent(events, 0, 'x', "expired", "session")
+	b.PublishKeyspaceEvent(events, 0, '$', "set", "session") // not selected
+
+	want := []string{
+		"*4\r\n$8\r\npmessage\r\n$10\r\n__key*__:*\r\n$22\r\n__keyspace@0__:session\r\n$7\r\nexpired\r\n",
+		"*4\r\n$8\r\npmessage\r\n$10\r\n__key*__:*\r\n$22\r\n__keyevent@0__:expired\r\n$7\r\nsession\r\n",
+	}
+	if got := drain(s); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q but got %q", want, got)
+	}
+
+	disabled, _ := ParseKeyspaceEvents("x")
+	b.PublishKeyspaceEvent(disabled, 0, 'x', "expired", "session")
+	if got := drain(s); len(got) != 0 {
+		t.Errorf("expected nothing without K or E but got %q", got)
+	}
+}
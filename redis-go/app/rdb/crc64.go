@@ -0,0 +1,34 @@
+package rdb
+
+// redis uses the reflected crc-64-jones variant (init 0, no final xor) for the
+// rdb trailer which isn't one of the presets in hash/crc64 - so roll our own
+
+const crc64JonesPoly uint64 = 0x95ac9329ac4bc9b5 // 0xad93d23594c935a9 reflected
+
+var crc64Table = makeCrc64Table()
+
+func makeCrc64Table() [256]uint64 {
+	var table [256]uint64
+
+	for i := 0; i < 256; i++ {
+		crc := uint64(i)
+		for j := 0; j < 8; j++ {
+			if crc&1 == 1 {
+				crc = (crc >> 1) ^ crc64JonesPoly
+			} else {
+				crc >>= 1
+			}
+		}
+		table[i] = crc
+	}
+
+	return table
+}
+
+func Crc64(crc uint64, data []byte) uint64 {
+	for _, b := range data {
+		crc = crc64Table[byte(crc)^b] ^ (crc >> 8)
+	}
+
+	return crc
+}
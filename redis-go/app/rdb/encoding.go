@@ -0,0 +1,277 @@
+package rdb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+/* Length encoding:
+
+	00xxxxxx                     // 6 bit length
+	01xxxxxx xxxxxxxx            // 14 bit length (big endian)
+	10000000 [4 bytes]           // 32 bit length (big endian)
+	10000001 [8 bytes]           // 64 bit length (big endian)
+	11xxxxxx                     // special string encoding follows (see below)
+
+String encoding (when the length prefix is 11xxxxxx):
+
+	C0 [1 byte]                  // 8 bit int
+	C1 [2 bytes]                 // 16 bit int (little endian)
+	C2 [4 bytes]                 // 32 bit int (little endian)
+	C3 <clen> <len> [clen bytes] // lzf compressed string
+*/
+
+const (
+	rdbLen6Bit  = 0
+	rdbLen14Bit = 1
+	rdbLen32Bit = 0x80
+	rdbLen64Bit = 0x81
+	rdbEncVal   = 3
+
+	rdbEncInt8  = 0
+	rdbEncInt16 = 1
+	rdbEncInt32 = 2
+	rdbEncLzf   = 3
+)
+
+var ErrInvalidLength = errors.New("invalid length encoding")
+
+// reads a length encoded value, encoded is true when the value is a special
+// string encoding (see above) rather than a length
+func readLength(reader *bufio.Reader) (uint64, bool, error) {
+	b, err := reader.ReadByte()
+	if err != nil {
+		return 0, false, err
+	}
+
+	switch b >> 6 {
+	case rdbLen6Bit:
+		return uint64(b & 0x3F), false, nil
+	case rdbLen14Bit:
+		next, err := reader.ReadByte()
+		if err != nil {
+			return 0, false, err
+		}
+		return uint64(b&0x3F)<<8 | uint64(next), false, nil
+	case rdbEncVal:
+		return uint64(b & 0x3F), true, nil
+	}
+
+	switch b {
+	case rdbLen32Bit:
+		buf := make([]byte, 4)
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			return 0, false, err
+		}
+		return uint64(binary.BigEndian.Uint32(buf)), false, nil
+	case rdbLen64Bit:
+		buf := make([]byte, 8)
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			return 0, false, err
+		}
+		return binary.BigEndian.Uint64(buf), false, nil
+	default:
+		return 0, false, fmt.Errorf("%w: %x", ErrInvalidLength, b)
+	}
+}
+
+func readPlainLength(reader *bufio.Reader) (uint64, error) {
+	length, encoded, err := readLength(reader)
+	if err != nil {
+		return 0, err
+	}
+
+	if encoded {
+		return 0, fmt.Errorf("%w: expected a length but got a string encoding", ErrInvalidLength)
+	}
+
+	return length, nil
+}
+
+func readStringValue(reader *bufio.Reader) (string, error) {
+	length, encoded, err := readLength(reader)
+	if err != nil {
+		return "", fmt.Errorf("expected to read attr-val length but got error: %w", err)
+	}
+
+	if encoded {
+		switch length {
+		case rdbEncInt8:
+			b, err := reader.ReadByte()
+			if err != nil {
+				return "", fmt.Errorf("error reading int8 value: %w", err)
+			}
+			return strconv.Itoa(int(int8(b))), nil
+		case rdbEncInt16:
+			buf := make([]byte, 2)
+			if _, err := io.ReadFull(reader, buf); err != nil {
+				return "", fmt.Errorf("error reading int16 value: %w", err)
+			}
+			return strconv.Itoa(int(int16(binary.LittleEndian.Uint16(buf)))), nil
+		case rdbEncInt32:
+			value, err := readUint32Value(reader)
+			if err != nil {
+				return "", err
+			}
+			return strconv.Itoa(int(int32(value))), nil
+		case rdbEncLzf:
+			return readLzfStringValue(reader)
+		default:
+			return "", fmt.Errorf("unexpected string encoding %d", length)
+		}
+	}
+
+	valbuf := make([]byte, length)
+	_, err = io.ReadFull(reader, valbuf)
+	if err != nil {
+		return "", fmt.Errorf("error reading attr-val %w", err)
+	}
+
+	return string(valbuf), nil
+}
+
+func readLzfStringValue(reader *bufio.Reader) (string, error) {
+	clen, err := readPlainLength(reader)
+	if err != nil {
+		return "", fmt.Errorf("error reading compressed length: %w", err)
+	}
+
+	length, err := readPlainLength(reader)
+	if err != nil {
+		return "", fmt.Errorf("error reading uncompressed length: %w", err)
+	}
+
+	compressed := make([]byte, clen)
+	if _, err := io.ReadFull(reader, compressed); err != nil {
+		return "", fmt.Errorf("error reading compressed string: %w", err)
+	}
+
+	out, err := lzfDecompress(compressed, int(length))
+	if err != nil {
+		return "", err
+	}
+
+	return string(out), nil
+}
+
+// see: https://github.com/redis/redis/blob/unstable/src/lzf_d.c
+func lzfDecompress(in []byte, length int) ([]byte, error) {
+	out := make([]byte, 0, length)
+
+	for i := 0; i < len(in); {
+		ctrl := int(in[i])
+		i++
+
+		if ctrl < 32 { // literal run of ctrl+1 bytes
+			ctrl++
+			if i+ctrl > len(in) {
+				return nil, errors.New("lzf literal run overflows input")
+			}
+			out = append(out, in[i:i+ctrl]...)
+			i += ctrl
+			continue
+		}
+
+		// back reference
+		n := ctrl >> 5
+		if n == 7 {
+			if i >= len(in) {
+				return nil, errors.New("lzf back reference overflows input")
+			}
+			n += int(in[i])
+			i++
+		}
+
+		if i >= len(in) {
+			return nil, errors.New("lzf back reference overflows input")
+		}
+
+		ref := len(out) - ((ctrl & 0x1F) << 8) - int(in[i]) - 1
+		i++
+
+		if ref < 0 {
+			return nil, errors.New("lzf back reference before start of output")
+		}
+
+		for j := 0; j < n+2; j++ { // byte by byte as the ref can overlap the output
+			out = append(out, out[ref+j])
+		}
+	}
+
+	if len(out) != length {
+		return nil, fmt.Errorf("expected lzf string of %d bytes but got %d", length, len(out))
+	}
+
+	return out, nil
+}
+
+func readUint64Value(reader *bufio.Reader) (uint64, error) {
+	buf := make([]byte, 8)
+
+	_, err := io.ReadFull(reader, buf)
+	if err != nil {
+		return 0, fmt.Errorf("error reading int value: %w", err)
+	}
+
+	return binary.LittleEndian.Uint64(buf), nil
+}
+
+func readUint32Value(reader *bufio.Reader) (uint32, error) {
+	buf := make([]byte, 4)
+
+	_, err := io.ReadFull(reader, buf)
+	if err != nil {
+		return 0, fmt.Errorf("error reading int value: %w", err)
+	}
+
+	return binary.LittleEndian.Uint32(buf), nil
+}
+
+func writeLength(buf *bytes.Buffer, length uint64) {
+	switch {
+	case length < 1<<6:
+		buf.WriteByte(byte(length))
+	case length < 1<<14:
+		buf.WriteByte(byte(length>>8) | rdbLen14Bit<<6)
+		buf.WriteByte(byte(length))
+	case length <= 0xFFFFFFFF:
+		buf.WriteByte(rdbLen32Bit)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(length)))
+	default:
+		buf.WriteByte(rdbLen64Bit)
+		buf.Write(binary.BigEndian.AppendUint64(nil, length))
+	}
+}
+
+// writes a string, using the int encodings where the string is a canonical
+// integer that fits in 32 bits (same as redis does)
+func writeStringValue(buf *bytes.Buffer, value string) {
+	if len(value) <= 11 {
+		if i, err := strconv.ParseInt(value, 10, 32); err == nil && strconv.FormatInt(i, 10) == value {
+			switch {
+			case i >= -(1<<7) && i < 1<<7:
+				buf.WriteByte(rdbEncVal<<6 | rdbEncInt8)
+				buf.WriteByte(byte(int8(i)))
+			case i >= -(1<<15) && i < 1<<15:
+				buf.WriteByte(rdbEncVal<<6 | rdbEncInt16)
+				buf.Write(binary.LittleEndian.AppendUint16(nil, uint16(int16(i))))
+			default:
+				buf.WriteByte(rdbEncVal<<6 | rdbEncInt32)
+				buf.Write(binary.LittleEndian.AppendUint32(nil, uint32(int32(i))))
+			}
+			return
+		}
+	}
+
+	writeLength(buf, uint64(len(value)))
+	buf.WriteString(value)
+}
+
+func writeUint64Value(buf *bytes.Buffer, value uint64) {
+	buf.Write(binary.LittleEndian.AppendUint64(nil, value))
+}
@@ -0,0 +1,78 @@
+package rdb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// function libraries are stored as their source code, each preceded by RdbFunction
+//
+//	format: 0xF5 <library code:string>
+
+// version written at the end of a FUNCTION DUMP payload
+const dumpRdbVersion = 11
+
+var ErrBadDumpPayload = errors.New("payload version or checksum are wrong")
+
+func writeFunctions(buf *bytes.Buffer, functions []string) {
+	for _, code := range functions {
+		buf.WriteByte(RdbFunction)
+		writeStringValue(buf, code)
+	}
+}
+
+// the libraries in the format FUNCTION DUMP returns, as in redis it's the rdb encoding
+// followed by the rdb version and a checksum
+//
+//	format: <functions> <rdb version:uint16 little endian> <crc64:uint64 little endian>
+func DumpFunctions(functions []string) []byte {
+	var buf bytes.Buffer
+
+	writeFunctions(&buf, functions)
+	binary.Write(&buf, binary.LittleEndian, uint16(dumpRdbVersion))
+	writeUint64Value(&buf, Crc64(0, buf.Bytes()))
+
+	return buf.Bytes()
+}
+
+// the libraries in a FUNCTION DUMP payload
+func RestoreFunctions(payload []byte) ([]string, error) {
+	if len(payload) < 10 {
+		return nil, ErrBadDumpPayload
+	}
+
+	body := payload[:len(payload)-10]
+	footer := payload[len(payload)-10:]
+
+	version := binary.LittleEndian.Uint16(footer[:2])
+	checksum := binary.LittleEndian.Uint64(footer[2:])
+	if version > dumpRdbVersion || checksum != Crc64(0, payload[:len(payload)-8]) {
+		return nil, ErrBadDumpPayload
+	}
+
+	reader := bufio.NewReader(bytes.NewReader(body))
+	functions := make([]string, 0)
+
+	for {
+		c, err := reader.ReadByte()
+		if err != nil {
+			break // end of the body
+		}
+
+		if c != RdbFunction {
+			return nil, fmt.Errorf("given type is not a function")
+		}
+
+		code, err := readStringValue(reader)
+		if err != nil {
+			return nil, fmt.Errorf("error reading function library: %w", err)
+		}
+
+		functions = append(functions, code)
+	}
+
+	return functions, nil
+}
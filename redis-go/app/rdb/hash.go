@@ -0,0 +1,111 @@
+package rdb
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+type Hash map[string]string
+
+const (
+	hashMaxListpackEntries = 128
+	hashMaxListpackValue   = 64
+)
+
+// small hashes are written as a listpack of field value pairs like redis does
+func hashEncoding(hash Hash) byte {
+	if len(hash) > hashMaxListpackEntries {
+		return RdbTypeHash
+	}
+
+	for field, value := range hash {
+		if len(field) > hashMaxListpackValue || len(value) > hashMaxListpackValue {
+			return RdbTypeHash
+		}
+	}
+
+	return RdbTypeHashListpack
+}
+
+func writeHash(buf *bytes.Buffer, hash Hash, t byte) {
+	fields := make([]string, 0, len(hash))
+	for field := range hash {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields) // deterministic output
+
+	if t == RdbTypeHashListpack {
+		lp := listpackWriter{}
+		for _, field := range fields {
+			lp.appendString(field)
+			lp.appendString(hash[field])
+		}
+
+		writeStringValue(buf, string(lp.Bytes()))
+		return
+	}
+
+	writeLength(buf, uint64(len(fields)))
+	for _, field := range fields {
+		writeStringValue(buf, field)
+		writeStringValue(buf, hash[field])
+	}
+}
+
+func readHash(reader *bufio.Reader, t byte) (Hash, error) {
+	switch t {
+	case RdbTypeHash:
+		size, err := readPlainLength(reader)
+		if err != nil {
+			return nil, fmt.Errorf("error reading hash length: %w", err)
+		}
+
+		hash := make(Hash, size)
+		for i := uint64(0); i < size; i++ {
+			field, err := readStringValue(reader)
+			if err != nil {
+				return nil, fmt.Errorf("error reading hash field: %w", err)
+			}
+
+			value, err := readStringValue(reader)
+			if err != nil {
+				return nil, fmt.Errorf("error reading hash value: %w", err)
+			}
+
+			hash[field] = value
+		}
+
+		return hash, nil
+	case RdbTypeHashZiplist, RdbTypeHashListpack:
+		blob, err := readStringValue(reader)
+		if err != nil {
+			return nil, fmt.Errorf("error reading hash encoding: %w", err)
+		}
+
+		var pairs []string
+		if t == RdbTypeHashZiplist {
+			pairs, err = readZiplist([]byte(blob))
+		} else {
+			pairs, err = readListpack([]byte(blob))
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("error reading hash entries: %w", err)
+		}
+
+		if len(pairs)%2 != 0 {
+			return nil, fmt.Errorf("expected hash to have field value pairs but got %d entries", len(pairs))
+		}
+
+		hash := make(Hash, len(pairs)/2)
+		for i := 0; i < len(pairs); i += 2 {
+			hash[pairs[i]] = pairs[i+1]
+		}
+
+		return hash, nil
+	default:
+		return nil, fmt.Errorf("unsupported hash encoding %d", t)
+	}
+}
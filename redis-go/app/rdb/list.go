@@ -0,0 +1,141 @@
+package rdb
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+)
+
+/* Lists are written as a quicklist of listpack nodes (RDB_TYPE_LIST_QUICKLIST_2):
+
+	<node-count:length> (<container:length> <node:string>)...
+
+where a packed container (2) holds a listpack of elements and a plain container (1)
+holds a single large element as is
+*/
+
+type List []string
+
+const (
+	quicklistNodePlain  = 1
+	quicklistNodePacked = 2
+
+	quicklistNodeMaxEntries = 128
+)
+
+func writeList(buf *bytes.Buffer, list List) {
+	nodes := (len(list) + quicklistNodeMaxEntries - 1) / quicklistNodeMaxEntries
+	writeLength(buf, uint64(nodes))
+
+	for i := 0; i < len(list); i += quicklistNodeMaxEntries {
+		end := min(i+quicklistNodeMaxEntries, len(list))
+
+		lp := listpackWriter{}
+		for _, element := range list[i:end] {
+			lp.appendString(element)
+		}
+
+		writeLength(buf, quicklistNodePacked)
+		writeStringValue(buf, string(lp.Bytes()))
+	}
+}
+
+func readList(reader *bufio.Reader, t byte) (List, error) {
+	switch t {
+	case RdbTypeList:
+		return readPlainList(reader)
+	case RdbTypeListZiplist:
+		blob, err := readStringValue(reader)
+		if err != nil {
+			return nil, fmt.Errorf("error reading list ziplist: %w", err)
+		}
+
+		return readZiplist([]byte(blob))
+	case RdbTypeListQuicklist:
+		return readQuicklist(reader)
+	case RdbTypeListQuicklist2:
+		return readQuicklist2(reader)
+	default:
+		return nil, fmt.Errorf("unsupported list encoding %d", t)
+	}
+}
+
+func readPlainList(reader *bufio.Reader) (List, error) {
+	size, err := readPlainLength(reader)
+	if err != nil {
+		return nil, fmt.Errorf("error reading list length: %w", err)
+	}
+
+	list := make(List, 0, size)
+	for i := uint64(0); i < size; i++ {
+		element, err := readStringValue(reader)
+		if err != nil {
+			return nil, fmt.Errorf("error reading list element: %w", err)
+		}
+
+		list = append(list, element)
+	}
+
+	return list, nil
+}
+
+// quicklist of ziplist nodes, written by redis 3.2 to 6.2
+func readQuicklist(reader *bufio.Reader) (List, error) {
+	nodes, err := readPlainLength(reader)
+	if err != nil {
+		return nil, fmt.Errorf("error reading quicklist node count: %w", err)
+	}
+
+	list := make(List, 0)
+	for n := uint64(0); n < nodes; n++ {
+		node, err := readStringValue(reader)
+		if err != nil {
+			return nil, fmt.Errorf("error reading quicklist node: %w", err)
+		}
+
+		elements, err := readZiplist([]byte(node))
+		if err != nil {
+			return nil, fmt.Errorf("error reading quicklist ziplist: %w", err)
+		}
+
+		list = append(list, elements...)
+	}
+
+	return list, nil
+}
+
+func readQuicklist2(reader *bufio.Reader) (List, error) {
+	nodes, err := readPlainLength(reader)
+	if err != nil {
+		return nil, fmt.Errorf("error reading quicklist node count: %w", err)
+	}
+
+	list := make(List, 0)
+	for n := uint64(0); n < nodes; n++ {
+		container, err := readPlainLength(reader)
+		if err != nil {
+			return nil, fmt.Errorf("error reading quicklist container: %w", err)
+		}
+
+		node, err := readStringValue(reader)
+		if err != nil {
+			return nil, fmt.Errorf("error reading quicklist node: %w", err)
+		}
+
+		switch container {
+		case quicklistNodePlain:
+			list = append(list, node)
+		case quicklistNodePacked:
+			elements, err := readListpack([]byte(node))
+			if err != nil {
+				return nil, fmt.Errorf("error reading quicklist listpack: %w", err)
+			}
+
+			list = append(list, elements...)
+		default:
+			return nil, fmt.Errorf("unexpected quicklist container %d", container)
+		}
+	}
+
+	return list, nil
+}
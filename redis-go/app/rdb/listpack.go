@@ -0,0 +1,263 @@
+package rdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+/* Listpack format:
+
+	<total-bytes:uint32> <num-elements:uint16> <element> ... <element> <end:0xFF>
+
+each element is <encoding+data> <backlen> where encoding is one of:
+
+	0xxxxxxx                     // 7 bit unsigned int
+	10xxxxxx [len bytes]         // string with 6 bit length
+	110xxxxx yyyyyyyy            // 13 bit signed int
+	1110xxxx yyyyyyyy [len bytes] // string with 12 bit length
+	11110000 [4 bytes] [len bytes] // string with 32 bit length
+	11110001 [2 bytes]           // 16 bit int
+	11110010 [3 bytes]           // 24 bit int
+	11110011 [4 bytes]           // 32 bit int
+	11110100 [8 bytes]           // 64 bit int
+
+see: https://github.com/antirez/listpack/blob/master/listpack.md
+*/
+
+const (
+	lpEncodingInt16 = 0xF1
+	lpEncodingInt24 = 0xF2
+	lpEncodingInt32 = 0xF3
+	lpEncodingInt64 = 0xF4
+	lpEncodingStr32 = 0xF0
+	lpEnd           = 0xFF
+)
+
+var ErrInvalidListpack = errors.New("invalid listpack")
+
+type listpackWriter struct {
+	buf   bytes.Buffer
+	count int
+}
+
+// appends the value as an int where it's a canonical integer, as redis does
+func (l *listpackWriter) appendString(value string) {
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil && strconv.FormatInt(i, 10) == value {
+		l.appendInt(i)
+		return
+	}
+
+	start := l.buf.Len()
+	size := len(value)
+
+	switch {
+	case size < 1<<6:
+		l.buf.WriteByte(0x80 | byte(size))
+	case size < 1<<12:
+		l.buf.WriteByte(0xE0 | byte(size>>8))
+		l.buf.WriteByte(byte(size))
+	default:
+		l.buf.WriteByte(lpEncodingStr32)
+		l.buf.Write(binary.LittleEndian.AppendUint32(nil, uint32(size)))
+	}
+
+	l.buf.WriteString(value)
+	l.writeBacklen(l.buf.Len() - start)
+}
+
+func (l *listpackWriter) appendInt(value int64) {
+	start := l.buf.Len()
+
+	switch {
+	case value >= 0 && value <= 127:
+		l.buf.WriteByte(byte(value))
+	case value >= -(1<<12) && value < 1<<12:
+		u := uint16(value) & 0x1FFF
+		l.buf.WriteByte(0xC0 | byte(u>>8))
+		l.buf.WriteByte(byte(u))
+	case value >= -(1<<15) && value < 1<<15:
+		l.buf.WriteByte(lpEncodingInt16)
+		l.buf.Write(binary.LittleEndian.AppendUint16(nil, uint16(value)))
+	case value >= -(1<<23) && value < 1<<23:
+		u := uint32(value)
+		l.buf.WriteByte(lpEncodingInt24)
+		l.buf.Write([]byte{byte(u), byte(u >> 8), byte(u >> 16)})
+	case value >= -(1<<31) && value < 1<<31:
+		l.buf.WriteByte(lpEncodingInt32)
+		l.buf.Write(binary.LittleEndian.AppendUint32(nil, uint32(value)))
+	default:
+		l.buf.WriteByte(lpEncodingInt64)
+		l.buf.Write(binary.LittleEndian.AppendUint64(nil, uint64(value)))
+	}
+
+	l.writeBacklen(l.buf.Len() - start)
+}
+
+func (l *listpackWriter) writeBacklen(size int) {
+	l.count++
+
+	switch {
+	case size <= 127:
+		l.buf.WriteByte(byte(size))
+	case size < 16383:
+		l.buf.WriteByte(byte(size >> 7))
+		l.buf.WriteByte(byte(size&127) | 128)
+	case size < 2097151:
+		l.buf.WriteByte(byte(size >> 14))
+		l.buf.WriteByte(byte((size>>7)&127) | 128)
+		l.buf.WriteByte(byte(size&127) | 128)
+	case size < 268435455:
+		l.buf.WriteByte(byte(size >> 21))
+		l.buf.WriteByte(byte((size>>14)&127) | 128)
+		l.buf.WriteByte(byte((size>>7)&127) | 128)
+		l.buf.WriteByte(byte(size&127) | 128)
+	default:
+		l.buf.WriteByte(byte(size >> 28))
+		l.buf.WriteByte(byte((size>>21)&127) | 128)
+		l.buf.WriteByte(byte((size>>14)&127) | 128)
+		l.buf.WriteByte(byte((size>>7)&127) | 128)
+		l.buf.WriteByte(byte(size&127) | 128)
+	}
+}
+
+func (l *listpackWriter) Bytes() []byte {
+	const headerSize = 6
+
+	total := headerSize + l.buf.Len() + 1
+	count := l.count
+	if count > 0xFFFF {
+		count = 0xFFFF // count unknown, readers have to scan
+	}
+
+	out := make([]byte, 0, total)
+	out = binary.LittleEndian.AppendUint32(out, uint32(total))
+	out = binary.LittleEndian.AppendUint16(out, uint16(count))
+	out = append(out, l.buf.Bytes()...)
+	out = append(out, lpEnd)
+
+	return out
+}
+
+func backlenSize(size int) int {
+	switch {
+	case size <= 127:
+		return 1
+	case size < 16383:
+		return 2
+	case size < 2097151:
+		return 3
+	case size < 268435455:
+		return 4
+	default:
+		return 5
+	}
+}
+
+// reads all the elements in the listpack, ints are returned in their string form
+func readListpack(data []byte) ([]string, error) {
+	const headerSize = 6
+	if len(data) < headerSize+1 {
+		return nil, fmt.Errorf("%w: too short", ErrInvalidListpack)
+	}
+
+	elements := make([]string, 0, binary.LittleEndian.Uint16(data[4:6]))
+
+	i := headerSize
+	for {
+		if i >= len(data) {
+			return nil, fmt.Errorf("%w: missing end byte", ErrInvalidListpack)
+		}
+
+		b := data[i]
+		if b == lpEnd {
+			break
+		}
+
+		var value string
+		var size int // size of encoding+data
+
+		need := func(n int) error {
+			if i+n > len(data) {
+				return fmt.Errorf("%w: element overflows listpack", ErrInvalidListpack)
+			}
+			return nil
+		}
+
+		switch {
+		case b&0x80 == 0:
+			value, size = strconv.Itoa(int(b)), 1
+		case b&0xC0 == 0x80:
+			strlen := int(b & 0x3F)
+			size = 1 + strlen
+			if err := need(size); err != nil {
+				return nil, err
+			}
+			value = string(data[i+1 : i+size])
+		case b&0xE0 == 0xC0:
+			if err := need(2); err != nil {
+				return nil, err
+			}
+			u := uint16(b&0x1F)<<8 | uint16(data[i+1])
+			v := int64(u)
+			if u >= 1<<12 {
+				v -= 1 << 13
+			}
+			value, size = strconv.FormatInt(v, 10), 2
+		case b&0xF0 == 0xE0:
+			if err := need(2); err != nil {
+				return nil, err
+			}
+			strlen := int(b&0x0F)<<8 | int(data[i+1])
+			size = 2 + strlen
+			if err := need(size); err != nil {
+				return nil, err
+			}
+			value = string(data[i+2 : i+size])
+		case b == lpEncodingStr32:
+			if err := need(5); err != nil {
+				return nil, err
+			}
+			strlen := int(binary.LittleEndian.Uint32(data[i+1 : i+5]))
+			size = 5 + strlen
+			if err := need(size); err != nil {
+				return nil, err
+			}
+			value = string(data[i+5 : i+size])
+		case b == lpEncodingInt16:
+			size = 3
+			if err := need(size); err != nil {
+				return nil, err
+			}
+			value = strconv.Itoa(int(int16(binary.LittleEndian.Uint16(data[i+1 : i+3]))))
+		case b == lpEncodingInt24:
+			size = 4
+			if err := need(size); err != nil {
+				return nil, err
+			}
+			u := int32(data[i+1]) | int32(data[i+2])<<8 | int32(data[i+3])<<16
+			value = strconv.Itoa(int(u << 8 >> 8)) // sign extend
+		case b == lpEncodingInt32:
+			size = 5
+			if err := need(size); err != nil {
+				return nil, err
+			}
+			value = strconv.Itoa(int(int32(binary.LittleEndian.Uint32(data[i+1 : i+5]))))
+		case b == lpEncodingInt64:
+			size = 9
+			if err := need(size); err != nil {
+				return nil, err
+			}
+			value = strconv.FormatInt(int64(binary.LittleEndian.Uint64(data[i+1:i+9])), 10)
+		default:
+			return nil, fmt.Errorf("%w: unexpected encoding %x", ErrInvalidListpack, b)
+		}
+
+		elements = append(elements, value)
+		i += size + backlenSize(size)
+	}
+
+	return elements, nil
+}
@@ -2,8 +2,8 @@ package rdb
 
 import (
 	"bufio"
-	"encoding/base64"
-	"encoding/binary"
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -12,15 +12,9 @@ import (
 	"strings"
 )
 
-// TODO - this was from the replication stage which needed a hardcoded rdb consolidate this with the ReadRdb function below later
-func ReadRdb() string {
-	// hardcoded response for this stage
-	//		see: https://github.com/codecrafters-io/redis-tester/blob/main/internal/assets/empty_rdb_hex.md
-	return "UkVESVMwMDEx+glyZWRpcy12ZXIFNy4yLjD6CnJlZGlzLWJpdHPAQPoFY3RpbWXCbQi8ZfoIdXNlZC1tZW3CsMQQAPoIYW9mLWJhc2XAAP/wbjv+wP9aog=="
-}
-
 type RdbContents struct {
 	Metadata  RedisMetadata
+	Functions []string // code of each function library
 	Databases []RedisDatabase
 }
 
@@ -32,8 +26,9 @@ type RedisMetadata struct {
 }
 
 type RedisDatabase struct {
-	Keys     map[string]string
-	Expiries map[string]uint64
+	Index    int                    // as selected by SELECT
+	Keys     map[string]interface{} // string or Stream
+	Expiries map[string]uint64      // unix ms
 }
 
 const (
@@ -43,312 +38,284 @@ const (
 	RdbKeyExpiryS             = 0xFD
 	RdbDatabaseSeperator      = 0xFE
 	RdbEofSeperator           = 0xFF
+	RdbKeyIdle                = 0xF8
+	RdbKeyFreq                = 0xF9
+	RdbFunction               = 0xF5
 )
 
+// value types
+const (
+	RdbTypeString           = 0
+	RdbTypeList             = 1
+	RdbTypeSet              = 2
+	RdbTypeZSet             = 3
+	RdbTypeHash             = 4
+	RdbTypeZSet2            = 5
+	RdbTypeListZiplist      = 10
+	RdbTypeSetIntset        = 11
+	RdbTypeZSetZiplist      = 12
+	RdbTypeHashZiplist      = 13
+	RdbTypeListQuicklist    = 14
+	RdbTypeHashListpack     = 16
+	RdbTypeZSetListpack     = 17
+	RdbTypeListQuicklist2   = 18
+	RdbTypeSetListpack      = 20
+	RdbTypeStreamListpacks  = 15
+	RdbTypeStreamListpacks2 = 19
+	RdbTypeStreamListpacks3 = 21
+)
+
+const RdbHeader = "REDIS0011"
+
+var ErrChecksumMismatch = errors.New("rdb checksum mismatch")
+
 func ReadRdbFromFile(path string, filename string) (*RdbContents, error) {
-	file, err := os.Open(filepath.Join(path, filename))
+	data, err := os.ReadFile(filepath.Join(path, filename))
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
 
-	reader := bufio.NewReader(file)
+	return ParseRdb(data)
+}
+
+func ParseRdb(data []byte) (*RdbContents, error) {
+	contents, _, err := ParseRdbPrefix(data)
+	return contents, err
+}
+
+// parses the rdb at the start of data (e.g. an aof preamble), also returning the
+// number of bytes the rdb took up
+func ParseRdbPrefix(data []byte) (*RdbContents, int, error) {
+	if len(data) < len(RdbHeader) || string(data[:5]) != "REDIS" {
+		return nil, 0, errors.New("expected rdb to start with REDIS header")
+	}
+
+	source := bytes.NewReader(data)
+	reader := bufio.NewReader(source)
+	consumed := func() int {
+		return len(data) - source.Len() - reader.Buffered()
+	}
 
 	// discard header (R E D I S 0 0 1 1)
-	reader.Discard(9)
+	reader.Discard(len(RdbHeader))
 
 	result := RdbContents{
 		Metadata:  RedisMetadata{},
 		Databases: make([]RedisDatabase, 0),
 	}
 
+	var database *RedisDatabase
+	var expiry uint64 = 0
+	hasExpiry := false
+
 outerLoop:
 	for {
 		// read the seperator byte
 		c, err := reader.ReadByte()
-		fmt.Printf("[rdb] seperator: %x\n", c)
 		if err != nil {
 			if err == io.EOF {
 				break
 			}
 
-			return nil, err
+			return nil, 0, err
 		}
 
 		switch int(c) {
 		case RdbMetadataSeperator:
 			{
-				fmt.Print("[rdb]reading metadata section\n")
 				key, err := readStringValue(reader)
 				if err != nil {
-					return nil, err
+					return nil, 0, err
+				}
+
+				val, err := readStringValue(reader)
+				if err != nil {
+					return nil, 0, fmt.Errorf("error reading %s value: %w", key, err)
 				}
 
 				switch key {
 				case "redis-ver":
-					{
-						fmt.Print("\t[rdb]reading redis-ver\n")
-						val, err := readStringValue(reader)
-						if err != nil {
-							return nil, fmt.Errorf("error reading redis-ver value: %w", err)
-						}
-						result.Metadata.RedisVersion = val
-					}
+					result.Metadata.RedisVersion = val
 				case "ctime":
-					{
-						fmt.Print("\t[rdb] reading ctime\n")
-						reader.Discard(1) // TODO what is this seperator byte?? 194
-						value, err := readUint32Value(reader)
-						if err != nil {
-							return nil, fmt.Errorf("error reading ctime value: %w", err)
-						}
-
-						result.Metadata.Ctime = uint64(value)
-					}
-				case "redis-bits":
-					{
-						fmt.Print("\t[rdb] reading redis-bits\n")
-						reader.Discard(1) // TODO what is this seperator byte?? 192
-						value, err := reader.ReadByte()
-						if err != nil {
-							return nil, fmt.Errorf("error reading redis-bits value: %w", err)
-						}
-
-						if value != 64 {
-							return nil, fmt.Errorf("unexpected redis-bits got %d", value)
-						}
-
-						result.Metadata.RedisBits = value
-					}
+					result.Metadata.Ctime, _ = strconv.ParseUint(val, 10, 64)
 				case "used-mem":
-					{
-						fmt.Print("\t[rdb] reading used-mem\n")
-						reader.Discard(1) // TODO what is this seperator byte?? 194
-						value, err := readUint32Value(reader)
-						if err != nil {
-							return nil, fmt.Errorf("error reading used-mem value: %w", err)
-						}
-
-						result.Metadata.UsedMem = uint64(value)
-					}
-				case "aof-base":
-					{
-						fmt.Print("\t[rdb] reading aof-base\n")
-						reader.Discard(2) // TODO unsure what this is - values 192, 0
+					result.Metadata.UsedMem, _ = strconv.ParseUint(val, 10, 64)
+				case "redis-bits":
+					if val != "64" {
+						return nil, 0, fmt.Errorf("unexpected redis-bits got %s", val)
 					}
-				default:
-					return nil, fmt.Errorf("unexpected metadata key: %s", key)
+					result.Metadata.RedisBits = 64
 				}
+				// other aux fields (aof-base, repl-id etc) are informational only
 			}
-		case RdbDatabaseSeperator:
+		case RdbFunction:
 			{
-				fmt.Print("[rdb]reading database section\n")
-				_, err := reader.ReadByte() // index of db
+				code, err := readStringValue(reader)
 				if err != nil {
-					return nil, fmt.Errorf("error reading database index %w", err)
+					return nil, 0, fmt.Errorf("error reading function library %w", err)
 				}
 
-				b, err := reader.ReadByte() // hash table info
+				result.Functions = append(result.Functions, code)
+			}
+		case RdbDatabaseSeperator:
+			{
+				index, err := readPlainLength(reader)
 				if err != nil {
-					return nil, fmt.Errorf("error reading hash table size %w", err)
+					return nil, 0, fmt.Errorf("error reading database index %w", err)
 				}
 
-				if b != RdbHashTableInfoSeperator { // indicates hash table size information follows
-					return nil, fmt.Errorf("expected hash table size info to follow but got %d", b)
+				result.Databases = append(result.Databases, RedisDatabase{
+					Index:    int(index),
+					Keys:     make(map[string]interface{}),
+					Expiries: make(map[string]uint64),
+				})
+				database = &result.Databases[len(result.Databases)-1]
+			}
+		case RdbHashTableInfoSeperator:
+			{
+				// key table size & expiry table size - only hints for preallocating
+				if _, err := readPlainLength(reader); err != nil {
+					return nil, 0, fmt.Errorf("error reading hash table size %w", err)
 				}
 
-				ksize, err := reader.ReadByte() // key table size
+				if _, err := readPlainLength(reader); err != nil {
+					return nil, 0, fmt.Errorf("error reading hash table size %w", err)
+				}
+			}
+		case RdbKeyExpiryMs:
+			{
+				expiry, err = readUint64Value(reader)
 				if err != nil {
-					return nil, fmt.Errorf("error reading hash table size %w", err)
+					return nil, 0, fmt.Errorf("error reading expiry as uint64 %w", err)
 				}
-
-				expsize, err := reader.ReadByte() // expiry table size
+				hasExpiry = true
+			}
+		case RdbKeyExpiryS:
+			{
+				e, err := readUint32Value(reader)
 				if err != nil {
-					return nil, fmt.Errorf("error reading hash table size %w", err)
+					return nil, 0, fmt.Errorf("error reading expiry as uint32 %w", err)
 				}
-
-				database := &RedisDatabase{
-					Keys:     make(map[string]string, ksize),
-					Expiries: make(map[string]uint64, expsize),
+				expiry = uint64(e) * 1000
+				hasExpiry = true
+			}
+		case RdbKeyIdle:
+			{
+				if _, err := readPlainLength(reader); err != nil {
+					return nil, 0, fmt.Errorf("error reading key idle time %w", err)
 				}
-
-				result.Databases = append(result.Databases, *database)
-				fmt.Print("\t[rdb]reading database\n")
-				for i := 0; i < int(ksize); i++ {
-					c, err := reader.Peek(1)
-					if err != nil {
-						return nil, fmt.Errorf("error peaking key expiry: %w", err)
-					}
-
-					hasExpiry := c[0] == RdbKeyExpiryMs || c[0] == RdbKeyExpiryS
-					var expiry uint64 = 0
-					if hasExpiry {
-						reader.Discard(1) // discard the expiry indicator
-
-						if c[0] == RdbKeyExpiryMs {
-							fmt.Print("\t[rdb] has expiry in ms\n")
-							expiry, err = readUint64Value(reader)
-							if err != nil {
-								return nil, fmt.Errorf("error reading expiry as uint64 %w", err)
-							}
-						} else {
-							fmt.Print("\t[rdb] has expiry in s\n")
-							e, err := readUint32Value(reader)
-							if err != nil {
-								return nil, fmt.Errorf("error reading expiry as uint32 %w", err)
-							}
-
-							expiry = uint64(e)
-						}
-					}
-
-					t, err := reader.ReadByte() // type
-					if err != nil {
-						return nil, fmt.Errorf("error reading the key type")
-					}
-					fmt.Printf("\t[rdb] has type of %d\n", t)
-
-					key, err := readStringValue(reader)
-					if err != nil {
-						return nil, fmt.Errorf("error reading key: %w", err)
-					}
-
-					if hasExpiry {
-						database.Expiries[key] = expiry
-					}
-
-					fmt.Printf("\t[rdb] read key: %s\n", key)
-
-					switch t {
-					case 0: // string
-						{
-							b, _ := reader.Peek(2)
-							fmt.Print(b)
-							val, err := readStringValue(reader)
-							if err != nil {
-								return nil, fmt.Errorf("error reading val: %w", err)
-							}
-
-							fmt.Printf("\t[rdb] read value: %s\n", val)
-
-							database.Keys[key] = val
-						}
-					default:
-						{
-							return nil, fmt.Errorf("unexpected key type: %d", t)
-						}
-					}
+			}
+		case RdbKeyFreq:
+			{
+				if _, err := reader.ReadByte(); err != nil {
+					return nil, 0, fmt.Errorf("error reading key frequency %w", err)
 				}
 			}
 		case RdbEofSeperator:
 			{
-				fmt.Println("at end of file")
-				chckbuf := make([]byte, 8)
-				_, err := reader.Read(chckbuf)
+				// checksum of everything before it - zero means checksums were disabled when saving
+				end := consumed()
+				expected, err := readUint64Value(reader)
 				if err != nil {
-					return nil, fmt.Errorf("error reading 8 byte checksum value: %w", err)
+					return nil, 0, fmt.Errorf("error reading 8 byte checksum value: %w", err)
+				}
+
+				if actual := Crc64(0, data[:end]); expected != 0 && expected != actual {
+					return nil, 0, fmt.Errorf("%w: expected %x but got %x", ErrChecksumMismatch, expected, actual)
 				}
 
 				break outerLoop
 			}
 		default:
 			{
-				return nil, fmt.Errorf("unexpected seperator byte %v", c)
-			}
-		}
-	}
-
-	return &result, nil
-}
-
-func readStringValue(reader *bufio.Reader) (string, error) {
-	len, err := reader.ReadByte()
-	if err != nil {
-		return "", fmt.Errorf("expected to read attr-val length but got error: %w", err)
-	}
-
-	valbuf := make([]byte, len)
-	n, err := reader.Read(valbuf)
-	if err != nil {
-		return "", fmt.Errorf("error reading attr-val %w", err)
-	}
-
-	if n != int(len) {
-		return "", fmt.Errorf("expected to read %d bytes when reading attr-val but got %d", len, n)
-	}
-
-	return string(valbuf), nil
-}
+				// anything else is the value type of a key
+				if database == nil {
+					return nil, 0, fmt.Errorf("unexpected seperator byte %v", c)
+				}
 
-func readUint64Value(reader *bufio.Reader) (uint64, error) {
-	buf := make([]byte, 8)
+				key, err := readStringValue(reader)
+				if err != nil {
+					return nil, 0, fmt.Errorf("error reading key: %w", err)
+				}
 
-	n, err := reader.Read(buf)
+				val, err := readValue(reader, c)
+				if err != nil {
+					return nil, 0, fmt.Errorf("error reading value for key %s: %w", key, err)
+				}
 
-	if err != nil {
-		return 0, fmt.Errorf("error reading int value: %w", err)
-	}
+				database.Keys[key] = val
+				if hasExpiry {
+					database.Expiries[key] = expiry
+				}
 
-	if n != 8 {
-		return 0, fmt.Errorf("expected to read int of %d bytes but got %d", 8, n)
+				expiry, hasExpiry = 0, false
+			}
+		}
 	}
 
-	return binary.LittleEndian.Uint64(buf), nil
+	return &result, consumed(), nil
 }
 
-func readUint32Value(reader *bufio.Reader) (uint32, error) {
-	buf := make([]byte, 4)
-
-	n, err := reader.Read(buf)
-
-	if err != nil {
-		return 0, fmt.Errorf("error reading int value: %w", err)
+func readValue(reader *bufio.Reader, t byte) (interface{}, error) {
+	switch t {
+	case RdbTypeString:
+		return readStringValue(reader)
+	case RdbTypeList, RdbTypeListZiplist, RdbTypeListQuicklist, RdbTypeListQuicklist2:
+		return readList(reader, t)
+	case RdbTypeSet, RdbTypeSetIntset, RdbTypeSetListpack:
+		return readSet(reader, t)
+	case RdbTypeZSet, RdbTypeZSet2, RdbTypeZSetZiplist, RdbTypeZSetListpack:
+		return readSortedSet(reader, t)
+	case RdbTypeHash, RdbTypeHashZiplist, RdbTypeHashListpack:
+		return readHash(reader, t)
+	case RdbTypeStreamListpacks, RdbTypeStreamListpacks2, RdbTypeStreamListpacks3:
+		return readStream(reader, t)
+	default:
+		return nil, fmt.Errorf("unexpected key type: %d", t)
 	}
-
-	if n != 4 {
-		return 0, fmt.Errorf("expected to read int of %d bytes but got %d", 4, n)
-	}
-
-	return binary.LittleEndian.Uint32(buf), nil
 }
 
-func SerializeB64RdbToString(contents string) (string, error) {
-	data, err := base64.StdEncoding.DecodeString(contents)
-	if err != nil {
-		return "", err
-	}
-
+// rdb payload sent to followers on a full resync, same as a bulk string but without the trailing crlf
+//
+//	format: $<length>\r\n<contents>
+func SerializeRdbPayload(data []byte) string {
 	var builder strings.Builder
 	builder.WriteString("$")
 	builder.WriteString(strconv.Itoa(len(data)))
 	builder.WriteString("\r\n")
 	builder.Write(data)
 
-	return builder.String(), nil
+	return builder.String()
 }
 
-func DeserializeRdb(r io.Reader) (string, error) {
+func DeserializeRdb(r io.Reader) ([]byte, error) {
 	reader := bufio.NewReader(r)
-	reader.Read(make([]byte, 1)) // read $ prefix
 
-	line := readLine(reader)
+	prefix, err := reader.ReadByte() // read $ prefix
+	if err != nil {
+		return nil, err
+	}
+
+	if prefix != '$' {
+		return nil, fmt.Errorf("expected rdb payload to start with $ but got %q", prefix)
+	}
+
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
 
-	count, err := strconv.Atoi(line)
+	count, err := strconv.Atoi(strings.TrimSuffix(line, "\r\n"))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	data := make([]byte, count)
 
-	_, err = reader.Read(data)
+	_, err = io.ReadFull(reader, data)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return string(data), nil
-}
-
-func readLine(r *bufio.Reader) string {
-	line, _ := r.ReadString('\n')
-	return line[:len(line)-2] // remove \r\n
+	return data, nil
 }
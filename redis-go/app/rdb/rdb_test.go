@@ -1,6 +1,9 @@
 package rdb
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 /* File format:
 
@@ -131,3 +134,38 @@ func TestReadRdbWithValuesAndExpiryInMillisFromFile(t *testing.T) {
 		t.Errorf("expected key to have expiry %d but got %d", expectedExpiry, expiry)
 	}
 }
+
+func TestReadZiplist(t *testing.T) {
+	// arrange
+	data := []byte{
+		0, 0, 0, 0, // zlbytes, unchecked
+		0, 0, 0, 0, // zltail, unchecked
+		5, 0, // zllen
+		0x00, 0x03, 'f', 'o', 'o', // 6 bit string
+		0x05, 0xF8, // immediate int 7
+		0x02, 0xFE, 0x9C, // int8 -100
+		0x03, 0xC0, 0xE8, 0x03, // int16 1000
+		0x04, 0x40, 0x41, // 14 bit string of 65 bytes, data appended below
+	}
+	data = append(data, strings.Repeat("a", 65)...)
+	data = append(data, 0xFF)
+
+	// act
+	entries, err := readZiplist(data)
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"foo", "7", "-100", "1000", strings.Repeat("a", 65)}
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %d entries but got %d", len(expected), len(entries))
+	}
+
+	for i := range expected {
+		if entries[i] != expected[i] {
+			t.Errorf("expected entry %d to be %q but got %q", i, expected[i], entries[i])
+		}
+	}
+}
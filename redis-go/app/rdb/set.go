@@ -0,0 +1,190 @@
+package rdb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+/* Intset format, used for small sets of integers:
+
+	<encoding:uint32> <length:uint32> <contents>
+
+where encoding is the size in bytes (2, 4 or 8) of every integer in contents, which
+are little endian and sorted ascending
+*/
+
+type Set map[string]struct{}
+
+const (
+	setMaxIntsetEntries   = 512
+	setMaxListpackEntries = 128
+	setMaxListpackValue   = 64
+)
+
+var ErrInvalidIntset = errors.New("invalid intset")
+
+// picks the most compact encoding redis would use for the set
+func setEncoding(set Set) byte {
+	ints := len(set) <= setMaxIntsetEntries
+	packed := len(set) <= setMaxListpackEntries
+
+	for member := range set {
+		if i, err := strconv.ParseInt(member, 10, 64); err != nil || strconv.FormatInt(i, 10) != member {
+			ints = false
+		}
+		if len(member) > setMaxListpackValue {
+			packed = false
+		}
+	}
+
+	switch {
+	case ints:
+		return RdbTypeSetIntset
+	case packed:
+		return RdbTypeSetListpack
+	default:
+		return RdbTypeSet
+	}
+}
+
+func writeSet(buf *bytes.Buffer, set Set, t byte) {
+	members := make([]string, 0, len(set))
+	for member := range set {
+		members = append(members, member)
+	}
+	sort.Strings(members) // deterministic output
+
+	switch t {
+	case RdbTypeSetIntset:
+		writeStringValue(buf, string(intsetBytes(members)))
+	case RdbTypeSetListpack:
+		lp := listpackWriter{}
+		for _, member := range members {
+			lp.appendString(member)
+		}
+		writeStringValue(buf, string(lp.Bytes()))
+	default:
+		writeLength(buf, uint64(len(members)))
+		for _, member := range members {
+			writeStringValue(buf, member)
+		}
+	}
+}
+
+// members must all be canonical integers
+func intsetBytes(members []string) []byte {
+	ints := make([]int64, 0, len(members))
+	encoding := 2
+	for _, member := range members {
+		i, _ := strconv.ParseInt(member, 10, 64)
+		ints = append(ints, i)
+
+		switch {
+		case i < -(1<<31) || i >= 1<<31:
+			encoding = 8
+		case (i < -(1<<15) || i >= 1<<15) && encoding < 4:
+			encoding = 4
+		}
+	}
+	sort.Slice(ints, func(a, b int) bool { return ints[a] < ints[b] })
+
+	out := make([]byte, 0, 8+len(ints)*encoding)
+	out = binary.LittleEndian.AppendUint32(out, uint32(encoding))
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(ints)))
+	for _, i := range ints {
+		switch encoding {
+		case 2:
+			out = binary.LittleEndian.AppendUint16(out, uint16(i))
+		case 4:
+			out = binary.LittleEndian.AppendUint32(out, uint32(i))
+		default:
+			out = binary.LittleEndian.AppendUint64(out, uint64(i))
+		}
+	}
+
+	return out
+}
+
+func readIntset(data []byte) ([]string, error) {
+	if len(data) < 8 {
+		return nil, fmt.Errorf("%w: too short", ErrInvalidIntset)
+	}
+
+	encoding := int(binary.LittleEndian.Uint32(data[0:4]))
+	length := int(binary.LittleEndian.Uint32(data[4:8]))
+
+	if encoding != 2 && encoding != 4 && encoding != 8 {
+		return nil, fmt.Errorf("%w: unexpected encoding %d", ErrInvalidIntset, encoding)
+	}
+
+	if len(data) < 8+length*encoding {
+		return nil, fmt.Errorf("%w: contents overflow intset", ErrInvalidIntset)
+	}
+
+	members := make([]string, 0, length)
+	for i := 0; i < length; i++ {
+		v := data[8+i*encoding : 8+(i+1)*encoding]
+
+		switch encoding {
+		case 2:
+			members = append(members, strconv.Itoa(int(int16(binary.LittleEndian.Uint16(v)))))
+		case 4:
+			members = append(members, strconv.Itoa(int(int32(binary.LittleEndian.Uint32(v)))))
+		default:
+			members = append(members, strconv.FormatInt(int64(binary.LittleEndian.Uint64(v)), 10))
+		}
+	}
+
+	return members, nil
+}
+
+func readSet(reader *bufio.Reader, t byte) (Set, error) {
+	var members []string
+
+	switch t {
+	case RdbTypeSet:
+		size, err := readPlainLength(reader)
+		if err != nil {
+			return nil, fmt.Errorf("error reading set length: %w", err)
+		}
+
+		members = make([]string, 0, size)
+		for i := uint64(0); i < size; i++ {
+			member, err := readStringValue(reader)
+			if err != nil {
+				return nil, fmt.Errorf("error reading set member: %w", err)
+			}
+
+			members = append(members, member)
+		}
+	case RdbTypeSetIntset, RdbTypeSetListpack:
+		blob, err := readStringValue(reader)
+		if err != nil {
+			return nil, fmt.Errorf("error reading set encoding: %w", err)
+		}
+
+		if t == RdbTypeSetIntset {
+			members, err = readIntset([]byte(blob))
+		} else {
+			members, err = readListpack([]byte(blob))
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("error reading set members: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported set encoding %d", t)
+	}
+
+	set := make(Set, len(members))
+	for _, member := range members {
+		set[member] = struct{}{}
+	}
+
+	return set, nil
+}
@@ -0,0 +1,130 @@
+package rdb
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+)
+
+type SortedSet map[string]float64
+
+// written as RDB_TYPE_ZSET_2, members followed by their score as a binary double
+func writeSortedSet(buf *bytes.Buffer, zset SortedSet) {
+	members := make([]string, 0, len(zset))
+	for member := range zset {
+		members = append(members, member)
+	}
+
+	// deterministic output
+	sort.Slice(members, func(a, b int) bool {
+		if zset[members[a]] != zset[members[b]] {
+			return zset[members[a]] < zset[members[b]]
+		}
+		return members[a] < members[b]
+	})
+
+	writeLength(buf, uint64(len(members)))
+	for _, member := range members {
+		writeStringValue(buf, member)
+		writeUint64Value(buf, math.Float64bits(zset[member]))
+	}
+}
+
+func readSortedSet(reader *bufio.Reader, t byte) (SortedSet, error) {
+	switch t {
+	case RdbTypeZSet, RdbTypeZSet2:
+		size, err := readPlainLength(reader)
+		if err != nil {
+			return nil, fmt.Errorf("error reading sorted set length: %w", err)
+		}
+
+		zset := make(SortedSet, size)
+		for i := uint64(0); i < size; i++ {
+			member, err := readStringValue(reader)
+			if err != nil {
+				return nil, fmt.Errorf("error reading sorted set member: %w", err)
+			}
+
+			var score float64
+			if t == RdbTypeZSet2 {
+				bits, err := readUint64Value(reader)
+				if err != nil {
+					return nil, fmt.Errorf("error reading sorted set score: %w", err)
+				}
+				score = math.Float64frombits(bits)
+			} else {
+				score, err = readStringDouble(reader)
+				if err != nil {
+					return nil, fmt.Errorf("error reading sorted set score: %w", err)
+				}
+			}
+
+			zset[member] = score
+		}
+
+		return zset, nil
+	case RdbTypeZSetZiplist, RdbTypeZSetListpack:
+		blob, err := readStringValue(reader)
+		if err != nil {
+			return nil, fmt.Errorf("error reading sorted set encoding: %w", err)
+		}
+
+		var pairs []string
+		if t == RdbTypeZSetZiplist {
+			pairs, err = readZiplist([]byte(blob))
+		} else {
+			pairs, err = readListpack([]byte(blob))
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("error reading sorted set entries: %w", err)
+		}
+
+		if len(pairs)%2 != 0 {
+			return nil, fmt.Errorf("expected sorted set to have member score pairs but got %d entries", len(pairs))
+		}
+
+		zset := make(SortedSet, len(pairs)/2)
+		for i := 0; i < len(pairs); i += 2 {
+			score, err := strconv.ParseFloat(pairs[i+1], 64)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing sorted set score %q: %w", pairs[i+1], err)
+			}
+
+			zset[pairs[i]] = score
+		}
+
+		return zset, nil
+	default:
+		return nil, fmt.Errorf("unsupported sorted set encoding %d", t)
+	}
+}
+
+// doubles in the original zset encoding are a length prefixed string, with
+// the lengths 253, 254 and 255 reserved for nan, +inf and -inf
+func readStringDouble(reader *bufio.Reader) (float64, error) {
+	length, err := reader.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+
+	switch length {
+	case 253:
+		return math.NaN(), nil
+	case 254:
+		return math.Inf(1), nil
+	case 255:
+		return math.Inf(-1), nil
+	}
+
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(reader, buf); err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseFloat(string(buf), 64)
+}
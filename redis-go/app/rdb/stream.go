@@ -0,0 +1,486 @@
+package rdb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+type StreamId struct {
+	Ms  uint64
+	Seq uint64
+}
+
+type StreamEntry struct {
+	Id     StreamId
+	Fields []string // field/value pairs, i.e. [f1, v1, f2, v2, ...]
+}
+
+type Stream struct {
+	Entries      []StreamEntry
+	LastId       StreamId
+	MaxDeletedId StreamId // the largest id deleted with XDEL
+	EntriesAdded uint64   // every entry ever added, including those since removed
+	Groups       []StreamGroup
+}
+
+type StreamGroup struct {
+	Name      string
+	LastId    StreamId // last entry delivered to the group
+	Pending   []StreamPendingEntry
+	Consumers []StreamConsumer
+}
+
+// an entry delivered to a consumer of the group but not yet acknowledged
+type StreamPendingEntry struct {
+	Id            StreamId
+	DeliveryTime  uint64 // unix time in ms
+	DeliveryCount uint64
+}
+
+type StreamConsumer struct {
+	Name       string
+	SeenTime   uint64     // unix time in ms the consumer last interacted with the group
+	ActiveTime uint64     // unix time in ms the consumer last read or claimed entries, 0 if it never has
+	Pending    []StreamId // the consumer's entries among the group's pending entries
+}
+
+const (
+	streamItemFlagDeleted    = 1
+	streamItemFlagSameFields = 2
+
+	streamNodeMaxEntries = 100 // same as redis' stream-node-max-entries default
+
+	// a group's entries read isn't tracked, so it's written as redis does when it can't
+	// tell (-1), leaving redis to estimate the group's lag
+	streamEntriesReadUnknown = math.MaxUint64
+)
+
+/* Stream (RDB_TYPE_STREAM_LISTPACKS) format:
+
+	<node count>
+	<master id (16 byte big endian string)> <listpack (string)>   // per node
+	<entry count> <last id ms> <last id seq>
+	[<first id ms> <first id seq> <max deleted id ms> <max deleted id seq> <entries added>]
+	<consumer group count> [...groups]
+
+where the ids after the last id & the entries added are only in the later types. Streams
+are written as the latest type (RDB_TYPE_STREAM_LISTPACKS_3)
+
+each consumer group is written as (entries read & active time are only in the later types)
+
+	<name> <last id ms> <last id seq> [<entries read>]
+	<pel size> [<raw id (16 byte big endian)> <delivery time (ms, 8 byte)> <delivery count>]...
+	<consumer count> [<name> <seen time (ms, 8 byte)> [<active time>] <pel size> [<raw id>]...]...
+
+each listpack node starts with a master entry followed by the entries:
+
+	<count> <deleted> <num master fields> <master field 1> ... <master field N> 0
+	<flags> <ms diff> <seq diff> [<num fields> <field 1>] <value 1> ... <lp count>
+
+where num fields and the field names are omitted when the entry has the
+same fields as the master entry
+*/
+
+func writeStream(buf *bytes.Buffer, stream Stream) {
+	nodes := (len(stream.Entries) + streamNodeMaxEntries - 1) / streamNodeMaxEntries
+	writeLength(buf, uint64(nodes))
+
+	for i := 0; i < len(stream.Entries); i += streamNodeMaxEntries {
+		end := min(i+streamNodeMaxEntries, len(stream.Entries))
+		node := stream.Entries[i:end]
+		master := node[0]
+
+		key := make([]byte, 0, 16)
+		key = binary.BigEndian.AppendUint64(key, master.Id.Ms)
+		key = binary.BigEndian.AppendUint64(key, master.Id.Seq)
+		writeStringValue(buf, string(key))
+
+		lp := listpackWriter{}
+		lp.appendInt(int64(len(node)))
+		lp.appendInt(0) // deleted
+		lp.appendInt(int64(len(master.Fields) / 2))
+		for f := 0; f < len(master.Fields); f += 2 {
+			lp.appendString(master.Fields[f])
+		}
+		lp.appendInt(0)
+
+		for _, entry := range node {
+			numfields := len(entry.Fields) / 2
+
+			if sameFields(master.Fields, entry.Fields) {
+				lp.appendInt(streamItemFlagSameFields)
+				lp.appendInt(int64(entry.Id.Ms - master.Id.Ms))
+				lp.appendInt(int64(entry.Id.Seq - master.Id.Seq))
+				for f := 1; f < len(entry.Fields); f += 2 {
+					lp.appendString(entry.Fields[f])
+				}
+				lp.appendInt(int64(numfields + 3))
+			} else {
+				lp.appendInt(0)
+				lp.appendInt(int64(entry.Id.Ms - master.Id.Ms))
+				lp.appendInt(int64(entry.Id.Seq - master.Id.Seq))
+				lp.appendInt(int64(numfields))
+				for _, f := range entry.Fields {
+					lp.appendString(f)
+				}
+				lp.appendInt(int64(numfields*2 + 4))
+			}
+		}
+
+		writeStringValue(buf, string(lp.Bytes()))
+	}
+
+	writeLength(buf, uint64(len(stream.Entries)))
+	writeLength(buf, stream.LastId.Ms)
+	writeLength(buf, stream.LastId.Seq)
+
+	var first StreamId
+	if len(stream.Entries) > 0 {
+		first = stream.Entries[0].Id
+	}
+	writeLength(buf, first.Ms)
+	writeLength(buf, first.Seq)
+	writeLength(buf, stream.MaxDeletedId.Ms)
+	writeLength(buf, stream.MaxDeletedId.Seq)
+	writeLength(buf, stream.EntriesAdded)
+
+	writeStreamGroups(buf, stream.Groups)
+}
+
+func writeStreamGroups(buf *bytes.Buffer, groups []StreamGroup) {
+	writeLength(buf, uint64(len(groups)))
+
+	for _, group := range groups {
+		writeStringValue(buf, group.Name)
+		writeLength(buf, group.LastId.Ms)
+		writeLength(buf, group.LastId.Seq)
+		writeLength(buf, streamEntriesReadUnknown)
+
+		writeLength(buf, uint64(len(group.Pending)))
+		for _, pending := range group.Pending {
+			writeRawStreamId(buf, pending.Id)
+			writeUint64Value(buf, pending.DeliveryTime)
+			writeLength(buf, pending.DeliveryCount)
+		}
+
+		writeLength(buf, uint64(len(group.Consumers)))
+		for _, consumer := range group.Consumers {
+			writeStringValue(buf, consumer.Name)
+			writeUint64Value(buf, consumer.SeenTime)
+			writeUint64Value(buf, consumer.ActiveTime)
+
+			writeLength(buf, uint64(len(consumer.Pending)))
+			for _, id := range consumer.Pending {
+				writeRawStreamId(buf, id)
+			}
+		}
+	}
+}
+
+func writeRawStreamId(buf *bytes.Buffer, id StreamId) {
+	buf.Write(binary.BigEndian.AppendUint64(nil, id.Ms))
+	buf.Write(binary.BigEndian.AppendUint64(nil, id.Seq))
+}
+
+func sameFields(master []string, entry []string) bool {
+	if len(master) != len(entry) {
+		return false
+	}
+
+	for i := 0; i < len(master); i += 2 {
+		if master[i] != entry[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func readStream(reader *bufio.Reader, t byte) (Stream, error) {
+	stream := Stream{Entries: make([]StreamEntry, 0)}
+
+	nodes, err := readPlainLength(reader)
+	if err != nil {
+		return stream, fmt.Errorf("error reading stream node count: %w", err)
+	}
+
+	for n := uint64(0); n < nodes; n++ {
+		key, err := readStringValue(reader)
+		if err != nil {
+			return stream, fmt.Errorf("error reading stream node key: %w", err)
+		}
+
+		if len(key) != 16 {
+			return stream, fmt.Errorf("expected stream node key of 16 bytes but got %d", len(key))
+		}
+
+		master := StreamId{
+			Ms:  binary.BigEndian.Uint64([]byte(key[:8])),
+			Seq: binary.BigEndian.Uint64([]byte(key[8:])),
+		}
+
+		lp, err := readStringValue(reader)
+		if err != nil {
+			return stream, fmt.Errorf("error reading stream listpack: %w", err)
+		}
+
+		elements, err := readListpack([]byte(lp))
+		if err != nil {
+			return stream, err
+		}
+
+		entries, err := readStreamNode(master, elements)
+		if err != nil {
+			return stream, err
+		}
+
+		stream.Entries = append(stream.Entries, entries...)
+	}
+
+	if _, err := readPlainLength(reader); err != nil { // length, recalculated from the entries
+		return stream, fmt.Errorf("error reading stream length: %w", err)
+	}
+
+	if stream.LastId, err = readStreamId(reader); err != nil {
+		return stream, fmt.Errorf("error reading stream last id: %w", err)
+	}
+
+	if t >= RdbTypeStreamListpacks2 {
+		// the first id is that of the first entry, so discarded
+		if _, err := readStreamId(reader); err != nil {
+			return stream, fmt.Errorf("error reading stream first id: %w", err)
+		}
+
+		if stream.MaxDeletedId, err = readStreamId(reader); err != nil {
+			return stream, fmt.Errorf("error reading stream max deleted id: %w", err)
+		}
+
+		if stream.EntriesAdded, err = readPlainLength(reader); err != nil {
+			return stream, fmt.Errorf("error reading stream entries added: %w", err)
+		}
+	} else {
+		// the best guess, as redis does for the older types
+		stream.EntriesAdded = uint64(len(stream.Entries))
+	}
+
+	if stream.Groups, err = readStreamGroups(reader, t); err != nil {
+		return stream, err
+	}
+
+	return stream, nil
+}
+
+func readStreamNode(master StreamId, elements []string) ([]StreamEntry, error) {
+	i := 0
+	next := func() (int64, error) {
+		if i >= len(elements) {
+			return 0, fmt.Errorf("%w: stream node ended early", ErrInvalidListpack)
+		}
+		v, err := strconv.ParseInt(elements[i], 10, 64)
+		i++
+		return v, err
+	}
+
+	count, err := next()
+	if err != nil {
+		return nil, err
+	}
+
+	deleted, err := next()
+	if err != nil {
+		return nil, err
+	}
+
+	numMasterFields, err := next()
+	if err != nil {
+		return nil, err
+	}
+
+	if i+int(numMasterFields)+1 > len(elements) {
+		return nil, fmt.Errorf("%w: stream master entry overflows node", ErrInvalidListpack)
+	}
+
+	masterFields := elements[i : i+int(numMasterFields)]
+	i += int(numMasterFields) + 1 // +1 for the master terminator
+
+	entries := make([]StreamEntry, 0, count)
+
+	for e := int64(0); e < count+deleted; e++ {
+		flags, err := next()
+		if err != nil {
+			return nil, err
+		}
+
+		msdiff, err := next()
+		if err != nil {
+			return nil, err
+		}
+
+		seqdiff, err := next()
+		if err != nil {
+			return nil, err
+		}
+
+		fields := make([]string, 0)
+
+		if flags&streamItemFlagSameFields != 0 {
+			if i+len(masterFields) > len(elements) {
+				return nil, fmt.Errorf("%w: stream entry overflows node", ErrInvalidListpack)
+			}
+
+			for f, name := range masterFields {
+				fields = append(fields, name, elements[i+f])
+			}
+			i += len(masterFields)
+		} else {
+			numfields, err := next()
+			if err != nil {
+				return nil, err
+			}
+
+			if i+int(numfields)*2 > len(elements) {
+				return nil, fmt.Errorf("%w: stream entry overflows node", ErrInvalidListpack)
+			}
+
+			fields = append(fields, elements[i:i+int(numfields)*2]...)
+			i += int(numfields) * 2
+		}
+
+		if _, err := next(); err != nil { // lp count
+			return nil, err
+		}
+
+		if flags&streamItemFlagDeleted != 0 {
+			continue
+		}
+
+		entries = append(entries, StreamEntry{
+			Id:     StreamId{Ms: master.Ms + uint64(msdiff), Seq: master.Seq + uint64(seqdiff)},
+			Fields: fields,
+		})
+	}
+
+	return entries, nil
+}
+
+func readStreamId(reader *bufio.Reader) (StreamId, error) {
+	ms, err := readPlainLength(reader)
+	if err != nil {
+		return StreamId{}, err
+	}
+
+	seq, err := readPlainLength(reader)
+	if err != nil {
+		return StreamId{}, err
+	}
+
+	return StreamId{Ms: ms, Seq: seq}, nil
+}
+
+func readStreamGroups(reader *bufio.Reader, t byte) ([]StreamGroup, error) {
+	count, err := readPlainLength(reader)
+	if err != nil {
+		return nil, fmt.Errorf("error reading stream group count: %w", err)
+	}
+
+	groups := make([]StreamGroup, 0, count)
+
+	for g := uint64(0); g < count; g++ {
+		var group StreamGroup
+
+		if group.Name, err = readStringValue(reader); err != nil {
+			return nil, fmt.Errorf("error reading stream group name: %w", err)
+		}
+
+		if group.LastId, err = readStreamId(reader); err != nil {
+			return nil, fmt.Errorf("error reading stream group last id: %w", err)
+		}
+
+		if t >= RdbTypeStreamListpacks2 {
+			if _, err := readPlainLength(reader); err != nil { // entries read, only used for lag
+				return nil, fmt.Errorf("error reading stream group entries read: %w", err)
+			}
+		}
+
+		pending, err := readPlainLength(reader)
+		if err != nil {
+			return nil, fmt.Errorf("error reading stream group pel size: %w", err)
+		}
+
+		for p := uint64(0); p < pending; p++ {
+			var entry StreamPendingEntry
+
+			if entry.Id, err = readRawStreamId(reader); err != nil {
+				return nil, fmt.Errorf("error reading stream group pel: %w", err)
+			}
+
+			if entry.DeliveryTime, err = readUint64Value(reader); err != nil {
+				return nil, fmt.Errorf("error reading stream group pel: %w", err)
+			}
+
+			if entry.DeliveryCount, err = readPlainLength(reader); err != nil {
+				return nil, fmt.Errorf("error reading stream group pel: %w", err)
+			}
+
+			group.Pending = append(group.Pending, entry)
+		}
+
+		consumers, err := readPlainLength(reader)
+		if err != nil {
+			return nil, fmt.Errorf("error reading stream consumer count: %w", err)
+		}
+
+		for c := uint64(0); c < consumers; c++ {
+			var consumer StreamConsumer
+
+			if consumer.Name, err = readStringValue(reader); err != nil {
+				return nil, fmt.Errorf("error reading stream consumer name: %w", err)
+			}
+
+			if consumer.SeenTime, err = readUint64Value(reader); err != nil {
+				return nil, fmt.Errorf("error reading stream consumer: %w", err)
+			}
+
+			if t >= RdbTypeStreamListpacks3 {
+				if consumer.ActiveTime, err = readUint64Value(reader); err != nil {
+					return nil, fmt.Errorf("error reading stream consumer: %w", err)
+				}
+			} else {
+				consumer.ActiveTime = consumer.SeenTime
+			}
+
+			pending, err := readPlainLength(reader)
+			if err != nil {
+				return nil, fmt.Errorf("error reading stream consumer pel size: %w", err)
+			}
+
+			for p := uint64(0); p < pending; p++ {
+				id, err := readRawStreamId(reader)
+				if err != nil {
+					return nil, fmt.Errorf("error reading stream consumer pel: %w", err)
+				}
+				consumer.Pending = append(consumer.Pending, id)
+			}
+
+			group.Consumers = append(group.Consumers, consumer)
+		}
+
+		groups = append(groups, group)
+	}
+
+	return groups, nil
+}
+
+func readRawStreamId(reader *bufio.Reader) (StreamId, error) {
+	buf := make([]byte, 16)
+	if _, err := io.ReadFull(reader, buf); err != nil {
+		return StreamId{}, err
+	}
+
+	return StreamId{Ms: binary.BigEndian.Uint64(buf[:8]), Seq: binary.BigEndian.Uint64(buf[8:])}, nil
+}
@@ -0,0 +1,152 @@
+package rdb
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const RedisVersion = "7.2.0"
+
+func NewMetadata() RedisMetadata {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	return RedisMetadata{
+		RedisVersion: RedisVersion,
+		Ctime:        uint64(time.Now().Unix()),
+		UsedMem:      mem.Alloc,
+		RedisBits:    64,
+	}
+}
+
+// serializes the contents to the rdb file format (see rdb_test.go for the layout)
+func SerializeRdb(contents RdbContents) ([]byte, error) {
+	var buf bytes.Buffer
+
+	buf.WriteString(RdbHeader)
+
+	writeAuxField(&buf, "redis-ver", contents.Metadata.RedisVersion)
+	writeAuxField(&buf, "redis-bits", strconv.Itoa(int(contents.Metadata.RedisBits)))
+	writeAuxField(&buf, "ctime", strconv.FormatUint(contents.Metadata.Ctime, 10))
+	writeAuxField(&buf, "used-mem", strconv.FormatUint(contents.Metadata.UsedMem, 10))
+	writeAuxField(&buf, "aof-base", "0")
+
+	writeFunctions(&buf, contents.Functions)
+
+	for _, db := range contents.Databases {
+		if len(db.Keys) == 0 {
+			continue
+		}
+
+		buf.WriteByte(RdbDatabaseSeperator)
+		writeLength(&buf, uint64(db.Index))
+
+		buf.WriteByte(RdbHashTableInfoSeperator)
+		writeLength(&buf, uint64(len(db.Keys)))
+		writeLength(&buf, uint64(len(db.Expiries)))
+
+		// sorted so the same contents always produce the same file
+		keys := make([]string, 0, len(db.Keys))
+		for key := range db.Keys {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if expiry, exists := db.Expiries[key]; exists {
+				buf.WriteByte(RdbKeyExpiryMs)
+				writeUint64Value(&buf, expiry)
+			}
+
+			if err := writeKeyValue(&buf, key, db.Keys[key]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	buf.WriteByte(RdbEofSeperator)
+	writeUint64Value(&buf, Crc64(0, buf.Bytes()))
+
+	return buf.Bytes(), nil
+}
+
+func writeAuxField(buf *bytes.Buffer, key string, value string) {
+	buf.WriteByte(RdbMetadataSeperator)
+	writeStringValue(buf, key)
+	writeStringValue(buf, value)
+}
+
+func writeKeyValue(buf *bytes.Buffer, key string, value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		buf.WriteByte(RdbTypeString)
+		writeStringValue(buf, key)
+		writeStringValue(buf, v)
+	case List:
+		buf.WriteByte(RdbTypeListQuicklist2)
+		writeStringValue(buf, key)
+		writeList(buf, v)
+	case Set:
+		t := setEncoding(v)
+		buf.WriteByte(t)
+		writeStringValue(buf, key)
+		writeSet(buf, v, t)
+	case SortedSet:
+		buf.WriteByte(RdbTypeZSet2)
+		writeStringValue(buf, key)
+		writeSortedSet(buf, v)
+	case Hash:
+		t := hashEncoding(v)
+		buf.WriteByte(t)
+		writeStringValue(buf, key)
+		writeHash(buf, v, t)
+	case Stream:
+		buf.WriteByte(RdbTypeStreamListpacks3)
+		writeStringValue(buf, key)
+		writeStream(buf, v)
+	default:
+		return fmt.Errorf("unsupported value type %T for key %s", value, key)
+	}
+
+	return nil
+}
+
+// writes to a temp file first and renames so a crash mid-save never leaves a truncated rdb behind
+func WriteRdbToFile(path string, filename string, contents RdbContents) error {
+	data, err := SerializeRdb(contents)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		return fmt.Errorf("error creating rdb directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(path, fmt.Sprintf("temp-%d-*.rdb", os.Getpid()))
+	if err != nil {
+		return fmt.Errorf("error creating temp rdb file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing rdb file: %w", err)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error syncing rdb file: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error closing rdb file: %w", err)
+	}
+
+	return os.Rename(tmp.Name(), filepath.Join(path, filename))
+}
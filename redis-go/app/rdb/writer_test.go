@@ -0,0 +1,556 @@
+package rdb
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCrc64(t *testing.T) {
+	// arrange
+	var expected uint64 = 0xe9c6d914c4b8d9ca
+
+	// act
+	result := Crc64(0, []byte("123456789"))
+
+	// assert
+	if result != expected {
+		t.Errorf("expected crc of %x but got %x", expected, result)
+	}
+}
+
+func TestWriteRdbRoundTrip(t *testing.T) {
+	// arrange
+	dir := t.TempDir()
+
+	keys := map[string]interface{}{
+		"foo":      "bar",
+		"counter":  "12345",
+		"negative": "-70000",
+		"padded":   "007",
+		"empty":    "",
+	}
+
+	// enough keys to need the 14 bit length encoding
+	for i := 0; i < 100; i++ {
+		keys[fmt.Sprintf("key:%d", i)] = fmt.Sprintf("value:%d", i)
+	}
+
+	contents := RdbContents{
+		Metadata: NewMetadata(),
+		Databases: []RedisDatabase{{
+			Keys:     keys,
+			Expiries: map[string]uint64{"foo": 1729939775013},
+		}},
+	}
+
+	// act
+	err := WriteRdbToFile(dir, "dump.rdb", contents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ReadRdbFromFile(dir, "dump.rdb")
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Metadata.RedisVersion != RedisVersion {
+		t.Errorf("expected redis-ver %s but got %s", RedisVersion, result.Metadata.RedisVersion)
+	}
+
+	if len(result.Databases) != 1 {
+		t.Fatalf("expected rdb to contain exactly one database but was %d", len(result.Databases))
+	}
+
+	database := result.Databases[0]
+
+	if len(database.Keys) != len(keys) {
+		t.Errorf("expected %d keys but got %d", len(keys), len(database.Keys))
+	}
+
+	for key, expected := range keys {
+		if database.Keys[key] != expected {
+			t.Errorf("expected %s to have value %q but got %q", key, expected, database.Keys[key])
+		}
+	}
+
+	expiry, exists := database.Expiries["foo"]
+	if !exists || expiry != 1729939775013 {
+		t.Errorf("expected foo to have expiry 1729939775013 but got %d", expiry)
+	}
+
+	if len(database.Expiries) != 1 {
+		t.Errorf("expected exactly one expiry but got %d", len(database.Expiries))
+	}
+}
+
+func TestWriteRdbStreamRoundTrip(t *testing.T) {
+	// arrange
+	stream := Stream{Entries: make([]StreamEntry, 0)}
+
+	// more than one listpack node, with a mix of same & different fields
+	for i := 0; i < 150; i++ {
+		fields := []string{"temperature", fmt.Sprint(i), "humidity", "95"}
+		if i%7 == 0 {
+			fields = []string{"other", "value"}
+		}
+
+		stream.Entries = append(stream.Entries, StreamEntry{
+			Id:     StreamId{Ms: 1526985054069 + uint64(i/3), Seq: uint64(i % 3)},
+			Fields: fields,
+		})
+	}
+	stream.LastId = stream.Entries[len(stream.Entries)-1].Id
+	stream.MaxDeletedId = StreamId{Ms: 1526985054000, Seq: 1}
+	stream.EntriesAdded = 200
+
+	contents := RdbContents{
+		Metadata: NewMetadata(),
+		Databases: []RedisDatabase{{
+			Keys:     map[string]interface{}{"stream_key": stream},
+			Expiries: map[string]uint64{},
+		}},
+	}
+
+	// act
+	data, err := SerializeRdb(contents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ParseRdb(data)
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok := result.Databases[0].Keys["stream_key"].(Stream)
+	if !ok {
+		t.Fatalf("expected stream_key to be a stream but got %T", result.Databases[0].Keys["stream_key"])
+	}
+
+	if got.LastId != stream.LastId {
+		t.Errorf("expected last id %v but got %v", stream.LastId, got.LastId)
+	}
+
+	if got.MaxDeletedId != stream.MaxDeletedId || got.EntriesAdded != stream.EntriesAdded {
+		t.Errorf("expected max deleted id %v & %d entries added but got %v & %d", stream.MaxDeletedId, stream.EntriesAdded, got.MaxDeletedId, got.EntriesAdded)
+	}
+
+	if len(got.Entries) != len(stream.Entries) {
+		t.Fatalf("expected %d entries but got %d", len(stream.Entries), len(got.Entries))
+	}
+
+	for i, entry := range stream.Entries {
+		if got.Entries[i].Id != entry.Id {
+			t.Errorf("expected entry %d to have id %v but got %v", i, entry.Id, got.Entries[i].Id)
+		}
+
+		if fmt.Sprint(got.Entries[i].Fields) != fmt.Sprint(entry.Fields) {
+			t.Errorf("expected entry %d to have fields %v but got %v", i, entry.Fields, got.Entries[i].Fields)
+		}
+	}
+}
+
+func TestWriteRdbStreamGroupsRoundTrip(t *testing.T) {
+	// arrange
+	first, second := StreamId{Ms: 1526985054069, Seq: 0}, StreamId{Ms: 1526985054069, Seq: 1}
+
+	stream := Stream{
+		Entries: []StreamEntry{
+			{Id: first, Fields: []string{"temperature", "36"}},
+			{Id: second, Fields: []string{"temperature", "37"}},
+		},
+		LastId: second,
+		Groups: []StreamGroup{
+			{
+				Name:   "mygroup",
+				LastId: second,
+				Pending: []StreamPendingEntry{
+					{Id: first, DeliveryTime: 1700000000000, DeliveryCount: 1},
+					{Id: second, DeliveryTime: 1700000000500, DeliveryCount: 3},
+				},
+				Consumers: []StreamConsumer{
+					{Name: "alice", SeenTime: 1700000000000, ActiveTime: 1699999999000, Pending: []StreamId{first}},
+					{Name: "bob", SeenTime: 1700000000500, Pending: []StreamId{second}},
+					{Name: "idle", SeenTime: 1690000000000},
+				},
+			},
+			{Name: "empty"},
+		},
+	}
+
+	contents := RdbContents{
+		Metadata: NewMetadata(),
+		Databases: []RedisDatabase{{
+			Keys:     map[string]interface{}{"stream_key": stream},
+			Expiries: map[string]uint64{},
+		}},
+	}
+
+	// act
+	data, err := SerializeRdb(contents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ParseRdb(data)
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok := result.Databases[0].Keys["stream_key"].(Stream)
+	if !ok {
+		t.Fatalf("expected stream_key to be a stream but got %T", result.Databases[0].Keys["stream_key"])
+	}
+
+	if len(got.Entries) != len(stream.Entries) {
+		t.Fatalf("expected %d entries but got %d", len(stream.Entries), len(got.Entries))
+	}
+
+	if fmt.Sprint(got.Groups) != fmt.Sprint(stream.Groups) {
+		t.Errorf("expected groups %v but got %v", stream.Groups, got.Groups)
+	}
+}
+
+func TestReadRdbChecksumMismatch(t *testing.T) {
+	// arrange
+	data, err := os.ReadFile("../../.dumps/with_key.rdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data[len(data)-1] ^= 0xFF
+
+	// act
+	_, err = ParseRdb(data)
+
+	// assert
+	if err == nil {
+		t.Error("expected checksum mismatch error but got none")
+	}
+}
+
+func TestLzfDecompress(t *testing.T) {
+	// arrange
+	// literal "abc" followed by a back reference of length 6 to offset 3 => "abcabcabc"
+	compressed := []byte{0x02, 'a', 'b', 'c', 0x80, 0x02}
+
+	// act
+	result, err := lzfDecompress(compressed, 9)
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(result) != "abcabcabc" {
+		t.Errorf("expected abcabcabc but got %s", result)
+	}
+}
+
+func TestWriteRdbListRoundTrip(t *testing.T) {
+	// arrange
+	list := make(List, 0)
+
+	// more than one quicklist node, with ints and large strings mixed in
+	for i := 0; i < 300; i++ {
+		switch i % 3 {
+		case 0:
+			list = append(list, fmt.Sprint(i*1000))
+		case 1:
+			list = append(list, fmt.Sprintf("job-%d", i))
+		default:
+			list = append(list, strings.Repeat("x", 100+i))
+		}
+	}
+
+	contents := RdbContents{
+		Metadata: NewMetadata(),
+		Databases: []RedisDatabase{{
+			Keys:     map[string]interface{}{"queue": list},
+			Expiries: map[string]uint64{},
+		}},
+	}
+
+	// act
+	data, err := SerializeRdb(contents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ParseRdb(data)
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok := result.Databases[0].Keys["queue"].(List)
+	if !ok {
+		t.Fatalf("expected queue to be a list but got %T", result.Databases[0].Keys["queue"])
+	}
+
+	if len(got) != len(list) {
+		t.Fatalf("expected %d elements but got %d", len(list), len(got))
+	}
+
+	for i, element := range list {
+		if got[i] != element {
+			t.Errorf("expected element %d to be %q but got %q", i, element, got[i])
+		}
+	}
+}
+
+func TestWriteRdbHashRoundTrip(t *testing.T) {
+	// arrange
+	small := Hash{"name": "alice", "visits": "42", "empty": ""}
+
+	large := Hash{"long": strings.Repeat("v", 100)} // too large a value for a listpack
+	for i := 0; i < 200; i++ {
+		large[fmt.Sprintf("field:%d", i)] = fmt.Sprint(i)
+	}
+
+	contents := RdbContents{
+		Metadata: NewMetadata(),
+		Databases: []RedisDatabase{{
+			Keys:     map[string]interface{}{"small": small, "large": large},
+			Expiries: map[string]uint64{},
+		}},
+	}
+
+	// act
+	data, err := SerializeRdb(contents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ParseRdb(data)
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, expected := range map[string]Hash{"small": small, "large": large} {
+		got, ok := result.Databases[0].Keys[key].(Hash)
+		if !ok {
+			t.Fatalf("expected %s to be a hash but got %T", key, result.Databases[0].Keys[key])
+		}
+
+		if len(got) != len(expected) {
+			t.Errorf("expected %s to have %d fields but got %d", key, len(expected), len(got))
+		}
+
+		for field, value := range expected {
+			if got[field] != value {
+				t.Errorf("expected %s field %s to be %q but got %q", key, field, value, got[field])
+			}
+		}
+	}
+}
+
+func TestWriteRdbSetRoundTrip(t *testing.T) {
+	// arrange
+	ints := Set{"1": {}, "-40000": {}, "70000": {}, "5000000000": {}}
+	small := Set{"red": {}, "green": {}, "12": {}}
+	large := Set{strings.Repeat("m", 100): {}}
+
+	sets := map[string]Set{"ints": ints, "small": small, "large": large}
+
+	keys := map[string]interface{}{}
+	for key, set := range sets {
+		keys[key] = set
+	}
+
+	contents := RdbContents{
+		Metadata:  NewMetadata(),
+		Databases: []RedisDatabase{{Keys: keys, Expiries: map[string]uint64{}}},
+	}
+
+	// act
+	data, err := SerializeRdb(contents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ParseRdb(data)
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, expected := range sets {
+		got, ok := result.Databases[0].Keys[key].(Set)
+		if !ok {
+			t.Fatalf("expected %s to be a set but got %T", key, result.Databases[0].Keys[key])
+		}
+
+		if len(got) != len(expected) {
+			t.Errorf("expected %s to have %d members but got %d", key, len(expected), len(got))
+		}
+
+		for member := range expected {
+			if _, exists := got[member]; !exists {
+				t.Errorf("expected %s to contain %q", key, member)
+			}
+		}
+	}
+}
+
+func TestWriteRdbSortedSetRoundTrip(t *testing.T) {
+	// arrange
+	zset := SortedSet{"alice": 1.5, "bob": -3, "carol": 1e20, "dave": 0, "eve": math.Inf(1)}
+
+	contents := RdbContents{
+		Metadata: NewMetadata(),
+		Databases: []RedisDatabase{{
+			Keys:     map[string]interface{}{"leaderboard": zset},
+			Expiries: map[string]uint64{},
+		}},
+	}
+
+	// act
+	data, err := SerializeRdb(contents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ParseRdb(data)
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok := result.Databases[0].Keys["leaderboard"].(SortedSet)
+	if !ok {
+		t.Fatalf("expected leaderboard to be a sorted set but got %T", result.Databases[0].Keys["leaderboard"])
+	}
+
+	if len(got) != len(zset) {
+		t.Errorf("expected %d members but got %d", len(zset), len(got))
+	}
+
+	for member, score := range zset {
+		if got[member] != score {
+			t.Errorf("expected %s to have score %v but got %v", member, score, got[member])
+		}
+	}
+}
+
+func TestWriteRdbDatabaseIndexes(t *testing.T) {
+	// arrange
+	contents := RdbContents{
+		Metadata: NewMetadata(),
+		Databases: []RedisDatabase{
+			{Index: 0, Keys: map[string]interface{}{"a": "0"}, Expiries: map[string]uint64{}},
+			{Index: 3, Keys: map[string]interface{}{"a": "3"}, Expiries: map[string]uint64{"a": 1729939775013}},
+			{Index: 15, Keys: map[string]interface{}{"b": "15"}, Expiries: map[string]uint64{}},
+		},
+	}
+
+	// act
+	data, err := SerializeRdb(contents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ParseRdb(data)
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Databases) != len(contents.Databases) {
+		t.Fatalf("expected %d databases but got %d", len(contents.Databases), len(result.Databases))
+	}
+
+	for i, expected := range contents.Databases {
+		got := result.Databases[i]
+
+		if got.Index != expected.Index {
+			t.Errorf("expected database %d to have index %d but got %d", i, expected.Index, got.Index)
+		}
+
+		for key, value := range expected.Keys {
+			if got.Keys[key] != value {
+				t.Errorf("expected db %d key %s to be %q but got %q", expected.Index, key, value, got.Keys[key])
+			}
+		}
+
+		if len(got.Expiries) != len(expected.Expiries) {
+			t.Errorf("expected db %d to have %d expiries but got %d", expected.Index, len(expected.Expiries), len(got.Expiries))
+		}
+	}
+}
+
+func TestWriteRdbFunctionsRoundTrip(t *testing.T) {
+	// arrange
+	functions := []string{
+		"#!lua name=first\nredis.register_function('one', function() return 1 end)",
+		"#!lua name=second\nredis.register_function('two', function() return 2 end)",
+	}
+
+	contents := RdbContents{
+		Metadata:  NewMetadata(),
+		Functions: functions,
+		Databases: []RedisDatabase{
+			{Index: 0, Keys: map[string]interface{}{"a": "0"}, Expiries: map[string]uint64{}},
+		},
+	}
+
+	// act
+	data, err := SerializeRdb(contents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ParseRdb(data)
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(result.Functions, "|") != strings.Join(functions, "|") {
+		t.Errorf("expected functions %q but got %q", functions, result.Functions)
+	}
+
+	if len(result.Databases) != 1 || result.Databases[0].Keys["a"] != "0" {
+		t.Errorf("expected the database to follow the functions but got %v", result.Databases)
+	}
+}
+
+func TestDumpFunctionsRoundTrip(t *testing.T) {
+	// arrange
+	functions := []string{"#!lua name=lib\nredis.register_function('f', function() return 1 end)"}
+
+	// act
+	payload := DumpFunctions(functions)
+	result, err := RestoreFunctions(payload)
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result) != 1 || result[0] != functions[0] {
+		t.Errorf("expected %q but got %q", functions, result)
+	}
+
+	payload[0] ^= 0xFF
+	if _, err := RestoreFunctions(payload); err != ErrBadDumpPayload {
+		t.Errorf("expected a corrupted payload to fail with %v but got %v", ErrBadDumpPayload, err)
+	}
+}
@@ -0,0 +1,153 @@
+package rdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+/* Ziplist format (superseded by listpacks in redis 7 but still found in older rdbs):
+
+	<zlbytes:uint32> <zltail:uint32> <zllen:uint16> <entry> ... <entry> <end:0xFF>
+
+each entry is <prevlen> <encoding> <data> where prevlen is a single byte, or 0xFE
+followed by a uint32 when 254 or more. The encoding is one of:
+
+	00pppppp                      // string with 6 bit length
+	01pppppp qqqqqqqq             // string with 14 bit big endian length
+	10000000 [4 bytes]            // string with 32 bit big endian length
+	11000000                      // int16
+	11010000                      // int32
+	11100000                      // int64
+	11110000                      // int24
+	11111110                      // int8
+	1111xxxx                      // 4 bit immediate int, xxxx - 1
+
+see: https://github.com/redis/redis/blob/7.2/src/ziplist.c
+*/
+
+const (
+	zipEncodingInt16 = 0xC0
+	zipEncodingInt32 = 0xD0
+	zipEncodingInt64 = 0xE0
+	zipEncodingInt24 = 0xF0
+	zipEncodingInt8  = 0xFE
+	zipEnd           = 0xFF
+)
+
+var ErrInvalidZiplist = errors.New("invalid ziplist")
+
+// reads all the entries in the ziplist, ints are returned in their string form
+func readZiplist(data []byte) ([]string, error) {
+	const headerSize = 10
+	if len(data) < headerSize+1 {
+		return nil, fmt.Errorf("%w: too short", ErrInvalidZiplist)
+	}
+
+	entries := make([]string, 0, binary.LittleEndian.Uint16(data[8:10]))
+
+	need := func(i int, n int) error {
+		if i+n > len(data) {
+			return fmt.Errorf("%w: entry overflows ziplist", ErrInvalidZiplist)
+		}
+		return nil
+	}
+
+	i := headerSize
+	for {
+		if i >= len(data) {
+			return nil, fmt.Errorf("%w: missing end byte", ErrInvalidZiplist)
+		}
+
+		if data[i] == zipEnd {
+			break
+		}
+
+		// prevlen is only needed to walk backwards
+		if data[i] == 0xFE {
+			i += 5
+		} else {
+			i++
+		}
+
+		if err := need(i, 1); err != nil {
+			return nil, err
+		}
+
+		b := data[i]
+
+		var value string
+		var size int // size of encoding+data
+
+		switch {
+		case b>>6 == 0:
+			strlen := int(b & 0x3F)
+			size = 1 + strlen
+			if err := need(i, size); err != nil {
+				return nil, err
+			}
+			value = string(data[i+1 : i+size])
+		case b>>6 == 1:
+			if err := need(i, 2); err != nil {
+				return nil, err
+			}
+			strlen := int(b&0x3F)<<8 | int(data[i+1])
+			size = 2 + strlen
+			if err := need(i, size); err != nil {
+				return nil, err
+			}
+			value = string(data[i+2 : i+size])
+		case b == 0x80:
+			if err := need(i, 5); err != nil {
+				return nil, err
+			}
+			strlen := int(binary.BigEndian.Uint32(data[i+1 : i+5]))
+			size = 5 + strlen
+			if err := need(i, size); err != nil {
+				return nil, err
+			}
+			value = string(data[i+5 : i+size])
+		case b == zipEncodingInt16:
+			size = 3
+			if err := need(i, size); err != nil {
+				return nil, err
+			}
+			value = strconv.Itoa(int(int16(binary.LittleEndian.Uint16(data[i+1 : i+3]))))
+		case b == zipEncodingInt32:
+			size = 5
+			if err := need(i, size); err != nil {
+				return nil, err
+			}
+			value = strconv.Itoa(int(int32(binary.LittleEndian.Uint32(data[i+1 : i+5]))))
+		case b == zipEncodingInt64:
+			size = 9
+			if err := need(i, size); err != nil {
+				return nil, err
+			}
+			value = strconv.FormatInt(int64(binary.LittleEndian.Uint64(data[i+1:i+9])), 10)
+		case b == zipEncodingInt24:
+			size = 4
+			if err := need(i, size); err != nil {
+				return nil, err
+			}
+			u := int32(data[i+1]) | int32(data[i+2])<<8 | int32(data[i+3])<<16
+			value = strconv.Itoa(int(u << 8 >> 8)) // sign extend
+		case b == zipEncodingInt8:
+			size = 2
+			if err := need(i, size); err != nil {
+				return nil, err
+			}
+			value = strconv.Itoa(int(int8(data[i+1])))
+		case b >= 0xF1 && b <= 0xFD:
+			value, size = strconv.Itoa(int(b&0x0F)-1), 1
+		default:
+			return nil, fmt.Errorf("%w: unexpected encoding %x", ErrInvalidZiplist, b)
+		}
+
+		entries = append(entries, value)
+		i += size
+	}
+
+	return entries, nil
+}
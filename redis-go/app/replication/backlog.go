@@ -0,0 +1,109 @@
+package replication
+
+import "sync"
+
+const DefaultBacklogSize = 1024 * 1024 // 1mb, same as redis' repl-backlog-size default
+
+// Bounded circular buffer of the most recent bytes sent to followers, used to
+// serve partial resyncs to followers that reconnect after a short disconnect.
+//
+// Offsets follow redis' conventions: the master offset is the total number of
+// bytes ever written and psync offsets are the offset of the next byte the
+// follower wants (i.e. the follower's offset + 1).
+type Backlog struct {
+	buf     []byte
+	idx     int // next write position in buf
+	histlen int // number of valid bytes in buf
+	offset  int // master repl offset
+	mu      sync.RWMutex
+}
+
+func NewBacklog(size int) *Backlog {
+	if size <= 0 {
+		size = DefaultBacklogSize
+	}
+
+	return &Backlog{buf: make([]byte, size)}
+}
+
+func (b *Backlog) Write(data []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.offset += len(data)
+
+	// only the tail can fit when the write is bigger than the buffer
+	if len(data) > len(b.buf) {
+		data = data[len(data)-len(b.buf):]
+	}
+
+	n := copy(b.buf[b.idx:], data)
+	copy(b.buf, data[n:])
+
+	b.idx = (b.idx + len(data)) % len(b.buf)
+	b.histlen = min(b.histlen+len(data), len(b.buf))
+}
+
+// discards the history and continues from the master offset, e.g. when a follower is
+// promoted and carries on from where its leader's stream left off
+func (b *Backlog) Reset(offset int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.offset = offset
+	b.idx = 0
+	b.histlen = 0
+}
+
+// master repl offset, i.e. total number of bytes written
+func (b *Backlog) Offset() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.offset
+}
+
+// offset of the oldest byte still held in the backlog
+func (b *Backlog) FirstByteOffset() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.offset - b.histlen + 1
+}
+
+func (b *Backlog) Size() int {
+	return len(b.buf)
+}
+
+func (b *Backlog) HistLen() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.histlen
+}
+
+// returns the bytes from the psync offset up to the master offset, ok is false
+// when the offset is no longer (or not yet) covered by the backlog
+func (b *Backlog) ReadFrom(psyncOffset int) ([]byte, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	first := b.offset - b.histlen + 1
+	if psyncOffset < first || psyncOffset > b.offset+1 {
+		return nil, false
+	}
+
+	skip := psyncOffset - first
+	length := b.histlen - skip
+	start := (b.idx - b.histlen + skip + len(b.buf)) % len(b.buf)
+
+	out := make([]byte, 0, length)
+	if start+length <= len(b.buf) {
+		out = append(out, b.buf[start:start+length]...)
+	} else {
+		out = append(out, b.buf[start:]...)
+		out = append(out, b.buf[:length-(len(b.buf)-start)]...)
+	}
+
+	return out, true
+}
@@ -0,0 +1,126 @@
+package replication
+
+import "testing"
+
+func TestBacklogReadFrom(t *testing.T) {
+	// arrange
+	backlog := NewBacklog(16)
+	backlog.Write([]byte("hello "))
+	backlog.Write([]byte("world"))
+
+	// act
+	data, ok := backlog.ReadFrom(7) // psync offsets are the next byte wanted
+
+	// assert
+	if !ok {
+		t.Fatal("expected offset to be in the backlog")
+	}
+
+	if string(data) != "world" {
+		t.Errorf("expected %q but got %q", "world", data)
+	}
+
+	if backlog.Offset() != 11 {
+		t.Errorf("expected master offset 11 but got %d", backlog.Offset())
+	}
+}
+
+func TestBacklogReadFromCaughtUp(t *testing.T) {
+	// arrange
+	backlog := NewBacklog(16)
+	backlog.Write([]byte("hello"))
+
+	// act
+	data, ok := backlog.ReadFrom(6)
+
+	// assert
+	if !ok || len(data) != 0 {
+		t.Errorf("expected an empty continue but got %q (ok: %v)", data, ok)
+	}
+}
+
+func TestBacklogWrapsAround(t *testing.T) {
+	// arrange
+	backlog := NewBacklog(8)
+	backlog.Write([]byte("abcdef"))
+	backlog.Write([]byte("ghijk")) // backlog now holds "defghijk"
+
+	// act
+	_, evicted := backlog.ReadFrom(3)
+	data, ok := backlog.ReadFrom(4)
+
+	// assert
+	if evicted {
+		t.Error("expected evicted offset to need a full resync")
+	}
+
+	if !ok {
+		t.Fatal("expected offset to be in the backlog")
+	}
+
+	if string(data) != "defghijk" {
+		t.Errorf("expected %q but got %q", "defghijk", data)
+	}
+
+	if backlog.FirstByteOffset() != 4 {
+		t.Errorf("expected first byte offset 4 but got %d", backlog.FirstByteOffset())
+	}
+}
+
+func TestBacklogWriteLargerThanBuffer(t *testing.T) {
+	// arrange
+	backlog := NewBacklog(4)
+
+	// act
+	backlog.Write([]byte("abcdefghij"))
+	data, ok := backlog.ReadFrom(7)
+
+	// assert
+	if !ok || string(data) != "ghij" {
+		t.Errorf("expected %q but got %q (ok: %v)", "ghij", data, ok)
+	}
+}
+
+func TestBacklogFutureOffset(t *testing.T) {
+	// arrange
+	backlog := NewBacklog(8)
+	backlog.Write([]byte("abc"))
+
+	// act
+	_, ok := backlog.ReadFrom(10)
+
+	// assert
+	if ok {
+		t.Error("expected offset ahead of the leader to need a full resync")
+	}
+}
+
+func TestBacklogResetContinuesFromOffset(t *testing.T) {
+	// arrange
+	backlog := NewBacklog(16)
+	backlog.Write([]byte("hello"))
+
+	// act
+	backlog.Reset(100)
+	_, before := backlog.ReadFrom(6)
+	empty, caughtUp := backlog.ReadFrom(101)
+	backlog.Write([]byte("world"))
+	data, ok := backlog.ReadFrom(101)
+
+	// assert
+	if before {
+		t.Error("expected history from before the reset to need a full resync")
+	}
+
+	if !caughtUp || len(empty) != 0 {
+		t.Errorf("expected an empty continue from the reset offset but got %q (ok: %v)", empty, caughtUp)
+	}
+
+	if !ok || string(data) != "world" {
+		t.Errorf("expected %q but got %q (ok: %v)", "world", data, ok)
+	}
+
+	if backlog.Offset() != 105 {
+		t.Errorf("expected master offset 105 but got %d", backlog.Offset())
+	}
+}
@@ -10,6 +10,7 @@ type PubSubManager struct {
 	subscribers          map[string]chan PubSubEvent
 	SubscriptionsChannel chan SubscriberEvent
 	EventsChannel        chan PubSubEvent
+	Backlog              *Backlog
 	Logger               zerolog.Logger
 	mu                   sync.RWMutex
 }
@@ -24,14 +25,22 @@ const (
 type SubscriberEvent struct {
 	Action            string
 	SubscriberId      string
-	SubscriberChannel chan PubSubEvent
+	SubscriberChannel chan PubSubEvent     // must be buffered, closed by the manager once unsubscribed
+	Offset            int                  // psync offset to replay the backlog from before new events, 0 to only receive new events
+	Ack               chan SubscriptionAck // optional, notified once subscribed
 }
 
-func NewPubSubManager(logger zerolog.Logger) PubSubManager {
+type SubscriptionAck struct {
+	Offset    int  // master offset the subscriber receives events from
+	Continued bool // backlog was replayed from the requested offset
+}
+
+func NewPubSubManager(backlogSize int, logger zerolog.Logger) PubSubManager {
 	return PubSubManager{
 		subscribers:          make(map[string]chan PubSubEvent),
 		SubscriptionsChannel: make(chan SubscriberEvent),
 		EventsChannel:        make(chan PubSubEvent),
+		Backlog:              NewBacklog(backlogSize),
 		Logger:               logger,
 	}
 }
@@ -44,13 +53,28 @@ func (mgr *PubSubManager) Start() {
 			case SubscribeAction:
 				mgr.mu.Lock()
 
-				mgr.Logger.Info().Str("subscriber_id", event.SubscriberId).Msg("Subscribing")
+				// the fanout can't run whilst the lock is held so nothing is missed or
+				// duplicated between the backlog replay & new events
+				ack := SubscriptionAck{Offset: mgr.Backlog.Offset()}
+				if event.Offset > 0 {
+					data, ok := mgr.Backlog.ReadFrom(event.Offset)
+					if ok && len(data) > 0 {
+						event.SubscriberChannel <- PubSubEvent(data) // subscriber channel must be buffered
+					}
+					ack.Continued = ok
+				}
+
+				mgr.Logger.Info().Str("subscriber_id", event.SubscriberId).Int("offset", ack.Offset).Bool("continued", ack.Continued).Msg("Subscribing")
 				mgr.subscribers[event.SubscriberId] = event.SubscriberChannel
 				mgr.mu.Unlock()
+
+				if event.Ack != nil {
+					event.Ack <- ack
+				}
 			case UnsubscribeAction:
 				mgr.mu.Lock()
 				mgr.Logger.Info().Str("subscriber_id", event.SubscriberId).Msg("Unsubscribing")
-				delete(mgr.subscribers, event.SubscriberId)
+				mgr.remove(event.SubscriberId)
 				mgr.mu.Unlock()
 			}
 		}
@@ -59,13 +83,61 @@ func (mgr *PubSubManager) Start() {
 	// fanout events to subscribers
 	go func() {
 		for event := range mgr.EventsChannel {
-			mgr.mu.RLock()
-			for _, channel := range mgr.subscribers {
-				channel <- event
-			}
-			mgr.mu.RUnlock()
+			mgr.Publish(event)
 		}
 	}()
 
 	mgr.Logger.Info().Msg("Pubsub manager started...")
 }
+
+// writes the event to the backlog and fans it out to the subscribers, returning the master
+// offset once it's written. A subscriber whose channel is full is dropped rather than
+// holding up the others, closing its channel so it can resync from the backlog
+func (mgr *PubSubManager) Publish(event PubSubEvent) int {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+
+	mgr.Backlog.Write([]byte(event))
+	for id, channel := range mgr.subscribers {
+		select {
+		case channel <- event:
+		default:
+			mgr.Logger.Warn().Str("subscriber_id", id).Msg("Dropping subscriber that isn't keeping up")
+			mgr.remove(id)
+		}
+	}
+
+	return mgr.Backlog.Offset()
+}
+
+// closes the subscriber's channel, once. Must be called with the lock held
+func (mgr *PubSubManager) remove(id string) {
+	if channel, exists := mgr.subscribers[id]; exists {
+		delete(mgr.subscribers, id)
+		close(channel)
+	}
+}
+
+// disconnects every subscriber by closing its channel, e.g. when the stream they were
+// following no longer matches the dataset
+func (mgr *PubSubManager) DropSubscribers() {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+
+	for id := range mgr.subscribers {
+		mgr.Logger.Info().Str("subscriber_id", id).Msg("Dropping subscriber")
+		mgr.remove(id)
+	}
+}
+
+// continues the stream from the master offset, see Backlog.Reset
+func (mgr *PubSubManager) ContinueFrom(offset int) {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+
+	mgr.Backlog.Reset(offset)
+}
+
+func (mgr *PubSubManager) Offset() int {
+	return mgr.Backlog.Offset()
+}
@@ -0,0 +1,49 @@
+package replication
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestFanoutDropsSlowSubscriber(t *testing.T) {
+	mgr := NewPubSubManager(1024, zerolog.Nop())
+	mgr.Start()
+
+	slow := make(chan PubSubEvent, 1)
+	fast := make(chan PubSubEvent, 8)
+	for id, channel := range map[string]chan PubSubEvent{"slow": slow, "fast": fast} {
+		ack := make(chan SubscriptionAck, 1)
+		mgr.SubscriptionsChannel <- SubscriberEvent{Action: SubscribeAction, SubscriberId: id, SubscriberChannel: channel, Ack: ack}
+		<-ack
+	}
+
+	// the slow subscriber never reads, which mustn't hold up the fast one
+	for _, event := range []PubSubEvent{"a", "b", "c"} {
+		mgr.EventsChannel <- event
+	}
+
+	for _, want := range []PubSubEvent{"a", "b", "c"} {
+		select {
+		case got := <-fast:
+			if got != want {
+				t.Errorf("expected %q but got %q", want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected %q to reach the fast subscriber", want)
+		}
+	}
+
+	if event, open := <-slow; event != "a" || !open {
+		t.Errorf("expected the first event before the slow subscriber was dropped but got %q", event)
+	}
+	if _, open := <-slow; open {
+		t.Errorf("expected the slow subscriber's channel to be closed")
+	}
+
+	// unsubscribing after being dropped doesn't close the channel again
+	mgr.SubscriptionsChannel <- SubscriberEvent{Action: UnsubscribeAction, SubscriberId: "slow"}
+	mgr.SubscriptionsChannel <- SubscriberEvent{Action: UnsubscribeAction, SubscriberId: "fast"}
+	mgr.SubscriptionsChannel <- SubscriberEvent{Action: UnsubscribeAction, SubscriberId: "fast"}
+}
@@ -4,8 +4,8 @@ import (
 	"bufio"
 	"fmt"
 	"net"
-	"os"
 	"strconv"
+	"strings"
 
 	"github.com/codecrafters-io/redis-starter-go/app/rdb"
 	"github.com/codecrafters-io/redis-starter-go/app/resp"
@@ -16,11 +16,14 @@ type ReplicationClient struct {
 	Conn           net.Conn
 	Reader         *bufio.Reader
 	Logger         zerolog.Logger
+	Load           func(contents rdb.RdbContents) error // replaces the follower's data on a full resync
 	inboundPort    int
 	leader_repl_id string
+	offset         int
+	fullResync     bool // last psync replaced the store with the leader's snapshot
 }
 
-func NewReplicationClient(leaderServerAddress string, inboundPort int, logger zerolog.Logger) (ReplicationClient, error) {
+func NewReplicationClient(leaderServerAddress string, inboundPort int, load func(rdb.RdbContents) error, logger zerolog.Logger) (ReplicationClient, error) {
 	conn, err := net.Dial("tcp", leaderServerAddress)
 
 	if err != nil {
@@ -33,8 +36,10 @@ func NewReplicationClient(leaderServerAddress string, inboundPort int, logger ze
 		Conn:           conn,
 		Reader:         reader,
 		Logger:         logger,
+		Load:           load,
 		inboundPort:    inboundPort,
 		leader_repl_id: "?",
+		offset:         -1,
 	}, nil
 }
 
@@ -61,32 +66,93 @@ func (r *ReplicationClient) SendHandshake() error {
 	return nil
 }
 
+// sets the replication id & offset the follower has already processed, so the next
+// psync can continue from the leader's backlog rather than needing a full resync
+func (r *ReplicationClient) SetReplicationState(replid string, offset int) {
+	r.leader_repl_id = replid
+	r.offset = offset
+}
+
+func (r *ReplicationClient) ReplId() string {
+	return r.leader_repl_id
+}
+
+// replication offset the follower is at once psynced
+func (r *ReplicationClient) Offset() int {
+	return r.offset
+}
+
 // used to synchronize the state with the leader
 //
 // format: PSYNC <LEADER_REPL_ID> <OFFSET>
+//
+// where offset is the next byte the follower needs, or PSYNC ? -1 to force a full resync
+func (r *ReplicationClient) FullResync() bool {
+	return r.fullResync
+}
+
 func (r *ReplicationClient) PSync() error {
 	r.Logger.Info().Msg("PSyncing with leader")
 
+	psyncOffset := -1
+	if r.leader_repl_id != "?" && r.offset >= 0 {
+		psyncOffset = r.offset + 1
+	}
+
 	res, err := r.send(resp.NewRespArray([]resp.RespType{
 		resp.NewRespBulkString("PSYNC"),
 		resp.NewRespBulkString(r.leader_repl_id),
-		resp.NewRespBulkString(strconv.Itoa(-1)),
+		resp.NewRespBulkString(strconv.Itoa(psyncOffset)),
 	}))
 
 	if err != nil {
 		return err
 	}
 
-	r.Logger.Info().Msgf("recieved psync res: %s\n", res) // TODO response not checked yet in this stage
+	r.Logger.Info().Msgf("recieved psync res: %s\n", res)
+
+	// format: +CONTINUE [<REPL_ID>]
+	if strings.HasPrefix(res, "+CONTINUE") {
+		if parts := strings.Fields(strings.TrimPrefix(res, "+")); len(parts) == 2 {
+			r.leader_repl_id = parts[1]
+		}
+
+		r.Logger.Info().Int("offset", r.offset).Msg("continuing replication from leader backlog")
+		return nil
+	}
+
+	// format: +FULLRESYNC <REPL_ID> <OFFSET>
+	parts := strings.Fields(strings.TrimPrefix(res, "+"))
+	if len(parts) != 3 || parts[0] != "FULLRESYNC" {
+		return fmt.Errorf("unexpected psync response %q", res)
+	}
+
+	offset, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return fmt.Errorf("unexpected psync offset %q: %w", parts[2], err)
+	}
+
+	r.leader_repl_id = parts[1]
+	r.offset = offset
+	r.fullResync = true
 
 	// handle rdb
-	_, err = rdb.DeserializeRdb(r.Reader)
+	data, err := rdb.DeserializeRdb(r.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to receive rdb file from leader: %w", err)
+	}
+
+	contents, err := rdb.ParseRdb(data)
 	if err != nil {
-		r.Logger.Error().Err(err).Msg("Failed to receive rdb file from leader")
-		os.Exit(1)
+		return fmt.Errorf("failed to parse rdb file from leader: %w", err)
+	}
+
+	// a full resync replaces whatever the follower had
+	if err := r.Load(*contents); err != nil {
+		return fmt.Errorf("failed to load rdb file from leader: %w", err)
 	}
 
-	r.Logger.Info().Msg("got rdb")
+	r.Logger.Info().Int("bytes", len(data)).Int("databases", len(contents.Databases)).Msg("loaded rdb from leader")
 
 	return nil
 }
@@ -137,7 +203,6 @@ func (r *ReplicationClient) sendReplconf2() error {
 		resp.NewRespBulkString("REPLCONF"),
 		resp.NewRespBulkString("capa"), // hardcoded capabilities for now
 		resp.NewRespBulkString("psync2"),
-		resp.NewRespBulkString(string(r.inboundPort)),
 	}))
 
 	if err != nil {
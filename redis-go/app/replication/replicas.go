@@ -0,0 +1,116 @@
+package replication
+
+import (
+	"sync"
+	"time"
+)
+
+// Tracks the followers connected to the leader and the replication offset each
+// has acknowledged via REPLCONF ACK <offset>
+type ReplicaRegistry struct {
+	acks    map[string]int
+	changed chan struct{} // closed & replaced whenever an ack arrives
+	mu      sync.Mutex
+}
+
+func NewReplicaRegistry() *ReplicaRegistry {
+	return &ReplicaRegistry{
+		acks:    make(map[string]int),
+		changed: make(chan struct{}),
+	}
+}
+
+// offset is where the follower starts replicating from, i.e. what it already has
+func (r *ReplicaRegistry) Register(id string, offset int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.acks[id] = offset
+	r.notify()
+}
+
+func (r *ReplicaRegistry) Unregister(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.acks, id)
+	r.notify()
+}
+
+func (r *ReplicaRegistry) IsReplica(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, exists := r.acks[id]
+	return exists
+}
+
+func (r *ReplicaRegistry) Ack(id string, offset int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if current, exists := r.acks[id]; exists && offset > current {
+		r.acks[id] = offset
+		r.notify()
+	}
+}
+
+func (r *ReplicaRegistry) Count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.acks)
+}
+
+// number of followers that have acknowledged at least the offset
+func (r *ReplicaRegistry) CountAcked(offset int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count, _ := r.countAcked(offset)
+	return count
+}
+
+func (r *ReplicaRegistry) countAcked(offset int) (int, chan struct{}) {
+	count := 0
+	for _, ack := range r.acks {
+		if ack >= offset {
+			count++
+		}
+	}
+
+	return count, r.changed
+}
+
+// blocks until numreplicas followers have acknowledged the offset or the timeout
+// expires (0 waits forever), returning the number that have acknowledged it
+func (r *ReplicaRegistry) WaitForAcks(offset int, numreplicas int, timeout time.Duration) int {
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	for {
+		r.mu.Lock()
+		count, changed := r.countAcked(offset)
+		r.mu.Unlock()
+
+		if count >= numreplicas {
+			return count
+		}
+
+		select {
+		case <-changed:
+		case <-expired:
+			return r.CountAcked(offset)
+		}
+	}
+}
+
+// must be called with the lock held
+func (r *ReplicaRegistry) notify() {
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
@@ -0,0 +1,140 @@
+package replication
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReplicaRegistryCountsAckedOffsets(t *testing.T) {
+	// arrange
+	registry := NewReplicaRegistry()
+	registry.Register("a", 10)
+	registry.Register("b", 10)
+	registry.Register("c", 10)
+
+	// act
+	registry.Ack("a", 30)
+	registry.Ack("b", 20)
+	registry.Ack("b", 15) // acks never go backwards
+
+	// assert
+	cases := []struct {
+		offset int
+		want   int
+	}{
+		{offset: 0, want: 3},
+		{offset: 10, want: 3},
+		{offset: 11, want: 2},
+		{offset: 20, want: 2},
+		{offset: 21, want: 1},
+		{offset: 30, want: 1},
+		{offset: 31, want: 0},
+	}
+
+	for _, c := range cases {
+		if got := registry.CountAcked(c.offset); got != c.want {
+			t.Errorf("expected %d followers to have acked %d but got %d", c.want, c.offset, got)
+		}
+	}
+
+	if registry.Count() != 3 {
+		t.Errorf("expected 3 followers but got %d", registry.Count())
+	}
+}
+
+func TestReplicaRegistryIgnoresAcksFromUnregistered(t *testing.T) {
+	// arrange
+	registry := NewReplicaRegistry()
+	registry.Register("a", 0)
+	registry.Unregister("a")
+
+	// act
+	registry.Ack("a", 10)
+	registry.Ack("b", 10)
+
+	// assert
+	if registry.IsReplica("a") || registry.IsReplica("b") {
+		t.Error("expected an ack not to register a follower")
+	}
+
+	if got := registry.CountAcked(0); got != 0 {
+		t.Errorf("expected no followers but got %d", got)
+	}
+}
+
+func TestWaitForAcksReturnsOnceEnoughAck(t *testing.T) {
+	// arrange
+	registry := NewReplicaRegistry()
+	registry.Register("a", 0)
+	registry.Register("b", 0)
+	registry.Register("c", 0)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		registry.Ack("a", 100)
+		registry.Ack("b", 100)
+	}()
+
+	// act
+	start := time.Now()
+	got := registry.WaitForAcks(100, 2, 0) // 0 waits forever
+
+	// assert
+	if got != 2 {
+		t.Errorf("expected 2 followers to have acked but got %d", got)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected to return once acked but took %v", elapsed)
+	}
+}
+
+func TestWaitForAcksTimesOut(t *testing.T) {
+	// arrange
+	registry := NewReplicaRegistry()
+	registry.Register("a", 0)
+	registry.Register("b", 0)
+	registry.Ack("a", 100)
+
+	// act
+	start := time.Now()
+	got := registry.WaitForAcks(100, 2, 50*time.Millisecond)
+
+	// assert
+	if got != 1 {
+		t.Errorf("expected the 1 follower that acked but got %d", got)
+	}
+
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("expected to wait for the timeout but returned after %v", elapsed)
+	}
+}
+
+func TestWaitForAcksAlreadySatisfied(t *testing.T) {
+	// arrange
+	registry := NewReplicaRegistry()
+	registry.Register("a", 100)
+	registry.Register("b", 100)
+
+	// act
+	got := registry.WaitForAcks(100, 1, 0)
+
+	// assert - returns every follower that's acked, not just numreplicas
+	if got != 2 {
+		t.Errorf("expected 2 followers to have acked but got %d", got)
+	}
+}
+
+func TestWaitForAcksCountsWithNoneRequested(t *testing.T) {
+	// arrange
+	registry := NewReplicaRegistry()
+	registry.Register("a", 0)
+
+	// act
+	got := registry.WaitForAcks(100, 0, time.Hour)
+
+	// assert
+	if got != 0 {
+		t.Errorf("expected no followers to have acked but got %d", got)
+	}
+}
@@ -41,31 +41,33 @@ func (l *Lexer) NextToken() (*Token, error) {
 		return nil, err
 	}
 
-	switch TokenType(next) {
-	case TokenSimpleString:
-		return &Token{Type: TokenSimpleString, Value: l.readLine()}, nil
-	case TokenError:
-		return &Token{Type: TokenError, Value: l.readLine()}, nil
-	case TokenBulkString:
-		return &Token{Type: TokenBulkString, Value: l.readLine()}, nil
-	case TokenArray:
-		return &Token{Type: TokenArray, Value: l.readLine()}, nil
-	case TokenInteger:
-		return &Token{Type: TokenInteger, Value: l.readLine()}, nil
+	tokenType := TokenType(next)
+
+	switch tokenType {
+	case TokenSimpleString, TokenError, TokenBulkString, TokenArray, TokenInteger, TokenBool:
+		line, err := l.readLine()
+		if err != nil {
+			return nil, err
+		}
+		return &Token{Type: tokenType, Value: line}, nil
 	case TokenNull:
 		return &Token{Type: TokenNull, Value: ""}, nil
-	case TokenBool:
-		return &Token{Type: TokenBool, Value: l.readLine()}, nil
 	default:
 		return nil, fmt.Errorf("unexpected character: %v", next)
 
 	}
 }
 
-func (l *Lexer) readLine() string {
-	line, _ := l.reader.ReadString('\n')
+func (l *Lexer) readLine() (string, error) {
+	line, err := l.reader.ReadString('\n')
 	l.ByteCounter += len(line)
-	return line[:len(line)-2] // remove \r\n
+	if err != nil {
+		return "", io.ErrUnexpectedEOF // input ended mid line
+	}
+	if len(line) < 2 {
+		return "", fmt.Errorf("expected line to end with crlf")
+	}
+	return line[:len(line)-2], nil // remove \r\n
 }
 
 func (l *Lexer) ConsumeCrlf() error {
@@ -90,17 +92,19 @@ func (l *Lexer) ConsumeCrlf() error {
 func (l *Lexer) ConsumeBytes(count int) (string, error) {
 	buf := make([]byte, count)
 
-	n, err := l.reader.Read(buf)
+	n, err := io.ReadFull(l.reader, buf)
+	l.ByteCounter += n
 
 	if err != nil {
-		return "", fmt.Errorf("failed to consume bytes %v", err)
+		return "", fmt.Errorf("failed to consume bytes: expected %d but got %d: %w", count, n, err)
 	}
 
-	if n != count {
-		return "", fmt.Errorf("expected to read %d bytes but got %d", count, n)
-	}
-
-	l.ByteCounter += count
-
 	return string(buf), nil
 }
+
+// blocks until there's input to read without consuming it, returning the read error
+// if the input ends first (e.g. the connection closed)
+func (l *Lexer) Peek() error {
+	_, err := l.reader.Peek(1)
+	return err
+}
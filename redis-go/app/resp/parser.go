@@ -22,12 +22,20 @@ func (p *Parser) Parse() (RespType, error) {
 			return nil, err
 		}
 
-		return nil, fmt.Errorf("unable to parse next token %v", err)
+		return nil, fmt.Errorf("unable to parse next token %w", err)
 	}
 
 	switch token.Type {
 	case TokenSimpleString:
 		return NewRespSimpleString(token.Value), nil
+	case TokenError:
+		return NewRespError(token.Value), nil
+	case TokenInteger:
+		value, err := strconv.Atoi(token.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid resp integer %v", err)
+		}
+		return NewRespInteger(value), nil
 	case TokenBulkString:
 		return p.parseBulkString(*token)
 	case TokenArray:
@@ -46,10 +54,14 @@ func (p *Parser) parseBulkString(token Token) (RespType, error) {
 		return nil, fmt.Errorf("invalid number of elements for resp array %v", err)
 	}
 
+	if count == -1 {
+		return NullBulkString(), nil
+	}
+
 	str, err := p.lexer.ConsumeBytes(count)
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to read bulk string %v", err)
+		return nil, fmt.Errorf("failed to read bulk string %w", err)
 	}
 
 	p.lexer.ConsumeCrlf()
@@ -65,6 +77,10 @@ func (p *Parser) parseArray(token Token) (RespType, error) {
 		return nil, fmt.Errorf("invalid number of elements for resp array %v", err)
 	}
 
+	if count == -1 {
+		return NullArray(), nil
+	}
+
 	elements := make([]RespType, 0, count)
 
 	for i := 0; i < count; i++ {
@@ -48,3 +48,30 @@ func TestParser(t *testing.T) {
 		}
 	}
 }
+
+// the replies commands send, e.g. as read back for a script
+func TestParserReplies(t *testing.T) {
+	tests := []struct {
+		input string
+		want  RespType
+	}{
+		{"+OK\r\n", NewRespSimpleString("OK")},
+		{"-ERR bad\r\n", NewRespError("ERR bad")},
+		{":-42\r\n", NewRespInteger(-42)},
+		{"$-1\r\n", NullBulkString()},
+		{"*-1\r\n", NullArray()},
+		{"*2\r\n:1\r\n$3\r\nfoo\r\n", NewRespArray([]RespType{NewRespInteger(1), NewRespBulkString("foo")})},
+	}
+
+	for _, test := range tests {
+		res, err := NewParser(NewLexer(strings.NewReader(test.input))).Parse()
+		if err != nil {
+			t.Errorf("%q: expected no error but got %v", test.input, err)
+			continue
+		}
+
+		if res.AsRespString() != test.want.AsRespString() {
+			t.Errorf("%q: expected %q but got %q", test.input, test.want.AsRespString(), res.AsRespString())
+		}
+	}
+}
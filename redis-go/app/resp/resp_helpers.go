@@ -10,6 +10,23 @@ func NullBulkString() *RespBulkString {
 	return NewRespBulkString("")
 }
 
+func NullArray() *RespArray {
+	return &RespArray{Null: true}
+}
+
+func WrongArgsError(command string) *RespError {
+	return NewRespError(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(command)))
+}
+
+func NewRespArrFromStrings(items []string) *RespArray {
+	elements := make([]RespType, 0, len(items))
+	for _, item := range items {
+		elements = append(elements, NewRespBulkString(item))
+	}
+
+	return NewRespArray(elements)
+}
+
 func OkResponse() *RespSimpleString {
 	return NewRespSimpleString("OK")
 }
@@ -18,6 +35,10 @@ func PSyncResponse(replid string, offset int) *RespSimpleString {
 	return NewRespSimpleString(fmt.Sprintf("FULLRESYNC %s %v", replid, offset))
 }
 
+func PSyncContinueResponse(replid string) *RespSimpleString {
+	return NewRespSimpleString(fmt.Sprintf("CONTINUE %s", replid))
+}
+
 func PingCommand() *RespArray {
 	return NewRespArray([]RespType{
 		NewRespBulkString("PING"),
@@ -32,13 +53,19 @@ func AckResponse(offset int) *RespArray {
 	})
 }
 
+func GetAckCommand() *RespArray {
+	return NewRespArray([]RespType{
+		NewRespBulkString("REPLCONF"),
+		NewRespBulkString("GETACK"),
+		NewRespBulkString("*"),
+	})
+}
+
 // TODO hack to workarond needing to parse the items as resp types before converting to array
 func NewRespArrStringFromRespStrings(items []string) string {
 	// format of array:
 	// *<count_in_arr> \r\n <item1> \r\n <item2> \r\n <...items...> \r\n
 
-	fmt.Printf("!! length: %d", len(items))
-
 	var builder strings.Builder
 	builder.WriteString(fmt.Sprintf("*%d\r\n", len(items)))
 	for _, item := range items {
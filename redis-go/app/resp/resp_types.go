@@ -98,6 +98,7 @@ func (s *RespBulkString) AsRespString() string {
 // Array
 type RespArray struct {
 	Elements []RespType
+	Null     bool // encoded as *-1 e.g. popping from a missing list
 }
 
 func NewRespArray(elements []RespType) *RespArray {
@@ -122,6 +123,10 @@ func (s *RespArray) AsRespString() string {
 	// format of array:
 	// *<count_in_arr> \r\n <item1> \r\n <item2> \r\n <...items...> \r\n
 
+	if s.Null {
+		return "*-1\r\n"
+	}
+
 	var builder strings.Builder
 	builder.WriteString(fmt.Sprintf("*%d\r\n", len(s.Elements)))
 	for _, item := range s.Elements {
@@ -0,0 +1,226 @@
+package script
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/rs/zerolog"
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+var (
+	ErrNotBusy    = errors.New("NOTBUSY No scripts in execution right now.")
+	ErrUnkillable = errors.New("UNKILLABLE Sorry the script already executed write commands against the dataset. You can either wait the script termination or kill the server in a hard way using the SHUTDOWN NOSAVE command.")
+	ErrBusy       = errors.New("BUSY Redis is busy running a script. You can only call SCRIPT KILL or SHUTDOWN NOSAVE.")
+)
+
+const errScriptKilled = "ERR Script killed by user with SCRIPT KILL..."
+
+// runs a command on behalf of a script, returning its reply and whether it wrote
+type Caller func(args []string) (resp.RespType, bool)
+
+// compiles, caches and runs lua scripts and function libraries. Only one runs at a
+// time, the caller is expected to keep other commands out whilst it does
+type Engine struct {
+	mu        sync.Mutex
+	scripts   map[string]*lua.FunctionProto // by sha1 of the body
+	libraries map[string]*library           // see function.go
+	functions map[string]*function          // every library's functions by name
+	running   *run
+	busyAfter time.Duration // how long a script runs before other clients are turned away
+	logger    zerolog.Logger
+}
+
+type run struct {
+	cancel  context.CancelFunc
+	started time.Time
+	wrote   bool // can't be killed once it's written as the write can't be undone
+}
+
+func NewEngine(busyAfter time.Duration, logger zerolog.Logger) *Engine {
+	return &Engine{
+		scripts:   make(map[string]*lua.FunctionProto),
+		libraries: make(map[string]*library),
+		functions: make(map[string]*function),
+		busyAfter: busyAfter,
+		logger:    logger,
+	}
+}
+
+func Sha1Hex(body string) string {
+	sum := sha1.Sum([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
+
+// compiles the script and caches it, returning its sha1
+func (e *Engine) Load(body string) (string, error) {
+	sha := Sha1Hex(body)
+
+	e.mu.Lock()
+	_, exists := e.scripts[sha]
+	e.mu.Unlock()
+
+	if exists {
+		return sha, nil
+	}
+
+	proto, err := compile(body, "user_script")
+	if err != nil {
+		return "", fmt.Errorf("ERR Error compiling script (new function): %s", oneLine(err.Error()))
+	}
+
+	e.mu.Lock()
+	e.scripts[sha] = proto
+	e.mu.Unlock()
+
+	return sha, nil
+}
+
+func compile(body string, name string) (*lua.FunctionProto, error) {
+	chunk, err := parse.Parse(strings.NewReader(body), name)
+	if err != nil {
+		return nil, err
+	}
+
+	return lua.Compile(chunk, name)
+}
+
+func (e *Engine) Exists(sha string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	_, exists := e.scripts[strings.ToLower(sha)]
+	return exists
+}
+
+func (e *Engine) Flush() {
+	e.mu.Lock()
+	e.scripts = make(map[string]*lua.FunctionProto)
+	e.mu.Unlock()
+}
+
+// runs the cached script with KEYS and ARGV set, returning its reply. Errors (including
+// the script being killed) are returned as error replies
+func (e *Engine) Run(sha string, keys []string, args []string, call Caller) (resp.RespType, bool) {
+	e.mu.Lock()
+	proto, exists := e.scripts[strings.ToLower(sha)]
+	e.mu.Unlock()
+
+	if !exists {
+		return nil, false
+	}
+
+	return e.run(proto, keys, args, call), true
+}
+
+func (e *Engine) run(proto *lua.FunctionProto, keys []string, args []string, call Caller) resp.RespType {
+	L := newState(e.logger)
+	defer L.Close()
+
+	L.SetGlobal("KEYS", stringsTable(L, keys))
+	L.SetGlobal("ARGV", stringsTable(L, args))
+	registerRedis(L, call, e.wrote, e.logger)
+
+	return e.execute(L, L.NewFunctionFromProto(proto))
+}
+
+// calls fn as the running script so it can be killed, returning its reply
+func (e *Engine) execute(L *lua.LState, fn *lua.LFunction, params ...lua.LValue) resp.RespType {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	L.SetContext(ctx)
+	defer L.RemoveContext()
+
+	e.mu.Lock()
+	e.running = &run{cancel: cancel, started: time.Now()}
+	e.mu.Unlock()
+
+	defer func() {
+		e.mu.Lock()
+		e.running = nil
+		e.mu.Unlock()
+	}()
+
+	// the state outlives the call for functions
+	top := L.GetTop()
+	defer L.SetTop(top)
+
+	L.Push(fn)
+	for _, param := range params {
+		L.Push(param)
+	}
+
+	if err := L.PCall(len(params), 1, nil); err != nil {
+		if ctx.Err() != nil {
+			return resp.NewRespError(errScriptKilled)
+		}
+
+		return scriptError(err)
+	}
+
+	return fromLua(L.Get(-1))
+}
+
+// the error a script failed with, error replies raised by redis.call are passed on as is
+func scriptError(err error) resp.RespType {
+	var apiErr *lua.ApiError
+	if errors.As(err, &apiErr) {
+		if t, ok := apiErr.Object.(*lua.LTable); ok {
+			if msg, ok := t.RawGetString("err").(lua.LString); ok {
+				return resp.NewRespError(string(msg))
+			}
+		}
+
+		return resp.NewRespError("ERR Error running script: " + oneLine(apiErr.Object.String()))
+	}
+
+	return resp.NewRespError("ERR Error running script: " + oneLine(err.Error()))
+}
+
+// lua's messages can span lines, which an error reply can't
+func oneLine(message string) string {
+	return strings.Join(strings.Fields(message), " ")
+}
+
+// marks the running script as having written, so it can't be killed
+func (e *Engine) wrote() {
+	e.mu.Lock()
+	if e.running != nil {
+		e.running.wrote = true
+	}
+	e.mu.Unlock()
+}
+
+// whether a script has run for long enough that other clients should be turned away
+func (e *Engine) Busy() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.running != nil && time.Since(e.running.started) >= e.busyAfter
+}
+
+// stops the running script, unless it's written in which case it has to finish
+func (e *Engine) Kill() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.running == nil {
+		return ErrNotBusy
+	}
+
+	if e.running.wrote {
+		return ErrUnkillable
+	}
+
+	e.running.cancel()
+	return nil
+}
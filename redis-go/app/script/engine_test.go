@@ -0,0 +1,144 @@
+package script
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/rs/zerolog"
+)
+
+// replies with canned responses keyed by the command name
+func fakeCaller(replies map[string]resp.RespType) Caller {
+	return func(args []string) (resp.RespType, bool) {
+		reply, ok := replies[strings.ToLower(args[0])]
+		if !ok {
+			return resp.NewRespError("ERR unknown command"), false
+		}
+		return reply, args[0] == "set"
+	}
+}
+
+func TestRun(t *testing.T) {
+	caller := fakeCaller(map[string]resp.RespType{
+		"get":   resp.NewRespBulkString("bar"),
+		"miss":  resp.NullBulkString(),
+		"incr":  resp.NewRespInteger(7),
+		"set":   resp.OkResponse(),
+		"lpop":  resp.NewRespArray([]resp.RespType{resp.NewRespBulkString("a"), resp.NewRespInteger(2)}),
+		"wrong": resp.NewRespError("WRONGTYPE Operation against a key holding the wrong kind of value"),
+	})
+
+	tests := []struct {
+		body string
+		want string
+	}{
+		{"return redis.call('get', KEYS[1])", "$3\r\nbar\r\n"},
+		{"return redis.call('miss') == false", ":1\r\n"},
+		{"return redis.call('incr', 'k') + 1", ":8\r\n"},
+		{"return redis.call('set', 'k', 1)", "+OK\r\n"},
+		{"return redis.call('lpop', 'k')", "*2\r\n$1\r\na\r\n:2\r\n"},
+		{"return {KEYS[1], ARGV[1], ARGV[2]}", "*3\r\n$3\r\nkey\r\n$1\r\nx\r\n$1\r\ny\r\n"},
+		{"return {1, 2, nil, 4}", "*2\r\n:1\r\n:2\r\n"},
+		{"return 3.9", ":3\r\n"},
+		{"return redis.status_reply('FINE')", "+FINE\r\n"},
+		{"return redis.error_reply('ERR mine')", "-ERR mine\r\n"},
+		{"return redis.call('wrong')", "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		{"return redis.pcall('wrong')['err']", "$65\r\nWRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		{"return redis.sha1hex('')", "$40\r\nda39a3ee5e6b4b0d3255bfef95601890afd80709\r\n"},
+	}
+
+	e := NewEngine(time.Second, zerolog.Nop())
+
+	for _, test := range tests {
+		sha, err := e.Load(test.body)
+		if err != nil {
+			t.Fatalf("%s: expected no error loading but got %v", test.body, err)
+		}
+
+		reply, exists := e.Run(sha, []string{"key"}, []string{"x", "y"}, caller)
+		if !exists {
+			t.Fatalf("%s: expected the script to be cached", test.body)
+		}
+
+		if reply.AsRespString() != test.want {
+			t.Errorf("%s: expected %q but got %q", test.body, test.want, reply.AsRespString())
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	e := NewEngine(time.Second, zerolog.Nop())
+
+	sha, err := e.Load("return 1")
+	if err != nil || sha != "e0e1f9fabfc9d4800c877a703b823ac0578ff8db" {
+		t.Errorf("expected the sha1 of the body but got %q, %v", sha, err)
+	}
+
+	if !e.Exists(strings.ToUpper(sha)) {
+		t.Errorf("expected the script to exist")
+	}
+
+	if _, err := e.Load("return +"); err == nil {
+		t.Errorf("expected a compile error")
+	}
+
+	e.Flush()
+	if e.Exists(sha) {
+		t.Errorf("expected the cache to be flushed")
+	}
+
+	if _, exists := e.Run(sha, nil, nil, fakeCaller(nil)); exists {
+		t.Errorf("expected a flushed script not to run")
+	}
+}
+
+func TestKill(t *testing.T) {
+	e := NewEngine(10*time.Millisecond, zerolog.Nop())
+
+	if err := e.Kill(); err != ErrNotBusy {
+		t.Errorf("expected %v but got %v", ErrNotBusy, err)
+	}
+
+	var stop atomic.Bool
+	caller := func(args []string) (resp.RespType, bool) {
+		if args[0] == "get" && stop.Load() {
+			return resp.NewRespBulkString("stop"), false
+		}
+		return resp.OkResponse(), args[0] == "set"
+	}
+
+	for _, test := range []struct {
+		body string
+		want error
+	}{
+		{"while redis.call('get') ~= 'stop' do end", nil},
+		{"redis.call('set', 'k', 'v') while redis.call('get') ~= 'stop' do end", ErrUnkillable},
+	} {
+		stop.Store(false)
+		sha, _ := e.Load(test.body)
+
+		done := make(chan resp.RespType)
+		go func() {
+			reply, _ := e.Run(sha, nil, nil, caller)
+			done <- reply
+		}()
+
+		for !e.Busy() {
+			time.Sleep(time.Millisecond)
+		}
+
+		if err := e.Kill(); err != test.want {
+			t.Errorf("%s: expected %v but got %v", test.body, test.want, err)
+		}
+
+		stop.Store(true) // lets the unkillable script finish
+
+		reply := <-done
+		if killed := reply.AsRespString() == "-"+errScriptKilled+"\r\n"; killed != (test.want == nil) {
+			t.Errorf("%s: expected killed to be %v but got %q", test.body, test.want == nil, reply.AsRespString())
+		}
+	}
+}
@@ -0,0 +1,413 @@
+package script
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// how long a library's code has to register its functions
+const libraryLoadTimeout = 500 * time.Millisecond
+
+var (
+	ErrLibraryNotFound  = errors.New("ERR Library not found")
+	ErrFunctionNotFound = errors.New("ERR Function not found")
+)
+
+// what FUNCTION RESTORE does with the libraries that are already loaded
+type RestorePolicy int
+
+const (
+	RestoreAppend  RestorePolicy = iota // fails if any library already exists
+	RestoreReplace                      // replaces libraries with the same name
+	RestoreFlush                        // drops every existing library first
+)
+
+// flags a function can be registered with, only no-writes changes anything here
+var functionFlags = map[string]bool{
+	"no-writes":             true,
+	"allow-oom":             true,
+	"allow-stale":           true,
+	"no-cluster":            true,
+	"allow-cross-slot-keys": true,
+}
+
+// a library loaded by FUNCTION LOAD. Each has its own lua state holding its functions
+type library struct {
+	name      string
+	code      string
+	state     *lua.LState
+	functions map[string]*function
+}
+
+type function struct {
+	info     FunctionInfo
+	callback *lua.LFunction
+	library  *library
+}
+
+type LibraryInfo struct {
+	Name      string
+	Code      string
+	Functions []FunctionInfo // sorted by name
+}
+
+type FunctionInfo struct {
+	Name        string
+	Description string
+	Flags       []string
+}
+
+// whether the function promised not to write, so FCALL_RO can run it
+func (f FunctionInfo) NoWrites() bool {
+	for _, flag := range f.Flags {
+		if flag == "no-writes" {
+			return true
+		}
+	}
+	return false
+}
+
+// loads the library, returning its name. The code starts with a shebang naming the
+// library and registers its functions with redis.register_function
+//
+//	format: #!lua name=<library>\n<code>
+func (e *Engine) LoadLibrary(code string, replace bool) (string, error) {
+	lib, err := e.newLibrary(code)
+	if err != nil {
+		return "", err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	libraries := e.copyLibraries()
+	if err := addLibrary(libraries, lib, replace); err != nil {
+		lib.state.Close()
+		return "", err
+	}
+
+	e.commitLibraries(libraries)
+	return lib.name, nil
+}
+
+// replaces or adds to the loaded libraries, either all of them are loaded or none are
+func (e *Engine) RestoreLibraries(codes []string, policy RestorePolicy) error {
+	loaded := make([]*library, 0, len(codes))
+	closeLoaded := func() {
+		for _, lib := range loaded {
+			lib.state.Close()
+		}
+	}
+
+	for _, code := range codes {
+		lib, err := e.newLibrary(code)
+		if err != nil {
+			closeLoaded()
+			return err
+		}
+		loaded = append(loaded, lib)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	libraries := make(map[string]*library)
+	if policy != RestoreFlush {
+		libraries = e.copyLibraries()
+	}
+
+	for _, lib := range loaded {
+		if err := addLibrary(libraries, lib, policy == RestoreReplace); err != nil {
+			closeLoaded()
+			return err
+		}
+	}
+
+	e.commitLibraries(libraries)
+	return nil
+}
+
+func (e *Engine) DeleteLibrary(name string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, exists := e.libraries[name]; !exists {
+		return ErrLibraryNotFound
+	}
+
+	libraries := e.copyLibraries()
+	delete(libraries, name)
+	e.commitLibraries(libraries)
+
+	return nil
+}
+
+func (e *Engine) FlushLibraries() {
+	e.mu.Lock()
+	e.commitLibraries(make(map[string]*library))
+	e.mu.Unlock()
+}
+
+// every loaded library sorted by name
+func (e *Engine) Libraries() []LibraryInfo {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	result := make([]LibraryInfo, 0, len(e.libraries))
+	for _, lib := range e.libraries {
+		info := LibraryInfo{Name: lib.name, Code: lib.code}
+		for _, fn := range lib.functions {
+			info.Functions = append(info.Functions, fn.info)
+		}
+		sort.Slice(info.Functions, func(i, j int) bool { return info.Functions[i].Name < info.Functions[j].Name })
+
+		result = append(result, info)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+func (e *Engine) Function(name string) (FunctionInfo, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	fn, exists := e.functions[name]
+	if !exists {
+		return FunctionInfo{}, false
+	}
+	return fn.info, true
+}
+
+// calls the function with its keys and args, returning its reply. Errors (including the
+// function being killed) are returned as error replies
+func (e *Engine) Call(name string, keys []string, args []string, call Caller) (resp.RespType, bool) {
+	e.mu.Lock()
+	fn, exists := e.functions[name]
+	e.mu.Unlock()
+
+	if !exists {
+		return nil, false
+	}
+
+	L := fn.library.state
+	registerRedis(L, call, e.wrote, e.logger)
+
+	return e.execute(L, fn.callback, stringsTable(L, keys), stringsTable(L, args)), true
+}
+
+// must be called with the lock held
+func (e *Engine) copyLibraries() map[string]*library {
+	libraries := make(map[string]*library, len(e.libraries))
+	for name, lib := range e.libraries {
+		libraries[name] = lib
+	}
+	return libraries
+}
+
+// swaps in the libraries, closing any that were dropped. Must be called with the lock held
+func (e *Engine) commitLibraries(libraries map[string]*library) {
+	for name, lib := range e.libraries {
+		if libraries[name] != lib {
+			lib.state.Close()
+		}
+	}
+
+	e.libraries = libraries
+	e.functions = make(map[string]*function)
+	for _, lib := range libraries {
+		for name, fn := range lib.functions {
+			e.functions[name] = fn
+		}
+	}
+}
+
+// function names are global, so a library can't take one from another
+func addLibrary(libraries map[string]*library, lib *library, replace bool) error {
+	if _, exists := libraries[lib.name]; exists && !replace {
+		return fmt.Errorf("ERR Library '%s' already exists", lib.name)
+	}
+
+	for name := range lib.functions {
+		for _, other := range libraries {
+			if other.name != lib.name && other.functions[name] != nil {
+				return fmt.Errorf("ERR Function %s already exists", name)
+			}
+		}
+	}
+
+	libraries[lib.name] = lib
+	return nil
+}
+
+// runs the library's code in a new state to collect the functions it registers
+func (e *Engine) newLibrary(code string) (*library, error) {
+	name, body, err := parseLibraryMetadata(code)
+	if err != nil {
+		return nil, err
+	}
+
+	proto, err := compile(body, "user_function")
+	if err != nil {
+		return nil, fmt.Errorf("ERR Error compiling function: %s", oneLine(err.Error()))
+	}
+
+	lib := &library{
+		name:      name,
+		code:      code,
+		state:     newState(e.logger),
+		functions: make(map[string]*function),
+	}
+
+	L := lib.state
+
+	// commands can't be run whilst loading, only functions registered
+	registerRedis(L, func(args []string) (resp.RespType, bool) {
+		return resp.NewRespError("ERR redis.call can only be called from a function"), false
+	}, func() {}, e.logger)
+	L.GetGlobal("redis").(*lua.LTable).RawSetString("register_function", L.NewFunction(lib.registerFunction))
+
+	ctx, cancel := context.WithTimeout(context.Background(), libraryLoadTimeout)
+	defer cancel()
+
+	L.SetContext(ctx)
+	defer L.RemoveContext()
+
+	L.Push(L.NewFunctionFromProto(proto))
+	if err := L.PCall(0, 0, nil); err != nil {
+		L.Close()
+
+		if ctx.Err() != nil {
+			return nil, errors.New("ERR FUNCTION LOAD timeout")
+		}
+		return nil, errors.New(scriptError(err).(*resp.RespError).Message)
+	}
+
+	if len(lib.functions) == 0 {
+		L.Close()
+		return nil, errors.New("ERR No functions registered")
+	}
+
+	return lib, nil
+}
+
+// the library's name from its shebang, and the code after it with the shebang blanked
+// so error line numbers still match
+func parseLibraryMetadata(code string) (string, string, error) {
+	shebang, body, _ := strings.Cut(code, "\n")
+	if !strings.HasPrefix(shebang, "#!") {
+		return "", "", errors.New("ERR Missing library metadata")
+	}
+
+	parts := strings.Fields(shebang[2:])
+	if len(parts) == 0 || parts[0] != "lua" {
+		engine := ""
+		if len(parts) > 0 {
+			engine = parts[0]
+		}
+		return "", "", fmt.Errorf("ERR Engine '%s' not found", engine)
+	}
+
+	name := ""
+	for _, part := range parts[1:] {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || key != "name" {
+			return "", "", fmt.Errorf("ERR Invalid metadata value given: %s", part)
+		}
+		name = value
+	}
+
+	if name == "" {
+		return "", "", errors.New("ERR Library name was not given")
+	}
+
+	if !validName(name) {
+		return "", "", errors.New("ERR Library names can only contain letters, numbers, or underscores(_) and must be at least one character long")
+	}
+
+	return name, "\n" + body, nil
+}
+
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// redis.register_function(name, callback) or redis.register_function{function_name=...,
+// callback=..., flags={...}, description=...}
+func (lib *library) registerFunction(L *lua.LState) int {
+	fn := &function{library: lib}
+
+	switch L.GetTop() {
+	case 1:
+		var badArg string
+		L.CheckTable(1).ForEach(func(k lua.LValue, v lua.LValue) {
+			switch k.String() {
+			case "function_name":
+				fn.info.Name = lua.LVAsString(v)
+			case "callback":
+				fn.callback, _ = v.(*lua.LFunction)
+			case "description":
+				fn.info.Description = lua.LVAsString(v)
+			case "flags":
+				flags, ok := v.(*lua.LTable)
+				if !ok {
+					badArg = "flags argument to redis.register_function must be a table representing function flags"
+					return
+				}
+				flags.ForEach(func(_ lua.LValue, flag lua.LValue) {
+					if !functionFlags[flag.String()] {
+						badArg = "unknown flag given"
+					}
+					fn.info.Flags = append(fn.info.Flags, flag.String())
+				})
+			default:
+				badArg = "unknown argument given to redis.register_function"
+			}
+		})
+
+		if badArg != "" {
+			raiseError(L, badArg)
+		}
+	case 2:
+		fn.info.Name = L.CheckString(1)
+		fn.callback = L.CheckFunction(2)
+	default:
+		raiseError(L, "wrong number of arguments to redis.register_function")
+	}
+
+	if fn.callback == nil {
+		raiseError(L, "redis.register_function must get a callback argument")
+	}
+
+	if !validName(fn.info.Name) {
+		raiseError(L, "Function names can only contain letters, numbers, or underscores(_) and must be at least one character long")
+	}
+
+	if _, exists := lib.functions[fn.info.Name]; exists {
+		raiseError(L, "Function already exists in the library")
+	}
+
+	lib.functions[fn.info.Name] = fn
+	return 0
+}
+
+// raises the message as an error reply
+func raiseError(L *lua.LState, message string) {
+	L.Error(replyTable(L, "err", "ERR "+message), 1)
+}
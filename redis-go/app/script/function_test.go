@@ -0,0 +1,135 @@
+package script
+
+import (
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/rs/zerolog"
+)
+
+const testLibrary = `#!lua name=mylib
+redis.register_function('echo_first', function(keys, args) return {keys[1], args[1]} end)
+redis.register_function{
+	function_name = 'get_ro',
+	callback = function(keys) return redis.call('get', keys[1]) end,
+	flags = {'no-writes'},
+	description = 'reads a key',
+}`
+
+func TestLoadLibrary(t *testing.T) {
+	e := NewEngine(time.Second, zerolog.Nop())
+
+	name, err := e.LoadLibrary(testLibrary, false)
+	if err != nil || name != "mylib" {
+		t.Fatalf("expected mylib to load but got %q, %v", name, err)
+	}
+
+	libraries := e.Libraries()
+	if len(libraries) != 1 || len(libraries[0].Functions) != 2 {
+		t.Fatalf("expected one library with two functions but got %+v", libraries)
+	}
+
+	ro := libraries[0].Functions[1]
+	if ro.Name != "get_ro" || ro.Description != "reads a key" || !ro.NoWrites() {
+		t.Errorf("expected get_ro to be described and flagged no-writes but got %+v", ro)
+	}
+
+	caller := fakeCaller(map[string]resp.RespType{"get": resp.NewRespBulkString("value")})
+
+	reply, exists := e.Call("echo_first", []string{"k"}, []string{"a"}, caller)
+	if !exists || reply.AsRespString() != "*2\r\n$1\r\nk\r\n$1\r\na\r\n" {
+		t.Errorf("expected the key and arg back but got %q", reply.AsRespString())
+	}
+
+	reply, _ = e.Call("get_ro", []string{"k"}, nil, caller)
+	if reply.AsRespString() != "$5\r\nvalue\r\n" {
+		t.Errorf("expected the value of the key but got %q", reply.AsRespString())
+	}
+
+	if _, err := e.LoadLibrary(testLibrary, false); err == nil || err.Error() != "ERR Library 'mylib' already exists" {
+		t.Errorf("expected loading again without replace to fail but got %v", err)
+	}
+
+	if _, err := e.LoadLibrary(testLibrary, true); err != nil {
+		t.Errorf("expected replace to succeed but got %v", err)
+	}
+
+	if err := e.DeleteLibrary("mylib"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, exists := e.Call("echo_first", nil, nil, caller); exists {
+		t.Errorf("expected the function to go with its library")
+	}
+
+	if err := e.DeleteLibrary("mylib"); err != ErrLibraryNotFound {
+		t.Errorf("expected %v but got %v", ErrLibraryNotFound, err)
+	}
+}
+
+func TestLoadLibraryErrors(t *testing.T) {
+	e := NewEngine(time.Second, zerolog.Nop())
+
+	if _, err := e.LoadLibrary("#!lua name=taken\nredis.register_function('f', function() end)", false); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"return 1", "ERR Missing library metadata"},
+		{"#!js name=lib\n", "ERR Engine 'js' not found"},
+		{"#!lua\n", "ERR Library name was not given"},
+		{"#!lua name=lib foo=bar\n", "ERR Invalid metadata value given: foo=bar"},
+		{"#!lua name=my-lib\n", "ERR Library names can only contain letters, numbers, or underscores(_) and must be at least one character long"},
+		{"#!lua name=lib\nlocal x = 1", "ERR No functions registered"},
+		{"#!lua name=lib\nredis.register_function('f', function() end)", "ERR Function f already exists"},
+		{"#!lua name=lib\nredis.register_function('g', function() end)\nredis.register_function('g', function() end)", "ERR Function already exists in the library"},
+		{"#!lua name=lib\nredis.register_function{function_name='g', callback=function() end, flags={'bogus'}}", "ERR unknown flag given"},
+		{"#!lua name=lib\nredis.call('get', 'k')", "ERR redis.call can only be called from a function"},
+		{"#!lua name=lib\nwhile true do end", "ERR FUNCTION LOAD timeout"},
+	}
+
+	for _, test := range tests {
+		if _, err := e.LoadLibrary(test.code, false); err == nil || err.Error() != test.want {
+			t.Errorf("%q: expected %q but got %v", test.code, test.want, err)
+		}
+	}
+
+	if len(e.Libraries()) != 1 {
+		t.Errorf("expected failed loads to leave the existing library alone")
+	}
+}
+
+func TestRestoreLibraries(t *testing.T) {
+	e := NewEngine(time.Second, zerolog.Nop())
+
+	first := "#!lua name=first\nredis.register_function('one', function() return 1 end)"
+	second := "#!lua name=second\nredis.register_function('two', function() return 2 end)"
+
+	if _, err := e.LoadLibrary(first, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.RestoreLibraries([]string{second, first}, RestoreAppend); err == nil {
+		t.Errorf("expected append to fail as first exists")
+	}
+
+	if len(e.Libraries()) != 1 {
+		t.Errorf("expected a failed restore to load nothing but got %+v", e.Libraries())
+	}
+
+	if err := e.RestoreLibraries([]string{second, first}, RestoreReplace); err != nil || len(e.Libraries()) != 2 {
+		t.Errorf("expected replace to load both but got %v, %+v", err, e.Libraries())
+	}
+
+	if err := e.RestoreLibraries([]string{second}, RestoreFlush); err != nil || len(e.Libraries()) != 1 {
+		t.Errorf("expected flush to leave only second but got %v, %+v", err, e.Libraries())
+	}
+
+	if _, exists := e.Function("one"); exists {
+		t.Errorf("expected one to be flushed")
+	}
+}
@@ -0,0 +1,187 @@
+package script
+
+import (
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/rs/zerolog"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// a state with only the libraries that can't reach outside the script
+func newState(logger zerolog.Logger) *lua.LState {
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+
+	libs := []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	}
+
+	for _, lib := range libs {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+
+	for _, name := range []string{"dofile", "loadfile"} {
+		L.SetGlobal(name, lua.LNil)
+	}
+
+	return L
+}
+
+func stringsTable(L *lua.LState, values []string) *lua.LTable {
+	t := L.CreateTable(len(values), 0)
+	for _, v := range values {
+		t.Append(lua.LString(v))
+	}
+	return t
+}
+
+// the redis global scripts use to run commands and build replies
+func registerRedis(L *lua.LState, call Caller, wrote func(), logger zerolog.Logger) {
+	redis := L.NewTable()
+
+	L.SetFuncs(redis, map[string]lua.LGFunction{
+		"call": func(L *lua.LState) int {
+			return redisCall(L, call, wrote, true)
+		},
+		"pcall": func(L *lua.LState) int {
+			return redisCall(L, call, wrote, false)
+		},
+		"sha1hex": func(L *lua.LState) int {
+			L.Push(lua.LString(Sha1Hex(L.CheckString(1))))
+			return 1
+		},
+		"error_reply": func(L *lua.LState) int {
+			L.Push(replyTable(L, "err", L.CheckString(1)))
+			return 1
+		},
+		"status_reply": func(L *lua.LState) int {
+			L.Push(replyTable(L, "ok", L.CheckString(1)))
+			return 1
+		},
+		"log": func(L *lua.LState) int {
+			parts := make([]string, 0, L.GetTop()-1)
+			for i := 2; i <= L.GetTop(); i++ {
+				parts = append(parts, L.ToStringMeta(L.Get(i)).String())
+			}
+			logger.Info().Int("level", L.CheckInt(1)).Msg(strings.Join(parts, " "))
+			return 0
+		},
+	})
+
+	for i, level := range []string{"LOG_DEBUG", "LOG_VERBOSE", "LOG_NOTICE", "LOG_WARNING"} {
+		redis.RawSetString(level, lua.LNumber(i))
+	}
+
+	L.SetGlobal("redis", redis)
+}
+
+// runs the command given by the arguments, an error reply is raised for redis.call and
+// returned for redis.pcall
+func redisCall(L *lua.LState, call Caller, wrote func(), raise bool) int {
+	if L.GetTop() == 0 {
+		L.RaiseError("Please specify at least one argument for this redis lib call")
+	}
+
+	args := make([]string, 0, L.GetTop())
+	for i := 1; i <= L.GetTop(); i++ {
+		switch v := L.Get(i).(type) {
+		case lua.LString:
+			args = append(args, string(v))
+		case lua.LNumber:
+			args = append(args, v.String())
+		default:
+			L.RaiseError("Lua redis lib command arguments must be strings or integers")
+		}
+	}
+
+	reply, didWrite := call(args)
+	if didWrite {
+		wrote()
+	}
+
+	if e, ok := reply.(*resp.RespError); ok && raise {
+		L.Error(replyTable(L, "err", e.Message), 1)
+	}
+
+	L.Push(toLua(L, reply))
+	return 1
+}
+
+// status and error replies are tables with a single ok or err field
+func replyTable(L *lua.LState, field string, message string) *lua.LTable {
+	t := L.NewTable()
+	t.RawSetString(field, lua.LString(message))
+	return t
+}
+
+// converts a command's reply for the script, nulls become false
+func toLua(L *lua.LState, reply resp.RespType) lua.LValue {
+	switch r := reply.(type) {
+	case *resp.RespInteger:
+		return lua.LNumber(r.Value)
+	case *resp.RespBulkString:
+		if r.Content == "" {
+			return lua.LFalse // empty bulk strings are sent as null
+		}
+		return lua.LString(r.Content)
+	case *resp.RespSimpleString:
+		return replyTable(L, "ok", r.Value)
+	case *resp.RespError:
+		return replyTable(L, "err", r.Message)
+	case *resp.RespArray:
+		if r.Null {
+			return lua.LFalse
+		}
+
+		t := L.CreateTable(len(r.Elements), 0)
+		for _, el := range r.Elements {
+			t.Append(toLua(L, el))
+		}
+		return t
+	default:
+		return lua.LFalse
+	}
+}
+
+// converts the script's return value to its reply. Numbers are truncated to integers and
+// arrays stop at the first nil, as in redis
+func fromLua(value lua.LValue) resp.RespType {
+	switch v := value.(type) {
+	case lua.LNumber:
+		return resp.NewRespInteger(int(v))
+	case lua.LString:
+		return resp.NewRespBulkString(string(v))
+	case lua.LBool:
+		if v {
+			return resp.NewRespInteger(1)
+		}
+		return resp.NullBulkString()
+	case *lua.LTable:
+		if msg, ok := v.RawGetString("err").(lua.LString); ok {
+			return resp.NewRespError(string(msg))
+		}
+		if msg, ok := v.RawGetString("ok").(lua.LString); ok {
+			return resp.NewRespSimpleString(string(msg))
+		}
+
+		elements := make([]resp.RespType, 0, v.Len())
+		for i := 1; ; i++ {
+			el := v.RawGetInt(i)
+			if el == lua.LNil {
+				break
+			}
+			elements = append(elements, fromLua(el))
+		}
+		return resp.NewRespArray(elements)
+	default:
+		return resp.NullBulkString()
+	}
+}
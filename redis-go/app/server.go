@@ -1,17 +1,23 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/codecrafters-io/redis-starter-go/app/aof"
 	"github.com/codecrafters-io/redis-starter-go/app/cmd"
+	"github.com/codecrafters-io/redis-starter-go/app/pubsub"
 	"github.com/codecrafters-io/redis-starter-go/app/replication"
 	"github.com/codecrafters-io/redis-starter-go/app/resp"
+	"github.com/codecrafters-io/redis-starter-go/app/script"
 	"github.com/codecrafters-io/redis-starter-go/app/store"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
@@ -19,11 +25,18 @@ import (
 )
 
 type ServerConfig struct {
-	Port       int
-	LeaderAddr string
-	Debug      bool
-	DbFilename string
-	Dir        string
+	Port                 int
+	LeaderAddr           string
+	Debug                bool
+	DbFilename           string
+	Dir                  string
+	ReplBacklogSize      int
+	AppendOnly           bool
+	AppendFsync          string
+	AppendFilename       string
+	Databases            int
+	LuaTimeLimit         int // ms a script runs before other clients get BUSY
+	NotifyKeyspaceEvents string
 }
 
 func main() {
@@ -40,24 +53,61 @@ func main() {
 	logger.Debug().Interface("config", conf).Msg("Parsed config")
 
 	hostctx := cmd.HostContext{
-		Store:         store.NewKvStore(logger.With().Str("component", "kvstore").Logger()),
+		Databases:     make([]*store.KvStore, conf.Databases),
 		ConfigStore:   store.NewKvStore(logger.With().Str("component", "confstore").Logger()),
 		TxQueue:       make(map[uuid.UUID][]cmd.QueuedCommand),
 		LeaderAddr:    conf.LeaderAddr,
 		Port:          conf.Port,
 		LeaderReplId:  "",
-		PubSubManager: replication.NewPubSubManager(logger.With().Str("component", "pubsubmgr").Logger()),
+		PubSubManager: replication.NewPubSubManager(conf.ReplBacklogSize, logger.With().Str("component", "pubsubmgr").Logger()),
+		Replicas:      replication.NewReplicaRegistry(),
+		PubSub:        pubsub.NewBroker(),
+		Scripts:       script.NewEngine(time.Duration(conf.LuaTimeLimit)*time.Millisecond, logger.With().Str("component", "script").Logger()),
 		Logger:        logger,
 	}
 
 	// TODO move this into rdb init?
 	hostctx.ConfigStore.Set("dir", conf.Dir, store.ValueOptions{})
 	hostctx.ConfigStore.Set("dbfilename", conf.DbFilename, store.ValueOptions{})
-
-	hostctx.Store.InitialiseFromRdbFile(conf.Dir, conf.DbFilename)
+	hostctx.ConfigStore.Set("appendonly", yesNo(conf.AppendOnly), store.ValueOptions{})
+	hostctx.ConfigStore.Set("appendfsync", conf.AppendFsync, store.ValueOptions{})
+	hostctx.ConfigStore.Set("appendfilename", conf.AppendFilename, store.ValueOptions{})
+	hostctx.ConfigStore.Set("databases", strconv.Itoa(conf.Databases), store.ValueOptions{})
+	hostctx.ConfigStore.Set("lua-time-limit", strconv.Itoa(conf.LuaTimeLimit), store.ValueOptions{})
+	if err := hostctx.SetKeyspaceEvents(conf.NotifyKeyspaceEvents); err != nil {
+		logger.Fatal().Err(err).Str("flags", conf.NotifyKeyspaceEvents).Msg("Invalid notify-keyspace-events")
+	}
 
 	hostctx.PubSubManager.Start()
 
+	for i := range hostctx.Databases {
+		db := store.NewKvStore(logger.With().Str("component", "kvstore").Int("db", i).Logger())
+		db.OnExpire(func(key string) { hostctx.PropagateExpired(i, key) })
+		db.OnKeyEvent(func(class store.EventClass, event string, key string) {
+			hostctx.NotifyKeyspaceEvent(i, class, event, key)
+		})
+		go db.ActiveExpire()
+
+		hostctx.Databases[i] = db
+	}
+
+	// the aof is always at least as up to date as the rdb, so takes priority when enabled
+	if conf.AppendOnly {
+		aofPath := filepath.Join(conf.Dir, conf.AppendFilename)
+
+		err := hostctx.LoadAof(aofPath)
+		if err != nil && !os.IsNotExist(err) {
+			logger.Fatal().Err(err).Msg("Error loading aof")
+		}
+
+		hostctx.Aof, err = aof.Open(aofPath, conf.AppendFsync, logger.With().Str("component", "aof").Logger())
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Error opening aof")
+		}
+	} else {
+		hostctx.InitialiseFromRdbFile(conf.Dir, conf.DbFilename)
+	}
+
 	is_leader := conf.LeaderAddr == ""
 
 	if is_leader {
@@ -67,25 +117,7 @@ func main() {
 	} else {
 		// follower initiation steps
 		logger.Info().Msg("Starting follower initiation steps...")
-		repl_client, err := replication.NewReplicationClient(conf.LeaderAddr, conf.Port, logger.With().Str("component", "replclient").Logger())
-		if err != nil {
-			logger.Error().Err(err).Msg("error creating connection to leader")
-			os.Exit(1)
-		}
-
-		err = repl_client.SendHandshake()
-		if err != nil {
-			logger.Fatal().Stack().Err(err).Msg("Error sending handshake")
-			os.Exit(1)
-		}
-
-		err = repl_client.PSync()
-		if err != nil {
-			logger.Fatal().Stack().Err(err).Msg("Error psyncing")
-			os.Exit(1)
-		}
-
-		startReplicationListener(&hostctx, repl_client.Conn, repl_client.Reader)
+		hostctx.StartReplication(conf.LeaderAddr)
 	}
 
 	// begin serving
@@ -121,60 +153,17 @@ func main() {
 	wg.Wait()
 }
 
-func startReplicationListener(hostctx *cmd.HostContext, conn net.Conn, reader io.Reader) {
-	logger := log.With().Str("component", "repl_listener").Logger()
-	go func() {
-		lexer := resp.NewLexer(reader)
-		parser := resp.NewParser(lexer)
-
-		for {
-			p := hostctx.ProcessedBytes
-			c, arr, err := cmd.ParseServerCommand(*parser)
-			if err != nil {
-				if err == io.EOF {
-					logger.Debug().Msg("EOF")
-					break
-				}
-
-				logger.Err(err).Msg("error parsing the server command")
-				continue // TODO better handling for EOF vs other errs
-			}
-
-			logger.Info().Int("elements", len(arr.Elements)).Str("command", c).Msg("got replication command")
-
-			commandCtx := cmd.HandleContext{
-				Conn:    conn,
-				HostCtx: hostctx,
-				RespArr: arr,
-				Logger:  logger.With().Str("command", c).Logger(),
-				ConnId:  uuid.New(),
-			}
-
-			res, err := cmd.HandleCommand(commandCtx, c)
-			if err != nil {
-				logger.Err(err).Msg("error handling command")
-				continue
-			}
-
-			// deliberately don't send response to conn for most replication commands
-			//		TODO need a better way to handle this
-			if strings.ToLower(c) == "replconf" && strings.ToLower(arr.Elements[1].(*resp.RespBulkString).Content) == "getack" {
-				conn.Write([]byte(res))
-			}
-
-			hostctx.AppendProcessedBytes(lexer.ByteCounter - p)
-			logger.Debug().Int("processed_bytes", lexer.ByteCounter-p).Msg("Processed bytes")
-		}
-	}()
-
-	logger.Info().Msg("Starting replication listener...")
-}
-
 func handleConnection(conn net.Conn, hostctx *cmd.HostContext) {
 	logger := log.With().Str("component", "conn_listener").Logger()
 
 	defer conn.Close()
 	connId := uuid.New()
+	defer hostctx.Replicas.Unregister(connId.String()) // no-op unless the connection psynced
+
+	session := &cmd.Session{}
+	defer hostctx.UnwatchAll(session)
+	defer hostctx.UnsubscribeAll(session)
+	defer hostctx.ConsumeTransactionQueue(connId) // drops an unfinished transaction
 
 	lexer := resp.NewLexer(conn)
 	parser := resp.NewParser(lexer)
@@ -190,11 +179,16 @@ func handleConnection(conn net.Conn, hostctx *cmd.HostContext) {
 		}
 
 		commandCtx := cmd.HandleContext{
-			Conn:    conn,
-			HostCtx: hostctx,
-			RespArr: arr,
-			Logger:  logger.With().Str("command", c).Logger(),
-			ConnId:  connId,
+			Conn:     conn,
+			HostCtx:  hostctx,
+			RespArr:  arr,
+			Logger:   logger.With().Str("command", c).Logger(),
+			ConnId:   connId,
+			Session:  session,
+			CanBlock: true,
+			WatchDisconnect: func() (<-chan struct{}, func()) {
+				return watchDisconnect(conn, lexer)
+			},
 		}
 
 		res, err := cmd.HandleCommand(commandCtx, c)
@@ -204,9 +198,34 @@ func handleConnection(conn net.Conn, hostctx *cmd.HostContext) {
 		}
 
 		if res != "" {
-			conn.Write([]byte(res))
+			session.Write(conn, res) // queued behind its messages once subscribed
+		}
+	}
+}
+
+// nothing is read from the connection whilst a command blocks, so peek at it in the
+// background to notice the client going away. Stopping interrupts the peek with a read
+// deadline, anything the client sent in the meantime stays buffered in the lexer
+func watchDisconnect(conn net.Conn, lexer *resp.Lexer) (<-chan struct{}, func()) {
+	closed := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		err := lexer.Peek()
+		if err != nil && !errors.Is(err, os.ErrDeadlineExceeded) {
+			close(closed)
 		}
+	}()
+
+	stop := func() {
+		conn.SetReadDeadline(time.Now())
+		<-done
+		conn.SetReadDeadline(time.Time{})
 	}
+
+	return closed, stop
 }
 
 func parseArgs(logger zerolog.Logger) ServerConfig {
@@ -215,6 +234,13 @@ func parseArgs(logger zerolog.Logger) ServerConfig {
 	debug := false
 	dbfilename := "dump.rdb"
 	dir := "/tmp/redis-files/"
+	replBacklogSize := replication.DefaultBacklogSize
+	appendOnly := false
+	appendFsync := aof.FsyncEverySec
+	appendFilename := "appendonly.aof"
+	databases := 16
+	luaTimeLimit := 5000
+	notifyKeyspaceEvents := "" // disabled
 
 	for i := 1; i < len(os.Args); i++ {
 		switch os.Args[i] {
@@ -275,6 +301,89 @@ func parseArgs(logger zerolog.Logger) ServerConfig {
 				logger.Error().Msg("Missing value for --dir")
 				os.Exit(1)
 			}
+		case "--repl-backlog-size":
+			if i+1 < len(os.Args) {
+				size, err := strconv.Atoi(os.Args[i+1])
+				if err != nil || size <= 0 {
+					logger.Error().Err(err).Msg("Invalid repl-backlog-size")
+					os.Exit(1)
+				}
+				replBacklogSize = size
+				i++
+			} else {
+				logger.Error().Msg("Missing value for --repl-backlog-size")
+				os.Exit(1)
+			}
+		case "--appendonly":
+			if i+1 < len(os.Args) {
+				switch os.Args[i+1] {
+				case "yes":
+					appendOnly = true
+				case "no":
+					appendOnly = false
+				default:
+					logger.Error().Msg("Invalid value for --appendonly expected 'yes' or 'no'")
+					os.Exit(1)
+				}
+				i++
+			} else {
+				logger.Error().Msg("Missing value for --appendonly")
+				os.Exit(1)
+			}
+		case "--appendfsync":
+			if i+1 < len(os.Args) {
+				appendFsync = os.Args[i+1]
+				if !aof.IsValidFsync(appendFsync) {
+					logger.Error().Msg("Invalid value for --appendfsync expected 'always', 'everysec' or 'no'")
+					os.Exit(1)
+				}
+				i++
+			} else {
+				logger.Error().Msg("Missing value for --appendfsync")
+				os.Exit(1)
+			}
+		case "--appendfilename":
+			if i+1 < len(os.Args) {
+				appendFilename = os.Args[i+1]
+				i++
+			} else {
+				logger.Error().Msg("Missing value for --appendfilename")
+				os.Exit(1)
+			}
+		case "--databases":
+			if i+1 < len(os.Args) {
+				n, err := strconv.Atoi(os.Args[i+1])
+				if err != nil || n <= 0 {
+					logger.Error().Err(err).Msg("Invalid databases")
+					os.Exit(1)
+				}
+				databases = n
+				i++
+			} else {
+				logger.Error().Msg("Missing value for --databases")
+				os.Exit(1)
+			}
+		case "--lua-time-limit":
+			if i+1 < len(os.Args) {
+				ms, err := strconv.Atoi(os.Args[i+1])
+				if err != nil || ms < 0 {
+					logger.Error().Err(err).Msg("Invalid lua-time-limit")
+					os.Exit(1)
+				}
+				luaTimeLimit = ms
+				i++
+			} else {
+				logger.Error().Msg("Missing value for --lua-time-limit")
+				os.Exit(1)
+			}
+		case "--notify-keyspace-events":
+			if i+1 < len(os.Args) {
+				notifyKeyspaceEvents = os.Args[i+1]
+				i++
+			} else {
+				logger.Error().Msg("Missing value for --notify-keyspace-events")
+				os.Exit(1)
+			}
 		default:
 			logger.Error().Str("arg", os.Args[i]).Msg("Unknown argument")
 			os.Exit(1)
@@ -282,10 +391,24 @@ func parseArgs(logger zerolog.Logger) ServerConfig {
 	}
 
 	return ServerConfig{
-		Port:       port,
-		LeaderAddr: leader_addr,
-		Debug:      debug,
-		DbFilename: dbfilename,
-		Dir:        dir,
+		Port:                 port,
+		LeaderAddr:           leader_addr,
+		Debug:                debug,
+		DbFilename:           dbfilename,
+		Dir:                  dir,
+		ReplBacklogSize:      replBacklogSize,
+		AppendOnly:           appendOnly,
+		AppendFsync:          appendFsync,
+		AppendFilename:       appendFilename,
+		Databases:            databases,
+		LuaTimeLimit:         luaTimeLimit,
+		NotifyKeyspaceEvents: notifyKeyspaceEvents,
+	}
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
 	}
+	return "no"
 }
@@ -0,0 +1,282 @@
+package store
+
+// A client blocked on one or more lists (BLPOP/BRPOP/BLMOVE). Waiters are queued per
+// key in arrival order and served by whichever push makes one of their keys non
+// empty, so the pushed element goes straight to the longest waiting client rather
+// than whoever happens to poll first. The waiter itself never holds the store lock
+type ListWaiter struct {
+	keys     []string
+	left     bool // pop from the head
+	move     bool // BLMOVE, push the popped element onto dest
+	dest     string
+	destLeft bool
+	served   bool
+	result   chan ListPop
+}
+
+// an element popped from a list, either directly or on behalf of a blocked client.
+// Moves record where the element was pushed so they can be propagated as LMOVE
+type ListPop struct {
+	Key      string
+	Value    string
+	Left     bool
+	Move     bool
+	Dest     string
+	DestLeft bool
+}
+
+// receives the pop once served, closed without one if the waiter was dropped because
+// the destination of a move no longer holds a list
+func (w *ListWaiter) Result() <-chan ListPop {
+	return w.result
+}
+
+// pops from the first non empty list of the keys, or when all are empty registers
+// a waiter to be served by a later push
+func (k *KvStore) BlockListPop(keys []string, left bool) (*ListWaiter, *ListPop, error) {
+	w := &ListWaiter{keys: keys, left: left}
+	pop, _, err := k.block(w, true)
+	if pop != nil || err != nil {
+		return nil, pop, err
+	}
+
+	return w, nil, nil
+}
+
+// moves from the source to the destination, or when wait is set and the source is
+// empty registers a waiter. Clients blocked on the destination are served by the
+// move and returned alongside it
+func (k *KvStore) BlockListMove(source string, dest string, left bool, destLeft bool, wait bool) (*ListWaiter, *ListPop, []ListPop, error) {
+	w := &ListWaiter{keys: []string{source}, left: left, move: true, dest: dest, destLeft: destLeft}
+	pop, served, err := k.block(w, wait)
+	if pop != nil || err != nil || !wait {
+		return nil, pop, served, err
+	}
+
+	return w, nil, nil, nil
+}
+
+func (k *KvStore) block(w *ListWaiter, wait bool) (*ListPop, []ListPop, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if w.move {
+		if _, _, err := k.getList(w.dest); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, key := range w.keys {
+		list, exists, err := k.getList(key)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if exists && len(list) > 0 {
+			pop := k.popFor(w, key)
+
+			var served []ListPop
+			if pop.Move {
+				served = k.serveWaiters(pop.Dest)
+			}
+
+			return &pop, served, nil
+		}
+	}
+
+	if wait {
+		w.result = make(chan ListPop, 1)
+		for _, key := range w.keys {
+			k.waiters[key] = append(k.waiters[key], w)
+		}
+	}
+
+	return nil, nil, nil
+}
+
+// removes the waiter from all the queues, returning false if it has already been
+// served in which case the pop is waiting in Result
+func (k *KvStore) Unblock(w *ListWaiter) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if w.served {
+		return false
+	}
+
+	k.removeWaiter(w)
+	return true
+}
+
+// must be called with the write lock held
+func (k *KvStore) removeWaiter(w *ListWaiter) {
+	for _, key := range w.keys {
+		queue := k.waiters[key]
+		for i, queued := range queue {
+			if queued == w {
+				queue = append(queue[:i:i], queue[i+1:]...)
+				break
+			}
+		}
+
+		if len(queue) == 0 {
+			delete(k.waiters, key)
+		} else {
+			k.waiters[key] = queue
+		}
+	}
+}
+
+// hands elements to the clients blocked on the key in FIFO order whilst the list has
+// any, following on to the destinations of any moves. Must be called with the write
+// lock held, the pops are returned so the caller can propagate them
+func (k *KvStore) serveWaiters(key string) []ListPop {
+	var served []ListPop
+
+	ready := []string{key}
+	for len(ready) > 0 {
+		key, ready = ready[0], ready[1:]
+
+		for len(k.waiters[key]) > 0 {
+			list, exists, err := k.getList(key)
+			if err != nil || !exists || len(list) == 0 {
+				break
+			}
+
+			w := k.waiters[key][0]
+			if w.move {
+				// the destination may have changed type since the client blocked
+				if _, _, err := k.getList(w.dest); err != nil {
+					k.removeWaiter(w)
+					w.served = true
+					close(w.result)
+					continue
+				}
+			}
+
+			k.removeWaiter(w)
+			w.served = true
+
+			pop := k.popFor(w, key)
+			w.result <- pop
+			served = append(served, pop)
+
+			if pop.Move {
+				ready = append(ready, pop.Dest)
+			}
+		}
+	}
+
+	return served
+}
+
+// pops an element from the non empty list for the waiter, must be called with the write lock held
+func (k *KvStore) popFor(w *ListWaiter, key string) ListPop {
+	list := k.values[key].(List)
+
+	pop := ListPop{Key: key, Left: w.left, Move: w.move, Dest: w.dest, DestLeft: w.destLeft}
+
+	if w.left {
+		pop.Value, list = list[0], list[1:]
+	} else {
+		pop.Value, list = list[len(list)-1], list[:len(list)-1]
+	}
+
+	k.notify(EventList, popEvent(w.left), key)
+
+	if len(list) == 0 {
+		k.del(key)
+	} else {
+		k.values[key] = list
+		k.touch(key)
+	}
+
+	if w.move {
+		dest, exists, _ := k.getList(w.dest)
+		if w.destLeft {
+			dest = append(List{pop.Value}, dest...)
+		} else {
+			dest = append(dest, pop.Value)
+		}
+
+		if exists {
+			k.values[w.dest] = dest
+		} else {
+			k.create(w.dest, dest)
+		}
+		k.touch(w.dest)
+		k.notify(EventList, pushEvent(w.destLeft), w.dest)
+	}
+
+	return pop
+}
+
+// A client blocked reading streams (XREAD/XREADGROUP with BLOCK). Unlike list waiters
+// they aren't handed anything: an append to one of the keys wakes every waiter on it to
+// read again, as XREAD doesn't consume entries. For a group whoever reads first gets the
+// entries and the others block again
+type StreamWaiter struct {
+	keys  []string
+	ready chan struct{}
+}
+
+// closed once one of the streams may have something new to read
+func (w *StreamWaiter) Ready() <-chan struct{} {
+	return w.ready
+}
+
+// registers a waiter on the keys. It's registered before the streams are read, so any
+// append after the read wakes it
+func (k *KvStore) BlockStreams(keys []string) *StreamWaiter {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	w := &StreamWaiter{keys: keys, ready: make(chan struct{})}
+	for _, key := range keys {
+		k.streamWaiters[key] = append(k.streamWaiters[key], w)
+	}
+
+	return w
+}
+
+// removes the waiter if it hasn't been woken already
+func (k *KvStore) UnblockStreams(w *StreamWaiter) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.removeStreamWaiter(w)
+}
+
+// must be called with the write lock held
+func (k *KvStore) removeStreamWaiter(w *StreamWaiter) {
+	for _, key := range w.keys {
+		queue := k.streamWaiters[key]
+		for i, queued := range queue {
+			if queued == w {
+				queue = append(queue[:i:i], queue[i+1:]...)
+				break
+			}
+		}
+
+		if len(queue) == 0 {
+			delete(k.streamWaiters, key)
+		} else {
+			k.streamWaiters[key] = queue
+		}
+	}
+}
+
+// wakes the clients blocked on the stream, must be called with the write lock held
+func (k *KvStore) wakeStreamWaiters(key string) {
+	for _, w := range k.streamWaiters[key] {
+		k.removeStreamWaiter(w)
+		close(w.ready)
+	}
+}
+
+// must be called with the write lock held
+func (k *KvStore) wakeAllStreamWaiters() {
+	for key := range k.streamWaiters {
+		k.wakeStreamWaiters(key)
+	}
+}
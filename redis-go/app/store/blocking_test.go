@@ -0,0 +1,197 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func blockPop(t *testing.T, kv *KvStore, keys ...string) *ListWaiter {
+	t.Helper()
+
+	w, pop, err := kv.BlockListPop(keys, true)
+	if err != nil || pop != nil {
+		t.Fatalf("expected to block but got %v, %v", pop, err)
+	}
+	return w
+}
+
+// the pop the waiter was served, failing if it hasn't been
+func servedPop(t *testing.T, w *ListWaiter) ListPop {
+	t.Helper()
+
+	select {
+	case pop, ok := <-w.Result():
+		if !ok {
+			t.Fatal("expected a pop but the waiter was dropped")
+		}
+		return pop
+	default:
+		t.Fatal("expected the waiter to have been served")
+		return ListPop{}
+	}
+}
+
+func notServed(t *testing.T, w *ListWaiter) {
+	t.Helper()
+
+	select {
+	case pop := <-w.Result():
+		t.Errorf("expected the waiter not to be served but got %v", pop)
+	default:
+	}
+}
+
+func TestBlockedClientsAreServedInArrivalOrder(t *testing.T) {
+	// arrange
+	kv := NewKvStore(zerolog.Nop())
+	first := blockPop(t, kv, "l")
+	second := blockPop(t, kv, "l")
+	third := blockPop(t, kv, "l")
+
+	// act
+	_, served, err := kv.ListPush("l", []string{"a", "b"}, false)
+
+	// assert
+	if err != nil {
+		t.Fatalf("error pushing: %v", err)
+	}
+
+	if len(served) != 2 {
+		t.Fatalf("expected 2 clients served but got %d", len(served))
+	}
+
+	if pop := servedPop(t, first); pop.Value != "a" {
+		t.Errorf("expected the first client to get a but got %s", pop.Value)
+	}
+
+	if pop := servedPop(t, second); pop.Value != "b" {
+		t.Errorf("expected the second client to get b but got %s", pop.Value)
+	}
+
+	notServed(t, third)
+
+	if _, exists := kv.Get("l"); exists {
+		t.Error("expected the list to be emptied by the blocked clients")
+	}
+}
+
+func TestBlockedClientOnSeveralKeysIsServedOnce(t *testing.T) {
+	// arrange
+	kv := NewKvStore(zerolog.Nop())
+	both := blockPop(t, kv, "a", "b")
+	other := blockPop(t, kv, "b")
+
+	// act
+	kv.ListPush("a", []string{"1"}, false)
+	kv.ListPush("b", []string{"2"}, false)
+
+	// assert
+	if pop := servedPop(t, both); pop.Key != "a" || pop.Value != "1" {
+		t.Errorf("expected 1 from a but got %s from %s", pop.Value, pop.Key)
+	}
+
+	if pop := servedPop(t, other); pop.Key != "b" || pop.Value != "2" {
+		t.Errorf("expected the next client on b to get 2 but got %s from %s", pop.Value, pop.Key)
+	}
+
+	if len(kv.waiters) != 0 {
+		t.Errorf("expected no clients left waiting but got %v", kv.waiters)
+	}
+}
+
+func TestUnblockedClientIsntServed(t *testing.T) {
+	// arrange
+	kv := NewKvStore(zerolog.Nop())
+	gone := blockPop(t, kv, "a", "b")
+	waiting := blockPop(t, kv, "b")
+
+	// act - as when the client disconnects or times out
+	unblocked := kv.Unblock(gone)
+	kv.ListPush("a", []string{"1"}, false)
+	kv.ListPush("b", []string{"2"}, false)
+
+	// assert
+	if !unblocked {
+		t.Error("expected the waiter to be removed")
+	}
+
+	notServed(t, gone)
+
+	if pop := servedPop(t, waiting); pop.Value != "2" {
+		t.Errorf("expected the remaining client to get 2 but got %s", pop.Value)
+	}
+
+	if list, _, _ := kv.getList("a"); len(list) != 1 {
+		t.Errorf("expected the element to stay in the list but got %v", list)
+	}
+
+	if _, exists := kv.waiters["a"]; exists {
+		t.Error("expected the unblocked client to be removed from every key")
+	}
+}
+
+func TestUnblockAfterBeingServed(t *testing.T) {
+	// arrange
+	kv := NewKvStore(zerolog.Nop())
+	w := blockPop(t, kv, "l")
+	kv.ListPush("l", []string{"a"}, false)
+
+	// act
+	unblocked := kv.Unblock(w)
+
+	// assert - the pop is the client's, so mustn't be lost
+	if unblocked {
+		t.Error("expected the served waiter not to be removed")
+	}
+
+	if pop := servedPop(t, w); pop.Value != "a" {
+		t.Errorf("expected a but got %s", pop.Value)
+	}
+}
+
+func TestBlockedMoveServesClientsBlockedOnTheDestination(t *testing.T) {
+	// arrange
+	kv := NewKvStore(zerolog.Nop())
+	move, pop, _, _ := kv.BlockListMove("src", "dst", true, false, true)
+	if move == nil || pop != nil {
+		t.Fatalf("expected the move to block but got %v", pop)
+	}
+	onDest := blockPop(t, kv, "dst")
+
+	// act
+	_, served, _ := kv.ListPush("src", []string{"a"}, false)
+
+	// assert
+	if len(served) != 2 {
+		t.Fatalf("expected the move and the pop from its destination but got %v", served)
+	}
+
+	if got := servedPop(t, move); !got.Move || got.Dest != "dst" || got.Value != "a" {
+		t.Errorf("expected a moved to dst but got %v", got)
+	}
+
+	if got := servedPop(t, onDest); got.Key != "dst" || got.Value != "a" {
+		t.Errorf("expected a popped from dst but got %v", got)
+	}
+}
+
+func TestBlockedMoveDroppedWhenDestinationChangesType(t *testing.T) {
+	// arrange
+	kv := NewKvStore(zerolog.Nop())
+	move, _, _, _ := kv.BlockListMove("src", "dst", true, false, true)
+	next := blockPop(t, kv, "src")
+	kv.Set("dst", "not a list", ValueOptions{})
+
+	// act
+	kv.ListPush("src", []string{"a"}, false)
+
+	// assert
+	if _, ok := <-move.Result(); ok {
+		t.Error("expected the move to be dropped")
+	}
+
+	if pop := servedPop(t, next); pop.Value != "a" {
+		t.Errorf("expected the next client to get a but got %s", pop.Value)
+	}
+}
@@ -0,0 +1,95 @@
+package store
+
+import "sync"
+
+// serialises operations spanning two databases, so they can lock both without deadlocking
+var crossDbMu sync.Mutex
+
+// empties the database, clients blocked on its keys stay blocked
+func (k *KvStore) Flush() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.values = make(map[string]interface{})
+	k.expiries = make(map[string]uint64)
+	k.resetScanIndexes()
+	k.touchAll()
+}
+
+// swaps the contents of the databases, clients blocked on either stay with their database
+// and are served by any lists it now has. Returns the pops made for each
+func (k *KvStore) SwapWith(other *KvStore) ([]ListPop, []ListPop) {
+	if k == other {
+		return nil, nil
+	}
+
+	crossDbMu.Lock()
+	defer crossDbMu.Unlock()
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	other.mu.Lock()
+	defer other.mu.Unlock()
+
+	k.values, other.values = other.values, k.values
+	k.expiries, other.expiries = other.expiries, k.expiries
+	k.keyIndex, other.keyIndex = other.keyIndex, k.keyIndex
+	k.memberIndexes, other.memberIndexes = other.memberIndexes, k.memberIndexes
+	k.touchAll()
+	other.touchAll()
+	k.wakeAllStreamWaiters()
+	other.wakeAllStreamWaiters()
+
+	return k.serveAllWaiters(), other.serveAllWaiters()
+}
+
+// moves the key and its expiry to the destination database, unless it already has the key.
+// Returns whether the key was moved and the pops made for clients blocked on it there
+func (k *KvStore) MoveTo(key string, dst *KvStore) (bool, []ListPop) {
+	crossDbMu.Lock()
+	defer crossDbMu.Unlock()
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	dst.mu.Lock()
+	defer dst.mu.Unlock()
+
+	val, exists := k.lookup(key)
+	if !exists {
+		return false, nil
+	}
+
+	if _, exists := dst.lookup(key); exists {
+		return false, nil
+	}
+
+	dst.create(key, val)
+	if expiry, exists := k.expiries[key]; exists {
+		dst.expiries[key] = expiry
+	}
+	k.remove(key)
+	dst.touch(key)
+
+	k.notify(EventGeneric, "move_from", key)
+	dst.notify(EventGeneric, "move_to", key)
+	k.wakeStreamWaiters(key)
+	dst.wakeStreamWaiters(key)
+
+	return true, dst.serveWaiters(key)
+}
+
+// must be called with the write lock held
+func (k *KvStore) serveAllWaiters() []ListPop {
+	var served []ListPop
+
+	keys := make([]string, 0, len(k.waiters))
+	for key := range k.waiters {
+		keys = append(keys, key)
+	}
+
+	for _, key := range keys {
+		served = append(served, k.serveWaiters(key)...)
+	}
+
+	return served
+}
@@ -0,0 +1,184 @@
+package store
+
+import "time"
+
+/* Keys are expired both lazily, when a lookup finds the key past its expiry, and
+actively by sampling keys with an expiry in the background like redis does:
+
+	every 100ms sample 20 keys with an expiry, deleting those that have expired, and
+	repeat straight away if more than a quarter had expired (up to 25ms per cycle)
+
+so keys that are never looked up again don't live forever. Either way the expire
+hook is called so the deletion can be propagated. Replicas never expire keys
+themselves, they only hide them until the leader's DEL arrives
+
+see: https://redis.io/docs/latest/commands/expire/#how-redis-expires-keys
+*/
+
+const (
+	activeExpireInterval = 100 * time.Millisecond
+	activeExpireSample   = 20
+	activeExpireBudget   = 25 * time.Millisecond
+)
+
+type ExpireOptions struct {
+	NX bool // only when the key has no expiry
+	XX bool // only when the key has an expiry
+	GT bool // only when the new expiry is later, no expiry counts as never
+	LT bool // only when the new expiry is earlier, no expiry counts as never
+}
+
+// sets the func called whenever a key expires, e.g. to propagate the deletion. It's
+// called with the lock held so must not call back into the store
+func (k *KvStore) OnExpire(fn func(key string)) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.onExpire = fn
+}
+
+// replicas keep expired keys until the leader deletes them, so they don't diverge
+func (k *KvStore) SetReplicaMode(replica bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.replica = replica
+}
+
+// runs the active expire cycle until the process exits
+func (k *KvStore) ActiveExpire() {
+	ticker := time.NewTicker(activeExpireInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		k.activeExpireCycle()
+	}
+}
+
+func (k *KvStore) activeExpireCycle() {
+	start := time.Now()
+
+	for time.Since(start) < activeExpireBudget {
+		sampled, expired := k.sampleExpired()
+		if expired*4 <= sampled {
+			return
+		}
+	}
+}
+
+// expires keys among a random sample of those with an expiry
+func (k *KvStore) sampleExpired() (int, int) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.replica {
+		return 0, 0
+	}
+
+	ms := currentMillis()
+	sampled, expired := 0, 0
+
+	// map iteration starts at a random position
+	for key, expiry := range k.expiries {
+		if sampled == activeExpireSample {
+			break
+		}
+		sampled++
+
+		if ms > expiry {
+			k.expire(key)
+			expired++
+		}
+	}
+
+	return sampled, expired
+}
+
+// must be called with the lock held
+func (k *KvStore) expired(key string, ms uint64) bool {
+	expiry, exists := k.expiries[key]
+	return exists && ms > expiry
+}
+
+// must be called with the write lock held
+func (k *KvStore) expire(key string) {
+	k.remove(key)
+
+	if k.onExpire != nil {
+		k.onExpire(key)
+	}
+	k.notify(EventExpired, "expired", key)
+}
+
+// sets the expiry of the key to the unix time in ms, returning whether it was applied.
+// An expiry that's already passed deletes the key, except on replicas
+func (k *KvStore) Expire(key string, at uint64, options ExpireOptions) (applied bool, deleted bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if _, exists := k.lookup(key); !exists {
+		return false, false
+	}
+
+	current, hasExpiry := k.expiries[key]
+
+	switch {
+	case options.NX && hasExpiry,
+		options.XX && !hasExpiry,
+		options.GT && (!hasExpiry || at <= current),
+		options.LT && hasExpiry && at >= current:
+		return false, false
+	}
+
+	if at <= currentMillis() && !k.replica {
+		k.del(key)
+		return true, true
+	}
+
+	k.expiries[key] = at
+	k.touch(key)
+	k.notify(EventGeneric, "expire", key)
+
+	return true, false
+}
+
+// ms until the key expires, -1 when it has no expiry and -2 when it doesn't exist
+func (k *KvStore) PTTL(key string) int64 {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if _, exists := k.lookup(key); !exists {
+		return -2
+	}
+
+	expiry, exists := k.expiries[key]
+	if !exists {
+		return -1
+	}
+
+	ms := currentMillis()
+	if expiry < ms {
+		return 0
+	}
+
+	return int64(expiry - ms)
+}
+
+// removes the key's expiry, returning false if it had none
+func (k *KvStore) Persist(key string) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if _, exists := k.lookup(key); !exists {
+		return false
+	}
+
+	if _, exists := k.expiries[key]; !exists {
+		return false
+	}
+
+	delete(k.expiries, key)
+	k.touch(key)
+	k.notify(EventGeneric, "persist", key)
+	return true
+}
@@ -0,0 +1,180 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestActiveExpireRemovesKeysNeverLookedUp(t *testing.T) {
+	// arrange
+	kv := NewKvStore(zerolog.Nop())
+
+	var expired []string
+	kv.OnExpire(func(key string) { expired = append(expired, key) })
+
+	for i := 0; i < 100; i++ {
+		kv.Set(fmt.Sprintf("short:%d", i), "v", ValueOptions{ExpireAt: currentMillis() + 10})
+	}
+	kv.Set("long", "v", ValueOptions{ExpireAt: currentMillis() + 60_000})
+	kv.Set("forever", "v", ValueOptions{})
+	time.Sleep(20 * time.Millisecond)
+
+	// act - repeats whilst most of the sample has expired
+	kv.activeExpireCycle()
+
+	// assert
+	if len(kv.values) != 2 || len(kv.expiries) != 1 {
+		t.Errorf("expected only the unexpired keys left but got %d keys and %d expiries", len(kv.values), len(kv.expiries))
+	}
+
+	if len(expired) != 100 {
+		t.Errorf("expected the hook called for each of the 100 expired keys but got %d", len(expired))
+	}
+}
+
+func TestReplicaKeepsExpiredKeysHidden(t *testing.T) {
+	// arrange
+	kv := NewKvStore(zerolog.Nop())
+	kv.SetReplicaMode(true)
+
+	expired := 0
+	kv.OnExpire(func(key string) { expired++ })
+
+	kv.Set("k", "v", ValueOptions{ExpireAt: currentMillis() + 10})
+	time.Sleep(20 * time.Millisecond)
+
+	// act
+	kv.activeExpireCycle()
+	_, found := kv.Get("k")
+
+	// assert - hidden, but kept until the leader's DEL
+	if found {
+		t.Error("expected the expired key to be hidden")
+	}
+
+	if _, kept := kv.values["k"]; !kept || expired != 0 {
+		t.Errorf("expected the replica not to expire the key itself but it was expired %d times", expired)
+	}
+}
+
+func TestLookupExpiresLazily(t *testing.T) {
+	// arrange
+	kv := NewKvStore(zerolog.Nop())
+
+	var expired []string
+	kv.OnExpire(func(key string) { expired = append(expired, key) })
+
+	kv.Set("k", "v", ValueOptions{ExpireAt: currentMillis() + 10})
+	time.Sleep(20 * time.Millisecond)
+
+	// act
+	_, found := kv.Get("k")
+
+	// assert
+	if found {
+		t.Error("expected the expired key not to be found")
+	}
+
+	if _, kept := kv.values["k"]; kept || fmt.Sprint(expired) != "[k]" {
+		t.Errorf("expected k to be removed and its expiry propagated but got %v", expired)
+	}
+
+	if keys := kv.List("*"); len(keys) != 0 {
+		t.Errorf("expected no keys listed but got %v", keys)
+	}
+}
+
+func TestExpireOptions(t *testing.T) {
+	now := currentMillis()
+	sooner, later := now+10_000, now+20_000
+
+	cases := []struct {
+		name    string
+		current uint64 // 0 for no expiry
+		at      uint64
+		options ExpireOptions
+		applied bool
+	}{
+		{name: "no expiry", at: sooner, applied: true},
+		{name: "NX without an expiry", at: sooner, options: ExpireOptions{NX: true}, applied: true},
+		{name: "NX with an expiry", current: sooner, at: later, options: ExpireOptions{NX: true}, applied: false},
+		{name: "XX without an expiry", at: sooner, options: ExpireOptions{XX: true}, applied: false},
+		{name: "XX with an expiry", current: sooner, at: later, options: ExpireOptions{XX: true}, applied: true},
+		{name: "GT later", current: sooner, at: later, options: ExpireOptions{GT: true}, applied: true},
+		{name: "GT sooner", current: later, at: sooner, options: ExpireOptions{GT: true}, applied: false},
+		{name: "GT without an expiry", at: later, options: ExpireOptions{GT: true}, applied: false},
+		{name: "LT sooner", current: later, at: sooner, options: ExpireOptions{LT: true}, applied: true},
+		{name: "LT later", current: sooner, at: later, options: ExpireOptions{LT: true}, applied: false},
+		{name: "LT without an expiry", at: later, options: ExpireOptions{LT: true}, applied: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// arrange
+			kv := NewKvStore(zerolog.Nop())
+			kv.Set("k", "v", ValueOptions{ExpireAt: c.current})
+
+			// act
+			applied, deleted := kv.Expire("k", c.at, c.options)
+
+			// assert
+			if applied != c.applied || deleted {
+				t.Errorf("expected applied to be %t without deleting but got %t, %t", c.applied, applied, deleted)
+			}
+
+			want := c.current
+			if c.applied {
+				want = c.at
+			}
+
+			if got := kv.expiries["k"]; got != want {
+				t.Errorf("expected the expiry to be %d but got %d", want, got)
+			}
+		})
+	}
+}
+
+func TestExpireInThePastDeletes(t *testing.T) {
+	// arrange
+	kv := NewKvStore(zerolog.Nop())
+	kv.Set("k", "v", ValueOptions{})
+
+	// act
+	applied, deleted := kv.Expire("k", currentMillis()-1, ExpireOptions{})
+	missingApplied, _ := kv.Expire("missing", currentMillis()+1000, ExpireOptions{})
+
+	// assert
+	if !applied || !deleted {
+		t.Errorf("expected the key to be deleted but got %t, %t", applied, deleted)
+	}
+
+	if _, found := kv.Get("k"); found {
+		t.Error("expected the key to be gone")
+	}
+
+	if missingApplied {
+		t.Error("expected no expiry set on a missing key")
+	}
+}
+
+func TestStreamRangeExpiresLazily(t *testing.T) {
+	// arrange
+	kv := newStreamTestStore(t, 2)
+	kv.Expire("s", currentMillis()+10, ExpireOptions{})
+	time.Sleep(20 * time.Millisecond)
+
+	// act
+	entries, err := kv.XRange("s", StreamId{}, MaxStreamId, 0)
+
+	// assert
+	if len(entries) != 0 || err != nil {
+		t.Errorf("expected the expired stream not to be found but got %v, %v", entries, err)
+	}
+
+	if _, kept := kv.values["s"]; kept {
+		t.Error("expected the expired stream to be removed")
+	}
+}
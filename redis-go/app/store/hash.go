@@ -0,0 +1,184 @@
+package store
+
+import (
+	"errors"
+	"math"
+	"strconv"
+)
+
+type Hash = map[string]string
+
+var (
+	ErrHashValueNotInteger = errors.New("ERR hash value is not an integer")
+	ErrIncrOverflow        = errors.New("ERR increment or decrement would overflow")
+)
+
+// must be called with the lock held, missing keys are returned as nil
+func (k *KvStore) getHash(key string) (Hash, error) {
+	val, exists := k.lookup(key)
+	if !exists {
+		return nil, nil
+	}
+
+	hash, ok := val.(Hash)
+	if !ok {
+		return nil, ErrWrongType
+	}
+
+	return hash, nil
+}
+
+// sets the field value pairs, returning the number of fields that were added
+func (k *KvStore) HashSet(key string, pairs []string) (int, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	hash, err := k.getHash(key)
+	if err != nil {
+		return 0, err
+	}
+
+	if hash == nil {
+		hash = make(Hash, len(pairs)/2)
+		k.create(key, hash)
+	}
+
+	added := 0
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if _, exists := hash[pairs[i]]; !exists {
+			k.indexMember(key, pairs[i])
+			added++
+		}
+		hash[pairs[i]] = pairs[i+1]
+	}
+
+	k.touch(key)
+	k.notify(EventHash, "hset", key)
+
+	return added, nil
+}
+
+// values of the fields in order, with exists false for fields not in the hash
+func (k *KvStore) HashGet(key string, fields []string) ([]string, []bool, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	hash, err := k.getHash(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	values := make([]string, len(fields))
+	exists := make([]bool, len(fields))
+	for i, field := range fields {
+		values[i], exists[i] = hash[field]
+	}
+
+	return values, exists, nil
+}
+
+// copy of the hash, empty when the key doesn't exist
+func (k *KvStore) HashGetAll(key string) (Hash, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	hash, err := k.getHash(key)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(Hash, len(hash))
+	for field, value := range hash {
+		result[field] = value
+	}
+
+	return result, nil
+}
+
+// removes the fields returning how many existed, an empty hash is removed
+func (k *KvStore) HashDel(key string, fields []string) (int, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	hash, err := k.getHash(key)
+	if err != nil || hash == nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, field := range fields {
+		if _, exists := hash[field]; exists {
+			delete(hash, field)
+			k.unindexMember(key, field)
+			removed++
+		}
+	}
+
+	if removed > 0 {
+		k.notify(EventHash, "hdel", key)
+	}
+
+	if len(hash) == 0 {
+		k.del(key)
+	} else if removed > 0 {
+		k.touch(key)
+	}
+
+	return removed, nil
+}
+
+func (k *KvStore) HashIncrBy(key string, field string, incr int64) (int64, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	hash, err := k.getHash(key)
+	if err != nil {
+		return 0, err
+	}
+
+	var current int64
+	if value, exists := hash[field]; exists {
+		current, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return 0, ErrHashValueNotInteger
+		}
+	}
+
+	if (incr > 0 && current > math.MaxInt64-incr) || (incr < 0 && current < math.MinInt64-incr) {
+		return 0, ErrIncrOverflow
+	}
+
+	if hash == nil {
+		hash = make(Hash)
+		k.create(key, hash)
+	}
+
+	current += incr
+	hash[field] = strconv.FormatInt(current, 10)
+	k.indexMember(key, field)
+	k.touch(key)
+	k.notify(EventHash, "hincrby", key)
+
+	return current, nil
+}
+
+func (k *KvStore) HashExists(key string, field string) (bool, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	hash, err := k.getHash(key)
+	if err != nil {
+		return false, err
+	}
+
+	_, exists := hash[field]
+	return exists, nil
+}
+
+func (k *KvStore) HashLen(key string) (int, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	hash, err := k.getHash(key)
+	return len(hash), err
+}
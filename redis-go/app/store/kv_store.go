@@ -2,315 +2,524 @@ package store
 
 import (
 	"errors"
-	"fmt"
-	"os"
+	"math"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/codecrafters-io/redis-starter-go/app/glob"
 	"github.com/codecrafters-io/redis-starter-go/app/rdb"
 	"github.com/rs/zerolog"
 )
 
 type KvStore struct {
-	logger   zerolog.Logger
-	values   map[string]interface{}
-	expiries map[string]uint64
-	valqueue map[string]interface{}
-	expqueue map[string]uint64
-	mu       sync.RWMutex
+	logger        zerolog.Logger
+	values        map[string]interface{}
+	expiries      map[string]uint64
+	valqueue      map[string]interface{}
+	expqueue      map[string]uint64
+	waiters       map[string][]*ListWaiter                         // clients blocked on each list, in arrival order
+	streamWaiters map[string][]*StreamWaiter                       // clients blocked reading each stream
+	onExpire      func(key string)                                 // called with the lock held when a key expires
+	onKeyEvent    func(class EventClass, event string, key string) // see notify.go
+	watched       map[string]*watchedKey                           // keys watched by at least one client
+	version       uint64                                           // incremented whenever a watched key is modified
+	replica       bool                                             // expired keys are left for the leader to delete
+	keyIndex      *scanIndex                                       // the keys ordered for SCAN, nil until first scanned
+	memberIndexes map[string]*scanIndex                            // each scanned hash & set's fields ordered for scanning
+	mu            sync.RWMutex
 }
 
 type ValueOptions struct {
-	Expiry uint64
+	ExpireAt uint64 // unix time in ms, 0 for no expiry
+	KeepTTL  bool   // keep the existing expiry rather than clearing it
+	NX       bool   // only set when the key doesn't exist
+	XX       bool   // only set when the key already exists
+	Get      bool   // fail with ErrWrongType if the existing value isn't a string
+}
+
+type SetResult struct {
+	Old     interface{} // the value before the set, nil if the key didn't exist
+	Written bool        // false when NX or XX prevented the set
 }
 
 func NewKvStore(logger zerolog.Logger) *KvStore {
 	return &KvStore{
-		values:   make(map[string]interface{}),
-		expiries: make(map[string]uint64),
-		valqueue: make(map[string]interface{}),
-		expqueue: make(map[string]uint64),
-		logger:   logger,
+		values:        make(map[string]interface{}),
+		expiries:      make(map[string]uint64),
+		valqueue:      make(map[string]interface{}),
+		expqueue:      make(map[string]uint64),
+		waiters:       make(map[string][]*ListWaiter),
+		streamWaiters: make(map[string][]*StreamWaiter),
+		watched:       make(map[string]*watchedKey),
+		memberIndexes: make(map[string]*scanIndex),
+		logger:        logger,
 	}
 }
 
-func (k *KvStore) InitialiseFromRdbFile(filepath string, filename string) {
-	k.logger.Info().Str("path", filepath).Str("filename", filename).Msg("loading rdb from file")
+// replaces the contents of the store with the rdb database
+func (k *KvStore) Load(db rdb.RedisDatabase) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
 
-	file, err := rdb.ReadRdbFromFile(filepath, filename)
+	k.values = make(map[string]interface{}, len(db.Keys))
+	k.expiries = make(map[string]uint64, len(db.Expiries))
+	k.resetScanIndexes()
 
-	if err != nil {
-		if os.IsNotExist(err) {
-			k.logger.Info().Msg("No rdb file found")
-			return
+	for key, value := range db.Keys {
+		switch v := value.(type) {
+		case rdb.Stream:
+			k.values[key] = streamFromRdb(v)
+		case rdb.List:
+			k.values[key] = List(v)
+		case rdb.Hash:
+			k.values[key] = Hash(v)
+		case rdb.Set:
+			k.values[key] = Set(v)
+		case rdb.SortedSet:
+			zset := NewSortedSet()
+			for member, score := range v {
+				zset.Add(member, score)
+			}
+			k.values[key] = zset
+		default:
+			k.values[key] = v
 		}
-
-		k.logger.Fatal().Err(err).Msg("Error reading rdb file")
-		os.Exit(1)
-	}
-
-	if len(file.Databases) == 0 {
-		k.logger.Info().Msg("no databases found in rdb file")
-		return
 	}
 
-	if len(file.Databases) > 1 {
-		k.logger.Fatal().Int("databases", len(file.Databases)).Msg("currently only supports loading a single database from rdb file but got multiple")
+	for key, value := range db.Expiries {
+		k.expiries[key] = value
 	}
 
-	db := file.Databases[0]
-	k.logger.Info().Int("keycount", len(db.Keys)).Int("expirycount", len(db.Expiries)).Msg("loading db")
+	k.touchAll()
+}
 
+// point in time copy of the store for persisting, expired keys are skipped
+func (k *KvStore) Snapshot() rdb.RedisDatabase {
 	k.mu.RLock()
 	defer k.mu.RUnlock()
 
-	for key, value := range db.Keys {
-		k.values[key] = value
+	ms := currentMillis()
+	db := rdb.RedisDatabase{
+		Keys:     make(map[string]interface{}, len(k.values)),
+		Expiries: make(map[string]uint64, len(k.expiries)),
 	}
 
-	for key, value := range db.Expiries {
-		k.expiries[key] = value
+	for key, value := range k.values {
+		if k.expired(key, ms) {
+			continue
+		}
+		expiry, exists := k.expiries[key]
+
+		switch v := value.(type) {
+		case *Stream:
+			db.Keys[key] = streamToRdb(v)
+		case List:
+			db.Keys[key] = rdb.List(append([]string(nil), v...))
+		case Hash:
+			hash := make(rdb.Hash, len(v))
+			for field, value := range v {
+				hash[field] = value
+			}
+			db.Keys[key] = hash
+		case Set:
+			set := make(rdb.Set, len(v))
+			for member := range v {
+				set[member] = struct{}{}
+			}
+			db.Keys[key] = set
+		case *SortedSet:
+			zset := make(rdb.SortedSet, v.Len())
+			for member, score := range v.scores {
+				zset[member] = score
+			}
+			db.Keys[key] = zset
+		default:
+			db.Keys[key] = v
+		}
+
+		if exists {
+			db.Expiries[key] = expiry
+		}
 	}
+
+	return db
 }
 
 func (k *KvStore) Get(key string) (interface{}, bool) {
-	k.mu.RLock()
-	defer k.mu.RUnlock()
+	k.mu.Lock()
+	defer k.mu.Unlock()
 
-	ms := currentMillis()
-	expiry, exists := k.expiries[key]
-	if exists && ms > expiry {
-		delete(k.expiries, key)
-		return "", false
+	return k.lookup(key)
+}
+
+var ErrWrongType = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+
+// value for the key treating expired keys as missing, must be called with the write lock held
+func (k *KvStore) lookup(key string) (interface{}, bool) {
+	if k.expired(key, currentMillis()) {
+		if !k.replica {
+			k.expire(key)
+		}
+		return nil, false
 	}
 
 	val, exists := k.values[key]
-
 	return val, exists
 }
 
+// must be called with the write lock held
+func (k *KvStore) remove(key string) {
+	delete(k.values, key)
+	delete(k.expiries, key)
+	k.unindexKey(key)
+	k.touch(key)
+}
+
 func (k *KvStore) List(pattern string) []string {
 	k.mu.RLock()
 	defer k.mu.RUnlock()
 
 	var keys []string
 
+	ms := currentMillis()
 	for key := range k.values {
-		keys = append(keys, key) // TODO for this stage we are not filtering by pattern
+		if k.expired(key, ms) || !glob.Match(pattern, key) {
+			continue
+		}
+		keys = append(keys, key)
 	}
 
 	return keys
 }
 
-func (k *KvStore) Set(key string, value interface{}, options ValueOptions) error {
-	ms := currentMillis()
+// sets the value, checking the NX and XX conditions atomically with the write
+func (k *KvStore) Set(key string, value interface{}, options ValueOptions) (SetResult, error) {
 	k.mu.Lock()
 	defer k.mu.Unlock()
 
-	if options.Expiry != 0 {
-		k.expiries[key] = ms + options.Expiry
+	old, exists := k.lookup(key)
+	result := SetResult{Old: old}
+
+	if options.Get && exists {
+		if _, ok := old.(string); !ok {
+			return result, ErrWrongType
+		}
+	}
+
+	if (options.NX && exists) || (options.XX && !exists) {
+		return result, nil
+	}
+
+	if options.ExpireAt != 0 {
+		k.expiries[key] = options.ExpireAt
+	} else if !options.KeepTTL {
+		delete(k.expiries, key)
+	}
+
+	if exists {
+		k.values[key] = value
+		delete(k.memberIndexes, key)
+	} else {
+		k.create(key, value)
+	}
+	k.touch(key)
+	result.Written = true
+
+	k.notify(EventString, "set", key)
+	if options.ExpireAt != 0 {
+		k.notify(EventGeneric, "expire", key)
+	}
+
+	return result, nil
+}
+
+var ErrNotInteger = errors.New("ERR value is not an integer or out of range")
+
+// adds incr to the integer held by the key, treating a missing key as 0, and returns the
+// result. The key keeps its expiry
+func (k *KvStore) IncrBy(key string, incr int64) (int64, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	val, exists := k.lookup(key)
+
+	var current int64
+	if exists {
+		s, ok := val.(string)
+		if !ok {
+			return 0, ErrWrongType
+		}
+
+		var err error
+		current, err = strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return 0, ErrNotInteger
+		}
+	}
+
+	if (incr > 0 && current > math.MaxInt64-incr) || (incr < 0 && current < math.MinInt64-incr) {
+		return 0, ErrIncrOverflow
+	}
+
+	current += incr
+	if exists {
+		k.values[key] = strconv.FormatInt(current, 10)
+	} else {
+		k.create(key, strconv.FormatInt(current, 10))
 	}
+	k.touch(key)
+	k.notify(EventString, "incrby", key)
+
+	return current, nil
+}
 
-	k.values[key] = value
+// deletes the keys returning how many existed
+func (k *KvStore) Delete(keys []string) int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
 
-	return nil
+	deleted := 0
+	for _, key := range keys {
+		if _, exists := k.lookup(key); exists {
+			k.del(key)
+			deleted++
+		}
+	}
+
+	return deleted
 }
 
 type StreamKey = string
 type StreamSeqKey = string
 type StreamEntry = struct {
 	Seqkey StreamSeqKey
-	Values map[string]interface{}
+	Fields []string // field/value pairs in the order added, nil for a deleted entry a group delivered
 }
 
-type Stream = []StreamEntry
+type Stream struct {
+	Entries      []StreamEntry
+	LastId       StreamId                  // the last id added, kept when it's deleted so ids only go up
+	MaxDeletedId StreamId                  // the largest id deleted with XDEL, trimming doesn't count
+	EntriesAdded uint64                    // every entry ever added, including those since removed
+	Groups       map[string]*ConsumerGroup // see stream_group.go
+}
 
 // {
 // 	"stream_key": [
 // 		{
 // 			seqkey: "1526985054069-0"
-// 			values: { "foo": "bar" }
+// 			fields: ["foo", "bar"]
 // 		}
 // 	]
 // }
 
-var (
-	ErrInvalidStream   = errors.New("expected stream to be a stream")
-	ErrStreamNotExists = errors.New("stream doesn't exist")
-)
+var ErrInvalidStream = ErrWrongType
+
+type StreamAddOptions struct {
+	NoMkStream bool        // don't create the stream when it doesn't exist
+	Trim       *StreamTrim // see stream.go, nil to not trim
+}
+
+type StreamAddResult struct {
+	Id      string // empty when NoMkStream and the stream doesn't exist
+	Trimmed int    // entries removed by trimming
+	Length  int    // after trimming
+}
 
-func (k *KvStore) SetStream(streamkey string, seqkey string, key string, value interface{}, options ValueOptions) (string, error) {
+// appends an entry with the fields, seqkey being the id, * for one generated from the
+// current time or <ms>-* for the next sequence number of the ms
+func (k *KvStore) SetStream(streamkey string, seqkey string, fields []string, options StreamAddOptions) (StreamAddResult, error) {
 	ms := currentMillis()
 	k.mu.Lock()
 	defer k.mu.Unlock()
 
-	stream, exists := k.values[streamkey]
-	if !exists {
-		stream = make(Stream, 0)
-		k.values[streamkey] = stream
+	stream, err := k.getStream(streamkey)
+	if err != nil {
+		return StreamAddResult{}, err
 	}
 
-	cast, ok := stream.(Stream)
-	if !ok {
-		return "", ErrInvalidStream
+	exists := stream != nil
+	if !exists {
+		if options.NoMkStream {
+			return StreamAddResult{}, nil
+		}
+		stream = &Stream{Groups: make(map[string]*ConsumerGroup)}
 	}
 
-	split := strings.Split(seqkey, "-")
+	id, err := nextStreamId(seqkey, stream.LastId, ms)
+	if err != nil {
+		return StreamAddResult{}, err
+	}
 
-	rtime := 0
-	rseq := 0
-	rseqwildcard := false
+	seqkey = id.String()
 
-	if len(split) == 1 {
-		rtime = int(ms)
-		rseq = 0
-	} else {
-		rtime, _ = strconv.Atoi(split[0])
-		r, rseqerr := strconv.Atoi(split[1])
-		rseqwildcard = rseqerr != nil
-		rseq = r
+	stream.Entries = append(stream.Entries, StreamEntry{Seqkey: seqkey, Fields: fields})
+	stream.LastId = id
+	stream.EntriesAdded++
+	if !exists {
+		k.create(streamkey, stream)
+	}
+	k.touch(streamkey)
+	k.notify(EventStream, "xadd", streamkey)
 
-		if rseqwildcard && rtime == 0 {
-			rseq = 1
+	result := StreamAddResult{Id: seqkey}
+	if options.Trim != nil {
+		if result.Trimmed = stream.trim(*options.Trim); result.Trimmed > 0 {
+			k.notify(EventStream, "xtrim", streamkey)
 		}
 	}
+	result.Length = len(stream.Entries)
 
-	if rtime <= 0 && rseq <= 0 {
-		return "", errors.New("ERR The ID specified in XADD must be greater than 0-0")
-	}
+	k.wakeStreamWaiters(streamkey)
 
-	if len(cast) != 0 {
-		last := cast[len(cast)-1]
+	return result, nil
+}
 
-		split = strings.Split(last.Seqkey, "-")
-		ltime, _ := strconv.Atoi(split[0])
-		lseq, _ := strconv.Atoi(split[1])
+// the id for a new entry after last, generating the parts given as *
+func nextStreamId(seqkey string, last StreamId, ms uint64) (StreamId, error) {
+	smallerErr := errors.New("ERR The ID specified in XADD is equal or smaller than the target stream top item")
 
-		if rseqwildcard && rtime == ltime {
-			rseq = lseq + 1
+	if seqkey == "*" {
+		if ms > last.Ms {
+			return StreamId{Ms: ms}, nil
 		}
-
-		if ltime > rtime || (ltime == rtime && lseq >= rseq) {
-			return "", errors.New("ERR The ID specified in XADD is equal or smaller than the target stream top item")
+		if last == MaxStreamId {
+			return StreamId{}, errors.New("ERR The stream has exhausted the last possible ID, unable to add more items")
 		}
+		return last.next(), nil // the clock went backwards or it's the same ms
 	}
 
-	seqkey = fmt.Sprintf("%d-%d", rtime, rseq)
-
-	cast = append(cast, StreamEntry{Seqkey: seqkey, Values: map[string]interface{}{key: value}})
-	k.values[streamkey] = cast
-
-	return seqkey, nil
-}
-
-func (k *KvStore) GetStream(streamkey string, start string, end string) ([]StreamEntry, error) {
-	k.mu.Lock()
-	defer k.mu.Unlock()
+	if msPart, found := strings.CutSuffix(seqkey, "-*"); found {
+		idMs, err := strconv.ParseUint(msPart, 10, 64)
+		if err != nil {
+			return StreamId{}, ErrInvalidStreamId
+		}
 
-	stream, exists := k.values[streamkey]
-	if !exists {
-		return make([]StreamEntry, 0), ErrStreamNotExists
+		switch {
+		case idMs < last.Ms || (idMs == last.Ms && last.Seq == math.MaxUint64):
+			return StreamId{}, smallerErr
+		case idMs == last.Ms:
+			return StreamId{Ms: idMs, Seq: last.Seq + 1}, nil
+		case idMs == 0:
+			return StreamId{Seq: 1}, nil
+		default:
+			return StreamId{Ms: idMs}, nil
+		}
 	}
 
-	cast, ok := stream.(Stream)
-	if !ok {
-		return make([]StreamEntry, 0), ErrInvalidStream
+	id, err := ParseStreamId(seqkey, 0)
+	if err != nil {
+		return StreamId{}, err
 	}
 
-	l := k.findl(start, cast)
-	r := k.findr(end, cast)
-
-	k.logger.Info().Int("start_i", l).Int("end_i", r).Msg("found stream search params")
-
-	return cast[l:r], nil
-}
+	if id == (StreamId{}) {
+		return StreamId{}, errors.New("ERR The ID specified in XADD must be greater than 0-0")
+	}
 
-func (k *KvStore) findl(start string, stream Stream) int {
-	if start == "-" {
-		return 0
+	if !last.Less(id) {
+		return StreamId{}, smallerErr
 	}
 
-	starttime, startseq := SplitSeqKey(start)
-	k.logger.Info().Int("start_t", starttime).Int("start_s", startseq).Msg("searching stream")
+	return id, nil
+}
 
-	l := 0
-	for i, entry := range stream {
-		ctime, cseq := SplitSeqKey(entry.Seqkey)
+// up to count entries (0 for all) with ids from start to end inclusive, none if the
+// stream doesn't exist
+func (k *KvStore) XRange(streamkey string, start StreamId, end StreamId, count int) ([]StreamEntry, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
 
-		c := compare(ctime, cseq, starttime, startseq)
+	stream, err := k.getStream(streamkey)
+	if err != nil || stream == nil {
+		return nil, err
+	}
 
-		if c <= 0 {
-			l = i
+	var entries []StreamEntry
+	for i := stream.search(start); i < len(stream.Entries); i++ {
+		if end.Less(entryId(stream.Entries[i])) || (count > 0 && len(entries) == count) {
 			break
 		}
+		entries = append(entries, stream.Entries[i])
 	}
 
-	return l
+	return entries, nil
 }
 
-func (k *KvStore) findr(end string, stream Stream) int {
-	if end == "+" {
-		return len(stream)
-	}
-
-	endtime, endseq := SplitSeqKey(end)
-	k.logger.Info().Int("end_t", endtime).Int("end_s", endseq).Int("len", len(stream)).Msg("searching stream")
+// up to count entries (0 for all) with ids after start, none if the stream doesn't exist
+func (k *KvStore) XReadStream(streamkey string, start StreamId, count int) ([]StreamEntry, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
 
-	r := len(stream) - 1
-	for i := len(stream) - 1; i >= 0; i-- {
-		entry := stream[i]
-		ctime, cseq := SplitSeqKey(entry.Seqkey)
+	stream, err := k.getStream(streamkey)
+	if err != nil || stream == nil {
+		return nil, err
+	}
 
-		c := compare(ctime, cseq, endtime, endseq)
-		if c >= 0 {
-			r = i + 1
-			break
-		}
+	entries := stream.Entries[stream.search(start.next()):]
+	if count > 0 && len(entries) > count {
+		entries = entries[:count]
 	}
 
-	return r
+	return entries, nil
 }
 
-func (k *KvStore) XReadStream(streamkey string, start string) ([]StreamEntry, error) {
+// the last id added to the stream, 0-0 if it's never had an entry or doesn't exist
+func (k *KvStore) StreamLastId(streamkey string) (StreamId, error) {
 	k.mu.Lock()
 	defer k.mu.Unlock()
 
-	stream, exists := k.values[streamkey]
-	if !exists {
-		return make([]StreamEntry, 0), ErrStreamNotExists
+	stream, err := k.getStream(streamkey)
+	if err != nil || stream == nil {
+		return StreamId{}, err
 	}
 
-	cast, ok := stream.(Stream)
-	if !ok {
-		return make([]StreamEntry, 0), ErrInvalidStream
-	}
+	return stream.LastId, nil
+}
 
-	l := k.xreadfindl(start, cast)
+func streamToRdb(stream *Stream) rdb.Stream {
+	result := rdb.Stream{
+		Entries:      make([]rdb.StreamEntry, 0, len(stream.Entries)),
+		LastId:       rdb.StreamId(stream.LastId),
+		MaxDeletedId: rdb.StreamId(stream.MaxDeletedId),
+		EntriesAdded: stream.EntriesAdded,
+		Groups:       groupsToRdb(stream.Groups),
+	}
 
-	k.logger.Info().Int("start_i", l).Int("end_i", len(cast)).Msg("found stream search params")
+	for _, entry := range stream.Entries {
+		id := entryId(entry)
+		result.Entries = append(result.Entries, rdb.StreamEntry{
+			Id:     rdb.StreamId(id),
+			Fields: append([]string(nil), entry.Fields...),
+		})
+	}
 
-	return cast[l:], nil
+	return result
 }
 
-func (k *KvStore) xreadfindl(start string, stream Stream) int {
-	starttime, startseq := SplitSeqKey(start)
-	k.logger.Info().Int("start_t", starttime).Int("start_s", startseq).Msg("searching stream")
-
-	l := 0
-	for i, entry := range stream {
-		ctime, cseq := SplitSeqKey(entry.Seqkey)
+func streamFromRdb(stream rdb.Stream) *Stream {
+	result := &Stream{
+		Entries:      make([]StreamEntry, 0, len(stream.Entries)),
+		LastId:       StreamId(stream.LastId),
+		MaxDeletedId: StreamId(stream.MaxDeletedId),
+		EntriesAdded: stream.EntriesAdded,
+		Groups:       groupsFromRdb(stream.Groups),
+	}
 
-		c := compare(ctime, cseq, starttime, startseq)
+	for _, entry := range stream.Entries {
+		result.Entries = append(result.Entries, StreamEntry{
+			Seqkey: StreamId(entry.Id).String(),
+			Fields: entry.Fields,
+		})
+	}
 
-		if c < 0 { // xread is exclusive - start needs to be bigger
-			l = i
-			break
-		}
+	// older files may not have the last id
+	if last := result.lastEntryId(); result.LastId.Less(last) {
+		result.LastId = last
 	}
 
-	return l
+	return result
 }
 
 func SplitSeqKey(key string) (int, int) {
@@ -330,18 +539,6 @@ func SplitSeqKey(key string) (int, int) {
 	return time, seq
 }
 
-func compare(ltime int, lseq int, rtime int, rseq int) int {
-	if ltime == rtime && lseq == rseq {
-		return 0 // same
-	}
-
-	if ltime > rtime || (ltime == rtime && lseq >= rseq) {
-		return -1 // left bigger
-	}
-
-	return 1 // right bigger
-}
-
 func currentMillis() uint64 {
 	return uint64(time.Now().UnixNano() / int64(time.Millisecond))
 }
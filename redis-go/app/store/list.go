@@ -0,0 +1,225 @@
+package store
+
+import "errors"
+
+type List = []string
+
+var (
+	ErrNoSuchKey       = errors.New("ERR no such key")
+	ErrIndexOutOfRange = errors.New("ERR index out of range")
+)
+
+// must be called with the lock held, missing keys are returned as an empty list
+func (k *KvStore) getList(key string) (List, bool, error) {
+	val, exists := k.lookup(key)
+	if !exists {
+		return nil, false, nil
+	}
+
+	list, ok := val.(List)
+	if !ok {
+		return nil, false, ErrWrongType
+	}
+
+	return list, true, nil
+}
+
+// pushes the values onto the head (left) or tail of the list one at a time, so
+// LPUSH k a b c leaves the list as c b a. Returns the new length before any clients
+// blocked on the key were served, along with what they popped
+func (k *KvStore) ListPush(key string, values []string, left bool) (int, []ListPop, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	list, exists, err := k.getList(key)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if left {
+		pushed := make(List, 0, len(values)+len(list))
+		for i := len(values) - 1; i >= 0; i-- {
+			pushed = append(pushed, values[i])
+		}
+		list = append(pushed, list...)
+	} else {
+		list = append(list, values...)
+	}
+
+	if exists {
+		k.values[key] = list
+	} else {
+		k.create(key, list)
+	}
+	k.touch(key)
+	k.notify(EventList, pushEvent(left), key)
+
+	return len(list), k.serveWaiters(key), nil
+}
+
+// pops up to count values from the head (left) or tail, an empty list is removed
+func (k *KvStore) ListPop(key string, count int, left bool) ([]string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	list, exists, err := k.getList(key)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	count = min(count, len(list))
+	popped := make([]string, 0, count)
+
+	if left {
+		popped = append(popped, list[:count]...)
+		list = list[count:]
+	} else {
+		for i := len(list) - 1; i >= len(list)-count; i-- {
+			popped = append(popped, list[i])
+		}
+		list = list[:len(list)-count]
+	}
+
+	k.notify(EventList, popEvent(left), key)
+
+	if len(list) == 0 {
+		k.del(key)
+	} else {
+		k.values[key] = list
+		k.touch(key)
+	}
+
+	return popped, nil
+}
+
+func (k *KvStore) ListLen(key string) (int, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	list, _, err := k.getList(key)
+	return len(list), err
+}
+
+// inclusive range where negative indexes count from the tail, same as LRANGE
+func (k *KvStore) ListRange(key string, start int, stop int) ([]string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	list, _, err := k.getList(key)
+	if err != nil {
+		return nil, err
+	}
+
+	l, r := listRange(len(list), start, stop)
+
+	return append([]string(nil), list[l:r]...), nil
+}
+
+func (k *KvStore) ListIndex(key string, index int) (string, bool, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	list, _, err := k.getList(key)
+	if err != nil {
+		return "", false, err
+	}
+
+	i, ok := listIndex(len(list), index)
+	if !ok {
+		return "", false, nil
+	}
+
+	return list[i], true, nil
+}
+
+func (k *KvStore) ListSet(key string, index int, value string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	list, exists, err := k.getList(key)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return ErrNoSuchKey
+	}
+
+	i, ok := listIndex(len(list), index)
+	if !ok {
+		return ErrIndexOutOfRange
+	}
+
+	list[i] = value
+	k.touch(key)
+	k.notify(EventList, "lset", key)
+
+	return nil
+}
+
+// keeps only the inclusive range, removing the list if nothing is left
+func (k *KvStore) ListTrim(key string, start int, stop int) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	list, exists, err := k.getList(key)
+	if err != nil || !exists {
+		return err
+	}
+
+	k.notify(EventList, "ltrim", key)
+
+	l, r := listRange(len(list), start, stop)
+	if l >= r {
+		k.del(key)
+		return nil
+	}
+
+	// copy so the trimmed elements can be collected
+	k.values[key] = append(List(nil), list[l:r]...)
+	k.touch(key)
+
+	return nil
+}
+
+func pushEvent(left bool) string {
+	if left {
+		return "lpush"
+	}
+	return "rpush"
+}
+
+func popEvent(left bool) string {
+	if left {
+		return "lpop"
+	}
+	return "rpop"
+}
+
+// converts an index which may be negative to a position in a list of the length
+func listIndex(length int, index int) (int, bool) {
+	if index < 0 {
+		index += length
+	}
+
+	return index, index >= 0 && index < length
+}
+
+// converts an inclusive start/stop which may be negative to a slice range
+func listRange(length int, start int, stop int) (int, int) {
+	if start < 0 {
+		start = max(start+length, 0)
+	}
+
+	if stop < 0 {
+		stop += length
+	}
+
+	stop = min(stop, length-1)
+
+	if start > stop || start >= length {
+		return 0, 0
+	}
+
+	return start, stop + 1
+}
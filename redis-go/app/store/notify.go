@@ -0,0 +1,58 @@
+package store
+
+/* Mutations report keyspace events through the hook set with OnKeyEvent, each tagged
+with its class so the notify-keyspace-events flags can select which are published. The
+class is the flag character that selects it. Events follow redis' naming, e.g. LPUSH
+reports lpush, a list emptied by a pop reports lpop then del, and a key created by any
+write reports new first
+
+see: https://redis.io/docs/latest/develop/use/keyspace-notifications/
+*/
+
+type EventClass byte
+
+const (
+	EventGeneric EventClass = 'g' // del, expire, persist, move_from, move_to
+	EventString  EventClass = '$'
+	EventList    EventClass = 'l'
+	EventSet     EventClass = 's'
+	EventHash    EventClass = 'h'
+	EventZSet    EventClass = 'z'
+	EventStream  EventClass = 't'
+	EventExpired EventClass = 'x'
+	EventEvicted EventClass = 'e' // never reported as keys aren't evicted, there's no maxmemory
+	EventNew     EventClass = 'n'
+)
+
+// sets the func called with each keyspace event. Like the expire hook it's called with
+// the lock held so must not call back into the store
+func (k *KvStore) OnKeyEvent(fn func(class EventClass, event string, key string)) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.onKeyEvent = fn
+}
+
+// must be called with the write lock held
+func (k *KvStore) notify(class EventClass, event string, key string) {
+	if k.onKeyEvent != nil {
+		k.onKeyEvent(class, event, key)
+	}
+}
+
+// stores the value of a key that doesn't exist yet, must be called with the write lock held
+func (k *KvStore) create(key string, value interface{}) {
+	k.values[key] = value
+	if k.keyIndex != nil {
+		k.keyIndex.add(key)
+	}
+	k.notify(EventNew, "new", key)
+}
+
+// removes the key reporting a del event, e.g. for DEL or when a pop empties a list. Must
+// be called with the write lock held
+func (k *KvStore) del(key string) {
+	k.remove(key)
+	k.notify(EventGeneric, "del", key)
+	k.wakeStreamWaiters(key) // blocked group readers fail as the group has gone
+}
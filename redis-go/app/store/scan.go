@@ -0,0 +1,224 @@
+package store
+
+import (
+	"hash/fnv"
+	"math"
+)
+
+/* SCAN cursors are positions in the hash space of the elements' names. Each call returns
+the elements with hashes from the cursor up to the COUNT-th smallest, and the next cursor
+is the hash of the first element after them:
+
+	cursor 0 ----[ a  c  b ]----[ e  d ]---- ... ---- 2^53 (cursor 0, done)
+	                          ^ next cursor
+
+an element's hash never changes, so one present for the whole iteration is always
+returned exactly once however the collection changes in between calls, whilst elements
+added or removed meanwhile may or may not be.
+
+The cursor is stateless, the elements are found from it with a scanIndex: a skiplist of
+the names ordered by hash, which is built the first time the collection is scanned and
+kept up to date from then on. The hashes are 53 bits so they're exact as skiplist scores.
+Building it walks the whole collection once, every call after costs O(log n + COUNT)
+
+see: https://redis.io/docs/latest/commands/scan/#scan-guarantees
+*/
+
+const scanHashBits = 53
+
+func scanHash(name string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return h.Sum64() >> (64 - scanHashBits)
+}
+
+// the names of a collection ordered by their scan hash
+type scanIndex struct {
+	zsl *skiplist
+}
+
+// indexes the names of a collection, which are unique
+func newScanIndex(each func(visit func(name string))) *scanIndex {
+	index := &scanIndex{zsl: newSkiplist()}
+	each(func(name string) {
+		index.zsl.insert(float64(scanHash(name)), name)
+	})
+	return index
+}
+
+// adds the name if it isn't already in the index
+func (s *scanIndex) add(name string) {
+	score := float64(scanHash(name))
+	if s.zsl.rank(score, name) == 0 {
+		s.zsl.insert(score, name)
+	}
+}
+
+func (s *scanIndex) remove(name string) {
+	s.zsl.delete(float64(scanHash(name)), name)
+}
+
+// the names in the next batch, at least count of them unless it's the last. Names with
+// the same hash are always returned together
+func (s *scanIndex) batch(cursor uint64, count int) (uint64, []string) {
+	if cursor >= 1<<scanHashBits {
+		return 0, []string{}
+	}
+
+	names := make([]string, 0, count)
+	last := -1.0
+	x := s.zsl.firstInRange(ScoreRange{Min: float64(cursor), Max: math.Inf(1)})
+	for ; x != nil; x = x.level[0].forward {
+		if len(names) >= count && x.score != last {
+			break
+		}
+		names = append(names, x.member)
+		last = x.score
+	}
+
+	if x == nil {
+		return 0, names // nothing left beyond this batch
+	}
+
+	return uint64(x.score), names
+}
+
+// must be called with the write lock held
+func (k *KvStore) unindexKey(key string) {
+	if k.keyIndex != nil {
+		k.keyIndex.remove(key)
+	}
+	delete(k.memberIndexes, key)
+}
+
+// drops the indexes when the contents of the store are replaced, they're rebuilt when
+// next scanned. Must be called with the write lock held
+func (k *KvStore) resetScanIndexes() {
+	k.keyIndex = nil
+	k.memberIndexes = make(map[string]*scanIndex)
+}
+
+// must be called with the write lock held
+func (k *KvStore) keyScanIndex() *scanIndex {
+	if k.keyIndex == nil {
+		k.keyIndex = newScanIndex(func(visit func(string)) {
+			for key := range k.values {
+				visit(key)
+			}
+		})
+	}
+	return k.keyIndex
+}
+
+// the index of the hash or set's fields, must be called with the write lock held
+func (k *KvStore) memberScanIndex(key string, each func(visit func(string))) *scanIndex {
+	index, exists := k.memberIndexes[key]
+	if !exists {
+		index = newScanIndex(each)
+		k.memberIndexes[key] = index
+	}
+	return index
+}
+
+// keeps the index of the hash or set's fields up to date when it has one, must be called
+// with the write lock held
+func (k *KvStore) indexMember(key string, member string) {
+	if index, exists := k.memberIndexes[key]; exists {
+		index.add(member)
+	}
+}
+
+func (k *KvStore) unindexMember(key string, member string) {
+	if index, exists := k.memberIndexes[key]; exists {
+		index.remove(member)
+	}
+}
+
+// next batch of keys, skipping those that have expired
+func (k *KvStore) Scan(cursor uint64, count int) (uint64, []string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	next, keys := k.keyScanIndex().batch(cursor, count)
+
+	ms := currentMillis()
+	unexpired := keys[:0]
+	for _, key := range keys {
+		if !k.expired(key, ms) {
+			unexpired = append(unexpired, key)
+		}
+	}
+
+	return next, unexpired
+}
+
+// next batch of the hash's fields and values, as a flat list of pairs
+func (k *KvStore) HashScan(key string, cursor uint64, count int) (uint64, []string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	hash, err := k.getHash(key)
+	if err != nil || hash == nil {
+		return 0, []string{}, err
+	}
+
+	next, fields := k.memberScanIndex(key, func(visit func(string)) {
+		for field := range hash {
+			visit(field)
+		}
+	}).batch(cursor, count)
+
+	pairs := make([]string, 0, len(fields)*2)
+	for _, field := range fields {
+		pairs = append(pairs, field, hash[field])
+	}
+
+	return next, pairs, nil
+}
+
+// next batch of the set's members
+func (k *KvStore) SetScan(key string, cursor uint64, count int) (uint64, []string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	set, err := k.getSet(key)
+	if err != nil || set == nil {
+		return 0, []string{}, err
+	}
+
+	next, members := k.memberScanIndex(key, func(visit func(string)) {
+		for member := range set {
+			visit(member)
+		}
+	}).batch(cursor, count)
+
+	return next, members, nil
+}
+
+// next batch of the sorted set's members with their scores
+func (k *KvStore) ZScan(key string, cursor uint64, count int) (uint64, []ZMember, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	zset, err := k.getSortedSet(key)
+	if err != nil || zset == nil {
+		return 0, []ZMember{}, err
+	}
+
+	if zset.index == nil {
+		zset.index = newScanIndex(func(visit func(string)) {
+			for member := range zset.scores {
+				visit(member)
+			}
+		})
+	}
+
+	next, names := zset.index.batch(cursor, count)
+
+	members := make([]ZMember, 0, len(names))
+	for _, member := range names {
+		members = append(members, ZMember{Member: member, Score: zset.scores[member]})
+	}
+
+	return next, members, nil
+}
@@ -0,0 +1,269 @@
+package store
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestScanReturnsKeysPresentThroughoutExactlyOnce(t *testing.T) {
+	// arrange
+	kv := NewKvStore(zerolog.Nop())
+	for i := 0; i < 100; i++ {
+		kv.Set(fmt.Sprintf("stays:%d", i), "v", ValueOptions{})
+		kv.Set(fmt.Sprintf("goes:%d", i), "v", ValueOptions{})
+	}
+
+	// act - the map grows and shrinks between calls, rehashing in go's case
+	seen := map[string]int{}
+	cursor, calls := uint64(0), 0
+	for {
+		next, keys := kv.Scan(cursor, 10)
+		for _, key := range keys {
+			seen[key]++
+		}
+
+		kv.Delete([]string{fmt.Sprintf("goes:%d", calls)})
+		for i := 0; i < 5; i++ {
+			kv.Set(fmt.Sprintf("added:%d:%d", calls, i), "v", ValueOptions{})
+		}
+
+		calls++
+		if cursor = next; cursor == 0 {
+			break
+		}
+
+		if calls > 1000 {
+			t.Fatal("expected the scan to finish")
+		}
+	}
+
+	// assert
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("stays:%d", i)
+		if seen[key] != 1 {
+			t.Errorf("expected %s to be returned once but got %d times", key, seen[key])
+		}
+	}
+
+	for key, n := range seen {
+		if n > 1 {
+			t.Errorf("expected %s to be returned at most once but got %d times", key, n)
+		}
+	}
+}
+
+func TestScanCount(t *testing.T) {
+	// arrange
+	kv := NewKvStore(zerolog.Nop())
+	for i := 0; i < 25; i++ {
+		kv.Set(fmt.Sprintf("key:%d", i), "v", ValueOptions{})
+	}
+
+	// act
+	var batches []int
+	cursor := uint64(0)
+	for {
+		next, keys := kv.Scan(cursor, 10)
+		batches = append(batches, len(keys))
+		if cursor = next; cursor == 0 {
+			break
+		}
+	}
+
+	// assert - no two of these keys share a hash, so every batch but the last is full
+	if got := fmt.Sprint(batches); got != "[10 10 5]" {
+		t.Errorf("expected batches of [10 10 5] but got %s", got)
+	}
+}
+
+func TestScanEmpty(t *testing.T) {
+	// arrange
+	kv := NewKvStore(zerolog.Nop())
+
+	// act
+	next, keys := kv.Scan(0, 10)
+
+	// assert
+	if next != 0 || len(keys) != 0 {
+		t.Errorf("expected an empty finished scan but got %d, %v", next, keys)
+	}
+}
+
+func TestScanBatchPastTheEndOfTheHashSpace(t *testing.T) {
+	// arrange
+	index := newScanIndex(func(visit func(string)) { visit("a") })
+
+	// act
+	next, batch := index.batch(math.MaxUint64, 10)
+
+	// assert - nothing hashes at or past the cursor
+	if next != 0 || len(batch) != 0 {
+		t.Errorf("expected a finished scan but got %d, %v", next, batch)
+	}
+}
+
+func TestScanIndexesFollowChanges(t *testing.T) {
+	cases := []struct {
+		name   string
+		setup  func(kv *KvStore)
+		modify func(kv *KvStore)
+		scan   func(kv *KvStore, cursor uint64) (uint64, []string)
+		want   string
+	}{
+		{
+			name:   "keys",
+			setup:  func(kv *KvStore) { kv.Set("a", "v", ValueOptions{}); kv.Set("b", "v", ValueOptions{}) },
+			modify: func(kv *KvStore) { kv.Delete([]string{"a"}); kv.ListPush("c", []string{"v"}, false) },
+			scan:   func(kv *KvStore, cursor uint64) (uint64, []string) { return kv.Scan(cursor, 10) },
+			want:   "[b c]",
+		},
+		{
+			name:   "flushed keys",
+			setup:  func(kv *KvStore) { kv.Set("a", "v", ValueOptions{}) },
+			modify: func(kv *KvStore) { kv.Flush(); kv.Set("b", "v", ValueOptions{}) },
+			scan:   func(kv *KvStore, cursor uint64) (uint64, []string) { return kv.Scan(cursor, 10) },
+			want:   "[b]",
+		},
+		{
+			name:  "swapped keys",
+			setup: func(kv *KvStore) { kv.Set("a", "v", ValueOptions{}) },
+			modify: func(kv *KvStore) {
+				other := NewKvStore(zerolog.Nop())
+				other.Set("x", "v", ValueOptions{})
+				other.Scan(0, 10)
+				kv.SwapWith(other)
+				other.Set("y", "v", ValueOptions{})
+				kv.Set("z", "v", ValueOptions{})
+			},
+			scan: func(kv *KvStore, cursor uint64) (uint64, []string) { return kv.Scan(cursor, 10) },
+			want: "[x z]",
+		},
+		{
+			name:   "hash fields",
+			setup:  func(kv *KvStore) { kv.HashSet("h", []string{"a", "1", "b", "2"}) },
+			modify: func(kv *KvStore) { kv.HashDel("h", []string{"a"}); kv.HashIncrBy("h", "c", 1) },
+			scan: func(kv *KvStore, cursor uint64) (uint64, []string) {
+				next, pairs, _ := kv.HashScan("h", cursor, 10)
+				return next, fieldsOf(pairs)
+			},
+			want: "[b c]",
+		},
+		{
+			name:  "hash replaced",
+			setup: func(kv *KvStore) { kv.HashSet("h", []string{"a", "1"}) },
+			modify: func(kv *KvStore) {
+				kv.Set("h", "v", ValueOptions{})
+				kv.Delete([]string{"h"})
+				kv.HashSet("h", []string{"b", "2"})
+			},
+			scan: func(kv *KvStore, cursor uint64) (uint64, []string) {
+				next, pairs, _ := kv.HashScan("h", cursor, 10)
+				return next, fieldsOf(pairs)
+			},
+			want: "[b]",
+		},
+		{
+			name:   "set members",
+			setup:  func(kv *KvStore) { kv.SetAdd("s", []string{"a", "b"}) },
+			modify: func(kv *KvStore) { kv.SetRemove("s", []string{"a"}); kv.SetAdd("s", []string{"c"}) },
+			scan: func(kv *KvStore, cursor uint64) (uint64, []string) {
+				next, members, _ := kv.SetScan("s", cursor, 10)
+				return next, members
+			},
+			want: "[b c]",
+		},
+		{
+			name: "sorted set members",
+			setup: func(kv *KvStore) {
+				kv.ZAdd("z", []ZMember{{Member: "a", Score: 1}, {Member: "b", Score: 2}}, ZAddOptions{})
+			},
+			modify: func(kv *KvStore) {
+				kv.ZRem("z", []string{"a"})
+				kv.ZAdd("z", []ZMember{{Member: "c", Score: 3}}, ZAddOptions{})
+			},
+			scan: func(kv *KvStore, cursor uint64) (uint64, []string) {
+				next, members, _ := kv.ZScan("z", cursor, 10)
+				names := make([]string, 0, len(members))
+				for _, m := range members {
+					names = append(names, m.Member)
+				}
+				return next, names
+			},
+			want: "[b c]",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// arrange - scanning builds the index before the changes
+			kv := NewKvStore(zerolog.Nop())
+			c.setup(kv)
+			c.scan(kv, 0)
+
+			// act
+			c.modify(kv)
+			next, got := c.scan(kv, 0)
+
+			// assert
+			if next != 0 {
+				t.Errorf("expected a single batch but got cursor %d", next)
+			}
+
+			sort.Strings(got)
+			if fmt.Sprint(got) != c.want {
+				t.Errorf("expected %s but got %v", c.want, got)
+			}
+		})
+	}
+}
+
+func fieldsOf(pairs []string) []string {
+	fields := make([]string, 0, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		fields = append(fields, pairs[i])
+	}
+	return fields
+}
+
+func TestHashScanReturnsFieldsPresentThroughoutExactlyOnce(t *testing.T) {
+	// arrange
+	kv := NewKvStore(zerolog.Nop())
+	for i := 0; i < 50; i++ {
+		kv.HashSet("h", []string{fmt.Sprintf("stays:%d", i), "v"})
+	}
+
+	// act
+	seen := map[string]int{}
+	cursor, calls := uint64(0), 0
+	for {
+		next, pairs, err := kv.HashScan("h", cursor, 5)
+		if err != nil {
+			t.Fatalf("error scanning: %v", err)
+		}
+
+		for i := 0; i < len(pairs); i += 2 {
+			seen[pairs[i]]++
+		}
+
+		for i := 0; i < 3; i++ {
+			kv.HashSet("h", []string{fmt.Sprintf("added:%d:%d", calls, i), "v"})
+		}
+
+		calls++
+		if cursor = next; cursor == 0 {
+			break
+		}
+	}
+
+	// assert
+	for i := 0; i < 50; i++ {
+		field := fmt.Sprintf("stays:%d", i)
+		if seen[field] != 1 {
+			t.Errorf("expected %s to be returned once but got %d times", field, seen[field])
+		}
+	}
+}
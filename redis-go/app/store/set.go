@@ -0,0 +1,211 @@
+package store
+
+type Set = map[string]struct{}
+
+// set algebra for SINTER/SUNION/SDIFF
+type SetOp int
+
+const (
+	SetInter SetOp = iota
+	SetUnion
+	SetDiff
+)
+
+// must be called with the lock held, missing keys are returned as nil
+func (k *KvStore) getSet(key string) (Set, error) {
+	val, exists := k.lookup(key)
+	if !exists {
+		return nil, nil
+	}
+
+	set, ok := val.(Set)
+	if !ok {
+		return nil, ErrWrongType
+	}
+
+	return set, nil
+}
+
+// adds the members returning how many weren't already in the set
+func (k *KvStore) SetAdd(key string, members []string) (int, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	set, err := k.getSet(key)
+	if err != nil {
+		return 0, err
+	}
+
+	if set == nil {
+		set = make(Set, len(members))
+		k.create(key, set)
+	}
+
+	added := 0
+	for _, member := range members {
+		if _, exists := set[member]; !exists {
+			set[member] = struct{}{}
+			k.indexMember(key, member)
+			added++
+		}
+	}
+
+	if added > 0 {
+		k.touch(key)
+		k.notify(EventSet, "sadd", key)
+	}
+
+	return added, nil
+}
+
+// removes the members returning how many were in the set, an empty set is removed
+func (k *KvStore) SetRemove(key string, members []string) (int, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	set, err := k.getSet(key)
+	if err != nil || set == nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, member := range members {
+		if _, exists := set[member]; exists {
+			delete(set, member)
+			k.unindexMember(key, member)
+			removed++
+		}
+	}
+
+	if removed > 0 {
+		k.notify(EventSet, "srem", key)
+	}
+
+	if len(set) == 0 {
+		k.del(key)
+	} else if removed > 0 {
+		k.touch(key)
+	}
+
+	return removed, nil
+}
+
+func (k *KvStore) SetIsMember(key string, member string) (bool, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	set, err := k.getSet(key)
+	if err != nil {
+		return false, err
+	}
+
+	_, exists := set[member]
+	return exists, nil
+}
+
+func (k *KvStore) SetCard(key string) (int, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	set, err := k.getSet(key)
+	return len(set), err
+}
+
+// combines the sets at the keys, SMEMBERS is the union of a single key
+func (k *KvStore) SetCombine(op SetOp, keys []string) (Set, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return k.combine(op, keys)
+}
+
+// stores the combination of the sets in dest, replacing whatever was there (or removing
+// it when the result is empty). Returns the size of the result
+func (k *KvStore) SetCombineStore(op SetOp, dest string, keys []string) (int, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	result, err := k.combine(op, keys)
+	if err != nil {
+		return 0, err
+	}
+
+	_, existed := k.lookup(dest)
+	k.remove(dest)
+
+	if len(result) > 0 {
+		if existed {
+			k.values[dest] = result
+			if k.keyIndex != nil {
+				k.keyIndex.add(dest)
+			}
+		} else {
+			k.create(dest, result)
+		}
+		k.touch(dest)
+		k.notify(EventSet, storeEvents[op], dest)
+	} else if existed {
+		k.notify(EventGeneric, "del", dest)
+	}
+
+	return len(result), nil
+}
+
+var storeEvents = map[SetOp]string{
+	SetInter: "sinterstore",
+	SetUnion: "sunionstore",
+	SetDiff:  "sdiffstore",
+}
+
+// must be called with the write lock held, always returns a new set
+func (k *KvStore) combine(op SetOp, keys []string) (Set, error) {
+	sets := make([]Set, 0, len(keys))
+	for _, key := range keys {
+		set, err := k.getSet(key)
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, set)
+	}
+
+	result := make(Set)
+
+	switch op {
+	case SetUnion:
+		for _, set := range sets {
+			for member := range set {
+				result[member] = struct{}{}
+			}
+		}
+	case SetInter:
+		for member := range sets[0] {
+			inAll := true
+			for _, set := range sets[1:] {
+				if _, exists := set[member]; !exists {
+					inAll = false
+					break
+				}
+			}
+
+			if inAll {
+				result[member] = struct{}{}
+			}
+		}
+	case SetDiff:
+		for member := range sets[0] {
+			inOther := false
+			for _, set := range sets[1:] {
+				if _, exists := set[member]; exists {
+					inOther = true
+					break
+				}
+			}
+
+			if !inOther {
+				result[member] = struct{}{}
+			}
+		}
+	}
+
+	return result, nil
+}
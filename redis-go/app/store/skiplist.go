@@ -0,0 +1,249 @@
+package store
+
+import "math/rand"
+
+/* Skiplist ordered by (score, member), as used by redis for sorted sets:
+
+	level 2:  head ------------------> c --------> nil
+	level 1:  head --------> b ------> c --------> nil
+	level 0:  head --> a --> b --> c --> d ------> nil
+
+every forward pointer records its span (the number of nodes it skips over) so the
+rank of a node can be found in O(log n) whilst searching for it, and the node at a
+rank can be found without walking level 0. Level 0 is doubly linked for reverse
+iteration
+
+see: https://github.com/redis/redis/blob/7.2/src/t_zset.c
+*/
+
+const (
+	skiplistMaxLevel = 32
+	skiplistP        = 0.25
+)
+
+type skiplistNode struct {
+	member   string
+	score    float64
+	backward *skiplistNode
+	level    []skiplistLevel
+}
+
+type skiplistLevel struct {
+	forward *skiplistNode
+	span    int
+}
+
+type skiplist struct {
+	header *skiplistNode
+	tail   *skiplistNode
+	length int
+	level  int
+}
+
+func newSkiplist() *skiplist {
+	return &skiplist{
+		header: &skiplistNode{level: make([]skiplistLevel, skiplistMaxLevel)},
+		level:  1,
+	}
+}
+
+func randomLevel() int {
+	level := 1
+	for level < skiplistMaxLevel && rand.Float64() < skiplistP {
+		level++
+	}
+
+	return level
+}
+
+// whether the node sorts before the score and member
+func (n *skiplistNode) before(score float64, member string) bool {
+	return n.score < score || (n.score == score && n.member < member)
+}
+
+// whether the node sorts after the score and member
+func (n *skiplistNode) after(score float64, member string) bool {
+	return n.score > score || (n.score == score && n.member > member)
+}
+
+// inserts the member, which must not already be in the list
+func (l *skiplist) insert(score float64, member string) *skiplistNode {
+	var update [skiplistMaxLevel]*skiplistNode
+	var rank [skiplistMaxLevel]int
+
+	x := l.header
+	for i := l.level - 1; i >= 0; i-- {
+		if i < l.level-1 {
+			rank[i] = rank[i+1]
+		}
+
+		for x.level[i].forward != nil && x.level[i].forward.before(score, member) {
+			rank[i] += x.level[i].span
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+
+	level := randomLevel()
+	if level > l.level {
+		for i := l.level; i < level; i++ {
+			rank[i] = 0
+			update[i] = l.header
+			update[i].level[i].span = l.length
+		}
+		l.level = level
+	}
+
+	x = &skiplistNode{member: member, score: score, level: make([]skiplistLevel, level)}
+	for i := 0; i < level; i++ {
+		x.level[i].forward = update[i].level[i].forward
+		update[i].level[i].forward = x
+
+		x.level[i].span = update[i].level[i].span - (rank[0] - rank[i])
+		update[i].level[i].span = rank[0] - rank[i] + 1
+	}
+
+	// untouched levels skip over the new node
+	for i := level; i < l.level; i++ {
+		update[i].level[i].span++
+	}
+
+	if update[0] != l.header {
+		x.backward = update[0]
+	}
+
+	if x.level[0].forward != nil {
+		x.level[0].forward.backward = x
+	} else {
+		l.tail = x
+	}
+
+	l.length++
+
+	return x
+}
+
+// removes the member with the score, returning false if it wasn't found
+func (l *skiplist) delete(score float64, member string) bool {
+	var update [skiplistMaxLevel]*skiplistNode
+
+	x := l.header
+	for i := l.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && x.level[i].forward.before(score, member) {
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+
+	x = x.level[0].forward
+	if x == nil || x.score != score || x.member != member {
+		return false
+	}
+
+	l.deleteNode(x, update[:l.level])
+	return true
+}
+
+func (l *skiplist) deleteNode(x *skiplistNode, update []*skiplistNode) {
+	for i := 0; i < l.level; i++ {
+		if update[i].level[i].forward == x {
+			update[i].level[i].span += x.level[i].span - 1
+			update[i].level[i].forward = x.level[i].forward
+		} else {
+			update[i].level[i].span--
+		}
+	}
+
+	if x.level[0].forward != nil {
+		x.level[0].forward.backward = x.backward
+	} else {
+		l.tail = x.backward
+	}
+
+	for l.level > 1 && l.header.level[l.level-1].forward == nil {
+		l.level--
+	}
+
+	l.length--
+}
+
+// 1 based rank of the member with the score, or 0 when it isn't in the list
+func (l *skiplist) rank(score float64, member string) int {
+	rank := 0
+
+	x := l.header
+	for i := l.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && !x.level[i].forward.after(score, member) {
+			rank += x.level[i].span
+			x = x.level[i].forward
+		}
+
+		if x != l.header && x.score == score && x.member == member {
+			return rank
+		}
+	}
+
+	return 0
+}
+
+// node at the 1 based rank, nil when out of range
+func (l *skiplist) byRank(rank int) *skiplistNode {
+	if rank < 1 || rank > l.length {
+		return nil
+	}
+
+	traversed := 0
+
+	x := l.header
+	for i := l.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && traversed+x.level[i].span <= rank {
+			traversed += x.level[i].span
+			x = x.level[i].forward
+		}
+
+		if traversed == rank {
+			return x
+		}
+	}
+
+	return nil
+}
+
+// first node that's within the range, nil when none are
+func (l *skiplist) firstInRange(r rangeSpec) *skiplistNode {
+	x := l.header
+	for i := l.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && !r.aboveMin(x.level[i].forward) {
+			x = x.level[i].forward
+		}
+	}
+
+	x = x.level[0].forward
+	if x == nil || !r.belowMax(x) {
+		return nil
+	}
+
+	return x
+}
+
+// last node that's within the range, nil when none are
+func (l *skiplist) lastInRange(r rangeSpec) *skiplistNode {
+	x := l.header
+	for i := l.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && r.belowMax(x.level[i].forward) {
+			x = x.level[i].forward
+		}
+	}
+
+	if x == l.header || !r.aboveMin(x) {
+		return nil
+	}
+
+	return x
+}
+
+// a range of nodes by either score or member, see ScoreRange and LexRange
+type rangeSpec interface {
+	aboveMin(n *skiplistNode) bool
+	belowMax(n *skiplistNode) bool
+}
@@ -0,0 +1,305 @@
+package store
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// sorts the model the way the skiplist orders its nodes
+func sortMembers(members []ZMember) {
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].Score != members[j].Score {
+			return members[i].Score < members[j].Score
+		}
+		return members[i].Member < members[j].Member
+	})
+}
+
+// checks every level's spans and the backward links agree with level 0
+func checkSkiplist(t *testing.T, l *skiplist) {
+	t.Helper()
+
+	positions := make(map[*skiplistNode]int)
+	var prev *skiplistNode
+	for x := l.header.level[0].forward; x != nil; x = x.level[0].forward {
+		if x.backward != prev {
+			t.Fatalf("expected %q's backward link to be the node before it", x.member)
+		}
+
+		positions[x] = len(positions) + 1
+		prev = x
+	}
+
+	if l.tail != prev || l.length != len(positions) {
+		t.Fatal("expected the tail and length to match level 0")
+	}
+
+	for i := 0; i < l.level; i++ {
+		rank := 0
+		for x := l.header; x.level[i].forward != nil; x = x.level[i].forward {
+			rank += x.level[i].span
+			if next := x.level[i].forward; positions[next] != rank {
+				t.Fatalf("level %d: expected %q at rank %d but the spans add up to %d", i, next.member, positions[next], rank)
+			}
+		}
+	}
+}
+
+func TestSkiplistMatchesSortedSlice(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	zset := NewSortedSet()
+	model := make(map[string]float64)
+
+	for op := 0; op < 5000; op++ {
+		member := fmt.Sprintf("m%d", rng.Intn(200))
+		score := float64(rng.Intn(50)) // plenty of ties to order by member
+
+		switch rng.Intn(3) {
+		case 0, 1: // insert or re-score
+			zset.Add(member, score)
+			model[member] = score
+		case 2:
+			_, exists := model[member]
+			if removed := zset.Remove(member); removed != exists {
+				t.Fatalf("op %d: expected removing %q to return %v", op, member, exists)
+			}
+			delete(model, member)
+		}
+
+		if op%100 != 0 {
+			continue
+		}
+
+		checkSkiplist(t, zset.zsl)
+
+		want := make([]ZMember, 0, len(model))
+		for m, s := range model {
+			want = append(want, ZMember{Member: m, Score: s})
+		}
+		sortMembers(want)
+
+		if got := zset.Members(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("op %d: expected members %v but got %v", op, want, got)
+		}
+
+		for i, m := range want {
+			if got := zset.zsl.rank(m.Score, m.Member); got != i+1 {
+				t.Fatalf("op %d: expected %q at rank %d but got %d", op, m.Member, i+1, got)
+			}
+
+			if node := zset.zsl.byRank(i + 1); node == nil || node.member != m.Member {
+				t.Fatalf("op %d: expected rank %d to be %q but got %v", op, i+1, m.Member, node)
+			}
+		}
+
+		if zset.zsl.byRank(0) != nil || zset.zsl.byRank(len(want)+1) != nil {
+			t.Fatalf("op %d: expected ranks out of range to be nil", op)
+		}
+
+		// a random score range, checked against a filter of the model
+		min, max := float64(rng.Intn(50)), float64(rng.Intn(50))
+		r := ScoreRange{Min: min, Max: max, MinEx: rng.Intn(2) == 0, MaxEx: rng.Intn(2) == 0}
+		inRange := make([]ZMember, 0)
+		for _, m := range want {
+			if r.aboveMin(&skiplistNode{score: m.Score}) && r.belowMax(&skiplistNode{score: m.Score}) {
+				inRange = append(inRange, m)
+			}
+		}
+
+		got := zset.rangeNodes(ZRangeSpec{By: ZRangeByScore, Score: r, Count: -1})
+		if !reflect.DeepEqual(got, inRange) {
+			t.Fatalf("op %d: expected %v in %+v but got %v", op, inRange, r, got)
+		}
+
+		// and the same range reversed, skipping the first and limited to 3
+		reversed := make([]ZMember, 0)
+		for i := len(inRange) - 2; i >= 0 && len(reversed) < 3; i-- {
+			reversed = append(reversed, inRange[i])
+		}
+
+		got = zset.rangeNodes(ZRangeSpec{By: ZRangeByScore, Score: r, Rev: true, Offset: 1, Count: 3})
+		if !reflect.DeepEqual(got, reversed) {
+			t.Fatalf("op %d: expected %v reversed in %+v but got %v", op, reversed, r, got)
+		}
+
+		// and a random index range
+		start, stop := rng.Intn(len(want)+2)-1, rng.Intn(len(want)+2)-1
+		from, to := listRange(len(want), start, stop)
+		byIndex := make([]ZMember, 0)
+		if from < to {
+			byIndex = want[from:to]
+		}
+
+		got = zset.rangeNodes(ZRangeSpec{By: ZRangeByIndex, Start: start, Stop: stop})
+		if !reflect.DeepEqual(got, byIndex) {
+			t.Fatalf("op %d: expected %v for ranks %d..%d but got %v", op, byIndex, start, stop, got)
+		}
+	}
+}
+
+func TestZAddOptions(t *testing.T) {
+	cases := []struct {
+		name        string
+		options     ZAddOptions
+		member      string
+		score       float64
+		wantAdded   int
+		wantChanged int
+		wantOk      bool
+		wantScore   float64 // the member's score afterwards, NaN for missing
+	}{
+		{name: "adds new", member: "new", score: 5, wantAdded: 1, wantOk: true, wantScore: 5},
+		{name: "updates existing", member: "a", score: 5, wantChanged: 1, wantOk: true, wantScore: 5},
+		{name: "same score isn't a change", member: "a", score: 2, wantOk: true, wantScore: 2},
+		{name: "NX adds new", options: ZAddOptions{NX: true}, member: "new", score: 5, wantAdded: 1, wantOk: true, wantScore: 5},
+		{name: "NX leaves existing", options: ZAddOptions{NX: true}, member: "a", score: 5, wantScore: 2},
+		{name: "XX updates existing", options: ZAddOptions{XX: true}, member: "a", score: 5, wantChanged: 1, wantOk: true, wantScore: 5},
+		{name: "XX doesn't add", options: ZAddOptions{XX: true}, member: "new", score: 5, wantScore: math.NaN()},
+		{name: "GT raises", options: ZAddOptions{GT: true}, member: "a", score: 5, wantChanged: 1, wantOk: true, wantScore: 5},
+		{name: "GT doesn't lower", options: ZAddOptions{GT: true}, member: "a", score: 1, wantScore: 2},
+		{name: "GT adds new", options: ZAddOptions{GT: true}, member: "new", score: 1, wantAdded: 1, wantOk: true, wantScore: 1},
+		{name: "LT lowers", options: ZAddOptions{LT: true}, member: "a", score: 1, wantChanged: 1, wantOk: true, wantScore: 1},
+		{name: "LT doesn't raise", options: ZAddOptions{LT: true}, member: "a", score: 5, wantScore: 2},
+		{name: "INCR increments", options: ZAddOptions{Incr: true}, member: "a", score: 3, wantChanged: 1, wantOk: true, wantScore: 5},
+		{name: "INCR adds new", options: ZAddOptions{Incr: true}, member: "new", score: 3, wantAdded: 1, wantOk: true, wantScore: 3},
+		{name: "INCR GT compares the result", options: ZAddOptions{Incr: true, GT: true}, member: "a", score: -1, wantScore: 2},
+		{name: "INCR NX leaves existing", options: ZAddOptions{Incr: true, NX: true}, member: "a", score: 3, wantScore: 2},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// arrange
+			k := NewKvStore(zerolog.Nop())
+			k.ZAdd("zset", []ZMember{{Member: "a", Score: 2}}, ZAddOptions{})
+
+			// act
+			added, changed, _, ok, err := k.ZAdd("zset", []ZMember{{Member: c.member, Score: c.score}}, c.options)
+
+			// assert
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if added != c.wantAdded || changed != c.wantChanged || ok != c.wantOk {
+				t.Errorf("expected added %d changed %d ok %v but got %d %d %v", c.wantAdded, c.wantChanged, c.wantOk, added, changed, ok)
+			}
+
+			score, exists, _ := k.ZScore("zset", c.member)
+			if math.IsNaN(c.wantScore) {
+				if exists {
+					t.Errorf("expected %q not to be added but has score %v", c.member, score)
+				}
+			} else if !exists || score != c.wantScore {
+				t.Errorf("expected %q to have score %v but got %v (exists: %v)", c.member, c.wantScore, score, exists)
+			}
+		})
+	}
+}
+
+func TestZAddIncrToNaN(t *testing.T) {
+	// arrange
+	k := NewKvStore(zerolog.Nop())
+	k.ZAdd("zset", []ZMember{{Member: "a", Score: math.Inf(1)}}, ZAddOptions{})
+
+	// act
+	_, _, _, _, err := k.ZAdd("zset", []ZMember{{Member: "a", Score: math.Inf(-1)}}, ZAddOptions{Incr: true})
+
+	// assert
+	if err != ErrScoreNaN {
+		t.Errorf("expected %v but got %v", ErrScoreNaN, err)
+	}
+}
+
+func TestZRangeByScoreBounds(t *testing.T) {
+	k := NewKvStore(zerolog.Nop())
+	k.ZAdd("zset", []ZMember{
+		{Member: "ninf", Score: math.Inf(-1)},
+		{Member: "one", Score: 1},
+		{Member: "two", Score: 2},
+		{Member: "three", Score: 3},
+		{Member: "inf", Score: math.Inf(1)},
+	}, ZAddOptions{})
+
+	cases := []struct {
+		name string
+		r    ScoreRange
+		want []string
+	}{
+		{name: "inclusive", r: ScoreRange{Min: 1, Max: 3}, want: []string{"one", "two", "three"}},
+		{name: "exclusive min", r: ScoreRange{Min: 1, Max: 3, MinEx: true}, want: []string{"two", "three"}},
+		{name: "exclusive max", r: ScoreRange{Min: 1, Max: 3, MaxEx: true}, want: []string{"one", "two"}},
+		{name: "both exclusive", r: ScoreRange{Min: 1, Max: 3, MinEx: true, MaxEx: true}, want: []string{"two"}},
+		{name: "empty exclusive", r: ScoreRange{Min: 2, Max: 2, MinEx: true}, want: []string{}},
+		{name: "infinite", r: ScoreRange{Min: math.Inf(-1), Max: math.Inf(1)}, want: []string{"ninf", "one", "two", "three", "inf"}},
+		{name: "exclusive infinite", r: ScoreRange{Min: math.Inf(-1), Max: math.Inf(1), MinEx: true, MaxEx: true}, want: []string{"one", "two", "three"}},
+		{name: "min above max", r: ScoreRange{Min: 3, Max: 1}, want: []string{}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			members, _ := k.ZRange("zset", ZRangeSpec{By: ZRangeByScore, Score: c.r, Count: -1})
+			count, _ := k.ZCount("zset", c.r)
+
+			if got := memberNames(members); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("expected %v but got %v", c.want, got)
+			}
+
+			if count != len(c.want) {
+				t.Errorf("expected a count of %d but got %d", len(c.want), count)
+			}
+		})
+	}
+}
+
+func TestZRangeByLexBounds(t *testing.T) {
+	k := NewKvStore(zerolog.Nop())
+	k.ZAdd("zset", []ZMember{
+		{Member: "a"}, {Member: "b"}, {Member: "c"}, {Member: "d"},
+	}, ZAddOptions{})
+
+	inclusive := func(v string) LexBound { return LexBound{Value: v} }
+	exclusive := func(v string) LexBound { return LexBound{Value: v, Exclusive: true} }
+	minusInf := LexBound{Inf: -1}
+	plusInf := LexBound{Inf: 1}
+
+	cases := []struct {
+		name string
+		r    LexRange
+		rev  bool
+		want []string
+	}{
+		{name: "inclusive", r: LexRange{Min: inclusive("b"), Max: inclusive("c")}, want: []string{"b", "c"}},
+		{name: "exclusive", r: LexRange{Min: exclusive("a"), Max: exclusive("d")}, want: []string{"b", "c"}},
+		{name: "- to +", r: LexRange{Min: minusInf, Max: plusInf}, want: []string{"a", "b", "c", "d"}},
+		{name: "- to bound", r: LexRange{Min: minusInf, Max: exclusive("c")}, want: []string{"a", "b"}},
+		{name: "bound to +", r: LexRange{Min: inclusive("c"), Max: plusInf}, want: []string{"c", "d"}},
+		{name: "+ as min", r: LexRange{Min: plusInf, Max: plusInf}, want: []string{}},
+		{name: "- as max", r: LexRange{Min: minusInf, Max: minusInf}, want: []string{}},
+		{name: "between members", r: LexRange{Min: inclusive("aa"), Max: inclusive("cc")}, want: []string{"b", "c"}},
+		{name: "reversed", r: LexRange{Min: exclusive("a"), Max: plusInf}, rev: true, want: []string{"d", "c", "b"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			members, _ := k.ZRange("zset", ZRangeSpec{By: ZRangeByLex, Lex: c.r, Rev: c.rev, Count: -1})
+
+			if got := memberNames(members); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("expected %v but got %v", c.want, got)
+			}
+		})
+	}
+}
+
+func memberNames(members []ZMember) []string {
+	names := make([]string, 0, len(members))
+	for _, m := range members {
+		names = append(names, m.Member)
+	}
+	return names
+}
@@ -0,0 +1,489 @@
+package store
+
+import (
+	"errors"
+	"math"
+)
+
+// Members are kept in a map for O(1) score lookups alongside a skiplist ordered by
+// score for O(log n) ranks and ranges
+type SortedSet struct {
+	scores map[string]float64
+	zsl    *skiplist
+	index  *scanIndex // the members ordered for ZSCAN, nil until first scanned
+}
+
+type ZMember struct {
+	Member string
+	Score  float64
+}
+
+type ZAddOptions struct {
+	NX   bool // only add new members
+	XX   bool // only update existing members
+	GT   bool // only update when the new score is greater
+	LT   bool // only update when the new score is less
+	Incr bool // increment the score rather than set it, like ZINCRBY
+}
+
+// score range where either bound may be exclusive, e.g. (1 5
+type ScoreRange struct {
+	Min, Max     float64
+	MinEx, MaxEx bool
+}
+
+// bound on a member for lex ranges, where Inf -1 is - and 1 is +
+type LexBound struct {
+	Value     string
+	Exclusive bool
+	Inf       int
+}
+
+// member range for sets where every score is the same, e.g. [a (c
+type LexRange struct {
+	Min, Max LexBound
+}
+
+type ZRangeBy int
+
+const (
+	ZRangeByIndex ZRangeBy = iota
+	ZRangeByScore
+	ZRangeByLex
+)
+
+// what ZRANGE selects. For ZRangeByIndex Start and Stop are inclusive ranks which
+// may be negative, Offset and Count (-1 for all) limit score and lex ranges
+type ZRangeSpec struct {
+	By     ZRangeBy
+	Start  int
+	Stop   int
+	Score  ScoreRange
+	Lex    LexRange
+	Rev    bool
+	Offset int
+	Count  int
+}
+
+var ErrScoreNaN = errors.New("ERR resulting score is not a number (NaN)")
+
+func NewSortedSet() *SortedSet {
+	return &SortedSet{
+		scores: make(map[string]float64),
+		zsl:    newSkiplist(),
+	}
+}
+
+func (z *SortedSet) Len() int {
+	return len(z.scores)
+}
+
+func (z *SortedSet) Score(member string) (float64, bool) {
+	score, exists := z.scores[member]
+	return score, exists
+}
+
+// adds or updates the member's score
+func (z *SortedSet) Add(member string, score float64) {
+	if current, exists := z.scores[member]; exists {
+		if current == score {
+			return
+		}
+		z.zsl.delete(current, member)
+	} else if z.index != nil {
+		z.index.add(member)
+	}
+
+	z.scores[member] = score
+	z.zsl.insert(score, member)
+}
+
+func (z *SortedSet) Remove(member string) bool {
+	score, exists := z.scores[member]
+	if !exists {
+		return false
+	}
+
+	delete(z.scores, member)
+	z.zsl.delete(score, member)
+	if z.index != nil {
+		z.index.remove(member)
+	}
+	return true
+}
+
+// members in ascending order
+func (z *SortedSet) Members() []ZMember {
+	members := make([]ZMember, 0, z.Len())
+	for x := z.zsl.header.level[0].forward; x != nil; x = x.level[0].forward {
+		members = append(members, ZMember{Member: x.member, Score: x.score})
+	}
+
+	return members
+}
+
+func (r ScoreRange) aboveMin(n *skiplistNode) bool {
+	if r.MinEx {
+		return n.score > r.Min
+	}
+	return n.score >= r.Min
+}
+
+func (r ScoreRange) belowMax(n *skiplistNode) bool {
+	if r.MaxEx {
+		return n.score < r.Max
+	}
+	return n.score <= r.Max
+}
+
+func (r LexRange) aboveMin(n *skiplistNode) bool {
+	switch {
+	case r.Min.Inf < 0:
+		return true
+	case r.Min.Inf > 0:
+		return false
+	case r.Min.Exclusive:
+		return n.member > r.Min.Value
+	default:
+		return n.member >= r.Min.Value
+	}
+}
+
+func (r LexRange) belowMax(n *skiplistNode) bool {
+	switch {
+	case r.Max.Inf > 0:
+		return true
+	case r.Max.Inf < 0:
+		return false
+	case r.Max.Exclusive:
+		return n.member < r.Max.Value
+	default:
+		return n.member <= r.Max.Value
+	}
+}
+
+// must be called with the lock held, missing keys are returned as nil
+func (k *KvStore) getSortedSet(key string) (*SortedSet, error) {
+	val, exists := k.lookup(key)
+	if !exists {
+		return nil, nil
+	}
+
+	zset, ok := val.(*SortedSet)
+	if !ok {
+		return nil, ErrWrongType
+	}
+
+	return zset, nil
+}
+
+// must be called with the write lock held
+func (k *KvStore) removeIfEmpty(key string, zset *SortedSet) {
+	if zset.Len() == 0 {
+		k.del(key)
+	}
+}
+
+var zremRangeEvents = map[ZRangeBy]string{
+	ZRangeByIndex: "zremrangebyrank",
+	ZRangeByScore: "zremrangebyscore",
+	ZRangeByLex:   "zremrangebylex",
+}
+
+// adds or updates the members, returning how many were added and how many existing
+// members had their score changed. With Incr the single member's new score is returned,
+// or ok false if the options prevented the update
+func (k *KvStore) ZAdd(key string, members []ZMember, options ZAddOptions) (added int, changed int, score float64, ok bool, err error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	zset, err := k.getSortedSet(key)
+	if err != nil {
+		return 0, 0, 0, false, err
+	}
+
+	// a new set is only stored once something's been added to it
+	created := zset == nil
+	if created {
+		if options.XX {
+			return 0, 0, 0, false, nil
+		}
+
+		zset = NewSortedSet()
+	}
+
+	for _, m := range members {
+		score = m.Score
+		current, exists := zset.Score(m.Member)
+
+		if exists && options.Incr {
+			score += current
+			if math.IsNaN(score) {
+				return 0, 0, 0, false, ErrScoreNaN
+			}
+		}
+
+		if (exists && options.NX) || (!exists && options.XX) {
+			continue
+		}
+
+		if exists && ((options.GT && score <= current) || (options.LT && score >= current)) {
+			continue
+		}
+
+		ok = true
+
+		if !exists {
+			added++
+		} else if score != current {
+			changed++
+		}
+
+		zset.Add(m.Member, score)
+	}
+
+	if created && zset.Len() > 0 {
+		k.create(key, zset)
+	}
+
+	if added+changed > 0 {
+		k.touch(key)
+
+		event := "zadd"
+		if options.Incr {
+			event = "zincr"
+		}
+		k.notify(EventZSet, event, key)
+	}
+
+	return added, changed, score, ok, nil
+}
+
+// removes the members returning how many were in the set
+func (k *KvStore) ZRem(key string, members []string) (int, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	zset, err := k.getSortedSet(key)
+	if err != nil || zset == nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, member := range members {
+		if zset.Remove(member) {
+			removed++
+		}
+	}
+
+	if removed > 0 {
+		k.touch(key)
+		k.notify(EventZSet, "zrem", key)
+	}
+
+	k.removeIfEmpty(key, zset)
+
+	return removed, nil
+}
+
+func (k *KvStore) ZScore(key string, member string) (float64, bool, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	zset, err := k.getSortedSet(key)
+	if err != nil || zset == nil {
+		return 0, false, err
+	}
+
+	score, exists := zset.Score(member)
+	return score, exists, nil
+}
+
+func (k *KvStore) ZCard(key string) (int, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	zset, err := k.getSortedSet(key)
+	if err != nil || zset == nil {
+		return 0, err
+	}
+
+	return zset.Len(), nil
+}
+
+// 0 based rank of the member, from the highest score when rev is set
+func (k *KvStore) ZRank(key string, member string, rev bool) (int, bool, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	zset, err := k.getSortedSet(key)
+	if err != nil || zset == nil {
+		return 0, false, err
+	}
+
+	score, exists := zset.Score(member)
+	if !exists {
+		return 0, false, nil
+	}
+
+	rank := zset.zsl.rank(score, member)
+	if rev {
+		return zset.Len() - rank, true, nil
+	}
+
+	return rank - 1, true, nil
+}
+
+// number of members with a score in the range
+func (k *KvStore) ZCount(key string, r ScoreRange) (int, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	zset, err := k.getSortedSet(key)
+	if err != nil || zset == nil {
+		return 0, err
+	}
+
+	first := zset.zsl.firstInRange(r)
+	if first == nil {
+		return 0, nil
+	}
+
+	last := zset.zsl.lastInRange(r)
+
+	return zset.zsl.rank(last.score, last.member) - zset.zsl.rank(first.score, first.member) + 1, nil
+}
+
+func (k *KvStore) ZRange(key string, spec ZRangeSpec) ([]ZMember, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	zset, err := k.getSortedSet(key)
+	if err != nil || zset == nil {
+		return nil, err
+	}
+
+	return zset.rangeNodes(spec), nil
+}
+
+// removes the members selected by the range, returning how many were removed
+func (k *KvStore) ZRemRange(key string, spec ZRangeSpec) (int, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	zset, err := k.getSortedSet(key)
+	if err != nil || zset == nil {
+		return 0, err
+	}
+
+	members := zset.rangeNodes(spec)
+	for _, m := range members {
+		zset.Remove(m.Member)
+	}
+
+	if len(members) > 0 {
+		k.touch(key)
+		k.notify(EventZSet, zremRangeEvents[spec.By], key)
+	}
+
+	k.removeIfEmpty(key, zset)
+
+	return len(members), nil
+}
+
+// pops up to count members with the lowest scores, or highest when max is set
+func (k *KvStore) ZPop(key string, count int, max bool) ([]ZMember, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	zset, err := k.getSortedSet(key)
+	if err != nil || zset == nil || count <= 0 {
+		return nil, err
+	}
+
+	popped := zset.rangeNodes(ZRangeSpec{By: ZRangeByIndex, Start: 0, Stop: count - 1, Rev: max})
+	for _, m := range popped {
+		zset.Remove(m.Member)
+	}
+
+	if len(popped) > 0 {
+		k.touch(key)
+
+		event := "zpopmin"
+		if max {
+			event = "zpopmax"
+		}
+		k.notify(EventZSet, event, key)
+	}
+
+	k.removeIfEmpty(key, zset)
+
+	return popped, nil
+}
+
+func (z *SortedSet) rangeNodes(spec ZRangeSpec) []ZMember {
+	var x *skiplistNode
+	remaining := z.Len()
+
+	switch spec.By {
+	case ZRangeByIndex:
+		start, stop := listRange(z.Len(), spec.Start, spec.Stop)
+		if start >= stop {
+			return []ZMember{}
+		}
+
+		remaining = stop - start
+
+		if spec.Rev {
+			x = z.zsl.byRank(z.Len() - start)
+		} else {
+			x = z.zsl.byRank(start + 1)
+		}
+	case ZRangeByScore, ZRangeByLex:
+		var r rangeSpec = spec.Score
+		if spec.By == ZRangeByLex {
+			r = spec.Lex
+		}
+
+		if spec.Rev {
+			x = z.zsl.lastInRange(r)
+		} else {
+			x = z.zsl.firstInRange(r)
+		}
+
+		for i := 0; i < spec.Offset && x != nil; i++ {
+			x = z.next(x, spec.Rev)
+		}
+
+		if spec.Count >= 0 {
+			remaining = spec.Count
+		}
+
+		members := make([]ZMember, 0)
+		for ; x != nil && remaining > 0; remaining-- {
+			if !r.aboveMin(x) || !r.belowMax(x) {
+				break
+			}
+
+			members = append(members, ZMember{Member: x.member, Score: x.score})
+			x = z.next(x, spec.Rev)
+		}
+
+		return members
+	}
+
+	members := make([]ZMember, 0, remaining)
+	for ; x != nil && remaining > 0; remaining-- {
+		members = append(members, ZMember{Member: x.member, Score: x.score})
+		x = z.next(x, spec.Rev)
+	}
+
+	return members
+}
+
+func (z *SortedSet) next(x *skiplistNode, rev bool) *skiplistNode {
+	if rev {
+		return x.backward
+	}
+	return x.level[0].forward
+}
@@ -0,0 +1,221 @@
+package store
+
+import "sort"
+
+// entries in a node of a stream's radix tree in redis (stream-node-max-entries), approximate
+// trimming only removes whole nodes worth of entries
+const streamNodeEntries = 100
+
+// entries approximate trimming removes at most unless given a LIMIT
+const DefaultTrimLimit = 100 * streamNodeEntries
+
+// MAXLEN or MINID, as given to XADD & XTRIM
+type StreamTrim struct {
+	MaxLen    bool     // keep Threshold entries rather than those from MinId on
+	Threshold int      // entries to keep
+	MinId     StreamId // the smallest id to keep
+	Approx    bool     // ~, entries are only removed a node's worth at a time so more may be kept
+	Limit     int      // most entries removed when Approx, 0 for no limit
+}
+
+type StreamInfo struct {
+	Length       int
+	LastId       StreamId
+	MaxDeletedId StreamId
+	EntriesAdded uint64
+	FirstId      StreamId // of the first entry, 0-0 when the stream is empty
+	Groups       int
+	First        *StreamEntry // nil when the stream is empty
+	Last         *StreamEntry
+}
+
+type GroupInfo struct {
+	Name          string
+	Consumers     int
+	Pending       int
+	LastDelivered StreamId
+}
+
+type ConsumerInfo struct {
+	Name     string
+	Pending  int
+	SeenAt   uint64 // unix time in ms
+	ActiveAt uint64 // unix time in ms, 0 if it's never read or claimed entries
+}
+
+// removes entries from the start of the stream, returning how many. Pending entries of
+// groups aren't touched, so may refer to entries no longer in the stream
+func (s *Stream) trim(t StreamTrim) int {
+	var n int
+	if t.MaxLen {
+		n = max(len(s.Entries)-t.Threshold, 0)
+	} else {
+		n = s.search(t.MinId)
+	}
+
+	if t.Approx {
+		if t.Limit > 0 {
+			n = min(n, t.Limit)
+		}
+		n -= n % streamNodeEntries
+	}
+
+	// re-sliced rather than moved as readers may still hold the old slice
+	s.Entries = s.Entries[n:]
+	return n
+}
+
+// number of entries in the stream, 0 if it doesn't exist
+func (k *KvStore) XLen(key string) (int, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	stream, err := k.getStream(key)
+	if err != nil || stream == nil {
+		return 0, err
+	}
+
+	return len(stream.Entries), nil
+}
+
+// deletes the entries with the ids, returning how many existed. The stream's last id is
+// kept so deleting the last entry doesn't allow its id to be reused
+func (k *KvStore) XDel(key string, ids []StreamId) (int, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	stream, err := k.getStream(key)
+	if err != nil || stream == nil {
+		return 0, err
+	}
+
+	deleting := make(map[StreamId]bool, len(ids))
+	for _, id := range ids {
+		if _, exists := stream.entry(id); exists {
+			deleting[id] = true
+
+			if stream.MaxDeletedId.Less(id) {
+				stream.MaxDeletedId = id
+			}
+		}
+	}
+
+	if len(deleting) == 0 {
+		return 0, nil
+	}
+
+	// a new slice, readers may still hold the old one
+	kept := make([]StreamEntry, 0, len(stream.Entries)-len(deleting))
+	for _, entry := range stream.Entries {
+		if !deleting[entryId(entry)] {
+			kept = append(kept, entry)
+		}
+	}
+	stream.Entries = kept
+
+	k.touch(key)
+	k.notify(EventStream, "xdel", key)
+
+	return len(deleting), nil
+}
+
+// trims the stream, returning the number of entries removed and how many are left
+func (k *KvStore) XTrim(key string, trim StreamTrim) (int, int, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	stream, err := k.getStream(key)
+	if err != nil || stream == nil {
+		return 0, 0, err
+	}
+
+	trimmed := stream.trim(trim)
+	if trimmed > 0 {
+		k.touch(key)
+		k.notify(EventStream, "xtrim", key)
+	}
+
+	return trimmed, len(stream.Entries), nil
+}
+
+func (k *KvStore) XInfoStream(key string) (StreamInfo, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	stream, err := k.getStream(key)
+	if err != nil {
+		return StreamInfo{}, err
+	}
+	if stream == nil {
+		return StreamInfo{}, ErrNoSuchKey
+	}
+
+	info := StreamInfo{
+		Length:       len(stream.Entries),
+		LastId:       stream.LastId,
+		MaxDeletedId: stream.MaxDeletedId,
+		EntriesAdded: stream.EntriesAdded,
+		Groups:       len(stream.Groups),
+	}
+	if len(stream.Entries) > 0 {
+		first, last := stream.Entries[0], stream.Entries[len(stream.Entries)-1]
+		info.First, info.Last = &first, &last
+		info.FirstId = entryId(first)
+	}
+
+	return info, nil
+}
+
+// the stream's groups sorted by name
+func (k *KvStore) XInfoGroups(key string) ([]GroupInfo, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	stream, err := k.getStream(key)
+	if err != nil {
+		return nil, err
+	}
+	if stream == nil {
+		return nil, ErrNoSuchKey
+	}
+
+	groups := make([]GroupInfo, 0, len(stream.Groups))
+	for name, g := range stream.Groups {
+		groups = append(groups, GroupInfo{
+			Name:          name,
+			Consumers:     len(g.consumers),
+			Pending:       len(g.pending),
+			LastDelivered: g.LastDelivered,
+		})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+
+	return groups, nil
+}
+
+// the group's consumers sorted by name
+func (k *KvStore) XInfoConsumers(key string, group string) ([]ConsumerInfo, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	stream, err := k.getStream(key)
+	if err != nil {
+		return nil, err
+	}
+	if stream == nil {
+		return nil, ErrNoSuchKey
+	}
+
+	g, exists := stream.Groups[group]
+	if !exists {
+		return nil, ErrNoGroup
+	}
+
+	consumers := make([]ConsumerInfo, 0, len(g.consumers))
+	for _, c := range g.consumers {
+		consumers = append(consumers, ConsumerInfo{Name: c.Name, Pending: len(c.pending), SeenAt: c.SeenAt, ActiveAt: c.ActiveAt})
+	}
+	sort.Slice(consumers, func(i, j int) bool { return consumers[i].Name < consumers[j].Name })
+
+	return consumers, nil
+}
@@ -0,0 +1,885 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/rdb"
+)
+
+/* Consumer groups let several consumers share a stream, each entry being delivered to
+one consumer of the group. A group tracks the last entry it delivered and a pending
+entries list (PEL) of those delivered but not yet acknowledged with XACK, each with
+its owner, when it was last delivered and how many times. Pending entries of a consumer
+that went away can be claimed by another with XCLAIM/XAUTOCLAIM once idle long enough
+
+see: https://redis.io/docs/latest/develop/data-types/streams/#consumer-groups
+*/
+
+type StreamId struct {
+	Ms  uint64
+	Seq uint64
+}
+
+type ConsumerGroup struct {
+	LastDelivered StreamId
+	pending       map[StreamId]*PendingEntry
+	consumers     map[string]*Consumer
+}
+
+type Consumer struct {
+	Name     string
+	SeenAt   uint64 // unix time in ms it last read, claimed or was created
+	ActiveAt uint64 // unix time in ms it was last given new entries or claimed some, 0 if never
+	pending  map[StreamId]*PendingEntry
+}
+
+// an entry delivered to a consumer but not acknowledged
+type PendingEntry struct {
+	Id          StreamId
+	Consumer    string
+	DeliveredAt uint64 // unix time in ms
+	Deliveries  uint64
+}
+
+// the summary form of XPENDING
+type PendingSummary struct {
+	Count     int
+	Min       StreamId
+	Max       StreamId
+	Consumers []ConsumerPending // consumers with pending entries, sorted by name
+}
+
+type ConsumerPending struct {
+	Name  string
+	Count int
+}
+
+// filters for the extended form of XPENDING
+type PendingQuery struct {
+	Start    StreamId
+	End      StreamId
+	Count    int
+	Consumer string // empty for every consumer
+	MinIdle  uint64 // ms
+}
+
+type ClaimOptions struct {
+	DeliveredAt   uint64 // unix time in ms to record as the delivery, 0 for now
+	SetRetryCount bool   // set the delivery count to RetryCount rather than incrementing it
+	RetryCount    uint64
+	Force         bool // claim ids that aren't pending, so long as the entry exists
+	JustId        bool // the delivery count isn't incremented
+	LastId        StreamId
+}
+
+var (
+	ErrInvalidStreamId = errors.New("ERR Invalid stream ID specified as stream command argument")
+	ErrBusyGroup       = errors.New("BUSYGROUP Consumer Group name already exists")
+	ErrGroupKeyMissing = errors.New("ERR The XGROUP subcommand requires the key to exist. Note that for CREATE you may want to use the MKSTREAM option to create an empty stream automatically.")
+
+	// the key or group doesn't exist, the message differs by command so it's left to the caller
+	ErrNoGroup = errors.New("NOGROUP")
+)
+
+var MaxStreamId = StreamId{Ms: math.MaxUint64, Seq: math.MaxUint64}
+
+// parses <ms>-<seq> or <ms>, where seq is used for a missing sequence number (0 for the
+// start of a range, math.MaxUint64 for the end)
+func ParseStreamId(s string, seq uint64) (StreamId, error) {
+	msPart, seqPart, hasSeq := strings.Cut(s, "-")
+
+	ms, err := strconv.ParseUint(msPart, 10, 64)
+	if err != nil {
+		return StreamId{}, ErrInvalidStreamId
+	}
+
+	if hasSeq {
+		if seq, err = strconv.ParseUint(seqPart, 10, 64); err != nil {
+			return StreamId{}, ErrInvalidStreamId
+		}
+	}
+
+	return StreamId{Ms: ms, Seq: seq}, nil
+}
+
+// parses the start or end of a range, - and + being the smallest and largest ids and a
+// leading ( making it exclusive. ok is false for an exclusive range that can't match
+func ParseStreamRangeId(s string, end bool) (id StreamId, ok bool, err error) {
+	switch s {
+	case "-":
+		return StreamId{}, true, nil
+	case "+":
+		return MaxStreamId, true, nil
+	}
+
+	exclusive := strings.HasPrefix(s, "(")
+	s = strings.TrimPrefix(s, "(")
+
+	var seq uint64
+	if end {
+		seq = math.MaxUint64
+	}
+
+	id, err = ParseStreamId(s, seq)
+	if err != nil || !exclusive {
+		return id, true, err
+	}
+
+	if end {
+		if id == (StreamId{}) {
+			return id, false, nil
+		}
+		return id.prev(), true, nil
+	}
+
+	if id == MaxStreamId {
+		return id, false, nil
+	}
+	return id.next(), true, nil
+}
+
+func (id StreamId) String() string {
+	return fmt.Sprintf("%d-%d", id.Ms, id.Seq)
+}
+
+func (id StreamId) Less(other StreamId) bool {
+	return id.Ms < other.Ms || (id.Ms == other.Ms && id.Seq < other.Seq)
+}
+
+func (id StreamId) next() StreamId {
+	if id.Seq == math.MaxUint64 {
+		return StreamId{Ms: id.Ms + 1}
+	}
+	return StreamId{Ms: id.Ms, Seq: id.Seq + 1}
+}
+
+func (id StreamId) prev() StreamId {
+	if id.Seq == 0 {
+		return StreamId{Ms: id.Ms - 1, Seq: math.MaxUint64}
+	}
+	return StreamId{Ms: id.Ms, Seq: id.Seq - 1}
+}
+
+func entryId(entry StreamEntry) StreamId {
+	ms, seq := SplitSeqKey(entry.Seqkey)
+	return StreamId{Ms: uint64(ms), Seq: uint64(seq)}
+}
+
+// id of the last entry, 0-0 when the stream is empty
+func (s *Stream) lastEntryId() StreamId {
+	if len(s.Entries) == 0 {
+		return StreamId{}
+	}
+	return entryId(s.Entries[len(s.Entries)-1])
+}
+
+// index of the first entry with an id of at least id
+func (s *Stream) search(id StreamId) int {
+	return sort.Search(len(s.Entries), func(i int) bool {
+		return !entryId(s.Entries[i]).Less(id)
+	})
+}
+
+func (s *Stream) entry(id StreamId) (StreamEntry, bool) {
+	i := s.search(id)
+	if i < len(s.Entries) && entryId(s.Entries[i]) == id {
+		return s.Entries[i], true
+	}
+	return StreamEntry{}, false
+}
+
+func newConsumerGroup(lastDelivered StreamId) *ConsumerGroup {
+	return &ConsumerGroup{
+		LastDelivered: lastDelivered,
+		pending:       make(map[StreamId]*PendingEntry),
+		consumers:     make(map[string]*Consumer),
+	}
+}
+
+// the named consumer, created when it doesn't exist
+func (g *ConsumerGroup) consumer(name string, ms uint64) (*Consumer, bool) {
+	if c, exists := g.consumers[name]; exists {
+		return c, false
+	}
+
+	c := &Consumer{Name: name, SeenAt: ms, pending: make(map[StreamId]*PendingEntry)}
+	g.consumers[name] = c
+	return c, true
+}
+
+// the group's pending entries ordered by id
+func (g *ConsumerGroup) sortedPending() []*PendingEntry {
+	pending := make([]*PendingEntry, 0, len(g.pending))
+	for _, p := range g.pending {
+		pending = append(pending, p)
+	}
+
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Id.Less(pending[j].Id) })
+	return pending
+}
+
+// gives the pending entry to the consumer, taking it from its current owner
+func (g *ConsumerGroup) assign(p *PendingEntry, c *Consumer) {
+	if owner, exists := g.consumers[p.Consumer]; exists {
+		delete(owner.pending, p.Id)
+	}
+
+	p.Consumer = c.Name
+	c.pending[p.Id] = p
+}
+
+func (g *ConsumerGroup) ack(id StreamId) bool {
+	p, exists := g.pending[id]
+	if !exists {
+		return false
+	}
+
+	delete(g.pending, id)
+	if owner, exists := g.consumers[p.Consumer]; exists {
+		delete(owner.pending, id)
+	}
+	return true
+}
+
+// must be called with the lock held, missing keys are returned as nil
+func (k *KvStore) getStream(key string) (*Stream, error) {
+	val, exists := k.lookup(key)
+	if !exists {
+		return nil, nil
+	}
+
+	stream, ok := val.(*Stream)
+	if !ok {
+		return nil, ErrWrongType
+	}
+
+	return stream, nil
+}
+
+// must be called with the lock held
+func (k *KvStore) getGroup(key string, name string) (*Stream, *ConsumerGroup, error) {
+	stream, err := k.getStream(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if stream == nil || stream.Groups[name] == nil {
+		return nil, nil, ErrNoGroup
+	}
+
+	return stream, stream.Groups[name], nil
+}
+
+// resolves $ to the stream's last id
+func parseGroupId(stream *Stream, id string) (StreamId, error) {
+	if id == "$" {
+		return stream.LastId, nil
+	}
+	return ParseStreamId(id, 0)
+}
+
+// creates the group delivering entries after id ($ for only new entries), with mkstream
+// creating an empty stream when the key doesn't exist
+func (k *KvStore) XGroupCreate(key string, group string, id string, mkstream bool) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	stream, err := k.getStream(key)
+	if err != nil {
+		return err
+	}
+
+	if stream == nil {
+		if !mkstream {
+			return ErrGroupKeyMissing
+		}
+		stream = &Stream{Groups: make(map[string]*ConsumerGroup)}
+	}
+
+	lastDelivered, err := parseGroupId(stream, id)
+	if err != nil {
+		return err
+	}
+
+	if _, exists := stream.Groups[group]; exists {
+		return ErrBusyGroup
+	}
+
+	if _, exists := k.values[key]; !exists {
+		k.create(key, stream)
+	}
+
+	stream.Groups[group] = newConsumerGroup(lastDelivered)
+	k.touch(key)
+	k.notify(EventStream, "xgroup-create", key)
+
+	return nil
+}
+
+// sets the last entry delivered to the group, $ being the stream's last entry
+func (k *KvStore) XGroupSetId(key string, group string, id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	stream, err := k.getStream(key)
+	if err != nil {
+		return err
+	}
+
+	if stream == nil {
+		return ErrGroupKeyMissing
+	}
+
+	g, exists := stream.Groups[group]
+	if !exists {
+		return ErrNoGroup
+	}
+
+	if g.LastDelivered, err = parseGroupId(stream, id); err != nil {
+		return err
+	}
+
+	k.touch(key)
+	k.notify(EventStream, "xgroup-setid", key)
+
+	return nil
+}
+
+// removes the group along with its consumers and pending entries, returning whether it existed
+func (k *KvStore) XGroupDestroy(key string, group string) (bool, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	stream, err := k.getStream(key)
+	if err != nil {
+		return false, err
+	}
+
+	if stream == nil {
+		return false, ErrGroupKeyMissing
+	}
+
+	if _, exists := stream.Groups[group]; !exists {
+		return false, nil
+	}
+
+	delete(stream.Groups, group)
+	k.touch(key)
+	k.notify(EventStream, "xgroup-destroy", key)
+	k.wakeStreamWaiters(key)
+
+	return true, nil
+}
+
+// returns whether the consumer was created, false when it already existed
+func (k *KvStore) XGroupCreateConsumer(key string, group string, consumer string) (bool, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	stream, err := k.getStream(key)
+	if err != nil {
+		return false, err
+	}
+
+	if stream == nil {
+		return false, ErrGroupKeyMissing
+	}
+
+	g, exists := stream.Groups[group]
+	if !exists {
+		return false, ErrNoGroup
+	}
+
+	if _, created := g.consumer(consumer, currentMillis()); !created {
+		return false, nil
+	}
+
+	k.touch(key)
+	k.notify(EventStream, "xgroup-createconsumer", key)
+
+	return true, nil
+}
+
+// removes the consumer, returning how many entries it had pending. They're removed from
+// the group's pending entries too, so can't be claimed
+func (k *KvStore) XGroupDelConsumer(key string, group string, consumer string) (int, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	stream, err := k.getStream(key)
+	if err != nil {
+		return 0, err
+	}
+
+	if stream == nil {
+		return 0, ErrGroupKeyMissing
+	}
+
+	g, exists := stream.Groups[group]
+	if !exists {
+		return 0, ErrNoGroup
+	}
+
+	c, exists := g.consumers[consumer]
+	if !exists {
+		return 0, nil
+	}
+
+	pending := len(c.pending)
+	for id := range c.pending {
+		delete(g.pending, id)
+	}
+	delete(g.consumers, consumer)
+
+	k.touch(key)
+	k.notify(EventStream, "xgroup-delconsumer", key)
+
+	return pending, nil
+}
+
+// reads for the consumer of the group. With > it's delivered up to count entries the group
+// hasn't delivered yet (0 for no limit), which are added to the pending entries unless
+// noack is set. Otherwise its pending entries after the id are returned again, those
+// since deleted from the stream with nil values
+func (k *KvStore) XReadGroup(key string, group string, consumer string, start string, count int, noack bool) ([]StreamEntry, error) {
+	results, _, err := k.XReadGroups(group, consumer, []GroupRead{{Key: key, Start: start}}, count, noack)
+	if err != nil {
+		return nil, err
+	}
+
+	return results[0].Entries, nil
+}
+
+// a stream read by XREADGROUP, from > or the id to re-read the consumer's pending entries after
+type GroupRead struct {
+	Key   string
+	Start string
+}
+
+type GroupReadResult struct {
+	Entries []StreamEntry
+	Created bool // whether the read created the consumer
+}
+
+// reads each stream like XReadGroup. Every stream is checked for the group before any is
+// read, so a missing one leaves the others untouched. Returns the key it failed on
+func (k *KvStore) XReadGroups(group string, consumer string, reads []GroupRead, count int, noack bool) ([]GroupReadResult, string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	streams := make([]*Stream, len(reads))
+	afters := make([]StreamId, len(reads))
+	for i, read := range reads {
+		stream, _, err := k.getGroup(read.Key, group)
+		if err != nil {
+			return nil, read.Key, err
+		}
+
+		if read.Start != ">" {
+			if afters[i], err = ParseStreamId(read.Start, 0); err != nil {
+				return nil, read.Key, err
+			}
+		}
+
+		streams[i] = stream
+	}
+
+	results := make([]GroupReadResult, len(reads))
+	for i, read := range reads {
+		results[i] = k.readGroup(read, streams[i], streams[i].Groups[group], consumer, afters[i], count, noack)
+	}
+
+	return results, "", nil
+}
+
+// must be called with the lock held
+func (k *KvStore) readGroup(read GroupRead, stream *Stream, g *ConsumerGroup, consumer string, after StreamId, count int, noack bool) GroupReadResult {
+	ms := currentMillis()
+	c, created := g.consumer(consumer, ms)
+	c.SeenAt = ms
+
+	if created {
+		k.touch(read.Key)
+		k.notify(EventStream, "xgroup-createconsumer", read.Key)
+	}
+
+	if read.Start != ">" {
+		return GroupReadResult{Entries: c.history(stream, after, count), Created: created}
+	}
+
+	var entries []StreamEntry
+	for i := stream.search(g.LastDelivered.next()); i < len(stream.Entries); i++ {
+		if count > 0 && len(entries) == count {
+			break
+		}
+
+		entry := stream.Entries[i]
+		id := entryId(entry)
+		entries = append(entries, entry)
+		g.LastDelivered = id
+
+		if noack {
+			continue
+		}
+
+		// an entry can only be pending for one consumer, a re-delivery after SETID moves it
+		p, exists := g.pending[id]
+		if !exists {
+			p = &PendingEntry{Id: id}
+			g.pending[id] = p
+		}
+		g.assign(p, c)
+		p.DeliveredAt = ms
+		p.Deliveries = 1
+	}
+
+	if len(entries) > 0 {
+		c.ActiveAt = ms
+		k.touch(read.Key)
+	}
+
+	return GroupReadResult{Entries: entries, Created: created}
+}
+
+// the consumer's pending entries after the id
+func (c *Consumer) history(stream *Stream, after StreamId, count int) []StreamEntry {
+	ids := make([]StreamId, 0, len(c.pending))
+	for id := range c.pending {
+		if after.Less(id) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].Less(ids[j]) })
+
+	if count > 0 && len(ids) > count {
+		ids = ids[:count]
+	}
+
+	entries := make([]StreamEntry, 0, len(ids))
+	for _, id := range ids {
+		entry, exists := stream.entry(id)
+		if !exists {
+			entry = StreamEntry{Seqkey: id.String()}
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+// acknowledges the entries, removing them from the pending entries. Returns how many were
+// pending, a missing key or group acknowledging nothing
+func (k *KvStore) XAck(key string, group string, ids []StreamId) (int, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	_, g, err := k.getGroup(key, group)
+	if err == ErrNoGroup {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	acked := 0
+	for _, id := range ids {
+		if g.ack(id) {
+			acked++
+		}
+	}
+
+	if acked > 0 {
+		k.touch(key)
+	}
+
+	return acked, nil
+}
+
+func (k *KvStore) XPendingSummary(key string, group string) (PendingSummary, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	_, g, err := k.getGroup(key, group)
+	if err != nil {
+		return PendingSummary{}, err
+	}
+
+	summary := PendingSummary{Count: len(g.pending)}
+	if summary.Count == 0 {
+		return summary, nil
+	}
+
+	pending := g.sortedPending()
+	summary.Min = pending[0].Id
+	summary.Max = pending[len(pending)-1].Id
+
+	for _, c := range g.consumers {
+		if len(c.pending) > 0 {
+			summary.Consumers = append(summary.Consumers, ConsumerPending{Name: c.Name, Count: len(c.pending)})
+		}
+	}
+	sort.Slice(summary.Consumers, func(i, j int) bool { return summary.Consumers[i].Name < summary.Consumers[j].Name })
+
+	return summary, nil
+}
+
+// the pending entries matching the query ordered by id, copied so they can be read
+// without the lock
+func (k *KvStore) XPending(key string, group string, query PendingQuery) ([]PendingEntry, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	_, g, err := k.getGroup(key, group)
+	if err != nil {
+		return nil, err
+	}
+
+	ms := currentMillis()
+	var result []PendingEntry
+
+	for _, p := range g.sortedPending() {
+		if len(result) == query.Count {
+			break
+		}
+
+		if p.Id.Less(query.Start) || query.End.Less(p.Id) {
+			continue
+		}
+
+		if query.Consumer != "" && p.Consumer != query.Consumer {
+			continue
+		}
+
+		if idle(p, ms) < query.MinIdle {
+			continue
+		}
+
+		result = append(result, *p)
+	}
+
+	return result, nil
+}
+
+// ms since the entry was last delivered
+func idle(p *PendingEntry, ms uint64) uint64 {
+	if ms < p.DeliveredAt {
+		return 0
+	}
+	return ms - p.DeliveredAt
+}
+
+// claims the pending entries idle for at least minIdle ms for the consumer, returning
+// those claimed. Entries deleted from the stream are dropped from the pending entries
+func (k *KvStore) XClaim(key string, group string, consumer string, minIdle uint64, ids []StreamId, options ClaimOptions) ([]StreamEntry, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	stream, g, err := k.getGroup(key, group)
+	if err != nil {
+		return nil, err
+	}
+
+	ms := currentMillis()
+	deliveredAt := options.DeliveredAt
+	if deliveredAt == 0 {
+		deliveredAt = ms
+	}
+
+	if g.LastDelivered.Less(options.LastId) {
+		g.LastDelivered = options.LastId
+	}
+
+	c, created := g.consumer(consumer, ms)
+	c.SeenAt = ms
+
+	if created {
+		k.notify(EventStream, "xgroup-createconsumer", key)
+	}
+
+	var claimed []StreamEntry
+	for _, id := range ids {
+		p, pending := g.pending[id]
+		entry, exists := stream.entry(id)
+
+		if !pending {
+			if !options.Force || !exists {
+				continue
+			}
+
+			p = &PendingEntry{Id: id, DeliveredAt: ms}
+			g.pending[id] = p
+		}
+
+		if !exists {
+			g.ack(id)
+			continue
+		}
+
+		if minIdle > 0 && idle(p, ms) < minIdle {
+			continue
+		}
+
+		g.assign(p, c)
+		p.DeliveredAt = deliveredAt
+
+		if options.SetRetryCount {
+			p.Deliveries = options.RetryCount
+		} else if !options.JustId {
+			p.Deliveries++
+		}
+
+		claimed = append(claimed, entry)
+	}
+
+	if len(claimed) > 0 {
+		c.ActiveAt = ms
+	}
+
+	k.touch(key)
+
+	return claimed, nil
+}
+
+// claims up to count pending entries from start onwards that have been idle for at least
+// minIdle ms, scanning at most 10 times count entries. Returns the id to continue from
+// (0-0 once the scan is complete), the entries claimed and the ids of those deleted from
+// the stream, which are dropped from the pending entries
+func (k *KvStore) XAutoClaim(key string, group string, consumer string, minIdle uint64, start StreamId, count int, justId bool) (StreamId, []StreamEntry, []StreamId, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	stream, g, err := k.getGroup(key, group)
+	if err != nil {
+		return StreamId{}, nil, nil, err
+	}
+
+	ms := currentMillis()
+	c, created := g.consumer(consumer, ms)
+	c.SeenAt = ms
+
+	if created {
+		k.notify(EventStream, "xgroup-createconsumer", key)
+	}
+
+	var claimed []StreamEntry
+	var deleted []StreamId
+
+	pending := g.sortedPending()
+	i := sort.Search(len(pending), func(i int) bool { return !pending[i].Id.Less(start) })
+
+	for attempts := count * 10; i < len(pending) && len(claimed) < count && attempts > 0; i, attempts = i+1, attempts-1 {
+		p := pending[i]
+
+		entry, exists := stream.entry(p.Id)
+		if !exists {
+			g.ack(p.Id)
+			deleted = append(deleted, p.Id)
+			continue
+		}
+
+		if idle(p, ms) < minIdle {
+			continue
+		}
+
+		g.assign(p, c)
+		p.DeliveredAt = ms
+		if !justId {
+			p.Deliveries++
+		}
+
+		claimed = append(claimed, entry)
+	}
+
+	next := StreamId{}
+	if i < len(pending) {
+		next = pending[i].Id
+	}
+
+	if len(claimed) > 0 {
+		c.ActiveAt = ms
+	}
+
+	k.touch(key)
+
+	return next, claimed, deleted, nil
+}
+
+func groupsToRdb(groups map[string]*ConsumerGroup) []rdb.StreamGroup {
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make([]rdb.StreamGroup, 0, len(groups))
+	for _, name := range names {
+		g := groups[name]
+		group := rdb.StreamGroup{Name: name, LastId: rdb.StreamId(g.LastDelivered)}
+
+		for _, p := range g.sortedPending() {
+			group.Pending = append(group.Pending, rdb.StreamPendingEntry{
+				Id:            rdb.StreamId(p.Id),
+				DeliveryTime:  p.DeliveredAt,
+				DeliveryCount: p.Deliveries,
+			})
+		}
+
+		consumers := make([]string, 0, len(g.consumers))
+		for name := range g.consumers {
+			consumers = append(consumers, name)
+		}
+		sort.Strings(consumers)
+
+		for _, name := range consumers {
+			c := g.consumers[name]
+			consumer := rdb.StreamConsumer{Name: name, SeenTime: c.SeenAt, ActiveTime: c.ActiveAt}
+
+			for _, p := range g.sortedPending() {
+				if p.Consumer == name {
+					consumer.Pending = append(consumer.Pending, rdb.StreamId(p.Id))
+				}
+			}
+
+			group.Consumers = append(group.Consumers, consumer)
+		}
+
+		result = append(result, group)
+	}
+
+	return result
+}
+
+func groupsFromRdb(groups []rdb.StreamGroup) map[string]*ConsumerGroup {
+	result := make(map[string]*ConsumerGroup, len(groups))
+
+	for _, group := range groups {
+		g := newConsumerGroup(StreamId(group.LastId))
+
+		for _, p := range group.Pending {
+			g.pending[StreamId(p.Id)] = &PendingEntry{
+				Id:          StreamId(p.Id),
+				DeliveredAt: p.DeliveryTime,
+				Deliveries:  p.DeliveryCount,
+			}
+		}
+
+		for _, consumer := range group.Consumers {
+			c, _ := g.consumer(consumer.Name, consumer.SeenTime)
+			c.ActiveAt = consumer.ActiveTime
+			for _, id := range consumer.Pending {
+				if p, exists := g.pending[StreamId(id)]; exists {
+					g.assign(p, c)
+				}
+			}
+		}
+
+		result[group.Name] = g
+	}
+
+	return result
+}
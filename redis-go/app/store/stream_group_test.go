@@ -0,0 +1,395 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+)
+
+// a stream with entries 1-0 to n-0 and a group reading from its start
+func newGroupTestStore(t *testing.T, n int) *KvStore {
+	t.Helper()
+
+	kv := newStreamTestStore(t, n)
+	if err := kv.XGroupCreate("s", "g", "0", false); err != nil {
+		t.Fatalf("error creating group: %v", err)
+	}
+
+	return kv
+}
+
+func ids(entries []StreamEntry) []string {
+	result := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, entry.Seqkey)
+	}
+	return result
+}
+
+func pendingFor(t *testing.T, kv *KvStore) []PendingEntry {
+	t.Helper()
+
+	pending, err := kv.XPending("s", "g", PendingQuery{End: MaxStreamId, Count: 100})
+	if err != nil {
+		t.Fatalf("error reading pending entries: %v", err)
+	}
+	return pending
+}
+
+func TestXReadGroupAddsToPendingEntries(t *testing.T) {
+	// arrange
+	kv := newGroupTestStore(t, 3)
+
+	// act
+	entries, err := kv.XReadGroup("s", "g", "alice", ">", 2, false)
+
+	// assert
+	if err != nil {
+		t.Fatalf("error reading: %v", err)
+	}
+
+	if got := fmt.Sprint(ids(entries)); got != "[1-0 2-0]" {
+		t.Errorf("expected [1-0 2-0] but got %s", got)
+	}
+
+	pending := pendingFor(t, kv)
+	if len(pending) != 2 {
+		t.Fatalf("expected 2 pending entries but got %d", len(pending))
+	}
+
+	for _, p := range pending {
+		if p.Consumer != "alice" || p.Deliveries != 1 {
+			t.Errorf("expected %s pending for alice once but got %s %d times", p.Id, p.Consumer, p.Deliveries)
+		}
+	}
+
+	// the next read carries on after the last delivered
+	entries, _ = kv.XReadGroup("s", "g", "bob", ">", 0, false)
+	if got := fmt.Sprint(ids(entries)); got != "[3-0]" {
+		t.Errorf("expected [3-0] but got %s", got)
+	}
+
+	summary, _ := kv.XPendingSummary("s", "g")
+	if summary.Count != 3 || summary.Min != (StreamId{Ms: 1}) || summary.Max != (StreamId{Ms: 3}) {
+		t.Errorf("expected 3 pending from 1-0 to 3-0 but got %d from %s to %s", summary.Count, summary.Min, summary.Max)
+	}
+
+	if got := fmt.Sprint(summary.Consumers); got != "[{alice 2} {bob 1}]" {
+		t.Errorf("expected [{alice 2} {bob 1}] but got %s", got)
+	}
+}
+
+func TestXReadGroupNoAckSkipsPendingEntries(t *testing.T) {
+	// arrange
+	kv := newGroupTestStore(t, 2)
+
+	// act
+	entries, _ := kv.XReadGroup("s", "g", "alice", ">", 0, true)
+
+	// assert
+	if len(entries) != 2 {
+		t.Errorf("expected 2 entries but got %d", len(entries))
+	}
+
+	if pending := pendingFor(t, kv); len(pending) != 0 {
+		t.Errorf("expected no pending entries but got %d", len(pending))
+	}
+}
+
+func TestXReadGroupHistoryDoesntCountAsDelivery(t *testing.T) {
+	// arrange
+	kv := newGroupTestStore(t, 3)
+	kv.XReadGroup("s", "g", "alice", ">", 0, false)
+	kv.XDel("s", []StreamId{{Ms: 2}})
+
+	// act
+	entries, _ := kv.XReadGroup("s", "g", "alice", "0", 0, false)
+	after, _ := kv.XReadGroup("s", "g", "alice", "1-0", 1, false)
+	other, _ := kv.XReadGroup("s", "g", "bob", "0", 0, false)
+
+	// assert
+	if got := fmt.Sprint(ids(entries)); got != "[1-0 2-0 3-0]" {
+		t.Errorf("expected [1-0 2-0 3-0] but got %s", got)
+	}
+
+	if entries[1].Fields != nil {
+		t.Errorf("expected the deleted entry to have no fields but got %v", entries[1].Fields)
+	}
+
+	if got := fmt.Sprint(ids(after)); got != "[2-0]" {
+		t.Errorf("expected [2-0] but got %s", got)
+	}
+
+	if len(other) != 0 {
+		t.Errorf("expected bob to have no history but got %v", ids(other))
+	}
+
+	for _, p := range pendingFor(t, kv) {
+		if p.Deliveries != 1 {
+			t.Errorf("expected %s to have been delivered once but got %d", p.Id, p.Deliveries)
+		}
+	}
+}
+
+func TestXAckRemovesPendingEntries(t *testing.T) {
+	// arrange
+	kv := newGroupTestStore(t, 3)
+	kv.XReadGroup("s", "g", "alice", ">", 0, false)
+
+	// act
+	acked, err := kv.XAck("s", "g", []StreamId{{Ms: 1}, {Ms: 3}, {Ms: 4}})
+	again, _ := kv.XAck("s", "g", []StreamId{{Ms: 1}})
+	missing, missingErr := kv.XAck("s", "nogroup", []StreamId{{Ms: 2}})
+
+	// assert
+	if err != nil || acked != 2 {
+		t.Errorf("expected 2 acked but got %d, %v", acked, err)
+	}
+
+	if again != 0 {
+		t.Errorf("expected acking twice to ack nothing but got %d", again)
+	}
+
+	if missingErr != nil || missing != 0 {
+		t.Errorf("expected a missing group to ack nothing but got %d, %v", missing, missingErr)
+	}
+
+	pending := pendingFor(t, kv)
+	if len(pending) != 1 || pending[0].Id != (StreamId{Ms: 2}) {
+		t.Errorf("expected only 2-0 pending but got %v", pending)
+	}
+
+	history, _ := kv.XReadGroup("s", "g", "alice", "0", 0, false)
+	if got := fmt.Sprint(ids(history)); got != "[2-0]" {
+		t.Errorf("expected alice's history to be [2-0] but got %s", got)
+	}
+}
+
+func TestXClaimMinIdle(t *testing.T) {
+	// arrange
+	kv := newGroupTestStore(t, 2)
+	kv.XReadGroup("s", "g", "alice", ">", 0, false)
+
+	// 1-0 delivered a minute ago, 2-0 just now
+	kv.XClaim("s", "g", "alice", 0, []StreamId{{Ms: 1}}, ClaimOptions{DeliveredAt: currentMillis() - 60_000, JustId: true})
+
+	// act
+	claimed, err := kv.XClaim("s", "g", "bob", 30_000, []StreamId{{Ms: 1}, {Ms: 2}}, ClaimOptions{})
+
+	// assert
+	if err != nil {
+		t.Fatalf("error claiming: %v", err)
+	}
+
+	if got := fmt.Sprint(ids(claimed)); got != "[1-0]" {
+		t.Errorf("expected only the idle entry 1-0 to be claimed but got %s", got)
+	}
+
+	pending := pendingFor(t, kv)
+	if pending[0].Consumer != "bob" || pending[0].Deliveries != 2 {
+		t.Errorf("expected 1-0 delivered to bob twice but got %s %d times", pending[0].Consumer, pending[0].Deliveries)
+	}
+
+	if pending[1].Consumer != "alice" || pending[1].Deliveries != 1 {
+		t.Errorf("expected 2-0 delivered to alice once but got %s %d times", pending[1].Consumer, pending[1].Deliveries)
+	}
+
+	// claiming again resets the idle time
+	again, _ := kv.XClaim("s", "g", "carol", 30_000, []StreamId{{Ms: 1}}, ClaimOptions{})
+	if len(again) != 0 {
+		t.Errorf("expected the just claimed entry not to be idle but got %v", ids(again))
+	}
+}
+
+func TestXClaimOptions(t *testing.T) {
+	cases := []struct {
+		name        string
+		id          StreamId
+		options     ClaimOptions
+		claimed     string
+		deliveries  uint64
+		pendingLeft int
+	}{
+		{name: "increments deliveries", id: StreamId{Ms: 1}, claimed: "[1-0]", deliveries: 2, pendingLeft: 1},
+		{name: "JUSTID", id: StreamId{Ms: 1}, options: ClaimOptions{JustId: true}, claimed: "[1-0]", deliveries: 1, pendingLeft: 1},
+		{name: "RETRYCOUNT", id: StreamId{Ms: 1}, options: ClaimOptions{SetRetryCount: true, RetryCount: 7}, claimed: "[1-0]", deliveries: 7, pendingLeft: 1},
+		{name: "not pending", id: StreamId{Ms: 2}, claimed: "[]", pendingLeft: 1},
+		{name: "FORCE", id: StreamId{Ms: 2}, options: ClaimOptions{Force: true}, claimed: "[2-0]", deliveries: 1, pendingLeft: 2},
+		{name: "FORCE missing entry", id: StreamId{Ms: 9}, options: ClaimOptions{Force: true}, claimed: "[]", pendingLeft: 1},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// arrange
+			kv := newGroupTestStore(t, 2)
+			kv.XReadGroup("s", "g", "alice", ">", 1, false)
+
+			// act
+			claimed, err := kv.XClaim("s", "g", "bob", 0, []StreamId{c.id}, c.options)
+
+			// assert
+			if err != nil {
+				t.Fatalf("error claiming: %v", err)
+			}
+
+			if got := fmt.Sprint(ids(claimed)); got != c.claimed {
+				t.Errorf("expected %s claimed but got %s", c.claimed, got)
+			}
+
+			pending := pendingFor(t, kv)
+			if len(pending) != c.pendingLeft {
+				t.Fatalf("expected %d pending but got %d", c.pendingLeft, len(pending))
+			}
+
+			if len(claimed) == 0 {
+				return
+			}
+
+			for _, p := range pending {
+				if p.Id == c.id && (p.Consumer != "bob" || p.Deliveries != c.deliveries) {
+					t.Errorf("expected %s delivered to bob %d times but got %s %d times", p.Id, c.deliveries, p.Consumer, p.Deliveries)
+				}
+			}
+		})
+	}
+}
+
+func TestXClaimDropsDeletedEntries(t *testing.T) {
+	// arrange
+	kv := newGroupTestStore(t, 2)
+	kv.XReadGroup("s", "g", "alice", ">", 0, false)
+	kv.XDel("s", []StreamId{{Ms: 1}})
+
+	// act
+	claimed, _ := kv.XClaim("s", "g", "bob", 0, []StreamId{{Ms: 1}, {Ms: 2}}, ClaimOptions{})
+
+	// assert
+	if got := fmt.Sprint(ids(claimed)); got != "[2-0]" {
+		t.Errorf("expected [2-0] but got %s", got)
+	}
+
+	pending := pendingFor(t, kv)
+	if len(pending) != 1 || pending[0].Id != (StreamId{Ms: 2}) {
+		t.Errorf("expected the deleted entry to be dropped from the pending entries but got %v", pending)
+	}
+}
+
+func TestXAutoClaimCursor(t *testing.T) {
+	// arrange
+	kv := newGroupTestStore(t, 5)
+	kv.XReadGroup("s", "g", "alice", ">", 0, false)
+
+	// act
+	next, claimed, deleted, err := kv.XAutoClaim("s", "g", "bob", 0, StreamId{}, 2, false)
+
+	// assert
+	if err != nil {
+		t.Fatalf("error claiming: %v", err)
+	}
+
+	if got := fmt.Sprint(ids(claimed)); got != "[1-0 2-0]" || next != (StreamId{Ms: 3}) || len(deleted) != 0 {
+		t.Errorf("expected [1-0 2-0] continuing from 3-0 but got %s continuing from %s", got, next)
+	}
+
+	next, claimed, _, _ = kv.XAutoClaim("s", "g", "bob", 0, next, 2, false)
+	if got := fmt.Sprint(ids(claimed)); got != "[3-0 4-0]" || next != (StreamId{Ms: 5}) {
+		t.Errorf("expected [3-0 4-0] continuing from 5-0 but got %s continuing from %s", got, next)
+	}
+
+	next, claimed, _, _ = kv.XAutoClaim("s", "g", "bob", 0, next, 2, true)
+	if got := fmt.Sprint(ids(claimed)); got != "[5-0]" || next != (StreamId{}) {
+		t.Errorf("expected [5-0] completing the scan but got %s continuing from %s", got, next)
+	}
+
+	for _, p := range pendingFor(t, kv) {
+		want := uint64(2)
+		if p.Id == (StreamId{Ms: 5}) {
+			want = 1 // claimed with JUSTID
+		}
+
+		if p.Consumer != "bob" || p.Deliveries != want {
+			t.Errorf("expected %s delivered to bob %d times but got %s %d times", p.Id, want, p.Consumer, p.Deliveries)
+		}
+	}
+}
+
+func TestXAutoClaimMinIdle(t *testing.T) {
+	// arrange
+	kv := newGroupTestStore(t, 3)
+	kv.XReadGroup("s", "g", "alice", ">", 0, false)
+	kv.XClaim("s", "g", "alice", 0, []StreamId{{Ms: 2}}, ClaimOptions{DeliveredAt: currentMillis() - 60_000, JustId: true})
+
+	// act
+	next, claimed, _, _ := kv.XAutoClaim("s", "g", "bob", 30_000, StreamId{}, 10, false)
+
+	// assert
+	if got := fmt.Sprint(ids(claimed)); got != "[2-0]" || next != (StreamId{}) {
+		t.Errorf("expected only the idle entry 2-0 claimed but got %s continuing from %s", got, next)
+	}
+}
+
+func TestXAutoClaimReportsDeletedEntries(t *testing.T) {
+	// arrange
+	kv := newGroupTestStore(t, 4)
+	kv.XReadGroup("s", "g", "alice", ">", 0, false)
+	kv.XDel("s", []StreamId{{Ms: 1}, {Ms: 3}})
+
+	// act
+	next, claimed, deleted, _ := kv.XAutoClaim("s", "g", "bob", 0, StreamId{}, 1, false)
+
+	// assert - a deleted entry counts towards the scan but not the claimed count
+	if got := fmt.Sprint(ids(claimed)); got != "[2-0]" {
+		t.Errorf("expected [2-0] but got %s", got)
+	}
+
+	if got := fmt.Sprint(deleted); got != "[1-0]" || next != (StreamId{Ms: 3}) {
+		t.Errorf("expected 1-0 deleted continuing from 3-0 but got %s continuing from %s", got, next)
+	}
+
+	_, _, deleted, _ = kv.XAutoClaim("s", "g", "bob", 0, next, 1, false)
+	if got := fmt.Sprint(deleted); got != "[3-0]" {
+		t.Errorf("expected 3-0 deleted but got %s", got)
+	}
+
+	pending := pendingFor(t, kv)
+	if len(pending) != 2 {
+		t.Errorf("expected the deleted entries dropped leaving 2 pending but got %d", len(pending))
+	}
+}
+
+func TestXAutoClaimScansAtMostTenTimesCount(t *testing.T) {
+	// arrange
+	kv := newGroupTestStore(t, 15)
+	kv.XReadGroup("s", "g", "alice", ">", 0, false)
+
+	// act - nothing is idle long enough so every entry is scanned without a claim
+	next, claimed, _, _ := kv.XAutoClaim("s", "g", "bob", 60_000, StreamId{}, 1, false)
+
+	// assert
+	if len(claimed) != 0 || next != (StreamId{Ms: 11}) {
+		t.Errorf("expected nothing claimed continuing from 11-0 but got %v continuing from %s", ids(claimed), next)
+	}
+}
+
+func TestGroupErrors(t *testing.T) {
+	// arrange
+	kv := newGroupTestStore(t, 1)
+
+	// act
+	_, readErr := kv.XReadGroup("s", "nogroup", "alice", ">", 0, false)
+	_, claimErr := kv.XClaim("missing", "g", "alice", 0, nil, ClaimOptions{})
+	createErr := kv.XGroupCreate("s", "g", "0", false)
+	missingErr := kv.XGroupCreate("missing", "g", "0", false)
+
+	// assert
+	if readErr != ErrNoGroup || claimErr != ErrNoGroup {
+		t.Errorf("expected NOGROUP but got %v, %v", readErr, claimErr)
+	}
+
+	if createErr != ErrBusyGroup {
+		t.Errorf("expected BUSYGROUP but got %v", createErr)
+	}
+
+	if missingErr != ErrGroupKeyMissing {
+		t.Errorf("expected the key to be required but got %v", missingErr)
+	}
+}
@@ -0,0 +1,203 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// a stream with entries 1-0 to n-0
+func newStreamTestStore(t *testing.T, n int) *KvStore {
+	t.Helper()
+
+	kv := NewKvStore(zerolog.Nop())
+	for i := 1; i <= n; i++ {
+		if _, err := kv.SetStream("s", fmt.Sprintf("%d-0", i), []string{"f", "v"}, StreamAddOptions{}); err != nil {
+			t.Fatalf("error adding entry: %v", err)
+		}
+	}
+
+	return kv
+}
+
+func firstEntryId(t *testing.T, kv *KvStore) StreamId {
+	t.Helper()
+
+	info, err := kv.XInfoStream("s")
+	if err != nil {
+		t.Fatalf("error reading stream info: %v", err)
+	}
+	return info.FirstId
+}
+
+func TestXTrim(t *testing.T) {
+	cases := []struct {
+		name    string
+		entries int
+		trim    StreamTrim
+		trimmed int
+	}{
+		{name: "MAXLEN", entries: 10, trim: StreamTrim{MaxLen: true, Threshold: 3}, trimmed: 7},
+		{name: "MAXLEN longer than the stream", entries: 10, trim: StreamTrim{MaxLen: true, Threshold: 20}, trimmed: 0},
+		{name: "MAXLEN 0", entries: 10, trim: StreamTrim{MaxLen: true}, trimmed: 10},
+		{name: "MINID", entries: 10, trim: StreamTrim{MinId: StreamId{Ms: 4}}, trimmed: 3},
+		{name: "MINID between ids", entries: 10, trim: StreamTrim{MinId: StreamId{Ms: 4, Seq: 1}}, trimmed: 4},
+		{name: "MINID before the first", entries: 10, trim: StreamTrim{MinId: StreamId{Ms: 0, Seq: 1}}, trimmed: 0},
+		// approximate trimming only removes whole nodes of 100 entries
+		{name: "~ MAXLEN within a node", entries: 150, trim: StreamTrim{MaxLen: true, Threshold: 100, Approx: true}, trimmed: 0},
+		{name: "~ MAXLEN", entries: 250, trim: StreamTrim{MaxLen: true, Threshold: 10, Approx: true}, trimmed: 200},
+		{name: "~ MINID", entries: 250, trim: StreamTrim{MinId: StreamId{Ms: 150}, Approx: true}, trimmed: 100},
+		{name: "~ LIMIT", entries: 350, trim: StreamTrim{MaxLen: true, Approx: true, Limit: 250}, trimmed: 200},
+		{name: "~ LIMIT under a node", entries: 250, trim: StreamTrim{MaxLen: true, Approx: true, Limit: 50}, trimmed: 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// arrange
+			kv := newStreamTestStore(t, c.entries)
+
+			// act
+			trimmed, length, err := kv.XTrim("s", c.trim)
+
+			// assert
+			if err != nil {
+				t.Fatalf("error trimming: %v", err)
+			}
+
+			if trimmed != c.trimmed || length != c.entries-c.trimmed {
+				t.Errorf("expected %d trimmed leaving %d but got %d leaving %d", c.trimmed, c.entries-c.trimmed, trimmed, length)
+			}
+
+			if xlen, _ := kv.XLen("s"); xlen != length {
+				t.Errorf("expected XLEN %d but got %d", length, xlen)
+			}
+
+			if length > 0 {
+				if first, want := firstEntryId(t, kv), (StreamId{Ms: uint64(c.trimmed + 1)}); first != want {
+					t.Errorf("expected the first entry to be %s but got %s", want, first)
+				}
+			}
+		})
+	}
+}
+
+func TestXAddTrims(t *testing.T) {
+	// arrange
+	kv := newStreamTestStore(t, 5)
+
+	// act
+	result, err := kv.SetStream("s", "6-0", []string{"f", "v"}, StreamAddOptions{Trim: &StreamTrim{MaxLen: true, Threshold: 2}})
+
+	// assert
+	if err != nil {
+		t.Fatalf("error adding entry: %v", err)
+	}
+
+	if result.Id != "6-0" || result.Trimmed != 4 || result.Length != 2 {
+		t.Errorf("expected 6-0 added trimming 4 leaving 2 but got %s trimming %d leaving %d", result.Id, result.Trimmed, result.Length)
+	}
+
+	info, _ := kv.XInfoStream("s")
+	if info.EntriesAdded != 6 || info.MaxDeletedId != (StreamId{}) {
+		t.Errorf("expected 6 entries added with trimming not counted as deleted but got %d and %s", info.EntriesAdded, info.MaxDeletedId)
+	}
+}
+
+func TestXAddNoMkStream(t *testing.T) {
+	// arrange
+	kv := newStreamTestStore(t, 1)
+
+	// act
+	missing, missingErr := kv.SetStream("missing", "*", []string{"f", "v"}, StreamAddOptions{NoMkStream: true})
+	existing, existingErr := kv.SetStream("s", "2-0", []string{"f", "v"}, StreamAddOptions{NoMkStream: true})
+
+	// assert
+	if missingErr != nil || missing.Id != "" {
+		t.Errorf("expected nothing added to the missing stream but got %q, %v", missing.Id, missingErr)
+	}
+
+	if _, exists := kv.Get("missing"); exists {
+		t.Error("expected the missing stream not to be created")
+	}
+
+	if existingErr != nil || existing.Id != "2-0" {
+		t.Errorf("expected 2-0 added to the existing stream but got %q, %v", existing.Id, existingErr)
+	}
+}
+
+func TestXDel(t *testing.T) {
+	// arrange
+	kv := newStreamTestStore(t, 5)
+
+	// act
+	deleted, err := kv.XDel("s", []StreamId{{Ms: 2}, {Ms: 5}, {Ms: 5}, {Ms: 9}})
+
+	// assert
+	if err != nil || deleted != 2 {
+		t.Errorf("expected 2 deleted but got %d, %v", deleted, err)
+	}
+
+	if again, _ := kv.XDel("s", []StreamId{{Ms: 2}}); again != 0 {
+		t.Errorf("expected deleting again to delete nothing but got %d", again)
+	}
+
+	if missing, err := kv.XDel("missing", []StreamId{{Ms: 1}}); missing != 0 || err != nil {
+		t.Errorf("expected nothing deleted from a missing stream but got %d, %v", missing, err)
+	}
+
+	info, _ := kv.XInfoStream("s")
+	if info.Length != 3 || info.EntriesAdded != 5 {
+		t.Errorf("expected 3 entries of the 5 added but got %d of %d", info.Length, info.EntriesAdded)
+	}
+
+	if info.LastId != (StreamId{Ms: 5}) || info.MaxDeletedId != (StreamId{Ms: 5}) {
+		t.Errorf("expected the last & max deleted ids to be 5-0 but got %s and %s", info.LastId, info.MaxDeletedId)
+	}
+
+	// the last id is kept so a deleted id can't be added again
+	if _, err := kv.SetStream("s", "5-0", []string{"f", "v"}, StreamAddOptions{}); err == nil {
+		t.Error("expected adding the deleted last id to fail")
+	}
+}
+
+func TestXLenMissingStream(t *testing.T) {
+	// arrange
+	kv := NewKvStore(zerolog.Nop())
+	kv.Set("str", "v", ValueOptions{})
+
+	// act
+	length, err := kv.XLen("missing")
+	_, wrongTypeErr := kv.XLen("str")
+
+	// assert
+	if length != 0 || err != nil {
+		t.Errorf("expected 0 for a missing stream but got %d, %v", length, err)
+	}
+
+	if wrongTypeErr != ErrWrongType {
+		t.Errorf("expected WRONGTYPE but got %v", wrongTypeErr)
+	}
+}
+
+func TestStreamMetadataSurvivesRdb(t *testing.T) {
+	// arrange
+	kv := newStreamTestStore(t, 3)
+	kv.XDel("s", []StreamId{{Ms: 2}})
+	kv.XGroupCreate("s", "g", "0", false)
+	kv.XReadGroup("s", "g", "alice", ">", 0, false)
+	kv.XGroupCreateConsumer("s", "g", "bob")
+
+	// act
+	loaded := streamFromRdb(streamToRdb(kv.values["s"].(*Stream)))
+
+	// assert
+	if loaded.EntriesAdded != 3 || loaded.MaxDeletedId != (StreamId{Ms: 2}) {
+		t.Errorf("expected 3 added & 2-0 deleted but got %d and %s", loaded.EntriesAdded, loaded.MaxDeletedId)
+	}
+
+	g := loaded.Groups["g"]
+	if g.consumers["alice"].ActiveAt == 0 || g.consumers["bob"].ActiveAt != 0 {
+		t.Errorf("expected only alice to have been active but got %d and %d", g.consumers["alice"].ActiveAt, g.consumers["bob"].ActiveAt)
+	}
+}
@@ -0,0 +1,77 @@
+package store
+
+// Keys being WATCHed carry the store version at which they were last modified, so EXEC
+// can tell whether a key changed since it was watched. Only watched keys are tracked, so
+// unwatched writes cost a single map lookup
+type watchedKey struct {
+	watchers int
+	version  uint64
+}
+
+// state of a key when it was watched, see KvStore.Changed
+type WatchToken struct {
+	version uint64
+	existed bool
+}
+
+// must be called with the write lock held whenever the key's value or expiry changes
+func (k *KvStore) touch(key string) {
+	if w, exists := k.watched[key]; exists {
+		k.version++
+		w.version = k.version
+	}
+}
+
+// must be called with the write lock held when every key may have changed
+func (k *KvStore) touchAll() {
+	for _, w := range k.watched {
+		k.version++
+		w.version = k.version
+	}
+}
+
+// starts tracking the key for a client, each Watch must be paired with an Unwatch
+func (k *KvStore) Watch(key string) WatchToken {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	_, exists := k.lookup(key)
+
+	w, tracked := k.watched[key]
+	if !tracked {
+		w = &watchedKey{}
+		k.watched[key] = w
+	}
+	w.watchers++
+
+	return WatchToken{version: w.version, existed: exists}
+}
+
+func (k *KvStore) Unwatch(key string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	w, tracked := k.watched[key]
+	if !tracked {
+		return
+	}
+
+	w.watchers--
+	if w.watchers == 0 {
+		delete(k.watched, key)
+	}
+}
+
+// whether the key was modified, or has expired, since it was watched
+func (k *KvStore) Changed(key string, token WatchToken) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	w, tracked := k.watched[key]
+	if !tracked || w.version != token.version {
+		return true
+	}
+
+	// expired keys count as changed even before they're deleted
+	return token.existed && k.expired(key, currentMillis())
+}
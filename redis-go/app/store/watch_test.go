@@ -0,0 +1,114 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestWatchedKeyChanges(t *testing.T) {
+	cases := []struct {
+		name    string
+		modify  func(kv *KvStore)
+		changed bool
+	}{
+		{name: "nothing", modify: func(kv *KvStore) {}, changed: false},
+		{name: "read", modify: func(kv *KvStore) { kv.Get("k") }, changed: false},
+		{name: "other key set", modify: func(kv *KvStore) { kv.Set("other", "v", ValueOptions{}) }, changed: false},
+		{name: "set", modify: func(kv *KvStore) { kv.Set("k", "v2", ValueOptions{}) }, changed: true},
+		{name: "set to the same value", modify: func(kv *KvStore) { kv.Set("k", "v", ValueOptions{}) }, changed: true},
+		{name: "set NX not applied", modify: func(kv *KvStore) { kv.Set("k", "v2", ValueOptions{NX: true}) }, changed: false},
+		{name: "incremented", modify: func(kv *KvStore) { kv.Set("k", "1", ValueOptions{}); kv.IncrBy("k", 1) }, changed: true},
+		{name: "deleted", modify: func(kv *KvStore) { kv.Delete([]string{"k"}) }, changed: true},
+		{name: "other key deleted", modify: func(kv *KvStore) { kv.Delete([]string{"other"}) }, changed: false},
+		{name: "expiry set", modify: func(kv *KvStore) {
+			kv.Expire("k", currentMillis()+60_000, ExpireOptions{})
+		}, changed: true},
+		{name: "expiry XX not applied", modify: func(kv *KvStore) {
+			kv.Expire("k", currentMillis()+60_000, ExpireOptions{XX: true})
+		}, changed: false},
+		{name: "persisted without an expiry", modify: func(kv *KvStore) { kv.Persist("k") }, changed: false},
+		{name: "flushed", modify: func(kv *KvStore) { kv.Flush() }, changed: true},
+		{name: "swapped", modify: func(kv *KvStore) { kv.SwapWith(NewKvStore(zerolog.Nop())) }, changed: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// arrange
+			kv := NewKvStore(zerolog.Nop())
+			kv.Set("k", "v", ValueOptions{})
+			kv.Set("other", "v", ValueOptions{})
+			token := kv.Watch("k")
+
+			// act
+			c.modify(kv)
+
+			// assert
+			if got := kv.Changed("k", token); got != c.changed {
+				t.Errorf("expected changed to be %t but got %t", c.changed, got)
+			}
+		})
+	}
+}
+
+func TestWatchedMissingKeyChangesWhenCreated(t *testing.T) {
+	// arrange
+	kv := NewKvStore(zerolog.Nop())
+	token := kv.Watch("k")
+
+	// act
+	unchanged := kv.Changed("k", token)
+	kv.ListPush("k", []string{"a"}, false)
+
+	// assert
+	if unchanged {
+		t.Error("expected a missing key not to have changed")
+	}
+
+	if !kv.Changed("k", token) {
+		t.Error("expected creating the key to change it")
+	}
+}
+
+func TestWatchedKeyChangesWhenItExpires(t *testing.T) {
+	// arrange
+	kv := NewKvStore(zerolog.Nop())
+	kv.Set("k", "v", ValueOptions{ExpireAt: currentMillis() + 20})
+	token := kv.Watch("k")
+
+	// act
+	time.Sleep(30 * time.Millisecond)
+
+	// assert - without the key having been deleted yet
+	if !kv.Changed("k", token) {
+		t.Error("expected an expired key to have changed")
+	}
+}
+
+func TestWatchCountsEachWatcher(t *testing.T) {
+	// arrange
+	kv := NewKvStore(zerolog.Nop())
+	kv.Set("k", "v", ValueOptions{})
+	first := kv.Watch("k")
+	second := kv.Watch("k")
+
+	// act - the first watcher's EXEC or UNWATCH mustn't stop the key being tracked
+	kv.Unwatch("k")
+	kv.Set("k", "v2", ValueOptions{})
+
+	// assert
+	if !kv.Changed("k", second) {
+		t.Error("expected the change to be seen by the remaining watcher")
+	}
+
+	kv.Unwatch("k")
+	if len(kv.watched) != 0 {
+		t.Errorf("expected the key not to be tracked once unwatched but got %v", kv.watched)
+	}
+
+	// an untracked key always counts as changed
+	if !kv.Changed("k", first) {
+		t.Error("expected an untracked key to count as changed")
+	}
+}